@@ -0,0 +1,28 @@
+// Package cache provides the caches used in front of the repositories: an
+// in-process LRU and a client for Redis-protocol servers. Store adds typed
+// values, stampede protection and hit/miss metrics on top of either.
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Cache stores opaque values by key. Implementations must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the value stored under key and whether there was one.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores value under key for ttl. A zero ttl never expires.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// Nop is a Cache that stores nothing, used when caching is disabled.
+type Nop struct{}
+
+func (Nop) Get(ctx context.Context, key string) ([]byte, bool, error) { return nil, false, nil }
+
+func (Nop) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error { return nil }
+
+func (Nop) Delete(ctx context.Context, keys ...string) error { return nil }
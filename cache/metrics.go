@@ -0,0 +1,57 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Stats counts how a Store was used since the process started.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+	// Loads is how many misses went to the database. It is lower than Misses
+	// when concurrent misses shared a load.
+	Loads  uint64 `json:"loads"`
+	Errors uint64 `json:"errors"`
+}
+
+type counters struct {
+	hits, misses, loads, errors atomic.Uint64
+}
+
+var (
+	registryMu sync.Mutex
+	registry   = map[string]*counters{}
+)
+
+// register returns the counters for the store name, so stores that are created
+// again under the same name keep counting.
+func register(name string) *counters {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if c, ok := registry[name]; ok {
+		return c
+	}
+	c := &counters{}
+	registry[name] = c
+	return c
+}
+
+// Metrics returns the stats of every store by name.
+func Metrics() map[string]Stats {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	metrics := make(map[string]Stats, len(registry))
+	for name, c := range registry {
+		metrics[name] = Stats{
+			Hits:   c.hits.Load(),
+			Misses: c.misses.Load(),
+			Loads:  c.loads.Load(),
+			Errors: c.errors.Load(),
+		}
+	}
+
+	return metrics
+}
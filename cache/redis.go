@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// Redis is a Cache backed by a server speaking the Redis protocol (RESP). It
+// only implements the handful of commands the cache needs.
+type Redis struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	conns    chan *redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewRedis returns a client for the server at addr that keeps up to poolSize
+// idle connections. Connections are opened lazily.
+func NewRedis(addr, password string, db, poolSize int) *Redis {
+	return &Redis{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  time.Second,
+		conns:    make(chan *redisConn, poolSize),
+	}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.do(ctx, "GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("redis: unexpected GET reply %v", reply)
+	}
+	return value, true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		args = append(args, "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	}
+
+	_, err := r.do(ctx, args...)
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	_, err := r.do(ctx, append([]string{"DEL"}, keys...)...)
+	return err
+}
+
+// Ping checks that the server is reachable.
+func (r *Redis) Ping(ctx context.Context) error {
+	_, err := r.do(ctx, "PING")
+	return err
+}
+
+func (r *Redis) do(ctx context.Context, args ...string) (interface{}, error) {
+	conn, err := r.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.command(r.deadline(ctx), args...)
+	if err != nil {
+		// The connection may be left mid-reply, so never reuse it.
+		conn.conn.Close()
+		return nil, err
+	}
+
+	r.put(conn)
+
+	if replyErr, ok := reply.(redisError); ok {
+		return nil, replyErr
+	}
+	return reply, nil
+}
+
+func (r *Redis) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case conn := <-r.conns:
+		return conn, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: r.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", r.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+	if r.password != "" {
+		if err := conn.expectOK(r.deadline(ctx), "AUTH", r.password); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+	if r.db != 0 {
+		if err := conn.expectOK(r.deadline(ctx), "SELECT", strconv.Itoa(r.db)); err != nil {
+			netConn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (r *Redis) put(conn *redisConn) {
+	select {
+	case r.conns <- conn:
+	default:
+		conn.conn.Close()
+	}
+}
+
+func (r *Redis) deadline(ctx context.Context) time.Time {
+	deadline := time.Now().Add(r.timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		return ctxDeadline
+	}
+	return deadline
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+func (c *redisConn) expectOK(deadline time.Time, args ...string) error {
+	reply, err := c.command(deadline, args...)
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := reply.(redisError); ok {
+		return replyErr
+	}
+	return nil
+}
+
+// command sends args as a RESP array of bulk strings and reads the reply.
+func (c *redisConn) command(deadline time.Time, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+
+	return c.read()
+}
+
+// read parses one RESP reply. Bulk strings are returned as []byte, a nil bulk
+// string as nil and error replies as redisError.
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, errors.New("redis: malformed reply")
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return redisError(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil || size < 0 {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil || count < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
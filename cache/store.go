@@ -0,0 +1,140 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"golang.org/x/sync/singleflight"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Store caches values of type T as JSON under keys prefixed with its name.
+type Store[T any] struct {
+	cache Cache
+	name  string
+	ttl   time.Duration
+	stats *counters
+
+	group singleflight.Group
+
+	mu sync.Mutex
+	// loading tracks the keys being loaded and whether they were invalidated
+	// meanwhile, in which case the loaded value is stale and isn't cached.
+	loading map[string]bool
+}
+
+func NewStore[T any](c Cache, name string, ttl time.Duration) *Store[T] {
+	return &Store[T]{
+		cache:   c,
+		name:    name,
+		ttl:     ttl,
+		stats:   register(name),
+		loading: make(map[string]bool),
+	}
+}
+
+// Get returns the value cached under id, or loads and caches it. Concurrent
+// misses for the same id share a single load so an expired hot key doesn't send
+// a stampede of queries to the database.
+func (s *Store[T]) Get(ctx context.Context, id string, load func(ctx context.Context) (T, error)) (T, error) {
+	key := s.key(id)
+
+	raw, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		// A broken cache must not take the API down with it.
+		s.stats.errors.Add(1)
+		log.Printf("Cache %s: %v", s.name, err)
+	}
+	if ok {
+		var value T
+		if err := json.Unmarshal(raw, &value); err == nil {
+			s.stats.hits.Add(1)
+			return value, nil
+		}
+	}
+	s.stats.misses.Add(1)
+
+	shared, err, _ := s.group.Do(key, func() (interface{}, error) {
+		return s.load(context.WithoutCancel(ctx), key, load)
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	// Every caller decodes its own copy so they can't modify each other's value.
+	var value T
+	err = json.Unmarshal(shared.([]byte), &value)
+	return value, err
+}
+
+// Invalidate drops the values cached under ids.
+func (s *Store[T]) Invalidate(ctx context.Context, ids ...string) {
+	keys := make([]string, 0, len(ids))
+
+	s.mu.Lock()
+	for _, id := range ids {
+		key := s.key(id)
+		if _, ok := s.loading[key]; ok {
+			s.loading[key] = true
+		}
+		keys = append(keys, key)
+	}
+	s.mu.Unlock()
+
+	if err := s.cache.Delete(ctx, keys...); err != nil {
+		s.stats.errors.Add(1)
+		log.Printf("Cache %s: %v", s.name, err)
+	}
+}
+
+func (s *Store[T]) load(ctx context.Context, key string, load func(ctx context.Context) (T, error)) ([]byte, error) {
+	s.mu.Lock()
+	s.loading[key] = false
+	s.mu.Unlock()
+
+	s.stats.loads.Add(1)
+	value, err := load(ctx)
+
+	s.mu.Lock()
+	stale := s.loading[key]
+	delete(s.loading, key)
+	s.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if !stale {
+		if err := s.cache.Set(ctx, key, raw, s.jitteredTTL()); err != nil {
+			s.stats.errors.Add(1)
+			log.Printf("Cache %s: %v", s.name, err)
+		}
+	}
+
+	return raw, nil
+}
+
+func (s *Store[T]) key(id string) string {
+	return s.name + ":" + id
+}
+
+// jitteredTTL spreads expiry over ±10% of the TTL so keys cached together don't
+// all expire at once.
+func (s *Store[T]) jitteredTTL() time.Duration {
+	if s.ttl <= 0 {
+		return s.ttl
+	}
+	spread := int64(s.ttl) / 5
+	if spread == 0 {
+		return s.ttl
+	}
+	return s.ttl - time.Duration(spread/2) + time.Duration(rand.Int63n(spread))
+}
@@ -0,0 +1,449 @@
+// Command fiabesco-admin runs operational tasks against the database configured
+// for the server.
+//
+//	go run ./cmd/fiabesco-admin <command> [-dry-run] [-json] [flags] [args]
+//
+// Users are referred to by ID, email or handle. Run a command with -h to see
+// its flags.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/internal/admin"
+	"github.com/edisss1/fiabesco-backend/internal/config"
+	"github.com/edisss1/fiabesco-backend/internal/migrations"
+	"github.com/edisss1/fiabesco-backend/internal/reconcile"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/cached"
+	"github.com/edisss1/fiabesco-backend/repository/mongodb"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, env *env, flags *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"create-user":          {"-email EMAIL -password PASSWORD [-first-name NAME] [-last-name NAME] [-handle HANDLE] [-roles ROLE,...]", createUser},
+	"suspend-user":         {"[-lift] USER", suspendUser},
+	"delete-user":          {"USER", deleteUser},
+	"reset-password":       {"[-password PASSWORD] USER", resetPassword},
+	"grant-role":           {"[-revoke] USER ROLE", grantRole},
+	"migrate":              {"[up|status]", migrate},
+	"reconcile":            {"[-batch N]", reconcileCounters},
+	"purge-media":          {"[-min-age DURATION]", purgeMedia},
+	"export-user":          {"[-o FILE] USER", exportUser},
+	"import-user":          {"[-i FILE]", importUser},
+	"inspect-conversation": {"CONVERSATION_ID", inspectConversation},
+}
+
+// env holds what every command shares.
+type env struct {
+	repos  *repository.Repositories
+	dryRun bool
+	json   bool
+}
+
+// output prints v as JSON with -json and otherwise prints text.
+func (e *env) output(v interface{}, text string, args ...interface{}) error {
+	if e.json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	if e.dryRun {
+		text = "[dry run] " + text
+	}
+	fmt.Printf(text+"\n", args...)
+	return nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", name)
+		usage()
+		os.Exit(2)
+	}
+
+	e := &env{}
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.BoolVar(&e.dryRun, "dry-run", false, "report what would change without changing it")
+	flags.BoolVar(&e.json, "json", false, "print the result as JSON")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: fiabesco-admin %s [-dry-run] [-json] %s\n", name, cmd.usage)
+		flags.PrintDefaults()
+	}
+
+	err := cmd.run(context.Background(), e, flags, os.Args[2:])
+	if errors.Is(err, errUsage) {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("usage")
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "usage: fiabesco-admin <command> [-dry-run] [-json] [flags] [args]")
+	fmt.Fprintln(os.Stderr, "\ncommands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-21s %s\n", name, commands[name].usage)
+	}
+}
+
+// parse parses the flags, checks that there are n positional arguments, or any
+// number when n is negative, and connects to the database.
+func (e *env) parse(flags *flag.FlagSet, args []string, n int) ([]string, error) {
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if n >= 0 && flags.NArg() != n {
+		return nil, errUsage
+	}
+
+	config.LoadEnv()
+	config.ConnectDB()
+	// Going through the cache invalidates what the server cached when it is shared.
+	e.repos = cached.Wrap(mongodb.New(db.Database), config.GetCache())
+
+	return flags.Args(), nil
+}
+
+func findUser(ctx context.Context, e *env, ref string) (types.User, error) {
+	user, err := admin.FindUser(ctx, e.repos, ref)
+	if errors.Is(err, repository.ErrNotFound) {
+		return user, fmt.Errorf("user %s not found", ref)
+	}
+	return user, err
+}
+
+// userSummary is what create-user and suspend-user print with -json, leaving
+// out the password hash and the profile.
+type userSummary struct {
+	ID          primitive.ObjectID `json:"_id"`
+	Email       string             `json:"email"`
+	Handle      string             `json:"handle"`
+	Roles       []string           `json:"roles,omitempty"`
+	SuspendedAt *time.Time         `json:"suspendedAt,omitempty"`
+}
+
+func summarize(user types.User) userSummary {
+	return userSummary{ID: user.ID, Email: user.Email, Handle: user.Handle, Roles: user.Roles, SuspendedAt: user.SuspendedAt}
+}
+
+func createUser(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	var input admin.NewUser
+	var roles string
+	flags.StringVar(&input.Email, "email", "", "email address")
+	flags.StringVar(&input.Password, "password", "", "password")
+	flags.StringVar(&input.FirstName, "first-name", "", "first name")
+	flags.StringVar(&input.LastName, "last-name", "", "last name")
+	flags.StringVar(&input.Handle, "handle", "", "handle, generated when empty")
+	flags.StringVar(&roles, "roles", "", "comma-separated roles: "+strings.Join(types.Roles, ", "))
+	if _, err := e.parse(flags, args, 0); err != nil {
+		return err
+	}
+	if input.Email == "" || input.Password == "" {
+		return errUsage
+	}
+	if roles != "" {
+		input.Roles = strings.Split(roles, ",")
+	}
+
+	user, err := admin.CreateUser(ctx, e.repos, input, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return e.output(summarize(user), "Created user %s (%s, @%s)", user.ID.Hex(), user.Email, user.Handle)
+}
+
+func suspendUser(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	lift := flags.Bool("lift", false, "lift the suspension")
+	args, err := e.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	user, err := findUser(ctx, e, args[0])
+	if err != nil {
+		return err
+	}
+	user, err = admin.Suspend(ctx, e.repos, user, !*lift, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	if *lift {
+		return e.output(summarize(user), "Lifted the suspension of %s", user.ID.Hex())
+	}
+	return e.output(summarize(user), "Suspended %s", user.ID.Hex())
+}
+
+func deleteUser(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	args, err := e.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	user, err := findUser(ctx, e, args[0])
+	if err != nil {
+		return err
+	}
+	report, err := admin.DeleteUser(ctx, e.repos, user, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return e.output(report, "Deleted %s with %d posts (%d interactions, %d revisions), %d drafts, %d collections, %d comments, %d likes, %d saves, %d reposts, %d follows, %d followers, %d follow requests, %d close friends, %d blocks, %d mutes, %d dismissed suggestions and %d timeline entries",
+		user.ID.Hex(), report.Posts, report.Interactions, report.Revisions, report.Drafts, report.Collections, report.Comments, report.Likes, report.Saves, report.Reposts, report.Following, report.Followers, report.FollowRequests, report.CloseFriends, report.Blocks, report.Mutes, report.Dismissals, report.TimelineEntries)
+}
+
+func resetPassword(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	password := flags.String("password", "", "new password, generated when empty")
+	args, err := e.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	user, err := findUser(ctx, e, args[0])
+	if err != nil {
+		return err
+	}
+	newPassword, err := admin.ResetPassword(ctx, e.repos, user, *password, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	result := map[string]string{"userID": user.ID.Hex()}
+	if *password == "" {
+		result["password"] = newPassword
+		return e.output(result, "Reset the password of %s to %s", user.ID.Hex(), newPassword)
+	}
+	return e.output(result, "Reset the password of %s", user.ID.Hex())
+}
+
+func grantRole(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	revoke := flags.Bool("revoke", false, "remove the role instead")
+	args, err := e.parse(flags, args, 2)
+	if err != nil {
+		return err
+	}
+
+	user, err := findUser(ctx, e, args[0])
+	if err != nil {
+		return err
+	}
+	roles, err := admin.GrantRole(ctx, e.repos, user, args[1], *revoke, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return e.output(map[string]interface{}{"userID": user.ID.Hex(), "roles": roles},
+		"Roles of %s: %s", user.ID.Hex(), strings.Join(roles, ", "))
+}
+
+func migrate(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	args, err := e.parse(flags, args, -1)
+	if err != nil {
+		return err
+	}
+	command := "up"
+	if len(args) > 0 {
+		command = args[0]
+	}
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(ctx, db.Database, e.dryRun)
+		if err != nil {
+			return err
+		}
+		lines := make([]string, 0, len(applied))
+		for _, migration := range applied {
+			lines = append(lines, fmt.Sprintf("%4d  %s", migration.Version, migration.Description))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "Database is up to date")
+		}
+		return e.output(applied, "%s", strings.Join(lines, "\n"))
+	case "status":
+		statuses, err := migrations.List(ctx, db.Database)
+		if err != nil {
+			return err
+		}
+		lines := make([]string, 0, len(statuses))
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied != nil {
+				applied = status.Applied.AppliedAt.Format(time.RFC3339)
+			}
+			lines = append(lines, fmt.Sprintf("%4d  %-25s  %s", status.Migration.Version, applied, status.Migration.Description))
+		}
+		return e.output(statuses, "%s", strings.Join(lines, "\n"))
+	default:
+		return errUsage
+	}
+}
+
+func reconcileCounters(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	batchSize := flags.Int64("batch", reconcile.DefaultBatchSize, "number of documents checked per query")
+	if _, err := e.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	report, err := reconcile.Run(ctx, e.repos, reconcile.Options{BatchSize: *batchSize, DryRun: e.dryRun})
+	if err != nil {
+		return err
+	}
+
+	return e.output(report, "Checked %d posts and %d users, found %d discrepancies, fixed %d",
+		report.PostsChecked, report.UsersChecked, len(report.Discrepancies), report.Fixed())
+}
+
+func purgeMedia(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	minAge := flags.Duration("min-age", 24*time.Hour, "keep files uploaded more recently")
+	if _, err := e.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	report, err := admin.PurgeMedia(ctx, e.repos, *minAge, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return e.output(report, "Checked %d files, purged %d orphans (%d bytes)", report.Checked, len(report.Orphans), report.Bytes)
+}
+
+func exportUser(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	output := flags.String("o", "", "file to write, stdout when empty")
+	args, err := e.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+
+	user, err := findUser(ctx, e, args[0])
+	if err != nil {
+		return err
+	}
+	export, err := admin.ExportUser(ctx, e.repos, user)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+func importUser(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	input := flags.String("i", "", "file to read, stdin when empty")
+	if _, err := e.parse(flags, args, 0); err != nil {
+		return err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "" {
+		file, err := os.Open(*input)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	var export admin.Export
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return fmt.Errorf("invalid export: %w", err)
+	}
+
+	report, err := admin.ImportUser(ctx, e.repos, export, e.dryRun)
+	if err != nil {
+		return err
+	}
+
+	return e.output(report, "Imported %s with %d posts, %d drafts (%d uploads), %d comments, %d likes, %d saves, %d collections (%d items), %d reposts, %d follows, %d close friends, %d follow requests, %d blocks and %d mutes (%d skipped)",
+		report.UserID.Hex(), report.Posts, report.Drafts, report.Uploads, report.Comments, report.Likes, report.Saves, report.Collections, report.CollectionItems, report.Reposts, report.Following, report.CloseFriends, report.FollowRequests, report.Blocks, report.Mutes, report.Skipped)
+}
+
+func inspectConversation(ctx context.Context, e *env, flags *flag.FlagSet, args []string) error {
+	args, err := e.parse(flags, args, 1)
+	if err != nil {
+		return err
+	}
+	id, err := utils.ParseHexID(args[0])
+	if err != nil {
+		return errUsage
+	}
+
+	dump, err := admin.InspectConversation(ctx, e.repos, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("conversation %s not found", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	names := map[string]string{}
+	participants := make([]string, 0, len(dump.Conversation.Participants))
+	for _, p := range dump.Conversation.Participants {
+		names[p.ID.Hex()] = p.UserName
+		participants = append(participants, p.UserName+" ("+p.ID.Hex()+")")
+	}
+
+	lines := []string{fmt.Sprintf("Conversation %s between %s, %d messages", id.Hex(), strings.Join(participants, ", "), len(dump.Messages))}
+	for _, m := range dump.Messages {
+		sender := names[m.SenderID.Hex()]
+		if sender == "" {
+			sender = m.SenderID.Hex()
+		}
+		edited := ""
+		if m.IsEdited {
+			edited = " (edited)"
+		}
+		lines = append(lines, fmt.Sprintf("%s  %s: %s%s", m.CreatedAt.Format(time.RFC3339), sender, m.Content, edited))
+	}
+
+	return e.output(dump, "%s", strings.Join(lines, "\n"))
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
-	"github.com/edisss1/fiabesco-backend/handlers/ws"
+	"context"
+	"github.com/edisss1/fiabesco-backend/db"
 	"github.com/edisss1/fiabesco-backend/internal/config"
+	"github.com/edisss1/fiabesco-backend/internal/migrations"
+	"github.com/edisss1/fiabesco-backend/internal/publish"
+	"github.com/edisss1/fiabesco-backend/internal/ranking"
+	"github.com/edisss1/fiabesco-backend/internal/reconcile"
 	"github.com/edisss1/fiabesco-backend/internal/server"
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/websocket/v2"
+	"github.com/edisss1/fiabesco-backend/internal/timeline"
+	"github.com/edisss1/fiabesco-backend/repository/cached"
+	"github.com/edisss1/fiabesco-backend/repository/mongodb"
 	"log"
 )
 
@@ -13,17 +19,23 @@ func main() {
 	config.LoadEnv()
 	config.ConnectDB()
 
-	app := server.Setup()
-
-	app.Use(func(c *fiber.Ctx) error {
-		if websocket.IsWebSocketUpgrade(c) {
-			return c.Next()
+	if config.MigrateOnStart() {
+		if _, err := migrations.Up(context.Background(), db.Database, false); err != nil {
+			log.Fatalf("Failed to apply migrations: %v", err)
 		}
-		return c.SendStatus(fiber.StatusUpgradeRequired)
+	}
+
+	repos := cached.Wrap(mongodb.New(db.Database), config.GetCache())
+	timeline.Precompute(config.GetTimelinePrecompute())
+	ranking.Configure(config.GetRankingOptions())
+
+	if interval := config.GetReconcileInterval(); interval > 0 {
+		go reconcile.Schedule(context.Background(), repos, interval, reconcile.Options{})
+	}
 
-	})
+	go publish.Schedule(context.Background(), repos, config.GetPublishInterval())
 
-	app.Get("/ws", websocket.New(ws.HandleWS))
+	app := server.Setup(repos)
 
 	port := config.GetPort()
 
@@ -0,0 +1,60 @@
+// Command migrate applies or lists the database migrations.
+//
+//	go run ./cmd/migrate [-dry-run] [up|status]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/internal/config"
+	"github.com/edisss1/fiabesco-backend/internal/migrations"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only print the migrations that would be applied")
+	flag.Parse()
+
+	command := flag.Arg(0)
+	if command == "" {
+		command = "up"
+	}
+
+	config.LoadEnv()
+	config.ConnectDB()
+
+	ctx := context.Background()
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(ctx, db.Database, *dryRun)
+		for _, migration := range applied {
+			fmt.Printf("%4d  %s\n", migration.Version, migration.Description)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("Database is up to date")
+		}
+	case "status":
+		statuses, err := migrations.List(ctx, db.Database)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied != nil {
+				applied = status.Applied.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-25s  %s\n", status.Migration.Version, applied, status.Migration.Description)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, expected up or status\n", command)
+		os.Exit(2)
+	}
+}
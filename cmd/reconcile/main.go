@@ -0,0 +1,56 @@
+// Command reconcile recomputes the like, comment, repost, quote and follow
+// counters and fixes the ones that drifted.
+//
+//	go run ./cmd/reconcile [-dry-run] [-batch 500] [-json]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/internal/config"
+	"github.com/edisss1/fiabesco-backend/internal/reconcile"
+	"github.com/edisss1/fiabesco-backend/repository/cached"
+	"github.com/edisss1/fiabesco-backend/repository/mongodb"
+	"log"
+	"os"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report discrepancies without fixing them")
+	batchSize := flag.Int64("batch", reconcile.DefaultBatchSize, "number of documents checked per query")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	config.LoadEnv()
+	config.ConnectDB()
+
+	report, err := reconcile.Run(context.Background(), cached.Wrap(mongodb.New(db.Database), config.GetCache()), reconcile.Options{
+		BatchSize: *batchSize,
+		DryRun:    *dryRun,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, d := range report.Discrepancies {
+		status := "fixed"
+		if !d.Fixed {
+			status = "not fixed"
+		}
+		fmt.Printf("%s %s %s: stored %d, actual %d (%s)\n", d.Collection, d.ID.Hex(), d.Field, d.Stored, d.Actual, status)
+	}
+	fmt.Printf("Checked %d posts and %d users, found %d discrepancies, fixed %d\n",
+		report.PostsChecked, report.UsersChecked, len(report.Discrepancies), report.Fixed())
+}
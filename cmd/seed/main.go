@@ -0,0 +1,71 @@
+// Command seed fills the database with generated data for local development.
+// Running it again with the same flags only creates what is missing.
+//
+//	go run ./cmd/seed [-seed 1] [-users 50] [-posts 5] [-password password] [-dry-run] [-json]
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/internal/config"
+	"github.com/edisss1/fiabesco-backend/internal/seed"
+	"github.com/edisss1/fiabesco-backend/repository/cached"
+	"github.com/edisss1/fiabesco-backend/repository/mongodb"
+	"log"
+	"os"
+)
+
+func main() {
+	seedValue := flag.Int64("seed", 1, "selects the generated data set")
+	users := flag.Int("users", seed.DefaultUsers, "number of users")
+	posts := flag.Int("posts", seed.DefaultPostsPerUser, "number of posts per user")
+	password := flag.String("password", seed.DefaultPassword, "password of every seeded user")
+	dryRun := flag.Bool("dry-run", false, "only count what would be created")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	config.LoadEnv()
+	config.ConnectDB()
+
+	report, err := seed.Run(context.Background(), cached.Wrap(mongodb.New(db.Database), config.GetCache()), seed.Options{
+		Seed:         *seedValue,
+		Users:        *users,
+		PostsPerUser: *posts,
+		Password:     *password,
+		DryRun:       *dryRun,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	for _, row := range []struct {
+		name  string
+		count seed.Count
+	}{
+		{"users", report.Users},
+		{"follows", report.Follows},
+		{"posts", report.Posts},
+		{"likes", report.Likes},
+		{"comments", report.Comments},
+		{"reposts", report.Reposts},
+		{"conversations", report.Conversations},
+		{"messages", report.Messages},
+		{"portfolios", report.Portfolios},
+	} {
+		fmt.Printf("%-14s %6d created %6d existing\n", row.name, row.count.Created, row.count.Existing)
+	}
+	fmt.Printf("%-14s %6d uploaded\n", "images", report.Images)
+	fmt.Printf("Log in as seed%d.user0@example.com with password %q\n", *seedValue, *password)
+}
@@ -1,36 +1,37 @@
 package auth
 
 import (
-	"context"
-	"github.com/edisss1/fiabesco-backend/db"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"time"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
-func SignUp(c *fiber.Ctx) error {
+func (h *Handler) SignUp(c *fiber.Ctx) error {
 	var input types.User
 
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
-	collection = db.Database.Collection("users")
-
-	var existingUser types.User
 
-	filter := bson.M{"email": input.Email}
-
-	err := collection.FindOne(context.Background(), filter).Decode(&existingUser)
+	_, err := h.repos.Users.FindByEmail(c.UserContext(), input.Email)
 
 	if err == nil {
 		return c.Status(404).JSON(fiber.Map{"error": "Invalid credentials"})
 	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
 
 	handle := utils.GenerateHandle(24)
 
@@ -38,22 +39,21 @@ func SignUp(c *fiber.Ctx) error {
 	input.Password = hash
 	input.Handle = handle
 	input.CreatedAt = time.Now()
+	input.Roles = nil
+	input.SuspendedAt = nil
 
-	res, err := collection.InsertOne(context.Background(), input)
+	// The unique email index catches sign-ups racing past the check above.
+	err = h.repos.Users.Create(c.UserContext(), &input)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return c.Status(404).JSON(fiber.Map{"error": "Invalid credentials"})
+	}
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 	}
 
-	collection = db.Database.Collection("settings")
-
-	settings := types.Settings{
-		UserID:            res.InsertedID.(primitive.ObjectID),
-		Theme:             "light",
-		Language:          "en",
-		ProfileVisibility: "public",
-	}
+	settings := types.DefaultSettings(input.ID)
 
-	_, err = collection.InsertOne(context.Background(), settings)
+	err = h.repos.Settings.Create(c.UserContext(), &settings)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 	}
@@ -61,19 +61,14 @@ func SignUp(c *fiber.Ctx) error {
 	return c.Status(201).JSON(fiber.Map{"msg": "User created"})
 }
 
-func Login(c *fiber.Ctx) error {
+func (h *Handler) Login(c *fiber.Ctx) error {
 	var input types.User
 
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	collection = db.Database.Collection("users")
-
-	filter := bson.M{"email": input.Email}
-
-	var user types.User
-	err := collection.FindOne(context.Background(), filter).Decode(&user)
+	user, err := h.repos.Users.FindByEmail(c.UserContext(), input.Email)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "User not found"})
 	}
@@ -82,6 +77,10 @@ func Login(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Incorrect password"})
 	}
 
+	if user.SuspendedAt != nil {
+		return c.Status(403).JSON(fiber.Map{"error": "Account suspended"})
+	}
+
 	token, err := GenerateToken(user.ID.Hex())
 
 	if err != nil {
@@ -0,0 +1,526 @@
+package collections
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// MaxNameLength is the most characters a collection name may have.
+	MaxNameLength = 60
+	// MaxDescriptionLength is the most characters a collection description
+	// may have.
+	MaxDescriptionLength = 300
+	// coverCandidates is how many of the first items stand in for a cover that
+	// isn't set or can't be shown.
+	coverCandidates = 5
+)
+
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
+
+// collectionBody is what can be set on a collection. Fields that are left out
+// aren't changed.
+type collectionBody struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	Visibility  *string `json:"visibility"`
+}
+
+// fields validates the body and returns the bson fields it sets.
+func (b collectionBody) fields() (bson.M, error) {
+	fields := bson.M{}
+
+	if b.Name != nil {
+		name := strings.TrimSpace(*b.Name)
+		if name == "" || utf8.RuneCountInString(name) > MaxNameLength {
+			return nil, fiber.NewError(400, "Name must have 1 to 60 characters")
+		}
+		fields["name"] = name
+	}
+	if b.Description != nil {
+		description := strings.TrimSpace(*b.Description)
+		if utf8.RuneCountInString(description) > MaxDescriptionLength {
+			return nil, fiber.NewError(400, "Description must have at most 300 characters")
+		}
+		fields["description"] = description
+	}
+	if b.Visibility != nil {
+		if *b.Visibility != types.VisibilityPublic && *b.Visibility != types.VisibilityPrivate {
+			return nil, fiber.NewError(400, "Visibility must be public or private")
+		}
+		fields["visibility"] = *b.Visibility
+	}
+
+	return fields, nil
+}
+
+// CreateCollection creates a collection for the current user. Collections are
+// private unless visibility is public.
+func (h *Handler) CreateCollection(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	var body collectionBody
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+	if body.Name == nil {
+		return utils.RespondWithError(c, 400, "Name must have 1 to 60 characters")
+	}
+
+	fields, err := body.fields()
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	collection := types.Collection{
+		UserID:     userID,
+		Name:       fields["name"].(string),
+		Visibility: types.VisibilityPrivate,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if description, ok := fields["description"].(string); ok {
+		collection.Description = description
+	}
+	if visibility, ok := fields["visibility"].(string); ok {
+		collection.Visibility = visibility
+	}
+
+	if err := h.repos.Collections.Create(c.UserContext(), &collection); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to create collection "+err.Error())
+	}
+
+	return c.Status(201).JSON(fiber.Map{"collection": collection})
+}
+
+// GetUserCollections lists the collections of the user in the path, newest
+// first. Other users only see the public ones, and only when they may see the
+// user's posts.
+func (h *Handler) GetUserCollections(c *fiber.Ctx) error {
+	viewerID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+	ownerID, err := utils.ParseHexID(c.Params("userID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	ctx := c.UserContext()
+
+	if ownerID != viewerID {
+		visible, err := helpers.CanSeeProfile(ctx, h.repos, viewerID, ownerID)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Failed to fetch collections "+err.Error())
+		}
+		if !visible {
+			return c.Status(200).JSON([]types.Collection{})
+		}
+	}
+
+	collections, err := h.repos.Collections.ListByUser(ctx, ownerID, ownerID != viewerID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collections "+err.Error())
+	}
+	if err := h.describe(ctx, viewerID, collections); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collections "+err.Error())
+	}
+
+	return c.Status(200).JSON(nonNil(collections))
+}
+
+func (h *Handler) GetCollection(c *fiber.Ctx) error {
+	viewerID, collection, err := h.collection(c, false)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	collections := []types.Collection{collection}
+	if err := h.describe(c.UserContext(), viewerID, collections); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collection "+err.Error())
+	}
+
+	return c.Status(200).JSON(collections[0])
+}
+
+// UpdateCollection changes the name, description or visibility of one of the
+// current user's collections.
+func (h *Handler) UpdateCollection(c *fiber.Ctx) error {
+	_, collection, err := h.collection(c, true)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body collectionBody
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	fields, err := body.fields()
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+	if len(fields) == 0 {
+		return utils.RespondWithError(c, 400, "Nothing to update")
+	}
+
+	ctx := c.UserContext()
+
+	if err := h.repos.Collections.Update(ctx, collection.ID, fields); err != nil {
+		return utils.RespondWithFiberError(c, collectionNotFound(err))
+	}
+
+	collections := []types.Collection{collection}
+	if collections[0], err = h.repos.Collections.FindByID(ctx, collection.ID); err != nil {
+		return utils.RespondWithFiberError(c, collectionNotFound(err))
+	}
+	if err := h.describe(ctx, collection.UserID, collections); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to update collection "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"collection": collections[0]})
+}
+
+// DeleteCollection deletes one of the current user's collections. The posts in
+// it stay saved.
+func (h *Handler) DeleteCollection(c *fiber.Ctx) error {
+	_, collection, err := h.collection(c, true)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	if err := h.repos.Collections.Delete(c.UserContext(), collection.ID); err != nil {
+		return utils.RespondWithFiberError(c, collectionNotFound(err))
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Collection deleted successfully"})
+}
+
+// GetCollectionItems lists the posts of a collection in their order, leaving
+// out the ones the viewer can't see. Items are reordered at any time, so it
+// pages with ?page= instead of a cursor.
+func (h *Handler) GetCollectionItems(c *fiber.Ctx) error {
+	viewerID, collection, err := h.collection(c, false)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil || page.After != nil {
+		return utils.RespondWithError(c, 400, "Invalid page or limit")
+	}
+
+	ctx := c.UserContext()
+
+	items, err := h.repos.Collections.ListItems(ctx, collection.ID, page.Skip, page.Limit+1)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collection "+err.Error())
+	}
+
+	paged := utils.Paged[types.FeedItem]{HasMore: int64(len(items)) > page.Limit}
+	if paged.HasMore {
+		items = items[:page.Limit]
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		postIDs = append(postIDs, item.PostID)
+	}
+
+	if paged.Items, err = h.feedItems(ctx, viewerID, postIDs); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collection "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, paged)
+}
+
+// AddToCollection puts a post first in one of the current user's collections
+// and saves it if they haven't yet.
+func (h *Handler) AddToCollection(c *fiber.Ctx) error {
+	userID, collection, err := h.collection(c, true)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body struct {
+		PostID string `json:"postID"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+	postID, err := utils.ParseHexID(body.PostID)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid post ID")
+	}
+
+	ctx := c.UserContext()
+
+	if err := h.checkVisible(ctx, userID, postID); err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	saved, err := h.repos.Saves.Exists(ctx, postID, userID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to add to collection "+err.Error())
+	}
+
+	item := types.CollectionItem{CollectionID: collection.ID, UserID: userID, PostID: postID, AddedAt: time.Now()}
+
+	err = h.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.repos.Collections.AddItem(ctx, &item); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Collections.RemoveItem(ctx, item.CollectionID, item.PostID)
+		})
+
+		if saved {
+			return nil
+		}
+		return h.save(ctx, userID, postID)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "Post already in collection")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to add to collection "+err.Error())
+	}
+
+	return c.Status(201).JSON(fiber.Map{"item": item})
+}
+
+// RemoveFromCollection takes a post out of one of the current user's
+// collections. The post stays saved.
+func (h *Handler) RemoveFromCollection(c *fiber.Ctx) error {
+	_, collection, err := h.collection(c, true)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid post ID")
+	}
+
+	err = h.repos.Collections.RemoveItem(c.UserContext(), collection.ID, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not in collection")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to remove from collection "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Post removed from collection"})
+}
+
+// ReorderCollection puts the posts of one of the current user's collections in
+// the order of postIDs, which must list every post in it once.
+func (h *Handler) ReorderCollection(c *fiber.Ctx) error {
+	_, collection, err := h.collection(c, true)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body struct {
+		PostIDs []string `json:"postIDs"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	ctx := c.UserContext()
+
+	current, err := h.repos.Collections.ItemPostIDs(ctx, collection.ID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to reorder collection "+err.Error())
+	}
+
+	remaining := make(map[primitive.ObjectID]bool, len(current))
+	for _, postID := range current {
+		remaining[postID] = true
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(body.PostIDs))
+	for _, hexID := range body.PostIDs {
+		postID, err := utils.ParseHexID(hexID)
+		if err != nil || !remaining[postID] {
+			return utils.RespondWithError(c, 400, "postIDs must list every post of the collection once")
+		}
+		delete(remaining, postID)
+		postIDs = append(postIDs, postID)
+	}
+	if len(remaining) > 0 {
+		return utils.RespondWithError(c, 400, "postIDs must list every post of the collection once")
+	}
+
+	if err := h.repos.Collections.Reorder(ctx, collection.ID, postIDs); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to reorder collection "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"postIDs": postIDs})
+}
+
+// SetCollectionCover makes a post of one of the current user's collections its
+// cover. Without a postID the cover goes back to the first item.
+func (h *Handler) SetCollectionCover(c *fiber.Ctx) error {
+	_, collection, err := h.collection(c, true)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body struct {
+		PostID string `json:"postID"`
+	}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	ctx := c.UserContext()
+	fields := bson.M{"coverPostID": nil}
+
+	if body.PostID != "" {
+		postID, err := utils.ParseHexID(body.PostID)
+		if err != nil {
+			return utils.RespondWithError(c, 400, "Invalid post ID")
+		}
+
+		postIDs, err := h.repos.Collections.ItemPostIDs(ctx, collection.ID)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Failed to set cover "+err.Error())
+		}
+		if !slices.Contains(postIDs, postID) {
+			return utils.RespondWithError(c, 404, "Post not in collection")
+		}
+		fields["coverPostID"] = postID
+	}
+
+	if err := h.repos.Collections.Update(ctx, collection.ID, fields); err != nil {
+		return utils.RespondWithFiberError(c, collectionNotFound(err))
+	}
+
+	collections := []types.Collection{collection}
+	if collections[0], err = h.repos.Collections.FindByID(ctx, collection.ID); err != nil {
+		return utils.RespondWithFiberError(c, collectionNotFound(err))
+	}
+	if err := h.describe(ctx, collection.UserID, collections); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to set cover "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"collection": collections[0]})
+}
+
+// collection returns the current user and the collection named by the
+// collectionID parameter. Collections the user may not see, or doesn't own
+// when owned is set, are reported as not found.
+func (h *Handler) collection(c *fiber.Ctx, owned bool) (primitive.ObjectID, types.Collection, error) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return userID, types.Collection{}, fiber.NewError(400, "Invalid user ID")
+	}
+
+	collectionID, err := utils.ParseHexID(c.Params("collectionID"))
+	if err != nil {
+		return userID, types.Collection{}, fiber.NewError(400, "Invalid ID")
+	}
+
+	ctx := c.UserContext()
+
+	collection, err := h.repos.Collections.FindByID(ctx, collectionID)
+	if err != nil {
+		return userID, collection, collectionNotFound(err)
+	}
+	if collection.UserID == userID {
+		return userID, collection, nil
+	}
+	if owned || collection.Visibility != types.VisibilityPublic {
+		return userID, types.Collection{}, fiber.NewError(404, "Collection not found")
+	}
+
+	visible, err := helpers.CanSeeProfile(ctx, h.repos, userID, collection.UserID)
+	if err != nil {
+		return userID, types.Collection{}, err
+	}
+	if !visible {
+		return userID, types.Collection{}, fiber.NewError(404, "Collection not found")
+	}
+
+	return userID, collection, nil
+}
+
+// describe fills in how many items each collection has and its cover as
+// viewerID sees it: the first image of the cover post, or of the first items
+// when there is no cover post or the viewer can't see it.
+func (h *Handler) describe(ctx context.Context, viewerID primitive.ObjectID, collections []types.Collection) error {
+	candidates := make([][]primitive.ObjectID, len(collections))
+	var postIDs []primitive.ObjectID
+
+	for i, collection := range collections {
+		items, err := h.repos.Collections.ItemPostIDs(ctx, collection.ID)
+		if err != nil {
+			return err
+		}
+		collections[i].ItemsCount = len(items)
+
+		if collection.CoverPostID != nil && slices.Contains(items, *collection.CoverPostID) {
+			candidates[i] = append(candidates[i], *collection.CoverPostID)
+		}
+		candidates[i] = append(candidates[i], items[:min(coverCandidates, len(items))]...)
+		postIDs = append(postIDs, candidates[i]...)
+	}
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	visible, err := quote.Find(ctx, h.repos, viewerID, postIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range collections {
+		for _, postID := range candidates[i] {
+			if item, ok := visible[postID]; ok && len(item.Post.Images) > 0 {
+				collections[i].Cover = item.Post.Images[0]
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func nonNil(collections []types.Collection) []types.Collection {
+	if collections == nil {
+		return []types.Collection{}
+	}
+	return collections
+}
+
+// collectionNotFound turns repository.ErrNotFound into a 404 and leaves any
+// other error to become a 500.
+func collectionNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return fiber.NewError(404, "Collection not found")
+	}
+	return err
+}
@@ -0,0 +1,231 @@
+package collections
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"time"
+)
+
+// SavePost saves the post in the path for the current user.
+func (h *Handler) SavePost(c *fiber.Ctx) error {
+	userID, postID, err := h.visiblePost(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	ctx := c.UserContext()
+
+	err = h.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		return h.save(ctx, userID, postID)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "Post already saved")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to save the post "+err.Error())
+	}
+
+	return h.respondWithSavesCount(c, postID)
+}
+
+// UnsavePost removes the current user's save of the post in the path, which
+// also takes it out of their collections.
+func (h *Handler) UnsavePost(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid post ID")
+	}
+
+	ctx := c.UserContext()
+
+	err = h.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.repos.Saves.Delete(ctx, postID, userID); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Saves.Create(ctx, &types.Save{PostID: postID, UserID: userID, CreatedAt: time.Now()})
+		})
+
+		return h.repos.Posts.IncrementCounter(ctx, postID, repository.SavesCount, -1)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not saved")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to unsave the post "+err.Error())
+	}
+
+	if _, err := h.repos.Collections.RemoveSaved(ctx, userID, postID); err != nil {
+		log.Println("Error removing unsaved post from collections: ", err)
+	}
+
+	return h.respondWithSavesCount(c, postID)
+}
+
+// GetSavedPosts lists the posts the current user saved, the most recently
+// saved first. Posts they can no longer see are left out.
+func (h *Handler) GetSavedPosts(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	ctx := c.UserContext()
+
+	saves, err := h.repos.Saves.ListByUser(ctx, userID, page)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch saved posts "+err.Error())
+	}
+
+	// The cursor follows the saves, so posts left out don't end the list early.
+	saved := utils.NewPaged(saves, page, func(save types.Save) utils.Cursor {
+		return utils.Cursor{CreatedAt: save.CreatedAt, ID: save.ID}
+	})
+
+	postIDs := make([]primitive.ObjectID, 0, len(saved.Items))
+	for _, save := range saved.Items {
+		postIDs = append(postIDs, save.PostID)
+	}
+
+	items, err := h.feedItems(ctx, userID, postIDs)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch saved posts "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, utils.Paged[types.FeedItem]{Items: items, NextCursor: saved.NextCursor, HasMore: saved.HasMore})
+}
+
+// GetPostCollections tells whether the current user saved the post in the path
+// and which of their collections it is in.
+func (h *Handler) GetPostCollections(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid post ID")
+	}
+
+	ctx := c.UserContext()
+
+	saved, err := h.repos.Saves.Exists(ctx, postID, userID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collections "+err.Error())
+	}
+
+	collections, err := h.repos.Collections.ListContaining(ctx, userID, postID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collections "+err.Error())
+	}
+	if err := h.describe(ctx, userID, collections); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch collections "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"saved": saved, "collections": nonNil(collections)})
+}
+
+// save saves the post for the user and counts it. It must run in a
+// transaction.
+func (h *Handler) save(ctx context.Context, userID, postID primitive.ObjectID) error {
+	save := types.Save{PostID: postID, UserID: userID, CreatedAt: time.Now()}
+	if err := h.repos.Saves.Create(ctx, &save); err != nil {
+		return err
+	}
+
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return h.repos.Saves.Delete(ctx, postID, userID)
+	})
+
+	return h.repos.Posts.IncrementCounter(ctx, postID, repository.SavesCount, 1)
+}
+
+// visiblePost returns the current user and the post in the path, which they
+// must be able to see.
+func (h *Handler) visiblePost(c *fiber.Ctx) (userID, postID primitive.ObjectID, err error) {
+	if userID, err = utils.GetUserID(c); err != nil {
+		return userID, postID, fiber.NewError(400, "Invalid user ID")
+	}
+	if postID, err = utils.ParseHexID(c.Params("postID")); err != nil {
+		return userID, postID, fiber.NewError(400, "Invalid post ID")
+	}
+
+	return userID, postID, h.checkVisible(c.UserContext(), userID, postID)
+}
+
+// checkVisible returns a 404 when userID can't see postID.
+func (h *Handler) checkVisible(ctx context.Context, userID, postID primitive.ObjectID) error {
+	post, err := h.repos.Posts.FindByID(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fiber.NewError(404, "Post not found")
+	}
+	if err != nil {
+		return err
+	}
+
+	visible, err := helpers.CanReach(ctx, h.repos, userID, post)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return fiber.NewError(404, "Post not found")
+	}
+
+	return nil
+}
+
+func (h *Handler) respondWithSavesCount(c *fiber.Ctx, postID primitive.ObjectID) error {
+	post, err := h.repos.Posts.FindByID(c.UserContext(), postID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to retrieve updated post: "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"savesCount": post.SavesCount})
+}
+
+// feedItems returns the posts viewerID may see among postIDs, in the order of
+// postIDs, with their quoted posts.
+func (h *Handler) feedItems(ctx context.Context, viewerID primitive.ObjectID, postIDs []primitive.ObjectID) ([]types.FeedItem, error) {
+	items := []types.FeedItem{}
+	if len(postIDs) == 0 {
+		return items, nil
+	}
+
+	visible, err := quote.Find(ctx, h.repos, viewerID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, postID := range postIDs {
+		if item, ok := visible[postID]; ok {
+			items = append(items, item)
+		}
+	}
+
+	embedded := make([]*types.FeedItem, 0, len(items))
+	for i := range items {
+		embedded = append(embedded, &items[i])
+	}
+	if err := quote.Embed(ctx, h.repos, viewerID, embedded); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
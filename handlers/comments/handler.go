@@ -2,27 +2,26 @@ package comments
 
 import (
 	"context"
-	"github.com/edisss1/fiabesco-backend/db"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/mention"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"net/http"
-	"strconv"
 	"time"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
 
-type CommentRes struct {
-	Comment  types.Comment `json:"comment"`
-	UserName string        `json:"userName"`
-	PhotoURL string        `json:"photoURL"`
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
 }
 
-func CommentPost(c *fiber.Ctx) error {
+func (h *Handler) CommentPost(c *fiber.Ctx) error {
 	id := c.Params("postID")
 	postID, err := utils.ParseHexID(id)
 	if err != nil {
@@ -46,81 +45,107 @@ func CommentPost(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	collection = db.Database.Collection("comments")
+	post, err := h.repos.Posts.FindByID(c.UserContext(), postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Error finding post "+err.Error())
+	}
+
+	reachable, err := helpers.CanReach(c.UserContext(), h.repos, userID, post)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Error finding post "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+
+	mentions, err := mention.Resolve(c.UserContext(), h.repos, userID, body.Content)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Error resolving mentions "+err.Error())
+	}
 
 	newComment := types.Comment{
 		Content:   body.Content,
+		Mentions:  mentions,
 		PostID:    postID,
 		UserID:    userID,
 		CreatedAt: time.Now(),
 	}
 
-	res, err := collection.InsertOne(context.Background(), newComment)
+	err = h.repos.Transactions.WithTransaction(c.UserContext(), func(ctx context.Context) error {
+		if err := h.repos.Comments.Create(ctx, &newComment); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Comments.Delete(ctx, newComment.ID)
+		})
+
+		return h.repos.Posts.IncrementCounter(ctx, postID, repository.CommentsCount, 1)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error inserting comment")
 	}
 
-	newComment.ID = res.InsertedID.(primitive.ObjectID)
-
-	collection = db.Database.Collection("posts")
-	filter := bson.M{"_id": postID}
-	update := bson.M{"$inc": bson.M{"commentsCount": 1}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	mention.NotifyComment(c.UserContext(), h.repos, newComment, nil)
 
 	return c.Status(201).JSON(newComment)
 
 }
 
-func GetComments(c *fiber.Ctx) error {
+func (h *Handler) GetComments(c *fiber.Ctx) error {
 	id := c.Params("postID")
 	postID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid ID")
 	}
 
-	pageParam := c.Query("page", "1")
-	l := 10
-	p, _ := strconv.Atoi(pageParam)
-	skip := int64(p*l - l)
-	limit := int64(l)
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid cursor or limit")
+	}
 
-	pipeline := utils.NewPipeline().
-		Match(bson.D{{"postID", postID}}).
-		Sort("createdAt", -1).
-		Skip(skip).Limit(limit).
-		Lookup("users", "userID", "_id", "user").
-		Unwind("$user", true).
-		Project(bson.D{
-			{"comment", "$$ROOT"},
-			{"userName", bson.D{{"$concat", bson.A{"$user.firstName", " ", "$user.lastName"}}}},
-			{"photoURL", "$user.photoURL"},
-		}).Build()
+	ctx := c.UserContext()
 
-	collection = db.Database.Collection("comments")
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	viewerID, _ := utils.GetUserID(c)
+
+	post, err := h.repos.Posts.FindByID(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get comments "+err.Error())
 	}
 
-	var comments []CommentRes
+	reachable, err := helpers.CanReach(ctx, h.repos, viewerID, post)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get comments "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
 
-	for cursor.Next(context.Background()) {
-		var comment CommentRes
-		if err := cursor.Decode(&comment); err != nil {
-			return utils.RespondWithError(c, 500, "Failed to decode comment")
-		}
-		if comment.PhotoURL != "" {
-			comment.PhotoURL = utils.BuildImgURL(comment.PhotoURL)
-		}
-		comments = append(comments, comment)
+	muted, err := helpers.MutedIDs(ctx, h.repos, viewerID, types.MuteScopeComments)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get comments "+err.Error())
 	}
 
-	return c.Status(http.StatusOK).JSON(comments)
+	comments, err := h.repos.Comments.ListByPost(ctx, postID, page, muted)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Failed to get comments "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, utils.NewPaged(comments, page, func(item types.CommentItem) utils.Cursor {
+		return utils.Cursor{CreatedAt: item.Comment.CreatedAt, ID: item.Comment.ID}
+	}))
 
 }
 
-func EditComment(c *fiber.Ctx) error {
+func (h *Handler) EditComment(c *fiber.Ctx) error {
 	id := c.Params("commentID")
 	commentID, err := utils.ParseHexID(id)
 	if err != nil {
@@ -135,38 +160,40 @@ func EditComment(c *fiber.Ctx) error {
 	if err := c.BodyParser(&body); err != nil {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
-	collection = db.Database.Collection("comments")
 
-	var comment types.Comment
-	filter := bson.M{"_id": commentID}
-	update := bson.M{"$set": bson.M{"content": body.NewContent}}
-
-	err = collection.FindOne(context.Background(), filter).Decode(&comment)
+	comment, err := h.repos.Comments.FindByID(c.UserContext(), commentID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error decoding comment"+err.Error())
 	}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	mentions, err := mention.Resolve(c.UserContext(), h.repos, comment.UserID, body.NewContent)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Error resolving mentions "+err.Error())
+	}
+
+	err = h.repos.Comments.UpdateContent(c.UserContext(), commentID, body.NewContent, mentions)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating comment"+err.Error())
 	}
 
+	previous := comment.Mentions
+	comment.Content, comment.Mentions = body.NewContent, mentions
+	mention.NotifyComment(c.UserContext(), h.repos, comment, previous)
+
 	return c.Status(200).JSON(fiber.Map{"msg": "Comment updated successfully"})
 }
 
-func DeleteComment(c *fiber.Ctx) error {
+func (h *Handler) DeleteComment(c *fiber.Ctx) error {
 	id := c.Params("commentID")
 	commentID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
 	}
 
-	collection := db.Database.Collection("comments")
-	filter := bson.M{"_id": commentID}
-
-	var comment types.Comment
-
-	err = collection.FindOne(context.Background(), filter).Decode(&comment)
+	comment, err := h.repos.Comments.FindByID(c.UserContext(), commentID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Comment not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error decoding comment "+err.Error())
 	}
@@ -180,18 +207,26 @@ func DeleteComment(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 401, "Unauthorized")
 	}
 
-	collection = db.Database.Collection("posts")
-	filter = bson.M{"_id": comment.PostID}
-	update := bson.M{"$inc": bson.M{"commentsCount": -1}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Error updating post "+err.Error())
+	err = h.repos.Transactions.WithTransaction(c.UserContext(), func(ctx context.Context) error {
+		if err := h.repos.Comments.Delete(ctx, commentID); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Comments.Create(ctx, &comment)
+		})
+
+		// The post may have been deleted since; its comments don't count anywhere then.
+		err := h.repos.Posts.IncrementCounter(ctx, comment.PostID, repository.CommentsCount, -1)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Comment not found")
 	}
-
-	_, err = collection.DeleteOne(context.Background(), filter)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Error deleting post "+err.Error())
+		return utils.RespondWithError(c, 500, "Error deleting comment "+err.Error())
 	}
 
 	return c.Status(200).JSON(fiber.Map{"msg": "Comment deleted successfully"})
@@ -1,30 +1,27 @@
 package messages
 
 import (
-	"context"
 	"errors"
-	"github.com/edisss1/fiabesco-backend/db"
 	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/mention"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"log"
-	"slices"
-	"strings"
 	"time"
 )
 
-var messagesCollection *mongo.Collection
-var conversationsCollection *mongo.Collection
-var usersCollection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
 
-func StartConversation(c *fiber.Ctx) error {
-	conversationsCollection = db.Database.Collection("conversations")
-	usersCollection = db.Database.Collection("users")
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
+func (h *Handler) StartConversation(c *fiber.Ctx) error {
 	var payload struct {
 		SenderID    string `json:"senderID"`
 		RecipientID string `json:"recipientID"`
@@ -43,53 +40,32 @@ func StartConversation(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid recipient ID")
 	}
 
-	var sender struct {
-		FirstName    string               `json:"firstName"`
-		LastName     string               `json:"lastName"`
-		PhotoURL     string               `json:"photoURL" bson:"photoURL"`
-		BlockedUsers []primitive.ObjectID `json:"blockedUsers" bson:"blockedUsers"`
-	}
-	var recipient struct {
-		FirstName    string               `json:"firstName"`
-		LastName     string               `json:"lastName"`
-		PhotoURL     string               `json:"photoURL" bson:"photoURL"`
-		BlockedUsers []primitive.ObjectID `json:"blockedUsers" bson:"blockedUsers"`
-	}
+	ctx := c.UserContext()
 
-	senderFilter := bson.M{"_id": senderID}
-	err = usersCollection.FindOne(context.Background(), senderFilter).Decode(&sender)
+	_, err = h.repos.Users.FindByID(ctx, senderID)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid sender ID")
 	}
 
-	recipientFilter := bson.M{"_id": recipientID}
-	err = usersCollection.FindOne(context.Background(), recipientFilter).Decode(&recipient)
+	_, err = h.repos.Users.FindByID(ctx, recipientID)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid recipient ID")
 	}
 
-	if slices.Contains(sender.BlockedUsers, recipientID) {
+	if blocked, _ := h.repos.Blocks.Exists(ctx, senderID, recipientID); blocked {
 		return c.Status(400).JSON(fiber.Map{"started": false, "msg": "You cannot send messages to blocked users"})
 	}
 
-	if slices.Contains(recipient.BlockedUsers, senderID) {
+	if blocked, _ := h.repos.Blocks.Exists(ctx, recipientID, senderID); blocked {
 		return c.Status(400).JSON(fiber.Map{"started": false, "msg": "Cannot send messages to users who blocked you"})
 	}
 
-	var conversation types.Conversation
-	filter := bson.M{
-		"isGroup": false,
-		"participants": bson.M{
-			"$all": []primitive.ObjectID{senderID, recipientID},
-		},
-	}
-
-	err = conversationsCollection.FindOne(context.Background(), filter).Decode(&conversation)
+	conversation, err := h.repos.Conversations.FindDirect(ctx, senderID, recipientID)
 	if err == nil {
 		return c.JSON(fiber.Map{
 			"conversationID": conversation.ID.Hex(),
 		})
-	} else if !errors.Is(err, mongo.ErrNoDocuments) {
+	} else if !errors.Is(err, repository.ErrNotFound) {
 		return utils.RespondWithError(c, 500, "DB error")
 	}
 
@@ -103,15 +79,15 @@ func StartConversation(c *fiber.Ctx) error {
 		UpdatedAt: time.Now(),
 	}
 
-	result, err := conversationsCollection.InsertOne(context.Background(), newConversation)
+	err = h.repos.Conversations.Create(ctx, &newConversation)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "DB error")
 	}
 
-	return c.Status(201).JSON(fiber.Map{"conversationID": result.InsertedID.(primitive.ObjectID).Hex(), "started": true})
+	return c.Status(201).JSON(fiber.Map{"conversationID": newConversation.ID.Hex(), "started": true})
 }
 
-func SendMessage(c *fiber.Ctx) error {
+func (h *Handler) SendMessage(c *fiber.Ctx) error {
 	conversationIDParam := c.Params("conversationID")
 	senderIDParam := c.Params("senderID")
 
@@ -133,17 +109,21 @@ func SendMessage(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	message, err := helpers.SaveMessage(senderID, conversationID, msg.Content)
+	mentions, err := mention.Resolve(c.UserContext(), h.repos, senderID, msg.Content)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to resolve mentions "+err.Error())
+	}
+
+	message, err := helpers.SaveMessage(h.repos, senderID, conversationID, msg.Content, mentions)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Error sending message")
 	}
+	mention.NotifyMessage(c.UserContext(), h.repos, message, nil)
 
 	return c.Status(201).JSON(fiber.Map{"newMessage": message})
 }
 
-func DeleteMessage(c *fiber.Ctx) error {
-	messagesCollection = db.Database.Collection("messages")
-
+func (h *Handler) DeleteMessage(c *fiber.Ctx) error {
 	var payload struct {
 		ID string `json:"id"`
 	}
@@ -158,9 +138,7 @@ func DeleteMessage(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid message ID")
 	}
 
-	filter := bson.M{"_id": messageID}
-
-	_, err = messagesCollection.DeleteOne(context.Background(), filter)
+	err = h.repos.Messages.Delete(c.UserContext(), messageID)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Failed to delete message")
 	}
@@ -168,10 +146,7 @@ func DeleteMessage(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "Message deleted"})
 }
 
-func DeleteConversation(c *fiber.Ctx) error {
-	conversationsCollection = db.Database.Collection("conversations")
-	messagesCollection = db.Database.Collection("messages")
-
+func (h *Handler) DeleteConversation(c *fiber.Ctx) error {
 	id := c.Params("conversationID")
 
 	conversationID, err := primitive.ObjectIDFromHex(id)
@@ -179,15 +154,12 @@ func DeleteConversation(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid conversation ID")
 	}
 
-	messagesFilter := bson.M{"conversationID": conversationID}
-	conversationFilter := bson.M{"_id": conversationID}
-
-	_, err = messagesCollection.DeleteMany(context.Background(), messagesFilter)
+	err = h.repos.Messages.DeleteByConversation(c.UserContext(), conversationID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to delete messages")
 	}
 
-	_, err = conversationsCollection.DeleteOne(context.Background(), conversationFilter)
+	err = h.repos.Conversations.Delete(c.UserContext(), conversationID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to delete conversation")
 	}
@@ -195,9 +167,7 @@ func DeleteConversation(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "Conversation deleted successfully"})
 }
 
-func EditMessage(c *fiber.Ctx) error {
-	messagesCollection = db.Database.Collection("messages")
-
+func (h *Handler) EditMessage(c *fiber.Ctx) error {
 	id := c.Params("_id")
 	var payload struct {
 		NewContent string `json:"newContent"`
@@ -212,98 +182,70 @@ func EditMessage(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid message ID")
 	}
 
-	filter := bson.M{"_id": messageID}
+	ctx := c.UserContext()
 
-	err = messagesCollection.FindOne(context.Background(), filter).Decode(&payload)
-	if err != nil {
+	previous, err := h.repos.Messages.FindByID(ctx, messageID)
+	if errors.Is(err, repository.ErrNotFound) {
 		return utils.RespondWithError(c, 404, "Message not found")
 	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to update message")
+	}
 
-	update := bson.M{"$set": bson.M{"content": payload.NewContent, "isEdited": true, "updatedAt": time.Now()}}
+	mentions, err := mention.Resolve(ctx, h.repos, previous.SenderID, payload.NewContent)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to resolve mentions "+err.Error())
+	}
 
-	_, err = messagesCollection.UpdateOne(context.Background(), filter, update)
+	message, err := h.repos.Messages.Edit(ctx, messageID, payload.NewContent, mentions)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Message not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to update message")
 	}
+	mention.NotifyMessage(ctx, h.repos, message, previous.Mentions)
 
 	return c.Status(200).JSON(fiber.Map{"msg": "Message updated"})
 }
 
-func GetConversation(c *fiber.Ctx) error {
-	conversationsCollection = db.Database.Collection("conversations")
-	messagesCollection = db.Database.Collection("messages")
-	usersCollection = db.Database.Collection("users")
+func (h *Handler) GetConversation(c *fiber.Ctx) error {
 	id := c.Params("conversationID")
 	conversationID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
 	}
 
-	filter := bson.M{"_id": conversationID}
-	var conversation types.Conversation
-	err = conversationsCollection.FindOne(context.Background(), filter).Decode(&conversation)
+	ctx := c.UserContext()
+
+	conversation, err := h.repos.Conversations.FindByID(ctx, conversationID)
 	if err != nil {
 		return utils.RespondWithError(c, 404, "Conversation not found "+err.Error())
 	}
 
-	var messages []types.Message
-	messagesFilter := bson.M{"conversationID": conversationID}
-	cursor, err := messagesCollection.Find(context.Background(), messagesFilter)
+	messages, err := h.repos.Messages.ListByConversation(ctx, conversationID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "DB error "+err.Error())
 	}
 
-	for cursor.Next(context.Background()) {
-		var message types.Message
-		err := cursor.Decode(&message)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "DB error "+err.Error())
-		}
-		messages = append(messages, message)
-	}
-
-	usersFilter := bson.M{"_id": bson.M{"$in": conversation.ParticipantsIds}}
-	cursor, err = usersCollection.Find(context.Background(), usersFilter)
+	users, err := h.repos.Users.FindByIDs(ctx, conversation.ParticipantsIds)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "DB error "+err.Error())
 	}
-	var enriched []types.Participant
-
-	for cursor.Next(context.Background()) {
-		var user struct {
-			ID        primitive.ObjectID `bson:"_id"`
-			FirstName string             `bson:"firstName"`
-			LastName  string             `bson:"lastName"`
-			Photo     string             `bson:"photoURL"`
-		}
-		_ = cursor.Decode(&user)
-
-		if user.Photo != "" {
-			user.Photo = utils.BuildImgURL(user.Photo)
-		} else {
-			user.Photo = ""
-		}
-
-		enriched = append(enriched, types.Participant{
-			ID:       user.ID,
-			UserName: strings.TrimSpace(user.FirstName + " " + user.LastName),
-			PhotoURL: user.Photo,
-		})
-	}
 
-	conversation.Participants = enriched
+	conversation.Participants = helpers.Participants(users)
 
 	return c.Status(200).JSON(fiber.Map{"conversation": conversation, "messages": messages})
 
 }
 
-func GetConversations(c *fiber.Ctx) error {
+func (h *Handler) GetConversations(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	conversations, err := helpers.GetConversations(userID)
+	conversations, err := helpers.GetConversations(h.repos, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Couldn't get conversations")
 	}
@@ -313,18 +255,14 @@ func GetConversations(c *fiber.Ctx) error {
 
 // GetMessage will be primarily used to get a single message that is being replied to and not present
 // in the loaded conversation on the frontend
-func GetMessage(c *fiber.Ctx) error {
-	messagesCollection = db.Database.Collection("messages")
+func (h *Handler) GetMessage(c *fiber.Ctx) error {
 	id := c.Params("messageID")
 	messageID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
 	}
 
-	filter := bson.M{"_id": messageID}
-	var message types.Message
-
-	err = messagesCollection.FindOne(context.Background(), filter).Decode(&message)
+	message, err := h.repos.Messages.FindByID(c.UserContext(), messageID)
 	if err != nil {
 		return utils.RespondWithError(c, 404, "Message not found")
 	}
@@ -332,7 +270,7 @@ func GetMessage(c *fiber.Ctx) error {
 	return c.Status(200).JSON(message)
 }
 
-func SendReply(c *fiber.Ctx) error {
+func (h *Handler) SendReply(c *fiber.Ctx) error {
 	var body struct {
 		Content string `json:"content"`
 		ReplyTo string `json:"replyTo"`
@@ -358,10 +296,16 @@ func SendReply(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid reply to ID")
 	}
 
-	reply, err := helpers.SaveReply(senderID, conversationID, body.Content, replyTo)
+	mentions, err := mention.Resolve(c.UserContext(), h.repos, senderID, body.Content)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to resolve mentions "+err.Error())
+	}
+
+	reply, err := helpers.SaveReply(h.repos, senderID, conversationID, body.Content, mentions, replyTo)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Error sending reply")
 	}
+	mention.NotifyMessage(c.UserContext(), h.repos, reply, nil)
 
 	return c.Status(200).JSON(fiber.Map{"newMessage": reply})
 }
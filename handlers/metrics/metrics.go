@@ -0,0 +1,11 @@
+package metrics
+
+import (
+	"github.com/edisss1/fiabesco-backend/cache"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CacheStats returns the hit and miss counts of every cache store.
+func CacheStats(c *fiber.Ctx) error {
+	return c.Status(200).JSON(cache.Metrics())
+}
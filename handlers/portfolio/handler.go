@@ -1,22 +1,24 @@
 package portfolio
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"github.com/edisss1/fiabesco-backend/db"
 	"github.com/edisss1/fiabesco-backend/handlers/uploads"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
-func CreatePortfolio(c *fiber.Ctx) error {
+func (h *Handler) CreatePortfolio(c *fiber.Ctx) error {
 	userID := c.Params("userID")
 
 	portfolioJSON := c.FormValue("portfolio")
@@ -25,21 +27,15 @@ func CreatePortfolio(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid portfolio data")
 	}
 
-	var existingPortfolio types.Portfolio
-	err := collection.FindOne(context.Background(), bson.M{"userID": userID}).Decode(&existingPortfolio)
+	_, err := h.repos.Portfolios.FindByUser(c.UserContext(), userID)
 
 	if err == nil {
 		return utils.RespondWithError(c, 400, "Portfolio already exists")
 	}
 
-	bucket, err := gridfs.NewBucket(db.Database)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to create bucket")
-	}
-
 	for i := range portfolio.Projects {
 		fieldName := fmt.Sprintf("project-img-%d", i)
-		ids, err := uploads.UploadFile(c, fieldName, bucket, false)
+		ids, err := uploads.UploadFile(c, fieldName, h.repos.Media, false)
 		if err != nil {
 			return utils.RespondWithError(c, 500, "Failed to upload file"+err.Error())
 		}
@@ -50,8 +46,7 @@ func CreatePortfolio(c *fiber.Ctx) error {
 	}
 
 	portfolio.UserID = userID
-	collection = db.Database.Collection("portfolios")
-	_, err = collection.InsertOne(context.Background(), portfolio)
+	err = h.repos.Portfolios.Create(c.UserContext(), &portfolio)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to create portfolio "+err.Error())
 	}
@@ -60,36 +55,25 @@ func CreatePortfolio(c *fiber.Ctx) error {
 
 }
 
-func GetPortfolio(c *fiber.Ctx) error {
+func (h *Handler) GetPortfolio(c *fiber.Ctx) error {
 	userID := c.Params("userID")
 
-	var portfolio types.Portfolio
-	collection = db.Database.Collection("portfolios")
-	filter := bson.M{"userID": userID}
-	err := collection.FindOne(context.Background(), filter).Decode(&portfolio)
+	portfolio, err := h.repos.Portfolios.FindByUser(c.UserContext(), userID)
 	if err != nil {
 
 		return utils.RespondWithError(c, 500, "Failed to get portfolio "+err.Error())
 	}
 
 	for i, project := range portfolio.Projects {
-
-		if err != nil {
-			continue
-		}
 		portfolio.Projects[i].Img = utils.BuildImgURL(project.Img)
 	}
 
-	var user types.User
-	collection = db.Database.Collection("users")
-
 	parsedUserID, err := utils.ParseHexID(userID)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
-	filter = bson.M{"_id": parsedUserID}
 
-	err = collection.FindOne(context.Background(), filter).Decode(&user)
+	user, err := h.repos.Users.FindByID(c.UserContext(), parsedUserID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to get user "+err.Error())
 	}
@@ -99,7 +83,7 @@ func GetPortfolio(c *fiber.Ctx) error {
 
 }
 
-func UpdatePortfolio(c *fiber.Ctx) error {
+func (h *Handler) UpdatePortfolio(c *fiber.Ctx) error {
 	//id := c.Params("userID")
 	//userID, err := utils.ParseHexID(id)
 	//if err != nil {
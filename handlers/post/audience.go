@@ -0,0 +1,59 @@
+package post
+
+import (
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// UpdatePostAudience changes who can see a post. Reposts made while it was
+// public stay, but only show to the viewers its new audience reaches.
+func (h *Handler) UpdatePostAudience(c *fiber.Ctx) error {
+	post, err := h.ownPost(c, c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body struct {
+		Audience string `json:"audience"`
+	}
+
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	if body.Audience == "" {
+		return utils.RespondWithError(c, 400, "Audience is required")
+	}
+	audience, err := parseAudience(body.Audience)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	err = h.repos.Posts.Update(c.UserContext(), post.ID, bson.M{"audience": audience})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to update audience "+err.Error())
+	}
+	post.Audience = audience
+
+	return c.Status(200).JSON(fiber.Map{"post": post})
+}
+
+// parseAudience returns audience, or public when it's empty, and a 400 when it
+// isn't one of the post audiences.
+func parseAudience(audience string) (string, error) {
+	if audience == "" {
+		return types.AudiencePublic, nil
+	}
+	if !types.ValidAudience(audience) {
+		return "", fiber.NewError(400, "Audience must be public, followers, close_friends or unlisted")
+	}
+
+	return audience, nil
+}
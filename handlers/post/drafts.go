@@ -0,0 +1,364 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/internal/publish"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"slices"
+	"time"
+)
+
+// scheduleBody is when to publish a draft: an RFC 3339 time, or a date and
+// time like "2026-05-01T18:00" in the IANA timezone.
+type scheduleBody struct {
+	PublishAt string `json:"publishAt"`
+	Timezone  string `json:"timezone"`
+}
+
+// parse returns when to publish, or nil when no time was given.
+func (b scheduleBody) parse() (*time.Time, error) {
+	if b.PublishAt == "" {
+		return nil, nil
+	}
+
+	publishAt, err := utils.ParseLocalTime(b.PublishAt, b.Timezone)
+	if err != nil {
+		return nil, fiber.NewError(400, "Invalid publishAt or timezone")
+	}
+	if !publishAt.After(time.Now()) {
+		return nil, fiber.NewError(400, "publishAt must be in the future")
+	}
+
+	return &publishAt, nil
+}
+
+// CreateDraft saves a post without publishing it, from the multipart form
+// field "draft". Every image and file is uploaded with it, as post-img-<index>
+// and post-file-<index>. A publishAt schedules it.
+func (h *Handler) CreateDraft(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	var body struct {
+		Caption      string              `json:"caption"`
+		Images       []string            `json:"images"`
+		Files        []string            `json:"files"`
+		Tags         []string            `json:"tags"`
+		QuotedPostID *primitive.ObjectID `json:"quotedPostID"`
+		Audience     string              `json:"audience"`
+		scheduleBody
+	}
+
+	if err := json.Unmarshal([]byte(c.FormValue("draft")), &body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	publishAt, err := body.parse()
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	tags, err := hashtag.ForPost(body.Tags, body.Caption)
+	if err != nil {
+		return utils.RespondWithError(c, 400, err.Error())
+	}
+
+	audience, err := parseAudience(body.Audience)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	if body.QuotedPostID != nil {
+		visible, err := quote.Visible(c.UserContext(), h.repos, userID, *body.QuotedPostID)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Failed to find quoted post "+err.Error())
+		}
+		if !visible {
+			return utils.RespondWithError(c, 404, "Quoted post not found")
+		}
+	}
+
+	for i := range body.Images {
+		if _, err := c.FormFile(imageField(i)); err != nil {
+			return utils.RespondWithError(c, 400, "Images must be uploaded with the draft")
+		}
+	}
+	for i := range body.Files {
+		if _, err := c.FormFile(fileField(i)); err != nil {
+			return utils.RespondWithError(c, 400, "Files must be uploaded with the draft")
+		}
+	}
+
+	uploaded, err := h.uploadImages(c, body.Images)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to upload images "+err.Error())
+	}
+	files, err := h.uploadFields(c, body.Files, fileField)
+	if err != nil {
+		h.deleteUploads(c.UserContext(), uploaded)
+		return utils.RespondWithError(c, 500, "Failed to upload files "+err.Error())
+	}
+	uploaded = append(uploaded, files...)
+
+	draft := types.Draft{
+		UserID:       userID,
+		Caption:      body.Caption,
+		Images:       body.Images,
+		Files:        body.Files,
+		Uploads:      slices.Concat(body.Images, body.Files),
+		Tags:         tags,
+		QuotedPostID: body.QuotedPostID,
+		Audience:     audience,
+		PublishAt:    publishAt,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if publishAt != nil {
+		draft.Timezone = body.Timezone
+	}
+
+	if err := h.repos.Drafts.Create(c.UserContext(), &draft); err != nil {
+		h.deleteUploads(c.UserContext(), uploaded)
+		return utils.RespondWithError(c, 500, "Failed to create draft "+err.Error())
+	}
+
+	return c.Status(201).JSON(fiber.Map{"draft": draft})
+}
+
+// GetDrafts lists the current user's drafts, newest first.
+func (h *Handler) GetDrafts(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	drafts, err := h.repos.Drafts.ListByUser(c.UserContext(), userID, page)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch drafts "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, utils.NewPaged(drafts, page, func(draft types.Draft) utils.Cursor {
+		return utils.Cursor{CreatedAt: draft.CreatedAt, ID: draft.ID}
+	}))
+}
+
+func (h *Handler) GetDraft(c *fiber.Ctx) error {
+	draft, err := h.ownDraft(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	return c.Status(200).JSON(draft)
+}
+
+// UpdateDraft changes the caption, tags and audience of a draft.
+func (h *Handler) UpdateDraft(c *fiber.Ctx) error {
+	draft, err := h.ownDraft(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body struct {
+		Caption  *string  `json:"caption"`
+		Tags     []string `json:"tags"`
+		Audience *string  `json:"audience"`
+	}
+
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	if body.Caption == nil && body.Tags == nil && body.Audience == nil {
+		return utils.RespondWithError(c, 400, "Nothing to update")
+	}
+
+	caption, tags := draft.Caption, hashtag.Explicit(draft.Tags, draft.Caption)
+	if body.Caption != nil {
+		caption = *body.Caption
+	}
+	if body.Tags != nil {
+		tags = body.Tags
+	}
+	if tags, err = hashtag.ForPost(tags, caption); err != nil {
+		return utils.RespondWithError(c, 400, err.Error())
+	}
+
+	draft.Caption, draft.Tags = caption, tags
+	fields := bson.M{"caption": caption, "tags": tags}
+
+	if body.Audience != nil {
+		if draft.Audience, err = parseAudience(*body.Audience); err != nil {
+			return utils.RespondWithFiberError(c, err)
+		}
+		fields["audience"] = draft.Audience
+	}
+
+	if err := h.repos.Drafts.Update(c.UserContext(), draft.ID, fields); err != nil {
+		return utils.RespondWithFiberError(c, draftNotFound(err))
+	}
+	draft.UpdatedAt = time.Now()
+
+	return c.Status(200).JSON(fiber.Map{"draft": draft})
+}
+
+// DeleteDraft deletes a draft together with the files uploaded with it that
+// nothing else refers to.
+func (h *Handler) DeleteDraft(c *fiber.Ctx) error {
+	draft, err := h.ownDraft(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	ctx := c.UserContext()
+
+	if err := h.repos.Drafts.Delete(ctx, draft.ID); err != nil {
+		return utils.RespondWithFiberError(c, draftNotFound(err))
+	}
+
+	h.deleteDraftUploads(ctx, draft)
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Draft deleted successfully"})
+}
+
+// PublishDraft publishes a draft right away, scheduled or not.
+func (h *Handler) PublishDraft(c *fiber.Ctx) error {
+	draft, err := h.ownDraft(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	post, err := publish.Draft(c.UserContext(), h.repos, draft)
+	if errors.Is(err, publish.ErrQuotedNotFound) {
+		return utils.RespondWithError(c, 404, "Quoted post not found")
+	}
+	if err != nil {
+		return utils.RespondWithFiberError(c, draftNotFound(err))
+	}
+
+	return c.Status(201).JSON(fiber.Map{"post": post})
+}
+
+// ScheduleDraft sets or moves the time a draft is published at.
+func (h *Handler) ScheduleDraft(c *fiber.Ctx) error {
+	draft, err := h.ownDraft(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body scheduleBody
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	publishAt, err := body.parse()
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+	if publishAt == nil {
+		return utils.RespondWithError(c, 400, "publishAt is required")
+	}
+
+	draft.PublishAt, draft.Timezone, draft.PublishError = publishAt, body.Timezone, ""
+	err = h.repos.Drafts.Update(c.UserContext(), draft.ID, bson.M{"publishAt": publishAt, "timezone": body.Timezone, "publishError": ""})
+	if err != nil {
+		return utils.RespondWithFiberError(c, draftNotFound(err))
+	}
+	draft.UpdatedAt = time.Now()
+
+	return c.Status(200).JSON(fiber.Map{"draft": draft})
+}
+
+// UnscheduleDraft cancels the publishing of a draft; it stays a draft.
+func (h *Handler) UnscheduleDraft(c *fiber.Ctx) error {
+	draft, err := h.ownDraft(c)
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	draft.PublishAt, draft.Timezone = nil, ""
+	if err := h.repos.Drafts.Update(c.UserContext(), draft.ID, bson.M{"publishAt": nil, "timezone": ""}); err != nil {
+		return utils.RespondWithFiberError(c, draftNotFound(err))
+	}
+	draft.UpdatedAt = time.Now()
+
+	return c.Status(200).JSON(fiber.Map{"draft": draft})
+}
+
+// ownDraft returns the current user's draft named by the draftID parameter.
+// Other users' drafts are reported as not found.
+func (h *Handler) ownDraft(c *fiber.Ctx) (types.Draft, error) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return types.Draft{}, fiber.NewError(400, "Invalid user ID")
+	}
+
+	draftID, err := utils.ParseHexID(c.Params("draftID"))
+	if err != nil {
+		return types.Draft{}, fiber.NewError(400, "Invalid ID")
+	}
+
+	draft, err := h.repos.Drafts.FindByID(c.UserContext(), draftID)
+	if err != nil {
+		return draft, draftNotFound(err)
+	}
+	if draft.UserID != userID {
+		return types.Draft{}, fiber.NewError(404, "Draft not found")
+	}
+
+	return draft, nil
+}
+
+// deleteDraftUploads deletes the files uploaded with a deleted draft, unless
+// something else refers to them. Files of drafts saved before their uploads
+// were recorded are left to the media cleanup.
+func (h *Handler) deleteDraftUploads(ctx context.Context, draft types.Draft) {
+	var ids []primitive.ObjectID
+	for _, hexID := range draft.Uploads {
+		if id, err := primitive.ObjectIDFromHex(hexID); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	referenced, err := h.repos.Media.Referenced(ctx, ids)
+	if err != nil {
+		log.Println("Error checking draft uploads: ", err)
+		return
+	}
+
+	var unused []primitive.ObjectID
+	for _, id := range ids {
+		if !referenced[id] {
+			unused = append(unused, id)
+		}
+	}
+	h.deleteUploads(ctx, unused)
+}
+
+// draftNotFound turns repository.ErrNotFound into a 404 and any other error
+// into a 500.
+func draftNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return fiber.NewError(404, "Draft not found")
+	}
+	return fiber.NewError(500, "Failed to access draft "+err.Error())
+}
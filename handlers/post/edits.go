@@ -0,0 +1,319 @@
+package post
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/internal/mention"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"slices"
+	"time"
+)
+
+// EditPost changes the caption, tags, images and alt texts of a post, from the
+// multipart form field "post". Fields that are left out stay as they are.
+// Images lists the post's images in their new order: kept images by ID, and
+// new ones uploaded as post-img-<index> like in CreatePost.
+func (h *Handler) EditPost(c *fiber.Ctx) error {
+	post, err := h.ownPost(c, c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	var body struct {
+		Caption  *string  `json:"caption"`
+		Images   []string `json:"images"`
+		AltTexts []string `json:"altTexts"`
+		Tags     []string `json:"tags"`
+	}
+
+	if err := json.Unmarshal([]byte(c.FormValue("post")), &body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	to := contentOf(post)
+	to.Tags = hashtag.Explicit(post.Tags, post.Caption)
+	if body.Caption != nil {
+		to.Caption = *body.Caption
+	}
+	if body.Tags != nil {
+		to.Tags = body.Tags
+	}
+	if body.Images != nil {
+		for i, image := range body.Images {
+			if _, err := c.FormFile(imageField(i)); err != nil && !slices.Contains(post.Images, image) {
+				return utils.RespondWithError(c, 400, "Unknown image "+image)
+			}
+		}
+		to.Images = body.Images
+	}
+	if body.AltTexts != nil && len(body.AltTexts) != len(to.Images) {
+		return utils.RespondWithError(c, 400, "altTexts must have one entry per image")
+	}
+	if _, err := hashtag.ForPost(to.Tags, to.Caption); err != nil {
+		return utils.RespondWithError(c, 400, err.Error())
+	}
+
+	var uploaded []primitive.ObjectID
+	if body.Images != nil {
+		if uploaded, err = h.uploadImages(c, body.Images); err != nil {
+			return utils.RespondWithError(c, 500, "Failed to upload images "+err.Error())
+		}
+		to.AltTexts = realignAltTexts(post, body.Images)
+	}
+	if body.AltTexts != nil {
+		to.AltTexts = body.AltTexts
+	}
+
+	if err := h.edit(c.UserContext(), &post, to); err != nil {
+		h.deleteUploads(c.UserContext(), uploaded)
+		return utils.RespondWithError(c, 500, "Failed to edit post "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"post": post})
+}
+
+func (h *Handler) UpdatePostCaption(c *fiber.Ctx) error {
+	var body struct {
+		Caption string `json:"caption"`
+	}
+
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if body.Caption == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "Caption is required"})
+	}
+
+	post, err := h.ownPost(c, c.Params("_id"))
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	to := contentOf(post)
+	to.Caption, to.Tags = body.Caption, hashtag.Explicit(post.Tags, post.Caption)
+	err = h.edit(c.UserContext(), &post, to)
+	if errors.Is(err, hashtag.ErrInvalid) {
+		return utils.RespondWithError(c, 400, err.Error())
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to update post "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Post updated successfully"})
+}
+
+// GetPostRevisions lists how a post looked before each of its edits, newest
+// first.
+func (h *Handler) GetPostRevisions(c *fiber.Ctx) error {
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid ID")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	ctx := c.UserContext()
+
+	post, err := h.repos.Posts.FindByID(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch revisions "+err.Error())
+	}
+
+	reachable, err := helpers.CanReach(ctx, h.repos, viewerID(c), post)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch revisions "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+
+	revisions, err := h.repos.Revisions.ListByPost(ctx, postID, page)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch revisions "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, utils.NewPaged(revisions, page, func(revision types.PostRevision) utils.Cursor {
+		return utils.Cursor{CreatedAt: revision.CreatedAt, ID: revision.ID}
+	}))
+}
+
+// RevertPost makes a post look like one of its revisions again. Reverting is
+// an edit too, so what it replaces becomes a revision itself.
+func (h *Handler) RevertPost(c *fiber.Ctx) error {
+	post, err := h.ownPost(c, c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithFiberError(c, err)
+	}
+
+	revisionID, err := utils.ParseHexID(c.Params("revisionID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid revision ID")
+	}
+
+	ctx := c.UserContext()
+
+	revision, err := h.repos.Revisions.FindByID(ctx, revisionID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 500, "Failed to find revision "+err.Error())
+	}
+	if err != nil || revision.PostID != post.ID {
+		return utils.RespondWithError(c, 404, "Revision not found")
+	}
+
+	err = h.edit(ctx, &post, revision)
+	if errors.Is(err, hashtag.ErrInvalid) {
+		return utils.RespondWithError(c, 400, err.Error())
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to revert post "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"post": post})
+}
+
+// ownPost returns the post with the hex ID if the current user wrote it.
+func (h *Handler) ownPost(c *fiber.Ctx, hexID string) (types.Post, error) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return types.Post{}, fiber.NewError(400, "Invalid user ID")
+	}
+
+	postID, err := utils.ParseHexID(hexID)
+	if err != nil {
+		return types.Post{}, fiber.NewError(400, "Invalid ID")
+	}
+
+	post, err := h.repos.Posts.FindByID(c.UserContext(), postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return post, fiber.NewError(404, "Post not found")
+	}
+	if err != nil {
+		return post, fiber.NewError(500, "Failed to find post "+err.Error())
+	}
+	if post.UserID != userID {
+		return post, fiber.NewError(401, "Unauthorized")
+	}
+
+	return post, nil
+}
+
+// edit gives post the content of to and keeps its previous content as a
+// revision. Nothing is recorded when the content doesn't change. The tags of
+// to are normalized and joined by the hashtags of its caption; edit returns
+// hashtag.ErrInvalid when they break the limits. A new caption has its
+// mentions resolved again and the users it newly mentions are notified.
+func (h *Handler) edit(ctx context.Context, post *types.Post, to types.PostRevision) error {
+	tags, err := hashtag.ForPost(to.Tags, to.Caption)
+	if err != nil {
+		return err
+	}
+	to.Tags = tags
+
+	revision := contentOf(*post)
+	if revision.Caption == to.Caption && slices.Equal(revision.Images, to.Images) &&
+		slices.Equal(revision.AltTexts, to.AltTexts) && slices.Equal(revision.Tags, to.Tags) {
+		return nil
+	}
+
+	mentions := post.Mentions
+	if to.Caption != post.Caption {
+		if mentions, err = mention.Resolve(ctx, h.repos, post.UserID, to.Caption); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	revision.PostID, revision.CreatedAt = post.ID, now
+
+	err = h.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := h.repos.Revisions.Create(ctx, &revision); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Revisions.Delete(ctx, revision.ID)
+		})
+
+		return h.repos.Posts.Update(ctx, post.ID, bson.M{
+			"caption":  to.Caption,
+			"images":   to.Images,
+			"altTexts": to.AltTexts,
+			"tags":     to.Tags,
+			"mentions": mentions,
+			"editedAt": now,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := hashtag.Count(ctx, h.repos, post.Tags, to.Tags); err != nil {
+		log.Println("Error counting post tags: ", err)
+	}
+
+	previous := post.Mentions
+	post.Caption, post.Images, post.AltTexts, post.Tags = to.Caption, to.Images, to.AltTexts, to.Tags
+	post.Mentions, post.EditedAt, post.UpdatedAt = mentions, &now, now
+	mention.NotifyPost(ctx, h.repos, *post, previous)
+	return nil
+}
+
+// contentOf returns the editable content of post.
+func contentOf(post types.Post) types.PostRevision {
+	return types.PostRevision{Caption: post.Caption, Images: post.Images, AltTexts: post.AltTexts, Tags: post.Tags}
+}
+
+// realignAltTexts moves the alt texts of post along with their images when
+// they are reordered. New images start without one.
+func realignAltTexts(post types.Post, images []string) []string {
+	if len(post.AltTexts) == 0 {
+		return nil
+	}
+
+	byImage := make(map[string]string, len(post.Images))
+	for i, image := range post.Images {
+		if i < len(post.AltTexts) {
+			byImage[image] = post.AltTexts[i]
+		}
+	}
+
+	altTexts := make([]string, len(images))
+	for i, image := range images {
+		altTexts[i] = byImage[image]
+	}
+	return altTexts
+}
+
+// postDeleted deletes the revisions and saves of a deleted post, takes it out
+// of collections and uncounts its tags. Failing to do so doesn't undo the
+// deletion.
+func (h *Handler) postDeleted(ctx context.Context, post types.Post) {
+	postIDs := []primitive.ObjectID{post.ID}
+	if _, err := h.repos.Revisions.DeleteByPosts(ctx, postIDs); err != nil {
+		log.Println("Error deleting post revisions: ", err)
+	}
+	if _, err := h.repos.Saves.DeleteByPosts(ctx, postIDs); err != nil {
+		log.Println("Error deleting post saves: ", err)
+	}
+	if _, err := h.repos.Collections.DeleteItemsByPosts(ctx, postIDs); err != nil {
+		log.Println("Error removing post from collections: ", err)
+	}
+	if err := hashtag.Count(ctx, h.repos, post.Tags, nil); err != nil {
+		log.Println("Error counting post tags: ", err)
+	}
+}
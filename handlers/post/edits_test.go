@@ -0,0 +1,194 @@
+package post
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRealignAltTexts(t *testing.T) {
+	post := types.Post{Images: []string{"a", "b", "c"}, AltTexts: []string{"A", "B", "C"}}
+
+	tests := []struct {
+		name   string
+		post   types.Post
+		images []string
+		want   []string
+	}{
+		{name: "same order", post: post, images: []string{"a", "b", "c"}, want: []string{"A", "B", "C"}},
+		{name: "reordered", post: post, images: []string{"c", "a", "b"}, want: []string{"C", "A", "B"}},
+		{name: "removed", post: post, images: []string{"b"}, want: []string{"B"}},
+		{name: "new image", post: post, images: []string{"a", "new"}, want: []string{"A", ""}},
+		{name: "no alt texts", post: types.Post{Images: []string{"a"}}, images: []string{"a", "b"}, want: nil},
+		{
+			name:   "fewer alt texts than images",
+			post:   types.Post{Images: []string{"a", "b"}, AltTexts: []string{"A"}},
+			images: []string{"b", "a"},
+			want:   []string{"", "A"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := realignAltTexts(tt.post, tt.images); !slices.Equal(got, tt.want) {
+				t.Errorf("realignAltTexts = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEdit(t *testing.T) {
+	tooMany := make([]string, hashtag.MaxPerPost+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("tag%d", i)
+	}
+
+	tests := []struct {
+		name          string
+		to            types.PostRevision
+		wantErr       error
+		wantCaption   string
+		wantRevisions int
+	}{
+		{name: "caption", to: types.PostRevision{Caption: "edited", Images: []string{"a"}}, wantCaption: "edited", wantRevisions: 1},
+		{name: "images", to: types.PostRevision{Caption: "original", Images: []string{"b", "a"}}, wantCaption: "original", wantRevisions: 1},
+		{name: "unchanged", to: types.PostRevision{Caption: "original", Images: []string{"a"}}, wantCaption: "original"},
+		{name: "too many tags", to: types.PostRevision{Caption: "edited", Tags: tooMany}, wantErr: hashtag.ErrInvalid, wantCaption: "original"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			h := NewHandler(repos)
+			post := createPost(t, repos, primitive.NewObjectID())
+
+			err := h.edit(ctx, &post, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			stored, err := repos.Posts.FindByID(ctx, post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Caption != tt.wantCaption {
+				t.Errorf("caption = %q, want %q", stored.Caption, tt.wantCaption)
+			}
+
+			revisions := listRevisions(t, repos, post.ID)
+			if len(revisions) != tt.wantRevisions {
+				t.Fatalf("got %d revisions, want %d", len(revisions), tt.wantRevisions)
+			}
+			if tt.wantRevisions > 0 && revisions[0].Caption != "original" {
+				t.Errorf("revision caption = %q, want the original", revisions[0].Caption)
+			}
+		})
+	}
+}
+
+func TestRevertPost(t *testing.T) {
+	tests := []struct {
+		name string
+		// otherPost reverts to a revision of another post of the same author.
+		otherPost bool
+		// stranger reverts someone else's post.
+		stranger      bool
+		wantStatus    int
+		wantCaption   string
+		wantRevisions int
+	}{
+		{name: "revert", wantStatus: 200, wantCaption: "original", wantRevisions: 2},
+		{name: "revision of another post", otherPost: true, wantStatus: 404, wantCaption: "edited", wantRevisions: 1},
+		{name: "someone else's post", stranger: true, wantStatus: 401, wantCaption: "edited", wantRevisions: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			h := NewHandler(repos)
+			author := primitive.NewObjectID()
+
+			post := createPost(t, repos, author)
+			if err := h.edit(ctx, &post, types.PostRevision{Caption: "edited", Images: post.Images}); err != nil {
+				t.Fatal(err)
+			}
+			revisionID := listRevisions(t, repos, post.ID)[0].ID
+
+			if tt.otherPost {
+				other := createPost(t, repos, author)
+				if err := h.edit(ctx, &other, types.PostRevision{Caption: "other", Images: other.Images}); err != nil {
+					t.Fatal(err)
+				}
+				revisionID = listRevisions(t, repos, other.ID)[0].ID
+			}
+
+			userID := author
+			if tt.stranger {
+				userID = primitive.NewObjectID()
+			}
+
+			app := fiber.New()
+			app.Post("/posts/:postID/revisions/:revisionID/revert", func(c *fiber.Ctx) error {
+				c.Locals("jwt", jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": userID.Hex()}))
+				return c.Next()
+			}, h.RevertPost)
+
+			req := httptest.NewRequest("POST", "/posts/"+post.ID.Hex()+"/revisions/"+revisionID.Hex()+"/revert", strings.NewReader(""))
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			stored, err := repos.Posts.FindByID(ctx, post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Caption != tt.wantCaption {
+				t.Errorf("caption = %q, want %q", stored.Caption, tt.wantCaption)
+			}
+			if got := len(listRevisions(t, repos, post.ID)); got != tt.wantRevisions {
+				t.Errorf("got %d revisions, want %d", got, tt.wantRevisions)
+			}
+		})
+	}
+}
+
+func createPost(t *testing.T, repos *repository.Repositories, userID primitive.ObjectID) types.Post {
+	t.Helper()
+
+	post := types.Post{UserID: userID, Caption: "original", Images: []string{"a"}, Audience: types.AudiencePublic, CreatedAt: time.Now()}
+	if err := repos.Posts.Create(context.Background(), &post); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+// listRevisions returns the revisions of postID, newest first.
+func listRevisions(t *testing.T, repos *repository.Repositories, postID primitive.ObjectID) []types.PostRevision {
+	t.Helper()
+
+	revisions, err := repos.Revisions.ListByPost(context.Background(), postID, utils.Page{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revisions
+}
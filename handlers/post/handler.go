@@ -3,33 +3,36 @@ package post
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"github.com/edisss1/fiabesco-backend/db"
 	"github.com/edisss1/fiabesco-backend/handlers/uploads"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/internal/publish"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/internal/ranking"
+	"github.com/edisss1/fiabesco-backend/internal/timeline"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
-	"strconv"
+	"log"
 	"strings"
 	"time"
 )
 
 // TODO: update other functions to behave like GetPostsByUser
 
-type FeedItem struct {
-	Post     types.Post `json:"post"`
-	UserName string     `json:"userName" bson:"userName"`
-	PhotoURL string     `json:"photoURL" bson:"photoURL"`
-	Handle   string     `json:"handle"`
+type Handler struct {
+	repos *repository.Repositories
 }
 
-var collection *mongo.Collection
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
-func CreatePost(c *fiber.Ctx) error {
+func (h *Handler) CreatePost(c *fiber.Ctx) error {
 	id := c.Params("userID")
 	userID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -43,29 +46,32 @@ func CreatePost(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body"+err.Error())
 	}
 
-	bucket, err := gridfs.NewBucket(db.Database)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Error creating bucket")
+	if post.Audience, err = parseAudience(post.Audience); err != nil {
+		return utils.RespondWithFiberError(c, err)
 	}
 
-	for i := range post.Images {
-		fieldName := fmt.Sprintf("post-img-%d", i)
-		ids, err := uploads.UploadFile(c, fieldName, bucket, false)
-		if err != nil {
-			continue
-		}
-		if len(ids) > 0 {
-			post.Images[i] = ids[0].Hex()
-		}
+	uploaded, err := h.uploadImages(c, post.Images)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to upload images "+err.Error())
 	}
 
 	post.CreatedAt = time.Now()
 	post.UpdatedAt = time.Now()
 
 	post.UserID = userID
-	collection = db.Database.Collection("posts")
+	post.QuotesCount = 0
+	post.SavesCount = 0
 
-	_, err = collection.InsertOne(context.Background(), post)
+	err = publish.Post(c.UserContext(), h.repos, &post)
+	if err != nil {
+		h.deleteUploads(c.UserContext(), uploaded)
+	}
+	if errors.Is(err, publish.ErrQuotedNotFound) {
+		return utils.RespondWithError(c, 404, "Quoted post not found")
+	}
+	if errors.Is(err, hashtag.ErrInvalid) {
+		return utils.RespondWithError(c, 400, err.Error())
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to create post "+err.Error())
 	}
@@ -73,253 +79,351 @@ func CreatePost(c *fiber.Ctx) error {
 	return c.Status(201).JSON(fiber.Map{"post": post})
 }
 
-func GetPostsByUser(c *fiber.Ctx) error {
-	id := c.Params("userID")
-	userID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return utils.RespondWithError(c, 400, "Invalid ID")
-	}
-
-	pageParam := c.Query("page", "1")
-
-	l := 10
-	p, _ := strconv.Atoi(pageParam)
-	skip := int64(p*l - l)
-
-	limit := int64(l)
+// uploadImages replaces the images with the IDs of the files uploaded as
+// post-img-0, post-img-1 and so on, and returns those IDs. Images without a
+// file are left as they are. When an upload fails, the files uploaded before
+// it are deleted.
+func (h *Handler) uploadImages(c *fiber.Ctx, images []string) ([]primitive.ObjectID, error) {
+	return h.uploadFields(c, images, imageField)
+}
 
-	var result []FeedItem
+// uploadFields is uploadImages for the files of the form fields named by field.
+func (h *Handler) uploadFields(c *fiber.Ctx, names []string, field func(int) string) ([]primitive.ObjectID, error) {
+	var uploaded []primitive.ObjectID
+	for i := range names {
+		if _, err := c.FormFile(field(i)); err != nil {
+			continue
+		}
 
-	collection = db.Database.Collection("posts")
-	pipeline := mongo.Pipeline{
-		bson.D{{"$match", bson.D{{"userID", userID}}}},
-		bson.D{{"$sort", bson.D{{"createdAt", -1}}}},
+		ids, err := uploads.UploadFile(c, field(i), h.repos.Media, false)
+		if err != nil {
+			h.deleteUploads(c.UserContext(), uploaded)
+			return nil, err
+		}
+		names[i] = ids[0].Hex()
+		uploaded = append(uploaded, ids[0])
+	}
+	return uploaded, nil
+}
 
-		// Pagination
-		bson.D{{"$skip", skip}},
-		bson.D{{"$limit", limit}},
+// deleteUploads deletes the files uploaded for a request that failed.
+func (h *Handler) deleteUploads(ctx context.Context, ids []primitive.ObjectID) {
+	for _, id := range ids {
+		if err := h.repos.Media.Delete(ctx, id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Println("Error deleting upload: ", err)
+		}
+	}
+}
 
-		bson.D{{"$lookup", bson.D{
-			{"from", "users"},
-			{"localField", "userID"},
-			{"foreignField", "_id"},
-			{"as", "user"},
-		}}},
+// imageField is the form field of the i-th image.
+func imageField(i int) string {
+	return fmt.Sprintf("post-img-%d", i)
+}
 
-		bson.D{{"$unwind", bson.D{
-			{"path", "$user"},
-			{"preserveNullAndEmptyArrays", true},
-		}}},
+// fileField is the form field of the i-th file of a draft.
+func fileField(i int) string {
+	return fmt.Sprintf("post-file-%d", i)
+}
 
-		bson.D{{"$project", bson.D{
-			{"post", "$$ROOT"},
-			{"userName", bson.D{{"$concat", bson.A{"$user.firstName", " ", "$user.lastName"}}}},
+func (h *Handler) GetPostsByUser(c *fiber.Ctx) error {
+	id := c.Params("userID")
+	userID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid ID")
+	}
 
-			{"photoURL", "$user.photoURL"},
-			{"handle", "$user.handle"},
-		}}},
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
 	}
 
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	result, err := timeline.Profile(c.UserContext(), h.repos, userID, viewerID(c), page)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to fetch posts: "+err.Error())
 	}
 
-	for cursor.Next(context.Background()) {
-		var post FeedItem
+	if err := quote.Embed(c.UserContext(), h.repos, viewerID(c), feedItems(result, timelineFeedItem)); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts: "+err.Error())
+	}
 
-		if err := cursor.Decode(&post); err != nil {
-			return utils.RespondWithError(c, 500, "Failed to decode post: "+err.Error())
-		}
+	return utils.RespondWithPage(c, utils.NewPaged(result, page, timelineItemCursor))
+}
 
-		if post.PhotoURL != "" {
-			post.PhotoURL = utils.BuildImgURL(post.PhotoURL)
-		}
-		if post.Post.Images != nil {
-			for i := range post.Post.Images {
-				post.Post.Images[i] = utils.BuildImgURL(post.Post.Images[i])
-			}
-		}
+// viewerID returns the user making the request, or a zero ID if it can't be told.
+func viewerID(c *fiber.Ctx) primitive.ObjectID {
+	userID, _ := utils.GetUserID(c)
+	return userID
+}
 
-		result = append(result, post)
+func feedItemCursor(item types.FeedItem) utils.Cursor {
+	return utils.Cursor{CreatedAt: item.Post.CreatedAt, ID: item.Post.ID}
+}
 
+// feedItems returns the feed items of items for quote.Embed.
+func feedItems[T any](items []T, feedItem func(*T) *types.FeedItem) []*types.FeedItem {
+	result := make([]*types.FeedItem, 0, len(items))
+	for i := range items {
+		result = append(result, feedItem(&items[i]))
 	}
+	return result
+}
 
-	return c.Status(200).JSON(result)
+func ownFeedItem(item *types.FeedItem) *types.FeedItem {
+	return item
+}
+
+func timelineFeedItem(item *types.TimelineItem) *types.FeedItem {
+	return &item.FeedItem
+}
+
+func rankedFeedItem(item *types.RankedItem) *types.FeedItem {
+	return &item.FeedItem
 }
 
-func DeletePost(c *fiber.Ctx) error {
+func (h *Handler) DeletePost(c *fiber.Ctx) error {
 	postID := c.Params("postID")
 
 	objectID, err := primitive.ObjectIDFromHex(postID)
 
-	postsCollection := db.Database.Collection("posts")
-
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
 	}
 
-	filter := bson.M{"_id": objectID}
+	post, err := h.repos.Posts.FindByID(c.UserContext(), objectID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "Error deleting the post"})
+	}
 
-	_, err = postsCollection.DeleteOne(context.Background(), filter)
+	err = h.repos.Transactions.WithTransaction(c.UserContext(), func(ctx context.Context) error {
+		if err := h.repos.Posts.Delete(ctx, objectID); err != nil || post.QuotedPostID == nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Posts.Create(ctx, &post)
+		})
+
+		err := h.repos.Posts.IncrementCounter(ctx, *post.QuotedPostID, repository.QuotesCount, -1)
+		if errors.Is(err, repository.ErrNotFound) {
+			// The quoted post is gone and its counter with it.
+			return nil
+		}
+		return err
+	})
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Error deleting the post"})
 	}
+	h.postDeleted(c.UserContext(), post)
 
 	return c.Status(200).JSON(fiber.Map{"msg": "Post was deleted successfully"})
 }
 
-func GetPost(c *fiber.Ctx) error {
+// GetPost returns the post in the path. Posts the viewer can't reach are not
+// found: when either blocked the other, when the author is private and not
+// followed, or when the audience leaves the viewer out. Unlisted posts are
+// found by anyone with their ID.
+func (h *Handler) GetPost(c *fiber.Ctx) error {
 	id := c.Params("postID")
 	postID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
 	}
 
-	pipeline := mongo.Pipeline{
-		bson.D{{"$match", bson.D{{"_id", postID}}}},
-		bson.D{{"$sort", bson.D{{"createdAt", -1}}}},
+	result, err := h.repos.Posts.FindItem(c.UserContext(), postID, viewerID(c))
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts: "+err.Error())
+	}
 
-		bson.D{{"$lookup", bson.D{
-			{"from", "users"},
-			{"localField", "userID"},
-			{"foreignField", "_id"},
-			{"as", "user"},
-		}}},
+	reachable, err := helpers.CanReach(c.UserContext(), h.repos, viewerID(c), result.Post)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts: "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
 
-		bson.D{{"$unwind", bson.D{
-			{"path", "$user"},
-			{"preserveNullAndEmptyArrays", true},
-		}}},
+	if err := quote.Embed(c.UserContext(), h.repos, viewerID(c), []*types.FeedItem{&result}); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts: "+err.Error())
+	}
 
-		bson.D{{"$project", bson.D{
-			{"post", "$$ROOT"},
-			{"userName", bson.D{{"$concat", bson.A{"$user.firstName", " ", "$user.lastName"}}}},
+	return c.Status(200).JSON(result)
+}
 
-			{"photoURL", "$user.photoURL"},
-			{"handle", "$user.handle"},
-		}}},
+// GetFeedPosts returns the public posts of public users, newest first, without
+// the ones by users the viewer blocked, was blocked by or muted. Posts for
+// followers or close friends only show up in timelines, profiles and the For
+// You feed of the viewers they reach.
+func (h *Handler) GetFeedPosts(c *fiber.Ctx) error {
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
 	}
 
-	var result FeedItem
+	ctx := c.UserContext()
+	viewer := viewerID(c)
 
-	collection := db.Database.Collection("posts")
+	excluded, err := h.excludedAuthors(ctx, viewer)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
+	}
 
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	result, err := h.repos.Posts.ListFeed(ctx, viewer, page, repository.FeedFilter{ExcludeAuthors: excluded})
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to fetch posts: "+err.Error())
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
 	}
 
-	for cursor.Next(context.Background()) {
-		if err := cursor.Decode(&result); err != nil {
-			return utils.RespondWithError(c, 500, "Failed to decode post: "+err.Error())
-		}
-		if result.PhotoURL != "" {
-			result.PhotoURL = utils.BuildImgURL(result.PhotoURL)
-		}
-		if result.Post.Images != nil {
-			for i := range result.Post.Images {
-				result.Post.Images[i] = utils.BuildImgURL(result.Post.Images[i])
-			}
-		}
+	if err := quote.Embed(ctx, h.repos, viewer, feedItems(result, ownFeedItem)); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
 	}
 
-	return c.Status(200).JSON(result)
+	return utils.RespondWithPage(c, utils.NewPaged(result, page, feedItemCursor))
 }
 
-func GetFeedPosts(c *fiber.Ctx) error {
-	pageParam := c.Query("page", "1")
+// GetQuotes returns the posts quoting a post, newest first, without the ones
+// by users the viewer blocked, was blocked by or muted and the ones out of
+// their reach.
+func (h *Handler) GetQuotes(c *fiber.Ctx) error {
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid ID")
+	}
 
-	l := 10
-	p, _ := strconv.Atoi(pageParam)
-	skip := int64(p*l - l)
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
 
-	limit := int64(l)
+	ctx := c.UserContext()
+	viewer := viewerID(c)
 
-	pipeline := utils.NewPipeline().
-		Sort("createdAt", -1).
-		Skip(skip).
-		Limit(limit).
-		Lookup("users", "userID", "_id", "user").
-		Unwind("$user", true).
-		Project(bson.D{
-			{"post", "$$ROOT"},
-			{"userName", bson.D{{"$concat", bson.A{"$user.firstName", " ", "$user.lastName"}}}},
+	post, err := h.repos.Posts.FindByID(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch quotes "+err.Error())
+	}
 
-			{"photoURL", "$user.photoURL"},
-			{"handle", "$user.handle"},
-		}).Build()
+	reachable, err := helpers.CanReach(ctx, h.repos, viewer, post)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch quotes "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
 
-	collection := db.Database.Collection("posts")
-	cursor, err := collection.Aggregate(context.Background(), pipeline)
+	reach, err := helpers.Reach(ctx, h.repos, viewer)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
+		return utils.RespondWithError(c, 500, "Failed to fetch quotes "+err.Error())
 	}
 
-	var result []FeedItem
+	excluded, err := h.excludedAuthors(ctx, viewer)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch quotes "+err.Error())
+	}
 
-	for cursor.Next(context.Background()) {
-		var feedItem FeedItem
-		if err := cursor.Decode(&feedItem); err != nil {
-			return utils.RespondWithError(c, 500, "Failed to decode post: "+err.Error())
-		}
-		if feedItem.PhotoURL != "" {
-			feedItem.PhotoURL = utils.BuildImgURL(feedItem.PhotoURL)
-		}
-		if feedItem.Post.Images != nil {
-			for i := range feedItem.Post.Images {
-				feedItem.Post.Images[i] = utils.BuildImgURL(feedItem.Post.Images[i])
-			}
-		}
+	filter := repository.FeedFilter{
+		ExcludeAuthors: excluded,
+		QuotedPostID:   postID,
+		Following:      append(reach.Followed, viewer),
+		Reach:          reach,
+	}
+	result, err := h.repos.Posts.ListFeed(ctx, viewer, page, filter)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch quotes "+err.Error())
+	}
 
-		result = append(result, feedItem)
+	if err := quote.Embed(ctx, h.repos, viewer, feedItems(result, ownFeedItem)); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch quotes "+err.Error())
 	}
 
-	return c.Status(200).JSON(result)
+	return utils.RespondWithPage(c, utils.NewPaged(result, page, feedItemCursor))
 }
 
-func UpdatePostCaption(c *fiber.Ctx) error {
-	id := c.Params("_id")
+// excludedAuthors returns the users whose posts viewer doesn't see in lists of
+// posts: the ones they muted and the ones they blocked or were blocked by.
+func (h *Handler) excludedAuthors(ctx context.Context, viewer primitive.ObjectID) ([]primitive.ObjectID, error) {
+	excluded, err := helpers.MutedIDs(ctx, h.repos, viewer, types.MuteScopePosts)
+	if err != nil || viewer.IsZero() {
+		return excluded, err
+	}
 
-	var body struct {
-		Caption string `json:"caption"`
+	blocked, err := helpers.BlockedIDs(ctx, h.repos, viewer)
+	return append(excluded, blocked...), err
+}
+
+// GetTimeline returns the current user's home timeline: their posts and the
+// posts and reposts of the users they follow, newest first.
+func (h *Handler) GetTimeline(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	if err := c.BodyParser(&body); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid request body"})
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	items, err := timeline.Read(c.UserContext(), h.repos, userID, page)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch timeline "+err.Error())
 	}
 
-	if body.Caption == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "Caption is required"})
+	if err := quote.Embed(c.UserContext(), h.repos, userID, feedItems(items, timelineFeedItem)); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch timeline "+err.Error())
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(id)
+	return utils.RespondWithPage(c, utils.NewPaged(items, page, timelineItemCursor))
+}
+
+// GetForYouFeed returns the current user's ranked For You feed. It is ranked
+// anew for every request, so it pages with ?page= instead of a cursor.
+// ?debug=true explains the score of every post.
+func (h *Handler) GetForYouFeed(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
+		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	collection := db.Database.Collection("posts")
-	filter := bson.M{"_id": objectID}
-	update := bson.M{"$set": bson.M{"caption": body.Caption}, "$currentDate": bson.M{"updatedAt": true}}
+	page, err := utils.ParsePage(c)
+	if err != nil || page.After != nil {
+		return utils.RespondWithError(c, 400, "Invalid page or limit")
+	}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	items, err := ranking.Rank(c.UserContext(), h.repos, userID, c.QueryBool("debug"))
 	if err != nil {
-		return err
+		return utils.RespondWithError(c, 500, "Failed to rank posts "+err.Error())
+	}
+
+	paged := utils.Paged[types.RankedItem]{Items: []types.RankedItem{}}
+	if page.Skip < int64(len(items)) {
+		paged.Items = items[page.Skip:min(page.Skip+page.Limit, int64(len(items)))]
+		paged.HasMore = page.Skip+page.Limit < int64(len(items))
+	}
+
+	if err := quote.Embed(c.UserContext(), h.repos, userID, feedItems(paged.Items, rankedFeedItem)); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to rank posts "+err.Error())
 	}
 
-	return c.Status(200).JSON(fiber.Map{"msg": "Post updated successfully"})
+	return utils.RespondWithPage(c, paged)
 }
 
-func LikePost(c *fiber.Ctx) error {
+func timelineItemCursor(item types.TimelineItem) utils.Cursor {
+	return utils.Cursor{CreatedAt: item.CreatedAt, ID: item.ID}
+}
+
+func (h *Handler) LikePost(c *fiber.Ctx) error {
 	var body struct {
 		UserID string `json:"userID"`
 		PostID string `json:"postID"`
 	}
 
-	likesCollection := db.Database.Collection("likes")
-	postsCollection := db.Database.Collection("posts")
-	usersCollection := db.Database.Collection("users")
-
 	if err := c.BodyParser(&body); err != nil {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
@@ -333,72 +437,66 @@ func LikePost(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	postFilter := bson.M{"_id": postID}
-	likeFilter := bson.M{"postID": postID, "userID": userID}
-	userFilter := bson.M{"_id": userID}
+	return h.toggleLike(c, postID, userID)
+}
 
-	var like types.Like
-	var post types.Post
-	var update bson.M
-	var user types.User
+// ToggleLike is the v2 version of LikePost: the post comes from the path and the
+// acting user from the token instead of the request body.
+func (h *Handler) ToggleLike(c *fiber.Ctx) error {
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid post ID")
+	}
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	return h.toggleLike(c, postID, userID)
+}
+
+func (h *Handler) toggleLike(c *fiber.Ctx, postID, userID primitive.ObjectID) error {
+	ctx := c.UserContext()
 
-	err = usersCollection.FindOne(context.Background(), userFilter).Decode(&user)
+	user, err := h.repos.Users.FindByID(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 404, "User not found")
 	}
 
 	userName := strings.TrimSpace(user.FirstName + " " + user.LastName)
 
-	err = postsCollection.FindOne(context.Background(), postFilter).Decode(&post)
+	post, err := h.repos.Posts.FindByID(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Post not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to retrieve post: "+err.Error())
 	}
 
-	err = likesCollection.FindOne(context.Background(), likeFilter).Decode(&like)
-
-	if err == nil {
-		_, err = likesCollection.DeleteOne(context.Background(), likeFilter)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "Failed to unlike the post: "+err.Error())
-		}
-
-		update = bson.M{"$inc": bson.M{"likesCount": -1}}
-
-		_, err = postsCollection.UpdateOne(context.Background(), postFilter, update)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "Failed to update post like count: "+err.Error())
-		}
-
-		err = postsCollection.FindOne(context.Background(), postFilter).Decode(&post)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "Failed to retrieve updated post: "+err.Error())
-		}
-
-		return c.Status(200).JSON(fiber.Map{
-			"likesCount": post.LikesCount,
-		})
+	reachable, err := helpers.CanReach(ctx, h.repos, userID, post)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to retrieve post: "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, 404, "Post not found")
 	}
 
-	update = bson.M{"$inc": bson.M{"likesCount": 1}}
-
-	newLike := types.Like{
+	like := types.Like{
 		PostID:    postID,
 		UserID:    userID,
 		UserName:  userName,
 		CreatedAt: time.Now(),
 	}
 
-	_, err = likesCollection.InsertOne(context.Background(), newLike)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to add like: "+err.Error())
-	}
-
-	_, err = postsCollection.UpdateOne(context.Background(), postFilter, update)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to update post like count: "+err.Error())
+	err = h.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		return h.toggle(ctx, like)
+	})
+	// ErrDuplicate means a concurrent request liked the post and counted it already.
+	if err != nil && !errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 500, "Failed to like the post: "+err.Error())
 	}
 
-	err = postsCollection.FindOne(context.Background(), postFilter).Decode(&post)
+	post, err = h.repos.Posts.FindByID(ctx, postID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to retrieve updated post: "+err.Error())
 	}
@@ -407,3 +505,33 @@ func LikePost(c *fiber.Ctx) error {
 		"likesCount": post.LikesCount,
 	})
 }
+
+// toggle removes the user's like of the post or adds it if there is none, and
+// keeps the post's likesCount in step. It must run in a transaction.
+func (h *Handler) toggle(ctx context.Context, like types.Like) error {
+	err := h.repos.Likes.Delete(ctx, like.PostID, like.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return h.like(ctx, like)
+	}
+	if err != nil {
+		return err
+	}
+
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return h.repos.Likes.Create(ctx, &like)
+	})
+
+	return h.repos.Posts.IncrementCounter(ctx, like.PostID, repository.LikesCount, -1)
+}
+
+func (h *Handler) like(ctx context.Context, like types.Like) error {
+	if err := h.repos.Likes.Create(ctx, &like); err != nil {
+		return err
+	}
+
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return h.repos.Likes.Delete(ctx, like.PostID, like.UserID)
+	})
+
+	return h.repos.Posts.IncrementCounter(ctx, like.PostID, repository.LikesCount, 1)
+}
@@ -0,0 +1,109 @@
+package post_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/edisss1/fiabesco-backend/handlers/post"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLikePost(t *testing.T) {
+	tests := []struct {
+		name string
+		// likes is how many times the user likes the post in a row.
+		likes int
+		// postID is the liked post, the existing one when empty.
+		postID         string
+		private        bool
+		wantStatus     int
+		wantLikesCount uint32
+	}{
+		{name: "like", likes: 1, wantStatus: 200, wantLikesCount: 1},
+		{name: "like twice unlikes", likes: 2, wantStatus: 200},
+		{name: "missing post", likes: 1, postID: primitive.NewObjectID().Hex(), wantStatus: 404},
+		{name: "invalid post ID", likes: 1, postID: "nope", wantStatus: 400},
+		{name: "private author not followed", likes: 1, private: true, wantStatus: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			app := fiber.New()
+			app.Post("/posts/like", post.NewHandler(repos).LikePost)
+
+			author := createUser(t, repos, "author")
+			liker := createUser(t, repos, "liker")
+			liked := types.Post{UserID: author, Caption: "hello", Audience: types.AudiencePublic, CreatedAt: time.Now()}
+			if err := repos.Posts.Create(ctx, &liked); err != nil {
+				t.Fatal(err)
+			}
+			if tt.private {
+				if err := repos.Settings.Set(ctx, author, bson.M{"profileVisibility": types.VisibilityPrivate}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			postID := liked.ID.Hex()
+			if tt.postID != "" {
+				postID = tt.postID
+			}
+
+			var status int
+			var body struct {
+				LikesCount uint32 `json:"likesCount"`
+			}
+			for range tt.likes {
+				payload := `{"userID":"` + liker.Hex() + `","postID":"` + postID + `"}`
+				req := httptest.NewRequest("POST", "/posts/like", strings.NewReader(payload))
+				req.Header.Set("Content-Type", "application/json")
+
+				resp, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				status = resp.StatusCode
+				if status == 200 {
+					if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+						t.Fatal(err)
+					}
+				}
+				resp.Body.Close()
+			}
+
+			if status != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", status, tt.wantStatus)
+			}
+			if body.LikesCount != tt.wantLikesCount {
+				t.Errorf("likesCount = %d, want %d", body.LikesCount, tt.wantLikesCount)
+			}
+
+			stored, err := repos.Posts.FindByID(ctx, liked.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.LikesCount != tt.wantLikesCount {
+				t.Errorf("stored likesCount = %d, want %d", stored.LikesCount, tt.wantLikesCount)
+			}
+		})
+	}
+}
+
+func createUser(t *testing.T, repos *repository.Repositories, handle string) primitive.ObjectID {
+	t.Helper()
+
+	user := types.User{Email: handle + "@example.com", Handle: handle}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
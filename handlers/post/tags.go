@@ -0,0 +1,99 @@
+package post
+
+import (
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"net/url"
+)
+
+// GetTagPosts lists the posts with a tag within the viewer's reach, newest
+// first, leaving out private users the viewer doesn't follow. The tag is
+// normalized like the tags of posts, so "%23Café" finds the posts tagged
+// "cafe".
+func (h *Handler) GetTagPosts(c *fiber.Ctx) error {
+	raw, err := url.PathUnescape(c.Params("tag"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid tag")
+	}
+	tag := hashtag.Normalize(raw)
+	if tag == "" {
+		return utils.RespondWithError(c, 400, "Invalid tag")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	ctx := c.UserContext()
+	viewer := viewerID(c)
+
+	excluded, err := h.excludedAuthors(ctx, viewer)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
+	}
+
+	reach, err := helpers.Reach(ctx, h.repos, viewer)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
+	}
+
+	filter := repository.FeedFilter{ExcludeAuthors: excluded, Tag: tag, Following: append(reach.Followed, viewer), Reach: reach}
+	result, err := h.repos.Posts.ListFeed(ctx, viewer, page, filter)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
+	}
+
+	if err := quote.Embed(ctx, h.repos, viewer, feedItems(result, ownFeedItem)); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch posts "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, utils.NewPaged(result, page, feedItemCursor))
+}
+
+// GetTagSuggestions completes the tag the user is typing in ?q=, the most used
+// tags first.
+func (h *Handler) GetTagSuggestions(c *fiber.Ctx) error {
+	limit, err := utils.ParseLimit(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid limit")
+	}
+
+	prefix := hashtag.Normalize(c.Query("q"))
+	if prefix == "" {
+		return c.Status(200).JSON([]types.Tag{})
+	}
+
+	tags, err := h.repos.Tags.Search(c.UserContext(), prefix, limit)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch tags "+err.Error())
+	}
+	if tags == nil {
+		tags = []types.Tag{}
+	}
+
+	return c.Status(200).JSON(tags)
+}
+
+// GetTrendingTags returns the tags used most in recent posts.
+func (h *Handler) GetTrendingTags(c *fiber.Ctx) error {
+	limit, err := utils.ParseLimit(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid limit")
+	}
+
+	tags, err := hashtag.Trending(c.UserContext(), h.repos, limit)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch trending tags "+err.Error())
+	}
+	if tags == nil {
+		tags = []types.TrendingTag{}
+	}
+
+	return c.Status(200).JSON(tags)
+}
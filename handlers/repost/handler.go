@@ -3,20 +3,29 @@ package repost
 import (
 	"context"
 	"errors"
-	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/timeline"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
+	"log"
 	"net/http"
 	"time"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
-func Repost(c *fiber.Ctx) error {
+// Repost reposts a public post for the current user. Posts with another
+// audience can't be reposted.
+func (h *Handler) Repost(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
@@ -39,27 +48,111 @@ func Repost(c *fiber.Ctx) error {
 		UpdatedAt:     time.Now(),
 	}
 
-	collection = db.Database.Collection("reposts")
+	post, err := h.repos.Posts.FindByID(c.UserContext(), body.PostID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error finding post: "+err.Error())
+	}
+
+	reachable, err := helpers.CanReach(c.UserContext(), h.repos, userID, post)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error finding post: "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
+	if post.Audience != "" && post.Audience != types.AudiencePublic {
+		return utils.RespondWithError(c, http.StatusBadRequest, "Only public posts can be reposted")
+	}
+
+	err = h.repos.Transactions.WithTransaction(c.UserContext(), func(ctx context.Context) error {
+		if err := h.repos.Reposts.Create(ctx, &repost); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Reposts.Delete(ctx, repost.ID)
+		})
 
-	_, err = collection.InsertOne(context.Background(), repost)
+		return h.repos.Posts.IncrementCounter(ctx, body.PostID, repository.RepostCount, 1)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, http.StatusBadRequest, "Post already reposted")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusInternalServerError, "Error creating repost: "+err.Error())
 	}
 
-	collection = db.Database.Collection("posts")
+	if err := timeline.Publish(c.UserContext(), h.repos, timeline.RepostEntry(repost, post)); err != nil {
+		log.Println("Error publishing repost to timelines: ", err)
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Repost created successfully", "repost": repost})
+}
+
+func (h *Handler) GetReposts(c *fiber.Ctx) error {
+	postID, err := utils.ParseHexID(c.Params("postID"))
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid ID")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid cursor or limit")
+	}
+
+	ctx := c.UserContext()
+	viewerID, _ := utils.GetUserID(c)
+
+	post, err := h.repos.Posts.FindByID(ctx, postID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error finding post: "+err.Error())
+	}
+
+	reachable, err := helpers.CanReach(ctx, h.repos, viewerID, post)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error finding post: "+err.Error())
+	}
+	if !reachable {
+		return utils.RespondWithError(c, http.StatusNotFound, "Post not found")
+	}
+
+	excluded, err := helpers.MutedIDs(ctx, h.repos, viewerID, types.MuteScopeReposts)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error getting reposts: "+err.Error())
+	}
+	blocked, err := helpers.BlockedIDs(ctx, h.repos, viewerID)
+	if err != nil {
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error getting reposts: "+err.Error())
+	}
+	excluded = append(excluded, blocked...)
 
-	filter := bson.M{"_id": body.PostID}
-	update := bson.M{"$inc": bson.M{"repostCount": 1}}
+	var following []primitive.ObjectID
+	if !viewerID.IsZero() {
+		if following, err = h.repos.Follows.FollowingIDs(ctx, viewerID); err != nil {
+			return utils.RespondWithError(c, http.StatusInternalServerError, "Error getting reposts: "+err.Error())
+		}
+		following = append(following, viewerID)
+	}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	reposts, err := h.repos.Reposts.ListByPost(ctx, postID, page, excluded, following)
 	if err != nil {
-		return utils.RespondWithError(c, http.StatusInternalServerError, "Error updating post: "+err.Error())
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error getting reposts: "+err.Error())
 	}
 
-	return c.Status(200).JSON(fiber.Map{"msg": "Repost created successfully"})
+	return utils.RespondWithPage(c, utils.NewPaged(reposts, page, func(item types.RepostItem) utils.Cursor {
+		return utils.Cursor{CreatedAt: item.CreatedAt, ID: item.ID}
+	}))
 }
 
-func EditRepostCaption(c *fiber.Ctx) error {
+func (h *Handler) EditRepostCaption(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
@@ -73,27 +166,19 @@ func EditRepostCaption(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
 	}
 
-	collection = db.Database.Collection("reposts")
-	var repost types.Repost
-
-	filter := bson.M{"_id": body.RepostID}
-
-	err = collection.FindOne(context.Background(), filter).Decode(&repost)
+	repost, err := h.repos.Reposts.FindByID(c.UserContext(), body.RepostID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Repost not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusInternalServerError, "Error finding repost: "+err.Error())
 	}
 
-	if errors.Is(err, mongo.ErrNoDocuments) {
-		return utils.RespondWithError(c, http.StatusNotFound, "Repost not found")
-	}
-
 	if repost.RepostedBy != userID {
 		return utils.RespondWithError(c, http.StatusUnauthorized, "You are not authorized to update this repost")
 	}
 
-	update := bson.M{"$set": bson.M{"repostCaption": body.NewRepostCaption}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Reposts.UpdateCaption(c.UserContext(), body.RepostID, body.NewRepostCaption)
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusInternalServerError, "Error updating repost caption: "+err.Error())
 	}
@@ -101,7 +186,7 @@ func EditRepostCaption(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "Repost caption updated successfully"})
 }
 
-func DeleteRepost(c *fiber.Ctx) error {
+func (h *Handler) DeleteRepost(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid user ID")
@@ -111,14 +196,14 @@ func DeleteRepost(c *fiber.Ctx) error {
 		RepostID primitive.ObjectID `json:"repostID" bson:"repostID"`
 	}
 
-	collection = db.Database.Collection("reposts")
-
-	filter := bson.M{"_id": body.RepostID}
-
-	var repost types.Repost
-
-	err = collection.FindOne(context.Background(), filter).Decode(&repost)
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, http.StatusBadRequest, "Invalid request body")
+	}
 
+	repost, err := h.repos.Reposts.FindByID(c.UserContext(), body.RepostID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Repost not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, http.StatusInternalServerError, "Error finding repost: "+err.Error())
 	}
@@ -127,21 +212,27 @@ func DeleteRepost(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, http.StatusUnauthorized, "You are not authorized to delete this repost")
 	}
 
-	_, err = collection.DeleteOne(context.Background(), filter)
-	if err != nil {
-		return utils.RespondWithError(c, http.StatusInternalServerError, "Error deleting repost: "+err.Error())
+	err = h.repos.Transactions.WithTransaction(c.UserContext(), func(ctx context.Context) error {
+		if err := h.repos.Reposts.Delete(ctx, body.RepostID); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return h.repos.Reposts.Create(ctx, &repost)
+		})
+
+		err := h.repos.Posts.IncrementCounter(ctx, repost.PostID, repository.RepostCount, -1)
+		if errors.Is(err, repository.ErrNotFound) {
+			// The post is gone and its counter with it.
+			return nil
+		}
+		return err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, http.StatusNotFound, "Repost not found")
 	}
-
-	collection = db.Database.Collection("posts")
-
-	filter = bson.M{"_id": repost.PostID}
-	update := bson.M{"$inc": bson.M{"repostCount": -1}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
 	if err != nil {
-		return utils.RespondWithError(c, http.StatusInternalServerError, "Error updating post: "+err.Error())
+		return utils.RespondWithError(c, http.StatusInternalServerError, "Error deleting repost: "+err.Error())
 	}
 
 	return c.Status(200).JSON(fiber.Map{"msg": "Repost deleted successfully"})
-
 }
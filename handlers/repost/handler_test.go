@@ -0,0 +1,91 @@
+package repost_test
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/handlers/repost"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRepost(t *testing.T) {
+	tests := []struct {
+		name        string
+		audience    string
+		follows     bool
+		wantStatus  int
+		wantReposts uint32
+	}{
+		{name: "public", audience: types.AudiencePublic, wantStatus: 200, wantReposts: 1},
+		{name: "followers only", audience: types.AudienceFollowers, follows: true, wantStatus: 400},
+		{name: "unlisted", audience: types.AudienceUnlisted, wantStatus: 400},
+		{name: "close friends out of reach", audience: types.AudienceCloseFriends, wantStatus: 404},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			author := createUser(t, repos, "author")
+			reposter := createUser(t, repos, "reposter")
+
+			app := fiber.New()
+			app.Post("/reposts", signedIn(reposter), repost.NewHandler(repos).Repost)
+
+			post := types.Post{UserID: author, Caption: "hello", Audience: tt.audience, CreatedAt: time.Now()}
+			if err := repos.Posts.Create(ctx, &post); err != nil {
+				t.Fatal(err)
+			}
+			if tt.follows {
+				if err := repos.Follows.Follow(ctx, reposter, author); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			req := httptest.NewRequest("POST", "/reposts", strings.NewReader(`{"postID":"`+post.ID.Hex()+`"}`))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("got status %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			stored, err := repos.Posts.FindByID(ctx, post.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.RepostCount != tt.wantReposts {
+				t.Errorf("repostCount = %d, want %d", stored.RepostCount, tt.wantReposts)
+			}
+		})
+	}
+}
+
+// signedIn sets the token that middleware.RequireJWT would set for userID.
+func signedIn(userID primitive.ObjectID) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals("jwt", jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"id": userID.Hex()}))
+		return c.Next()
+	}
+}
+
+func createUser(t *testing.T, repos *repository.Repositories, handle string) primitive.ObjectID {
+	t.Helper()
+
+	user := types.User{Email: handle + "@example.com", Handle: handle}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
@@ -0,0 +1,215 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxQueryLength is the most characters a search query may have.
+const MaxQueryLength = 100
+
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
+
+// Search finds users by name and handle, posts by caption and tags, and tags
+// starting with ?q=, the best matches first. Without ?type= it returns the
+// first page of every group; ?type=users, posts or tags pages through one
+// group with ?cursor=. Blocked users and their posts are left out, and so are
+// muted users' posts, the posts of private users the viewer doesn't follow and
+// the posts whose audience leaves the viewer out.
+func (h *Handler) Search(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" || utf8.RuneCountInString(text) > MaxQueryLength {
+		return utils.RespondWithError(c, 400, "Query must have 1 to 100 characters")
+	}
+
+	page, err := utils.ParseScorePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	ctx := c.UserContext()
+	query := repository.SearchQuery{Text: text, ViewerID: userID, Page: page}
+
+	switch c.Query("type") {
+	case "":
+		if page.After != nil {
+			return utils.RespondWithError(c, 400, "A cursor needs a type")
+		}
+
+		users, err := h.users(ctx, query)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Failed to search users "+err.Error())
+		}
+		posts, err := h.posts(ctx, query)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Failed to search posts "+err.Error())
+		}
+		tags, err := h.tags(ctx, query)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Failed to search tags "+err.Error())
+		}
+
+		return c.Status(200).JSON(fiber.Map{"users": users, "posts": posts, "tags": tags})
+	case "users":
+		users, err := h.users(ctx, query)
+		if err != nil {
+			return respondWithSearchError(c, "users", err)
+		}
+		return utils.RespondWithPage(c, users)
+	case "posts":
+		posts, err := h.posts(ctx, query)
+		if err != nil {
+			return respondWithSearchError(c, "posts", err)
+		}
+		return utils.RespondWithPage(c, posts)
+	case "tags":
+		tags, err := h.tags(ctx, query)
+		if err != nil {
+			return respondWithSearchError(c, "tags", err)
+		}
+		return utils.RespondWithPage(c, tags)
+	default:
+		return utils.RespondWithError(c, 400, "Type must be users, posts or tags")
+	}
+}
+
+// GetHandleSuggestions completes the handle the user is typing in ?q=, the
+// most followed users first.
+func (h *Handler) GetHandleSuggestions(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	limit, err := utils.ParseLimit(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid limit")
+	}
+
+	prefix := strings.TrimPrefix(strings.TrimSpace(c.Query("q")), "@")
+	if prefix == "" || utf8.RuneCountInString(prefix) > MaxQueryLength {
+		return c.Status(200).JSON([]types.UserResult{})
+	}
+
+	ctx := c.UserContext()
+
+	blocked, err := helpers.BlockedIDs(ctx, h.repos, userID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch users "+err.Error())
+	}
+
+	users, err := h.repos.Search.Handles(ctx, prefix, blocked, limit)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to fetch users "+err.Error())
+	}
+	if users == nil {
+		users = []types.UserResult{}
+	}
+
+	return c.Status(200).JSON(users)
+}
+
+// users searches the users, leaving out the ones the viewer blocked or was
+// blocked by.
+func (h *Handler) users(ctx context.Context, query repository.SearchQuery) (utils.Paged[types.UserResult], error) {
+	blocked, err := helpers.BlockedIDs(ctx, h.repos, query.ViewerID)
+	if err != nil {
+		return utils.Paged[types.UserResult]{}, err
+	}
+	query.Exclude = blocked
+
+	users, err := h.repos.Search.Users(ctx, query)
+	if err != nil {
+		return utils.Paged[types.UserResult]{}, err
+	}
+
+	return utils.NewScorePaged(users, query.Page, func(user types.UserResult) utils.ScoreCursor {
+		return utils.ScoreCursor{Score: user.Score, ID: user.ID.Hex()}
+	}), nil
+}
+
+// posts searches the posts within the viewer's reach, leaving out the ones of
+// users they muted.
+func (h *Handler) posts(ctx context.Context, query repository.SearchQuery) (utils.Paged[types.PostResult], error) {
+	muted, err := helpers.MutedIDs(ctx, h.repos, query.ViewerID, types.MuteScopePosts)
+	if err != nil {
+		return utils.Paged[types.PostResult]{}, err
+	}
+	blocked, err := helpers.BlockedIDs(ctx, h.repos, query.ViewerID)
+	if err != nil {
+		return utils.Paged[types.PostResult]{}, err
+	}
+	reach, err := helpers.Reach(ctx, h.repos, query.ViewerID)
+	if err != nil {
+		return utils.Paged[types.PostResult]{}, err
+	}
+	query.Exclude = append(muted, blocked...)
+	query.Following = append(reach.Followed, query.ViewerID)
+	query.Reach = reach
+
+	posts, err := h.repos.Search.Posts(ctx, query)
+	if err != nil {
+		return utils.Paged[types.PostResult]{}, err
+	}
+
+	paged := utils.NewScorePaged(posts, query.Page, func(post types.PostResult) utils.ScoreCursor {
+		return utils.ScoreCursor{Score: post.Score, ID: post.Post.ID.Hex()}
+	})
+
+	items := make([]*types.FeedItem, 0, len(paged.Items))
+	for i := range paged.Items {
+		items = append(items, &paged.Items[i].FeedItem)
+	}
+	if err := quote.Embed(ctx, h.repos, query.ViewerID, items); err != nil {
+		return utils.Paged[types.PostResult]{}, err
+	}
+
+	return paged, nil
+}
+
+// tags searches the tags starting with the query, which is normalized like the
+// tags of posts.
+func (h *Handler) tags(ctx context.Context, query repository.SearchQuery) (utils.Paged[types.Tag], error) {
+	query.Text = hashtag.Normalize(query.Text)
+	if query.Text == "" {
+		return utils.Paged[types.Tag]{Items: []types.Tag{}}, nil
+	}
+
+	tags, err := h.repos.Search.Tags(ctx, query)
+	if err != nil {
+		return utils.Paged[types.Tag]{}, err
+	}
+
+	return utils.NewScorePaged(tags, query.Page, func(tag types.Tag) utils.ScoreCursor {
+		return utils.ScoreCursor{Score: float64(tag.PostsCount), ID: tag.Name}
+	}), nil
+}
+
+// respondWithSearchError answers a cursor of another group with a 400 and any
+// other error with a 500.
+func respondWithSearchError(c *fiber.Ctx, group string, err error) error {
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		return utils.RespondWithError(c, 400, "Invalid cursor")
+	}
+	return utils.RespondWithError(c, 500, "Failed to search "+group+" "+err.Error())
+}
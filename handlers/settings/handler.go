@@ -1,20 +1,26 @@
 package settings
 
 import (
-	"context"
-	"github.com/edisss1/fiabesco-backend/db"
+	"errors"
 	"github.com/edisss1/fiabesco-backend/handlers/auth"
 	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/mongo"
+	"log"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
-func ChangeFirstName(c *fiber.Ctx) error {
+func (h *Handler) ChangeFirstName(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
@@ -28,12 +34,7 @@ func ChangeFirstName(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"firstName": body.FirstName}}
-
-	collection = db.Database.Collection("users")
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"firstName": body.FirstName})
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating first name "+err.Error())
 	}
@@ -41,7 +42,7 @@ func ChangeFirstName(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "First name updated successfully"})
 }
 
-func ChangeLastName(c *fiber.Ctx) error {
+func (h *Handler) ChangeLastName(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
@@ -55,12 +56,7 @@ func ChangeLastName(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"lastName": body.LastName}}
-
-	collection = db.Database.Collection("users")
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"lastName": body.LastName})
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating last name "+err.Error())
 	}
@@ -68,7 +64,7 @@ func ChangeLastName(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "Last name updated successfully"})
 }
 
-func ChangeEmail(c *fiber.Ctx) error {
+func (h *Handler) ChangeEmail(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
@@ -82,12 +78,10 @@ func ChangeEmail(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"email": body.Email}}
-
-	collection = db.Database.Collection("users")
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"email": body.Email})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "Email already in use")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating email "+err.Error())
 	}
@@ -95,7 +89,7 @@ func ChangeEmail(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "Email updated successfully"})
 }
 
-func ChangeHandle(c *fiber.Ctx) error {
+func (h *Handler) ChangeHandle(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
@@ -109,29 +103,29 @@ func ChangeHandle(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	filter := bson.M{"handle": body.Handle}
-
-	collection = db.Database.Collection("users")
-
-	if err := collection.FindOne(context.Background(), filter).Err(); err == nil {
+	if _, err := h.repos.Users.FindByHandle(c.UserContext(), body.Handle); err == nil {
 		return utils.RespondWithError(c, 400, "Handle already exists")
 	}
 
-	filter = bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"handle": body.Handle}}
-
-	collection = db.Database.Collection("users")
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"handle": body.Handle})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "Handle already exists")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating handle "+err.Error())
 	}
 
+	// Captions, comments and messages show the handle of the users they
+	// mention, so they follow the change.
+	if _, err := h.repos.Mentions.Rename(c.UserContext(), userID, body.Handle); err != nil {
+		log.Println("Error renaming mentions: ", err)
+	}
+
 	return c.Status(200).JSON(fiber.Map{"msg": "Handle updated successfully"})
 
 }
 
-func ChangePassword(c *fiber.Ctx) error {
+func (h *Handler) ChangePassword(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
@@ -145,12 +139,7 @@ func ChangePassword(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	var user types.User
-	filter := bson.M{"_id": userID}
-
-	collection = db.Database.Collection("users")
-
-	err = collection.FindOne(context.Background(), filter).Decode(&user)
+	user, err := h.repos.Users.FindByID(c.UserContext(), userID)
 	if err != nil {
 		return utils.RespondWithError(c, 404, "User not found")
 	}
@@ -161,10 +150,7 @@ func ChangePassword(c *fiber.Ctx) error {
 
 	hashedPassword := auth.HashPassword(body.Password)
 
-	filter = bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"password": hashedPassword}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"password": hashedPassword})
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating password "+err.Error())
 	}
@@ -172,7 +158,7 @@ func ChangePassword(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"msg": "Password updated successfully"})
 }
 
-func ChangeTheme(c *fiber.Ctx) error {
+func (h *Handler) ChangeTheme(c *fiber.Ctx) error {
 
 	var body struct {
 		Theme string `json:"theme"`
@@ -182,7 +168,7 @@ func ChangeTheme(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	err := helpers.SaveSetting(c, map[string]interface{}{"theme": body.Theme})
+	err := helpers.SaveSetting(c, h.repos, map[string]interface{}{"theme": body.Theme})
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating theme "+err.Error())
 	}
@@ -191,7 +177,7 @@ func ChangeTheme(c *fiber.Ctx) error {
 
 }
 
-func ChangeLanguage(c *fiber.Ctx) error {
+func (h *Handler) ChangeLanguage(c *fiber.Ctx) error {
 
 	var body struct {
 		Language string `json:"language"`
@@ -201,7 +187,7 @@ func ChangeLanguage(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	err := helpers.SaveSetting(c, map[string]interface{}{"language": body.Language})
+	err := helpers.SaveSetting(c, h.repos, map[string]interface{}{"language": body.Language})
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating language "+err.Error())
 	}
@@ -210,7 +196,13 @@ func ChangeLanguage(c *fiber.Ctx) error {
 
 }
 
-func ChangeProfileVisibility(c *fiber.Ctx) error {
+// ChangeProfileVisibility switches the current user's profile between public
+// and private. Going public approves every pending follow request.
+func (h *Handler) ChangeProfileVisibility(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid ID")
+	}
 
 	var body struct {
 		ProfileVisibility string `json:"profileVisibility"`
@@ -220,21 +212,44 @@ func ChangeProfileVisibility(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	err := helpers.SaveSetting(c, map[string]interface{}{"profileVisibility": body.ProfileVisibility})
+	if body.ProfileVisibility != types.VisibilityPublic && body.ProfileVisibility != types.VisibilityPrivate {
+		return utils.RespondWithError(c, 400, "Profile visibility must be public or private")
+	}
+
+	ctx := c.UserContext()
+
+	err = h.repos.Settings.Set(ctx, userID, bson.M{"profileVisibility": body.ProfileVisibility})
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error updating profile visibility "+err.Error())
 	}
 
+	if body.ProfileVisibility == types.VisibilityPublic {
+		requests, err := h.repos.FollowRequests.FindIncoming(ctx, userID)
+		if err != nil {
+			return utils.RespondWithError(c, 500, "Error approving follow requests "+err.Error())
+		}
+
+		for _, request := range requests {
+			// A request cancelled meanwhile is gone already.
+			err := helpers.ApproveFollowRequest(ctx, h.repos, request)
+			if err != nil && !errors.Is(err, repository.ErrNotFound) {
+				return utils.RespondWithError(c, 500, "Error approving follow requests "+err.Error())
+			}
+		}
+	}
+
 	return c.Status(200).JSON(fiber.Map{"msg": "Profile visibility updated successfully"})
 
 }
 
-func DownloadUserData(c *fiber.Ctx) error {
+func (h *Handler) DownloadUserData(c *fiber.Ctx) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid ID")
 	}
 
+	ctx := c.UserContext()
+
 	var user types.User
 	var posts []types.Post
 	var comments []types.Comment
@@ -242,72 +257,53 @@ func DownloadUserData(c *fiber.Ctx) error {
 	var likes []types.Like
 	var conversations []types.Conversation
 
-	filter := bson.M{"_id": userID}
-
-	collection = db.Database.Collection("users")
-
-	err = collection.FindOne(context.Background(), filter).Decode(&user)
+	user, err = h.repos.Users.FindByID(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 404, "User not found")
 	}
 
 	user.Password = ""
 
-	collection = db.Database.Collection("posts")
-
-	cursor, err := collection.Find(context.Background(), bson.M{"userID": userID})
+	posts, err = h.repos.Posts.FindByUser(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error finding posts "+err.Error())
 	}
 
-	err = cursor.All(context.Background(), &posts)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Error decoding posts "+err.Error())
-	}
-
-	collection = db.Database.Collection("comments")
-
-	cursor, err = collection.Find(context.Background(), bson.M{"userID": userID})
+	comments, err = h.repos.Comments.FindByUser(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error finding comments "+err.Error())
 	}
 
-	err = cursor.All(context.Background(), &comments)
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Error decoding comments "+err.Error())
-	}
-
-	collection = db.Database.Collection("settings")
-
-	err = collection.FindOne(context.Background(), bson.M{"userID": userID}).Decode(&settings)
+	settings, err = h.repos.Settings.FindByUser(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error finding settings "+err.Error())
 	}
 
-	collection = db.Database.Collection("likes")
-
-	cursor, err = collection.Find(context.Background(), bson.M{"userID": userID})
+	likes, err = h.repos.Likes.FindByUser(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Error finding likes "+err.Error())
 	}
 
-	err = cursor.All(context.Background(), &likes)
+	saves, err := h.repos.Saves.FindByUser(ctx, userID)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Error decoding likes "+err.Error())
+		return utils.RespondWithError(c, 500, "Error finding saves "+err.Error())
 	}
 
-	collection = db.Database.Collection("conversations")
+	collections, err := h.repos.Collections.ListByUser(ctx, userID, false)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Error finding collections "+err.Error())
+	}
 
-	cursor, err = collection.Find(context.Background(), bson.M{"participants": userID})
+	closeFriends, err := h.repos.CloseFriends.FriendIDs(ctx, userID)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Error finding conversations "+err.Error())
+		return utils.RespondWithError(c, 500, "Error finding close friends "+err.Error())
 	}
 
-	err = cursor.All(context.Background(), &conversations)
+	conversations, err = h.repos.Conversations.ListByParticipant(ctx, userID)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Error decoding conversations "+err.Error())
+		return utils.RespondWithError(c, 500, "Error finding conversations "+err.Error())
 	}
 
-	return c.Status(200).JSON(fiber.Map{"user": user, "posts": posts, "comments": comments, "settings": settings, "likes": likes, "conversations": conversations})
+	return c.Status(200).JSON(fiber.Map{"user": user, "posts": posts, "comments": comments, "settings": settings, "likes": likes, "saves": saves, "collections": collections, "closeFriends": closeFriends, "conversations": conversations})
 
 }
@@ -0,0 +1,130 @@
+package social
+
+import (
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"time"
+)
+
+type CloseFriendRes struct {
+	ID        primitive.ObjectID `json:"_id"`
+	FirstName string             `json:"firstName"`
+	LastName  string             `json:"lastName"`
+	Handle    string             `json:"handle"`
+	PhotoURL  string             `json:"photoURL"`
+}
+
+// AddCloseFriend puts the user in the path on the current user's close friends
+// list, which lets them see the current user's close-friends posts.
+func (h *Handler) AddCloseFriend(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	friendID, err := utils.ParseHexID(c.Params("userID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid friend ID")
+	}
+	if friendID == userID {
+		return utils.RespondWithError(c, 400, "Cannot add yourself")
+	}
+
+	ctx := c.UserContext()
+
+	if _, err := h.repos.Users.FindByID(ctx, friendID); err != nil {
+		return utils.RespondWithError(c, 404, "User not found")
+	}
+
+	blocked, err := helpers.BlockedIDs(ctx, h.repos, userID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to add close friend "+err.Error())
+	}
+	if slices.Contains(blocked, friendID) {
+		return utils.RespondWithError(c, 404, "User not found")
+	}
+
+	closeFriend := types.CloseFriend{UserID: userID, FriendID: friendID, CreatedAt: time.Now()}
+	err = h.repos.CloseFriends.Add(ctx, &closeFriend)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "User already a close friend")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to add close friend "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Close friend added", "closeFriend": closeFriend})
+}
+
+// RemoveCloseFriend takes the user in the path off the current user's close
+// friends list.
+func (h *Handler) RemoveCloseFriend(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	friendID, err := utils.ParseHexID(c.Params("userID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid friend ID")
+	}
+
+	err = h.repos.CloseFriends.Remove(c.UserContext(), userID, friendID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "User not a close friend")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to remove close friend "+err.Error())
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Close friend removed"})
+}
+
+// GetCloseFriends lists the current user's close friends, the most recently
+// added first. Only the current user sees their list.
+func (h *Handler) GetCloseFriends(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	ctx := c.UserContext()
+
+	friendIDs, err := h.repos.CloseFriends.FriendIDs(ctx, userID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to get close friends "+err.Error())
+	}
+
+	users, err := h.repos.Users.FindByIDs(ctx, friendIDs)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "User fetch error: "+err.Error())
+	}
+
+	byID := make(map[primitive.ObjectID]types.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	friends := make([]CloseFriendRes, 0, len(friendIDs))
+	for _, friendID := range friendIDs {
+		user, ok := byID[friendID]
+		if !ok {
+			continue
+		}
+		friends = append(friends, CloseFriendRes{
+			ID:        user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Handle:    user.Handle,
+			PhotoURL:  utils.MediaURL(user.PhotoURL),
+		})
+	}
+
+	return c.Status(200).JSON(friends)
+}
@@ -2,18 +2,23 @@ package social
 
 import (
 	"context"
-	"github.com/edisss1/fiabesco-backend/db"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
 type GetBlockedRes struct {
 	ID        primitive.ObjectID `json:"_id" bson:"_id"`
@@ -22,7 +27,7 @@ type GetBlockedRes struct {
 	LastName  string             `json:"lastName"`
 }
 
-func FollowUser(c *fiber.Ctx) error {
+func (h *Handler) FollowUser(c *fiber.Ctx) error {
 	id := c.Params("_id")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
@@ -37,99 +42,129 @@ func FollowUser(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Missing or invalid request body")
 	}
 
-	collection := db.Database.Collection("users")
+	ctx := c.UserContext()
 
-	var user types.User
-	filter := bson.M{"_id": userID}
-	err = collection.FindOne(context.Background(), filter).Decode(&user)
+	_, err = h.repos.Users.FindByID(ctx, userID)
 	if err != nil {
 		return utils.RespondWithError(c, 404, "User not found")
 	}
 
-	for _, followedID := range user.FollowedUsers {
-		if followedID == body.ID {
-			return utils.RespondWithError(c, 400, "Already following this user")
-		}
-
+	followingID, err := utils.ParseHexID(body.ID)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid follower ID")
+	}
+	if followingID == userID {
+		return utils.RespondWithError(c, 400, "Cannot follow yourself")
 	}
 
-	update := bson.M{"$push": bson.M{"followedUsers": body.ID}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	private, err := helpers.IsPrivate(ctx, h.repos, followingID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to follow the user")
 	}
+	if private {
+		return h.requestFollow(c, userID, followingID)
+	}
 
-	incrementFollowers := bson.M{"$inc": bson.M{"followersCount": 1}}
-	incrementFollowed := bson.M{"$inc": bson.M{"followingCount": 1}}
-
-	followerID, err := utils.ParseHexID(body.ID)
+	err = h.repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		return helpers.Follow(ctx, h.repos, userID, followingID)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "Already following this user")
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "User not found")
+	}
 	if err != nil {
-		return utils.RespondWithError(c, 400, "Invalid follower ID")
+		return utils.RespondWithError(c, 500, "Failed to follow the user")
 	}
 
-	followerFilter := bson.M{"_id": followerID}
-
-	_, err = collection.UpdateOne(context.Background(), filter, incrementFollowers)
-	_, err = collection.UpdateOne(context.Background(), followerFilter, incrementFollowed)
+	helpers.Notify(ctx, h.repos, types.Notification{
+		Type:        types.NotificationFollow,
+		RecipientID: followingID,
+		ActorID:     userID,
+	})
 
 	return c.Status(200).JSON(fiber.Map{"msg": "Successfully followed the user"})
 }
 
-func GetFollowing(c *fiber.Ctx) error {
-	id := c.Params("_id")
-	userID, err := utils.ParseHexID(id)
+// UnfollowUser makes the user in the path stop following the user in the body.
+func (h *Handler) UnfollowUser(c *fiber.Ctx) error {
+	userID, err := utils.ParseHexID(c.Params("_id"))
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	collection := db.Database.Collection("users")
+	var body struct {
+		ID string `json:"id"`
+	}
 
-	var user types.User
-	userFilter := bson.M{"_id": userID}
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Missing or invalid request body")
+	}
 
-	err = collection.FindOne(context.Background(), userFilter).Decode(&user)
+	followingID, err := utils.ParseHexID(body.ID)
 	if err != nil {
-		return utils.RespondWithError(c, 404, "User not found")
+		return utils.RespondWithError(c, 400, "Invalid followed user ID")
 	}
 
-	var followedUserIDs []primitive.ObjectID
-	for _, followedID := range user.FollowedUsers {
-		fid, err := utils.ParseHexID(followedID)
-		if err != nil {
-			return utils.RespondWithError(c, 400, "Invalid followed user ID")
-		}
-		followedUserIDs = append(followedUserIDs, fid)
+	err = h.repos.Transactions.WithTransaction(c.UserContext(), func(ctx context.Context) error {
+		return helpers.Unfollow(ctx, h.repos, userID, followingID)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Not following this user")
 	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to unfollow the user")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Successfully unfollowed the user"})
+}
+
+// GetFollowing lists the users that the user in the path follows, most recently
+// followed first.
+func (h *Handler) GetFollowing(c *fiber.Ctx) error {
+	return h.listFollows(c, h.repos.Follows.ListFollowing)
+}
+
+// GetFollowers lists the users that follow the user in the path, most recent
+// followers first.
+func (h *Handler) GetFollowers(c *fiber.Ctx) error {
+	return h.listFollows(c, h.repos.Follows.ListFollowers)
+}
 
-	filter := bson.M{"_id": bson.M{"$in": followedUserIDs}}
+type listFunc func(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error)
 
-	projection := bson.M{
-		"firstName": 1,
-		"lastName":  1,
-		"handle":    1,
-		"photoURL":  1,
-		"bio":       1,
-		"_id":       1,
+func (h *Handler) listFollows(c *fiber.Ctx, list listFunc) error {
+	userID, err := utils.ParseHexID(c.Params("_id"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
 	}
 
-	opts := options.Find().SetProjection(projection)
+	ctx := c.UserContext()
 
-	cursor, err := collection.Find(context.Background(), filter, opts)
+	if _, err := h.repos.Users.FindByID(ctx, userID); err != nil {
+		return utils.RespondWithError(c, 404, "User not found")
+	}
+
+	viewerID, _ := utils.GetUserID(c)
+	users, err := list(ctx, userID, viewerID, page)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Database error: "+err.Error())
 	}
-	defer cursor.Close(context.Background())
 
-	var followed []types.User
-	if err := cursor.All(context.Background(), &followed); err != nil {
-		return utils.RespondWithError(c, 500, "Failed to decode followed users")
-	}
+	return utils.RespondWithPage(c, utils.NewPaged(users, page, followItemCursor))
+}
 
-	return c.Status(200).JSON(followed)
+func followItemCursor(item types.FollowItem) utils.Cursor {
+	return utils.Cursor{CreatedAt: item.FollowedAt, ID: item.FollowID}
 }
 
-func BlockUser(c *fiber.Ctx) error {
+func (h *Handler) BlockUser(c *fiber.Ctx) error {
 	id := c.Params("userID")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
@@ -144,21 +179,18 @@ func BlockUser(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid request body")
 	}
 
-	collection = db.Database.Collection("blocked_users")
-
 	blockedID, err := utils.ParseHexID(body.BlockedID)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid blocked user ID")
 	}
 
-	filter := bson.M{"userID": userID, "blockedID": blockedID}
-	count, err := collection.CountDocuments(context.Background(), filter)
+	exists, err := h.repos.Blocks.Exists(c.UserContext(), userID, blockedID)
 
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Database error: "+err.Error())
 	}
 
-	if count > 0 {
+	if exists {
 		return utils.RespondWithError(c, 400, "User already blocked")
 	}
 
@@ -168,7 +200,7 @@ func BlockUser(c *fiber.Ctx) error {
 		CreatedAt: time.Now(),
 	}
 
-	_, err = collection.InsertOne(context.Background(), blocked)
+	err = h.repos.Blocks.Create(c.UserContext(), &blocked)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to block the user")
 	}
@@ -177,7 +209,7 @@ func BlockUser(c *fiber.Ctx) error {
 
 }
 
-func UnblockUser(c *fiber.Ctx) error {
+func (h *Handler) UnblockUser(c *fiber.Ctx) error {
 	id := c.Params("userID")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
@@ -197,12 +229,9 @@ func UnblockUser(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Invalid blocked user ID")
 	}
 
-	collection = db.Database.Collection("blocked_users")
-	filter := bson.M{"userID": userID, "blockedID": blockedID}
-
-	res, err := collection.DeleteOne(context.Background(), filter)
+	err = h.repos.Blocks.Delete(c.UserContext(), userID, blockedID)
 
-	if res.DeletedCount == 0 {
+	if errors.Is(err, repository.ErrNotFound) {
 		return utils.RespondWithError(c, 404, "User not found")
 	}
 
@@ -214,42 +243,36 @@ func UnblockUser(c *fiber.Ctx) error {
 
 }
 
-func GetBlockedUsers(c *fiber.Ctx) error {
+func (h *Handler) GetBlockedUsers(c *fiber.Ctx) error {
 	id := c.Params("userID")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	collection = db.Database.Collection("blocked_users")
-	filter := bson.M{"userID": userID}
-
-	resCursor, err := collection.Find(context.Background(), filter)
-
-	var blocks []types.Block
-	var blocked []GetBlockedRes
-
-	err = resCursor.All(context.Background(), &blocks)
+	blocks, err := h.repos.Blocks.ListByUser(c.UserContext(), userID)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to get blocked users"+err.Error())
 	}
 
-	collection = db.Database.Collection("users")
 	blockedIDs := make([]primitive.ObjectID, 0, len(blocks))
 	for _, b := range blocks {
 		blockedIDs = append(blockedIDs, b.BlockedID)
 	}
 
-	collection = db.Database.Collection("users")
-	filter = bson.M{"_id": bson.M{"$in": blockedIDs}}
-
-	cursor, err := collection.Find(context.Background(), filter)
+	users, err := h.repos.Users.FindByIDs(c.UserContext(), blockedIDs)
 	if err != nil {
 		return utils.RespondWithError(c, 500, "User fetch error: "+err.Error())
 	}
 
-	if err = cursor.All(context.Background(), &blocked); err != nil {
-		return utils.RespondWithError(c, 500, "User cursor error: "+err.Error())
+	var blocked []GetBlockedRes
+	for _, user := range users {
+		blocked = append(blocked, GetBlockedRes{
+			ID:        user.ID,
+			PhotoURL:  user.PhotoURL,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+		})
 	}
 
 	return c.Status(200).JSON(blocked)
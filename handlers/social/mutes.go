@@ -0,0 +1,159 @@
+package social
+
+import (
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"time"
+)
+
+type MutedUserRes struct {
+	types.Mute
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Handle    string `json:"handle"`
+	PhotoURL  string `json:"photoURL"`
+}
+
+// MuteUser hides the posts, reposts, comments and messages of a user from the
+// current user, or only the given scopes. Muting a muted user again replaces
+// the scopes and expiry. The expiry is either a time or a duration like "24h".
+func (h *Handler) MuteUser(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	var body struct {
+		UserID    string     `json:"userID"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expiresAt"`
+		Duration  string     `json:"duration"`
+	}
+
+	if err := c.BodyParser(&body); err != nil {
+		return utils.RespondWithError(c, 400, "Invalid request body")
+	}
+
+	mutedID, err := utils.ParseHexID(body.UserID)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid muted user ID")
+	}
+	if mutedID == userID {
+		return utils.RespondWithError(c, 400, "Cannot mute yourself")
+	}
+
+	mute := types.Mute{
+		UserID:    userID,
+		MutedID:   mutedID,
+		Scopes:    body.Scopes,
+		ExpiresAt: body.ExpiresAt,
+		CreatedAt: time.Now(),
+	}
+
+	if len(mute.Scopes) == 0 {
+		mute.Scopes = types.MuteScopes
+	}
+	for _, scope := range mute.Scopes {
+		if !slices.Contains(types.MuteScopes, scope) {
+			return utils.RespondWithError(c, 400, "Invalid mute scope: "+scope)
+		}
+	}
+
+	if body.Duration != "" {
+		duration, err := time.ParseDuration(body.Duration)
+		if err != nil || duration <= 0 {
+			return utils.RespondWithError(c, 400, "Invalid mute duration")
+		}
+		expiresAt := mute.CreatedAt.Add(duration)
+		mute.ExpiresAt = &expiresAt
+	}
+	if mute.ExpiresAt != nil && !mute.ExpiresAt.After(mute.CreatedAt) {
+		return utils.RespondWithError(c, 400, "Mute must expire in the future")
+	}
+
+	ctx := c.UserContext()
+
+	if _, err := h.repos.Users.FindByID(ctx, mutedID); err != nil {
+		return utils.RespondWithError(c, 404, "User not found")
+	}
+
+	if err := h.repos.Mutes.Set(ctx, &mute); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to mute the user")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "User muted", "mute": mute})
+}
+
+func (h *Handler) UnmuteUser(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	mutedID, err := utils.ParseHexID(c.Params("userID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid muted user ID")
+	}
+
+	err = h.repos.Mutes.Delete(c.UserContext(), userID, mutedID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "User not muted")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to unmute the user")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "User unmuted"})
+}
+
+// GetMutedUsers lists the current user's mutes that haven't expired.
+func (h *Handler) GetMutedUsers(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	ctx := c.UserContext()
+
+	mutes, err := h.repos.Mutes.ListByUser(ctx, userID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to get muted users "+err.Error())
+	}
+
+	mutedIDs := make([]primitive.ObjectID, 0, len(mutes))
+	for _, mute := range mutes {
+		mutedIDs = append(mutedIDs, mute.MutedID)
+	}
+
+	users, err := h.repos.Users.FindByIDs(ctx, mutedIDs)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "User fetch error: "+err.Error())
+	}
+
+	byID := make(map[primitive.ObjectID]types.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	muted := make([]MutedUserRes, 0, len(mutes))
+	for _, mute := range mutes {
+		user, ok := byID[mute.MutedID]
+		if !ok {
+			continue
+		}
+		muted = append(muted, MutedUserRes{
+			Mute:      mute,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Handle:    user.Handle,
+			PhotoURL:  utils.MediaURL(user.PhotoURL),
+		})
+	}
+
+	return c.Status(200).JSON(muted)
+}
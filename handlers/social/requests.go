@@ -0,0 +1,162 @@
+package social
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// requestFollow asks the private account targetID to let requesterID follow it.
+func (h *Handler) requestFollow(c *fiber.Ctx, requesterID, targetID primitive.ObjectID) error {
+	ctx := c.UserContext()
+
+	following, err := h.repos.Follows.IsFollowing(ctx, requesterID, targetID)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to follow the user")
+	}
+	if following {
+		return utils.RespondWithError(c, 400, "Already following this user")
+	}
+
+	if _, err := h.repos.Users.FindByID(ctx, targetID); err != nil {
+		return utils.RespondWithError(c, 404, "User not found")
+	}
+
+	request := types.FollowRequest{
+		RequesterID: requesterID,
+		TargetID:    targetID,
+		CreatedAt:   time.Now(),
+	}
+
+	err = h.repos.FollowRequests.Create(ctx, &request)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return utils.RespondWithError(c, 400, "Follow request already sent")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to send the follow request")
+	}
+
+	helpers.Notify(ctx, h.repos, types.Notification{
+		Type:        types.NotificationFollowRequest,
+		RecipientID: targetID,
+		ActorID:     requesterID,
+		SubjectID:   request.ID,
+	})
+
+	return c.Status(202).JSON(fiber.Map{"msg": "Follow request sent", "request": request})
+}
+
+// GetIncomingFollowRequests lists the requests to follow the current user,
+// newest first.
+func (h *Handler) GetIncomingFollowRequests(c *fiber.Ctx) error {
+	return h.listFollowRequests(c, h.repos.FollowRequests.ListIncoming)
+}
+
+// GetOutgoingFollowRequests lists the current user's pending requests, newest
+// first.
+func (h *Handler) GetOutgoingFollowRequests(c *fiber.Ctx) error {
+	return h.listFollowRequests(c, h.repos.FollowRequests.ListOutgoing)
+}
+
+func (h *Handler) listFollowRequests(c *fiber.Ctx, list func(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error)) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	page, err := utils.ParsePage(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid cursor or limit")
+	}
+
+	requests, err := list(c.UserContext(), userID, page)
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Database error: "+err.Error())
+	}
+
+	return utils.RespondWithPage(c, utils.NewPaged(requests, page, func(item types.FollowRequestItem) utils.Cursor {
+		return utils.Cursor{CreatedAt: item.CreatedAt, ID: item.ID}
+	}))
+}
+
+// AcceptFollowRequest makes the requester a follower of the current user.
+func (h *Handler) AcceptFollowRequest(c *fiber.Ctx) error {
+	request, ok, err := h.followRequest(c, func(userID primitive.ObjectID, request types.FollowRequest) bool {
+		return request.TargetID == userID
+	})
+	if !ok {
+		return err
+	}
+
+	err = helpers.ApproveFollowRequest(c.UserContext(), h.repos, request)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Follow request not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to accept the follow request")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Follow request accepted"})
+}
+
+// DeclineFollowRequest removes a request to follow the current user. The
+// requester isn't told.
+func (h *Handler) DeclineFollowRequest(c *fiber.Ctx) error {
+	return h.deleteFollowRequest(c, "Follow request declined", func(userID primitive.ObjectID, request types.FollowRequest) bool {
+		return request.TargetID == userID
+	})
+}
+
+// CancelFollowRequest withdraws one of the current user's requests.
+func (h *Handler) CancelFollowRequest(c *fiber.Ctx) error {
+	return h.deleteFollowRequest(c, "Follow request cancelled", func(userID primitive.ObjectID, request types.FollowRequest) bool {
+		return request.RequesterID == userID
+	})
+}
+
+func (h *Handler) deleteFollowRequest(c *fiber.Ctx, msg string, allowed func(userID primitive.ObjectID, request types.FollowRequest) bool) error {
+	request, ok, err := h.followRequest(c, allowed)
+	if !ok {
+		return err
+	}
+
+	err = h.repos.FollowRequests.Delete(c.UserContext(), request.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Follow request not found")
+	}
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to delete the follow request")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": msg})
+}
+
+// followRequest loads the request in the path if allowed lets the current user
+// act on it. Otherwise it responds with an error and returns false.
+func (h *Handler) followRequest(c *fiber.Ctx, allowed func(userID primitive.ObjectID, request types.FollowRequest) bool) (types.FollowRequest, bool, error) {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return types.FollowRequest{}, false, utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	requestID, err := utils.ParseHexID(c.Params("requestID"))
+	if err != nil {
+		return types.FollowRequest{}, false, utils.RespondWithError(c, 400, "Invalid follow request ID")
+	}
+
+	request, err := h.repos.FollowRequests.FindByID(c.UserContext(), requestID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !allowed(userID, request)) {
+		return types.FollowRequest{}, false, utils.RespondWithError(c, 404, "Follow request not found")
+	}
+	if err != nil {
+		return types.FollowRequest{}, false, utils.RespondWithError(c, 500, "Database error: "+err.Error())
+	}
+
+	return request, true, nil
+}
@@ -0,0 +1,47 @@
+package social
+
+import (
+	"github.com/edisss1/fiabesco-backend/internal/suggest"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetSuggestions ranks users for the current user to follow by mutual follows,
+// the tags of the posts they like and how active the users are.
+func (h *Handler) GetSuggestions(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	limit, err := utils.ParseLimit(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid limit")
+	}
+
+	suggestions, err := suggest.Suggest(c.UserContext(), h.repos, userID, int(limit))
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Failed to get suggestions: "+err.Error())
+	}
+
+	return c.Status(200).JSON(suggestions)
+}
+
+// DismissSuggestion keeps a user out of the current user's suggestions.
+func (h *Handler) DismissSuggestion(c *fiber.Ctx) error {
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid user ID")
+	}
+
+	dismissedID, err := utils.ParseHexID(c.Params("userID"))
+	if err != nil {
+		return utils.RespondWithError(c, 400, "Invalid dismissed user ID")
+	}
+
+	if err := h.repos.Suggestions.Dismiss(c.UserContext(), userID, dismissedID); err != nil {
+		return utils.RespondWithError(c, 500, "Failed to dismiss the suggestion")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Suggestion dismissed"})
+}
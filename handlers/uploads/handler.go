@@ -2,16 +2,24 @@ package uploads
 
 import (
 	"bytes"
-	"github.com/edisss1/fiabesco-backend/db"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
-	"io"
+	"mime/multipart"
 	"net/http"
 )
 
-func UploadFile(c *fiber.Ctx, field string, bucket *gridfs.Bucket, allowMultiple bool) ([]primitive.ObjectID, error) {
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
+
+func UploadFile(c *fiber.Ctx, field string, media repository.MediaRepository, allowMultiple bool) ([]primitive.ObjectID, error) {
 	var uploadedIDs []primitive.ObjectID
 
 	if allowMultiple {
@@ -21,58 +29,50 @@ func UploadFile(c *fiber.Ctx, field string, bucket *gridfs.Bucket, allowMultiple
 		}
 		files := form.File[field]
 		for _, fh := range files {
-			file, err := fh.Open()
+			id, err := upload(c, fh, media)
 			if err != nil {
 				return nil, err
 			}
-			defer file.Close()
 
-			uploadStream, err := bucket.OpenUploadStream(fh.Filename)
-			if err != nil {
-				return nil, err
-			}
-			defer uploadStream.Close()
-			io.Copy(uploadStream, file)
-
-			uploadedIDs = append(uploadedIDs, uploadStream.FileID.(primitive.ObjectID))
+			uploadedIDs = append(uploadedIDs, id)
 		}
 	} else {
 		fh, err := c.FormFile(field)
 		if err != nil {
 			return nil, err
 		}
-		file, err := fh.Open()
+		id, err := upload(c, fh, media)
 		if err != nil {
 			return nil, err
 		}
-		defer file.Close()
-
-		uploadStream, err := bucket.OpenUploadStream(fh.Filename)
-		if err != nil {
-			return nil, err
-		}
-		defer uploadStream.Close()
-		io.Copy(uploadStream, file)
-		uploadedIDs = append(uploadedIDs, uploadStream.FileID.(primitive.ObjectID))
+		uploadedIDs = append(uploadedIDs, id)
 
 	}
 	return uploadedIDs, nil
 }
 
-func ServeImage(c *fiber.Ctx) error {
-	id := c.Params("imageID")
-	imageID, err := utils.ParseHexID(id)
+func upload(c *fiber.Ctx, fh *multipart.FileHeader, media repository.MediaRepository) (primitive.ObjectID, error) {
+	file, err := fh.Open()
 	if err != nil {
-		return utils.RespondWithError(c, 400, "Invalid image ID")
+		return primitive.NilObjectID, err
 	}
+	defer file.Close()
+
+	return media.Upload(c.UserContext(), fh.Filename, file)
+}
 
-	bucket, err := gridfs.NewBucket(db.Database)
+func (h *Handler) ServeImage(c *fiber.Ctx) error {
+	id := c.Params("imageID")
+	imageID, err := utils.ParseHexID(id)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to create bucket")
+		return utils.RespondWithError(c, 400, "Invalid image ID")
 	}
 
 	var buf bytes.Buffer
-	_, err = bucket.DownloadToStream(imageID, &buf)
+	err = h.repos.Media.Download(c.UserContext(), imageID, &buf)
+	if errors.Is(err, repository.ErrNotFound) {
+		return utils.RespondWithError(c, 404, "Image not found")
+	}
 	if err != nil {
 		return utils.RespondWithError(c, 500, "Failed to download image")
 	}
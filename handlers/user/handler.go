@@ -1,23 +1,26 @@
 package user
 
 import (
-	"context"
-	"github.com/edisss1/fiabesco-backend/db"
 	"github.com/edisss1/fiabesco-backend/handlers/auth"
 	"github.com/edisss1/fiabesco-backend/handlers/uploads"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
 	"net/http"
 	"strings"
 	"time"
 )
 
-var collection *mongo.Collection
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
 
 type MeRes struct {
 	ID             primitive.ObjectID `json:"_id" bson:"_id"`
@@ -33,7 +36,7 @@ type MeRes struct {
 	FollowingCount uint32             `json:"followingCount"`
 }
 
-func GetUserData(c *fiber.Ctx) error {
+func (h *Handler) GetUserData(c *fiber.Ctx) error {
 
 	authHeader := c.Get("Authorization")
 
@@ -49,17 +52,30 @@ func GetUserData(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 401, "Unauthorized")
 	}
 
-	var user MeRes
-
-	collection = db.Database.Collection("users")
 	userID, err := primitive.ObjectIDFromHex(claims.ID)
-	filter := bson.M{"_id": userID}
+	if err != nil {
+		return utils.RespondWithError(c, 401, "Unauthorized")
+	}
 
-	err = collection.FindOne(context.Background(), filter).Decode(&user)
+	found, err := h.repos.Users.FindByID(c.UserContext(), userID)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid credentials " + err.Error()})
 	}
 
+	user := MeRes{
+		ID:             found.ID,
+		FirstName:      found.FirstName,
+		LastName:       found.LastName,
+		Email:          found.Email,
+		Handle:         found.Handle,
+		PhotoURL:       found.PhotoURL,
+		Bio:            found.Bio,
+		Settings:       found.Settings,
+		CreatedAt:      found.CreatedAt,
+		FollowersCount: found.FollowersCount,
+		FollowingCount: found.FollowingCount,
+	}
+
 	if user.PhotoURL != "" {
 		user.PhotoURL = utils.BuildImgURL(user.PhotoURL)
 	}
@@ -67,7 +83,7 @@ func GetUserData(c *fiber.Ctx) error {
 	return c.Status(200).JSON(user)
 }
 
-func GetProfileData(c *fiber.Ctx) error {
+func (h *Handler) GetProfileData(c *fiber.Ctx) error {
 	id := c.Params("_id")
 	objectID, err := primitive.ObjectIDFromHex(id)
 
@@ -75,13 +91,10 @@ func GetProfileData(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid ID"})
 	}
 
-	var user types.User
-
-	collection = db.Database.Collection("users")
-
-	filter := bson.M{"_id": objectID}
-
-	err = collection.FindOne(context.Background(), filter).Decode(&user)
+	user, err := h.repos.Users.FindProfile(c.UserContext(), objectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "User not found"})
+	}
 
 	if user.PhotoURL != "" {
 		user.PhotoURL = utils.BuildImgURL(user.PhotoURL)
@@ -90,18 +103,11 @@ func GetProfileData(c *fiber.Ctx) error {
 		user.BannerURL = utils.BuildImgURL(user.BannerURL)
 	}
 
-	user.Password = ""
-	user.Email = ""
-
-	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "User not found"})
-	}
-
 	return c.Status(200).JSON(user)
 
 }
 
-func EditBio(c *fiber.Ctx) error {
+func (h *Handler) EditBio(c *fiber.Ctx) error {
 	id := c.Params("_id")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
@@ -120,12 +126,7 @@ func EditBio(c *fiber.Ctx) error {
 		return utils.RespondWithError(c, 400, "Bio cannot be empty")
 	}
 
-	collection = db.Database.Collection("users")
-
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"bio": body.Bio}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"bio": body.Bio})
 	if err != nil {
 		return utils.RespondWithError(c, 500, err.Error())
 
@@ -134,25 +135,19 @@ func EditBio(c *fiber.Ctx) error {
 	return c.Status(200).JSON(fiber.Map{"newBio": body.Bio})
 }
 
-func ChangePFP(c *fiber.Ctx) error {
+func (h *Handler) ChangePFP(c *fiber.Ctx) error {
 	id := c.Params("userID")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID "+err.Error())
 	}
 
-	bucket, err := gridfs.NewBucket(db.Database)
+	ids, err := uploads.UploadFile(c, "pfp", h.repos.Media, false)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to create bucket")
+		return utils.RespondWithError(c, 400, "Failed to upload file "+err.Error())
 	}
-	collection := db.Database.Collection("users")
-
-	ids, err := uploads.UploadFile(c, "pfp", bucket, false)
 
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"photoURL": ids[0].Hex()}}
-
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"photoURL": ids[0].Hex()})
 	if err != nil {
 		return utils.RespondWithError(c, 500, err.Error())
 	}
@@ -161,25 +156,19 @@ func ChangePFP(c *fiber.Ctx) error {
 
 }
 
-func UploadBanner(c *fiber.Ctx) error {
+func (h *Handler) UploadBanner(c *fiber.Ctx) error {
 	id := c.Params("userID")
 	userID, err := utils.ParseHexID(id)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID "+err.Error())
 	}
 
-	bucket, err := gridfs.NewBucket(db.Database)
+	ids, err := uploads.UploadFile(c, "banner", h.repos.Media, false)
 	if err != nil {
-		return utils.RespondWithError(c, 500, "Failed to create bucket")
+		return utils.RespondWithError(c, 400, "Failed to upload file "+err.Error())
 	}
-	collection := db.Database.Collection("users")
-
-	ids, err := uploads.UploadFile(c, "banner", bucket, false)
-
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{"bannerURL": ids[0].Hex()}}
 
-	_, err = collection.UpdateOne(context.Background(), filter, update)
+	err = h.repos.Users.Update(c.UserContext(), userID, bson.M{"bannerURL": ids[0].Hex()})
 	if err != nil {
 		return utils.RespondWithError(c, 500, err.Error())
 	}
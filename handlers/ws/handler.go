@@ -1,9 +1,13 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/internal/mention"
+	"github.com/edisss1/fiabesco-backend/notify"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/websocket/v2"
@@ -13,9 +17,28 @@ import (
 	"time"
 )
 
-var clients = make(map[string]*websocket.Conn)
+// sendBuffer is how many messages may wait for a connection before new ones
+// are dropped.
+const sendBuffer = 64
+
+// client is a connected user. Websocket connections allow one writer at a
+// time, so everything for them is queued on send and written by writeLoop.
+type client struct {
+	conn *websocket.Conn
+	send chan interface{}
+}
+
+var clients = make(map[string]*client)
 var mu sync.Mutex
 
+type Handler struct {
+	repos *repository.Repositories
+}
+
+func NewHandler(repos *repository.Repositories) *Handler {
+	return &Handler{repos: repos}
+}
+
 type BaseWSMessage struct {
 	Type string          `json:"type"`
 	Data json.RawMessage `json:"data"`
@@ -51,17 +74,31 @@ type GetConversationsPayload struct {
 	UserID string `json:"userID"`
 }
 
-func HandleWS(conn *websocket.Conn) {
+func (h *Handler) HandleWS(conn *websocket.Conn) {
 	userID := conn.Query("userID")
 
+	c := &client{conn: conn, send: make(chan interface{}, sendBuffer)}
+	done := make(chan struct{})
+	go func() {
+		c.writeLoop()
+		close(done)
+	}()
+
 	mu.Lock()
-	clients[userID] = conn
+	if previous, ok := clients[userID]; ok {
+		close(previous.send)
+	}
+	clients[userID] = c
 	mu.Unlock()
 
 	defer func() {
 		mu.Lock()
-		delete(clients, userID)
+		if clients[userID] == c {
+			delete(clients, userID)
+			close(c.send)
+		}
 		mu.Unlock()
+		<-done
 		conn.Close()
 	}()
 
@@ -91,32 +128,38 @@ func HandleWS(conn *websocket.Conn) {
 				continue
 			}
 
-			message, err := helpers.SaveMessage(senderID, conversationID, payload.Content)
+			mentions, err := mention.Resolve(context.Background(), h.repos, senderID, payload.Content)
+			if err != nil {
+				log.Println("Error resolving mentions: ", err)
+				continue
+			}
+
+			message, err := helpers.SaveMessage(h.repos, senderID, conversationID, payload.Content, mentions)
 			if err != nil {
 				log.Println("Error saving message: ", err)
 				continue
 			}
+			mention.NotifyMessage(context.Background(), h.repos, message, nil)
 
-			conversation, err := helpers.GetConversation(conversationID)
+			conversation, err := helpers.GetConversation(h.repos, conversationID)
 			if err != nil {
 				log.Println("Error getting conversation: ", err)
 			}
 
-			fmt.Println(clients)
 			fmt.Println("Participants: ", conversation.Participants)
 
 			for _, user := range conversation.Participants {
 				fmt.Println("user: ", user)
-				conn, ok := clients[user.ID.Hex()]
-				if ok {
-					err = conn.WriteJSON(struct {
-						Type    string        `json:"type"`
-						Message types.Message `json:"message"`
-					}{
-						Type:    "conversations_update",
-						Message: message,
-					})
+				if h.muted(user.ID, message.SenderID) {
+					continue
 				}
+				push(user.ID.Hex(), struct {
+					Type    string        `json:"type"`
+					Message types.Message `json:"message"`
+				}{
+					Type:    "conversations_update",
+					Message: message,
+				})
 			}
 
 		case "edit_message":
@@ -141,26 +184,37 @@ func HandleWS(conn *websocket.Conn) {
 				log.Println("Invalid senderID: ", err)
 			}
 
-			message, err := helpers.SaveEditedMessage(messageID, payload.Content, conversationID, senderID)
+			previous, err := h.repos.Messages.FindByID(context.Background(), messageID)
+			if err != nil {
+				log.Println("Error finding message: ", err)
+				continue
+			}
+
+			mentions, err := mention.Resolve(context.Background(), h.repos, previous.SenderID, payload.Content)
+			if err != nil {
+				log.Println("Error resolving mentions: ", err)
+				continue
+			}
+
+			message, err := helpers.SaveEditedMessage(h.repos, messageID, payload.Content, mentions, conversationID, senderID)
 
 			if err != nil {
 				log.Println("Error saving message: ", err)
 				continue
 			}
+			mention.NotifyMessage(context.Background(), h.repos, message, previous.Mentions)
 
-			conversation, err := helpers.GetConversation(message.ConversationID)
+			conversation, err := helpers.GetConversation(h.repos, message.ConversationID)
 			if err != nil {
 				log.Println("Error getting conversation: ", err)
 			}
 
 			for _, user := range conversation.Participants {
-
-				if conn, ok := clients[user.ID.Hex()]; ok {
-					err = conn.WriteJSON(message)
-					if err != nil {
-						log.Println("Error sending message to user: ", err)
-					}
+				if h.muted(user.ID, message.SenderID) {
+					continue
 				}
+
+				push(user.ID.Hex(), message)
 			}
 		case "get_conversations":
 			var payload GetConversationsPayload
@@ -175,25 +229,19 @@ func HandleWS(conn *websocket.Conn) {
 				continue
 			}
 
-			conversations, err := helpers.GetConversations(userID)
+			conversations, err := helpers.GetConversations(h.repos, userID)
 			if err != nil {
 				log.Println("Error getting conversations: ", err)
 				continue
 			}
 
-			conn, ok := clients[payload.UserID]
-			if ok {
-				err = conn.WriteJSON(struct {
-					Type          string               `json:"type"`
-					Conversations []types.Conversation `json:"conversations"`
-				}{
-					Type:          "conversations",
-					Conversations: conversations,
-				})
-				if err != nil {
-					log.Println("Error sending conversations to user: ", err)
-				}
-			}
+			push(payload.UserID, struct {
+				Type          string               `json:"type"`
+				Conversations []types.Conversation `json:"conversations"`
+			}{
+				Type:          "conversations",
+				Conversations: conversations,
+			})
 		case "update_status":
 			var payload UpdateStatusPayload
 			if err := json.Unmarshal(base.Data, &payload); err != nil {
@@ -206,7 +254,7 @@ func HandleWS(conn *websocket.Conn) {
 				continue
 			}
 
-			err = helpers.UpdateUserStatus(userID, payload.Status)
+			err = helpers.UpdateUserStatus(h.repos, userID, payload.Status)
 			if err != nil {
 				log.Println("Error updating user status: ", err)
 				continue
@@ -235,11 +283,18 @@ func HandleWS(conn *websocket.Conn) {
 				log.Println("Invalid replyTo: ", err)
 				continue
 			}
-			message, err := helpers.SaveReply(senderID, conversationID, payload.Content, replyTo)
+			mentions, err := mention.Resolve(context.Background(), h.repos, senderID, payload.Content)
+			if err != nil {
+				log.Println("Error resolving mentions: ", err)
+				continue
+			}
+
+			message, err := helpers.SaveReply(h.repos, senderID, conversationID, payload.Content, mentions, replyTo)
 			if err != nil {
 				log.Println("Error saving reply: ", err)
 				continue
 			}
+			mention.NotifyMessage(context.Background(), h.repos, message, nil)
 
 			log.Printf(
 				"Reply saved: ID=%s, ConversationID=%s, SenderID=%s, Content=%q, Files=%v, Read=%v, CreatedAt=%s, UpdatedAt=%s, IsEdited=%v, IsReply=%v, ReplyTo=%s",
@@ -256,25 +311,23 @@ func HandleWS(conn *websocket.Conn) {
 				message.ReplyTo.Hex(),
 			)
 
-			conversation, err := helpers.GetConversation(conversationID)
+			conversation, err := helpers.GetConversation(h.repos, conversationID)
 			if err != nil {
 				log.Println("Error getting conversation: ", err)
 				continue
 			}
 
 			for _, user := range conversation.Participants {
-				if conn, ok := clients[user.ID.Hex()]; ok {
-					err = conn.WriteJSON(struct {
-						Type    string        `json:"type"`
-						Message types.Message `json:"message"`
-					}{
-						Type:    "conversations_update",
-						Message: message,
-					})
-					if err != nil {
-						log.Println("Error sending message to user: ", err)
-					}
+				if h.muted(user.ID, message.SenderID) {
+					continue
 				}
+				push(user.ID.Hex(), struct {
+					Type    string        `json:"type"`
+					Message types.Message `json:"message"`
+				}{
+					Type:    "conversations_update",
+					Message: message,
+				})
 			}
 
 		default:
@@ -283,3 +336,55 @@ func HandleWS(conn *websocket.Conn) {
 		}
 	}
 }
+
+// muted reports whether the messages of senderID shouldn't be pushed to
+// recipientID. They are still saved to the conversation.
+func (h *Handler) muted(recipientID, senderID primitive.ObjectID) bool {
+	muted, err := h.repos.Mutes.IsMuted(context.Background(), recipientID, senderID, types.MuteScopeMessages)
+	if err != nil {
+		log.Println("Error checking mutes: ", err)
+	}
+	return muted
+}
+
+func init() {
+	notify.Register(pushNotification)
+}
+
+// pushNotification sends the notification to its recipient if they're connected.
+func pushNotification(ctx context.Context, notification types.Notification) {
+	push(notification.RecipientID.Hex(), struct {
+		Type         string             `json:"type"`
+		Notification types.Notification `json:"notification"`
+	}{
+		Type:         "notification",
+		Notification: notification,
+	})
+}
+
+// push queues v for userID if they're connected. It never blocks: messages for
+// a connection that fell sendBuffer messages behind are dropped.
+func push(userID string, v interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	c, ok := clients[userID]
+	if !ok {
+		return
+	}
+
+	select {
+	case c.send <- v:
+	default:
+		log.Printf("Dropping websocket message for %s, their connection is behind", userID)
+	}
+}
+
+// writeLoop writes the messages queued for c until send is closed.
+func (c *client) writeLoop() {
+	for v := range c.send {
+		if err := c.conn.WriteJSON(v); err != nil {
+			log.Println("Error writing to websocket: ", err)
+		}
+	}
+}
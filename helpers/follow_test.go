@@ -0,0 +1,109 @@
+package helpers_test
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"testing"
+)
+
+func TestFollowAndUnfollow(t *testing.T) {
+	missing := primitive.NewObjectID()
+
+	tests := []struct {
+		name     string
+		follows  bool
+		unfollow bool
+		// target is the followed user, the existing one when zero.
+		target        primitive.ObjectID
+		wantErr       error
+		wantFollows   bool
+		wantFollowing uint32
+		wantFollowers uint32
+	}{
+		{name: "follow", wantFollows: true, wantFollowing: 1, wantFollowers: 1},
+		{name: "follow twice", follows: true, wantErr: repository.ErrDuplicate, wantFollows: true, wantFollowing: 1, wantFollowers: 1},
+		{name: "follow missing user rolls back", target: missing, wantErr: repository.ErrNotFound},
+		{name: "unfollow", follows: true, unfollow: true},
+		{name: "unfollow without following", unfollow: true, wantErr: repository.ErrNotFound},
+		{name: "unfollow missing user rolls back", follows: true, unfollow: true, target: missing, wantErr: repository.ErrNotFound, wantFollows: true, wantFollowing: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			follower := createUser(t, repos, "follower")
+			following := createUser(t, repos, "following")
+			target := following
+			if !tt.target.IsZero() {
+				target = tt.target
+			}
+
+			if tt.follows {
+				// The existing follow is set up by hand so that the counters of
+				// a missing target can be checked as well.
+				if err := repos.Follows.Follow(ctx, follower, target); err != nil {
+					t.Fatal(err)
+				}
+				if err := repos.Users.IncrementCounter(ctx, follower, repository.FollowingCount, 1); err != nil {
+					t.Fatal(err)
+				}
+				if target == following {
+					if err := repos.Users.IncrementCounter(ctx, following, repository.FollowersCount, 1); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+				if tt.unfollow {
+					return helpers.Unfollow(ctx, repos, follower, target)
+				}
+				return helpers.Follow(ctx, repos, follower, target)
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			followed, err := repos.Follows.FollowingIDs(ctx, follower)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := slices.Contains(followed, target); got != tt.wantFollows {
+				t.Errorf("follows = %v, want %v", got, tt.wantFollows)
+			}
+			if got := findUser(t, repos, follower).FollowingCount; got != tt.wantFollowing {
+				t.Errorf("followingCount = %d, want %d", got, tt.wantFollowing)
+			}
+			if got := findUser(t, repos, following).FollowersCount; got != tt.wantFollowers {
+				t.Errorf("followersCount = %d, want %d", got, tt.wantFollowers)
+			}
+		})
+	}
+}
+
+func createUser(t *testing.T, repos *repository.Repositories, handle string) primitive.ObjectID {
+	t.Helper()
+
+	user := types.User{Email: handle + "@example.com", Handle: handle}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func findUser(t *testing.T, repos *repository.Repositories, id primitive.ObjectID) types.User {
+	t.Helper()
+
+	user, err := repos.Users.FindByID(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
@@ -3,56 +3,39 @@ package helpers
 import (
 	"context"
 	"errors"
-	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/notify"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/edisss1/fiabesco-backend/utils"
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
+	"slices"
 	"strings"
 	"time"
 )
 
-func SaveMessage(senderID, conversationID primitive.ObjectID, content string) (types.Message, error) {
+func SaveMessage(repos *repository.Repositories, senderID, conversationID primitive.ObjectID, content string, mentions []types.Mention) (types.Message, error) {
 	message := types.Message{
 		SenderID:       senderID,
 		ConversationID: conversationID,
 		Content:        content,
+		Mentions:       mentions,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 		Read:           false,
 	}
 
-	messagesCollection := db.Database.Collection("messages")
-	conversationsCollection := db.Database.Collection("conversations")
-
-	count, err := conversationsCollection.CountDocuments(context.Background(), bson.M{"_id": conversationID})
-	if err != nil || count == 0 {
-		return types.Message{}, errors.New("conversation not found")
-	}
-	res, err := messagesCollection.InsertOne(context.Background(), message)
-	if err != nil {
-		return types.Message{}, err
-	}
-	message.ID = res.InsertedID.(primitive.ObjectID)
-
-	filter := bson.M{"_id": conversationID}
-	update := bson.M{"$set": bson.M{"lastMessage": message}}
-
-	_, err = conversationsCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		return types.Message{}, err
-	}
-
-	return message, nil
+	return saveToConversation(repos, message)
 }
 
-func SaveReply(senderID, conversationID primitive.ObjectID, content string, replyTo primitive.ObjectID) (types.Message, error) {
+func SaveReply(repos *repository.Repositories, senderID, conversationID primitive.ObjectID, content string, mentions []types.Mention, replyTo primitive.ObjectID) (types.Message, error) {
 	reply := types.Message{
 		SenderID:       senderID,
 		ConversationID: conversationID,
 		Content:        content,
+		Mentions:       mentions,
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 		Read:           false,
@@ -61,60 +44,44 @@ func SaveReply(senderID, conversationID primitive.ObjectID, content string, repl
 		IsEdited:       false,
 	}
 
-	messagesCollection := db.Database.Collection("messages")
-	conversationsCollection := db.Database.Collection("conversations")
+	return saveToConversation(repos, reply)
 
-	count, err := conversationsCollection.CountDocuments(context.Background(), bson.M{"_id": conversationID})
-	if err != nil || count == 0 {
+}
+
+func saveToConversation(repos *repository.Repositories, message types.Message) (types.Message, error) {
+	ctx := context.Background()
+
+	if _, err := repos.Conversations.FindByID(ctx, message.ConversationID); err != nil {
 		return types.Message{}, errors.New("conversation not found")
 	}
 
-	res, err := messagesCollection.InsertOne(context.Background(), reply)
-	if err != nil {
+	if err := repos.Messages.Create(ctx, &message); err != nil {
 		return types.Message{}, err
 	}
-	reply.ID = res.InsertedID.(primitive.ObjectID)
-
-	filter := bson.M{"_id": conversationID}
-	update := bson.M{"$set": bson.M{"lastMessage": reply}}
 
-	_, err = conversationsCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
+	if err := repos.Conversations.SetLastMessage(ctx, message.ConversationID, message); err != nil {
 		return types.Message{}, err
 	}
 
-	return reply, nil
-
+	return message, nil
 }
 
-func SaveEditedMessage(messageID primitive.ObjectID, content string, conversationID primitive.ObjectID, senderID primitive.ObjectID) (types.Message, error) {
-	messagesCollection := db.Database.Collection("messages")
-	conversationsCollection := db.Database.Collection("conversations")
-	filter := bson.M{"_id": messageID}
-	update := bson.M{"$set": bson.M{"content": content}}
+func SaveEditedMessage(repos *repository.Repositories, messageID primitive.ObjectID, content string, mentions []types.Mention, conversationID primitive.ObjectID, senderID primitive.ObjectID) (types.Message, error) {
+	ctx := context.Background()
 
-	_, err := messagesCollection.UpdateOne(context.Background(), filter, update)
+	updatedMessage, err := repos.Messages.Edit(ctx, messageID, content, mentions)
 	if err != nil {
 		return types.Message{}, err
 	}
 
-	var updatedMessage types.Message
-	err = messagesCollection.FindOne(context.Background(), filter).Decode(&updatedMessage)
-	if err != nil {
-		return types.Message{}, err
-	}
-
-	var conversation types.Conversation
-	err = conversationsCollection.FindOne(context.Background(), bson.M{"_id": conversationID}).Decode(&conversation)
+	conversation, err := repos.Conversations.FindByID(ctx, conversationID)
 	if err != nil {
 		log.Println("Error decoding conversation")
 	}
 
 	lastMessage := conversation.LastMessage
 	if lastMessage.ID == messageID {
-		filter := bson.M{"_id": conversationID}
-		update := bson.M{"$set": bson.M{"lastMessage": updatedMessage}}
-		_, err := conversationsCollection.UpdateOne(context.Background(), filter, update)
+		err := repos.Conversations.SetLastMessage(ctx, conversationID, updatedMessage)
 		if err != nil {
 			return types.Message{}, err
 		}
@@ -123,46 +90,32 @@ func SaveEditedMessage(messageID primitive.ObjectID, content string, conversatio
 	return updatedMessage, nil
 }
 
-func GetConversation(conversationID primitive.ObjectID) (types.Conversation, error) {
-	conversationsCollection := db.Database.Collection("conversations")
-	usersCollection := db.Database.Collection("users")
+func GetConversation(repos *repository.Repositories, conversationID primitive.ObjectID) (types.Conversation, error) {
+	ctx := context.Background()
 
-	filter := bson.M{"_id": conversationID}
-	var conversation types.Conversation
-	err := conversationsCollection.FindOne(context.Background(), filter).Decode(&conversation)
+	conversation, err := repos.Conversations.FindByID(ctx, conversationID)
 	if err != nil {
 		return types.Conversation{}, err
 	}
 
-	filter = bson.M{"_id": bson.M{"$in": conversation.ParticipantsIds}}
-	cursor, err := usersCollection.Find(context.Background(), filter)
+	users, err := repos.Users.FindByIDs(ctx, conversation.ParticipantsIds)
 	if err != nil {
 		return types.Conversation{}, err
 	}
-	var participants []types.Participant
-	if err := cursor.All(context.Background(), &participants); err != nil {
-		return types.Conversation{}, err
-	}
-	conversation.Participants = participants
+
+	conversation.Participants = Participants(users)
 
 	return conversation, nil
 }
 
-func GetConversations(userID primitive.ObjectID) ([]types.Conversation, error) {
-	conversationsCollection := db.Database.Collection("conversations")
-	usersCollection := db.Database.Collection("users")
+func GetConversations(repos *repository.Repositories, userID primitive.ObjectID) ([]types.Conversation, error) {
+	ctx := context.Background()
 
-	filter := bson.M{"participantsIds": userID}
-	cursor, err := conversationsCollection.Find(context.Background(), filter)
+	conversations, err := repos.Conversations.ListByParticipant(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	var conversations []types.Conversation
-	if err := cursor.All(context.Background(), &conversations); err != nil {
-		return nil, err
-	}
-
 	participantIDSet := make(map[primitive.ObjectID]struct{})
 	for _, conv := range conversations {
 		for _, id := range conv.ParticipantsIds {
@@ -174,35 +127,14 @@ func GetConversations(userID primitive.ObjectID) ([]types.Conversation, error) {
 		participantIDs = append(participantIDs, id)
 	}
 
-	usersFilter := bson.M{"_id": bson.M{"$in": participantIDs}}
-	cursor, err = usersCollection.Find(context.Background(), usersFilter)
+	users, err := repos.Users.FindByIDs(ctx, participantIDs)
 	if err != nil {
 		return nil, err
 	}
 
 	userMap := make(map[primitive.ObjectID]types.Participant)
-	for cursor.Next(context.Background()) {
-		var user struct {
-			ID        primitive.ObjectID `bson:"_id"`
-			FirstName string             `bson:"firstName"`
-			LastName  string             `bson:"lastName"`
-			Photo     string             `bson:"photoURL"`
-		}
-		if err := cursor.Decode(&user); err != nil {
-			continue
-		}
-
-		if user.Photo != "" {
-			user.Photo = utils.BuildImgURL(user.Photo)
-		} else {
-			user.Photo = ""
-		}
-
-		userMap[user.ID] = types.Participant{
-			ID:       user.ID,
-			UserName: strings.TrimSpace(user.FirstName + " " + user.LastName),
-			PhotoURL: user.Photo,
-		}
+	for _, participant := range Participants(users) {
+		userMap[participant.ID] = participant
 	}
 
 	for i, conv := range conversations {
@@ -218,14 +150,34 @@ func GetConversations(userID primitive.ObjectID) ([]types.Conversation, error) {
 	return conversations, nil
 }
 
-func SaveSetting(c *fiber.Ctx, setting map[string]interface{}) error {
+// Participants turns users into the participant cards shown in conversations.
+func Participants(users []types.User) []types.Participant {
+	var participants []types.Participant
+
+	for _, user := range users {
+		photo := ""
+		if user.PhotoURL != "" {
+			photo = utils.BuildImgURL(user.PhotoURL)
+		}
+
+		participants = append(participants, types.Participant{
+			ID:       user.ID,
+			UserName: strings.TrimSpace(user.FirstName + " " + user.LastName),
+			PhotoURL: photo,
+			IsOnline: user.IsOnline,
+			LastSeen: user.LastSeen,
+		})
+	}
+
+	return participants
+}
+
+func SaveSetting(c *fiber.Ctx, repos *repository.Repositories, setting map[string]interface{}) error {
 	userID, err := utils.GetUserID(c)
 	if err != nil {
 		return utils.RespondWithError(c, 400, "Invalid user ID")
 	}
 
-	collection := db.Database.Collection("settings")
-
 	allowedFields := map[string]bool{
 		"theme":             true,
 		"language":          true,
@@ -239,57 +191,225 @@ func SaveSetting(c *fiber.Ctx, setting map[string]interface{}) error {
 		}
 	}
 
-	filter := bson.M{"userID": userID}
+	err = repos.Settings.Set(c.UserContext(), userID, bson.M(setting))
+	if err != nil {
+		return utils.RespondWithError(c, 500, "Error updating settings")
+	}
+
+	return c.Status(200).JSON(fiber.Map{"msg": "Settings updated successfully"})
+}
+
+func UpdateUserStatus(repos *repository.Repositories, userID primitive.ObjectID, status string) error {
 	update := bson.M{
-		"$set": setting,
+		"status":   status,
+		"lastSeen": time.Now(),
 	}
 
-	defaultSettings := types.Settings{
-		UserID:            userID,
-		Theme:             "light",
-		Language:          "en",
-		ProfileVisibility: "public",
+	err := repos.Users.Update(context.Background(), userID, update)
+	if err != nil {
+		return err
 	}
 
-	count, err := collection.CountDocuments(context.Background(), filter)
+	return nil
 
-	if err != nil {
-		return utils.RespondWithError(c, 500, "Error checking document count")
+}
+
+// Follow makes followerID follow followingID and updates both users' counters.
+// It must run in a transaction.
+func Follow(ctx context.Context, repos *repository.Repositories, followerID, followingID primitive.ObjectID) error {
+	if err := repos.Follows.Follow(ctx, followerID, followingID); err != nil {
+		return err
 	}
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return repos.Follows.Unfollow(ctx, followerID, followingID)
+	})
 
-	if count == 0 {
-		_, err = collection.InsertOne(context.Background(), defaultSettings)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "Error inserting default settings")
-		}
-		_, err = collection.UpdateOne(context.Background(), filter, update)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "Error updating settings")
-		}
-	} else {
-		_, err = collection.UpdateOne(context.Background(), filter, update)
-		if err != nil {
-			return utils.RespondWithError(c, 500, "Error updating settings")
-		}
+	if err := repos.Users.IncrementCounter(ctx, followerID, repository.FollowingCount, 1); err != nil {
+		return err
+	}
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return repos.Users.IncrementCounter(ctx, followerID, repository.FollowingCount, -1)
+	})
+
+	if err := repos.Users.IncrementCounter(ctx, followingID, repository.FollowersCount, 1); err != nil {
+		return err
 	}
 
-	return c.Status(200).JSON(fiber.Map{"message": "Settings updated successfully"})
+	// A precomputed timeline lacks the posts of the followed user, so it is
+	// rebuilt on the next read.
+	return repos.Timelines.Clear(ctx, followerID)
 }
 
-func UpdateUserStatus(userID primitive.ObjectID, status string) error {
-	collection := db.Database.Collection("users")
+// Unfollow undoes Follow. It must run in a transaction.
+func Unfollow(ctx context.Context, repos *repository.Repositories, followerID, followingID primitive.ObjectID) error {
+	if err := repos.Follows.Unfollow(ctx, followerID, followingID); err != nil {
+		return err
+	}
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return repos.Follows.Follow(ctx, followerID, followingID)
+	})
 
-	filter := bson.M{"_id": userID}
-	update := bson.M{"$set": bson.M{
-		"status":   status,
-		"lastSeen": time.Now(),
-	}}
+	if err := repos.Users.IncrementCounter(ctx, followerID, repository.FollowingCount, -1); err != nil {
+		return err
+	}
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return repos.Users.IncrementCounter(ctx, followerID, repository.FollowingCount, 1)
+	})
 
-	_, err := collection.UpdateOne(context.Background(), filter, update)
+	return repos.Users.IncrementCounter(ctx, followingID, repository.FollowersCount, -1)
+}
+
+// ApproveFollowRequest replaces the request with a follow and lets the requester
+// know. A requester who already follows the target only loses the request.
+func ApproveFollowRequest(ctx context.Context, repos *repository.Repositories, request types.FollowRequest) error {
+	followed := false
+	err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repos.FollowRequests.Delete(ctx, request.ID); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			restored := request
+			return repos.FollowRequests.Create(ctx, &restored)
+		})
+
+		err := Follow(ctx, repos, request.RequesterID, request.TargetID)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil
+		}
+		followed = err == nil
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
+	if followed {
+		Notify(ctx, repos, types.Notification{
+			Type:        types.NotificationFollowAccepted,
+			RecipientID: request.RequesterID,
+			ActorID:     request.TargetID,
+			SubjectID:   request.ID,
+		})
+	}
+
 	return nil
+}
+
+// MutedIDs returns the users viewerID muted for scope. Anonymous viewers haven't
+// muted anyone.
+func MutedIDs(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, scope string) ([]primitive.ObjectID, error) {
+	if viewerID.IsZero() {
+		return nil, nil
+	}
+	return repos.Mutes.MutedIDs(ctx, viewerID, scope)
+}
+
+// BlockedIDs returns the users userID blocked or was blocked by.
+func BlockedIDs(ctx context.Context, repos *repository.Repositories, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	blocks, err := repos.Blocks.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []primitive.ObjectID
+	for _, block := range blocks {
+		ids = append(ids, block.BlockedID)
+	}
+
+	blockers, err := repos.Blocks.BlockerIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(ids, blockers...), nil
+}
+
+// Reach returns the posts besides public ones viewerID may see: the
+// followers-only posts of the users they follow and the close-friends posts of
+// the users that listed them. Anonymous viewers only see public posts.
+func Reach(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID) (repository.Reach, error) {
+	if viewerID.IsZero() {
+		return repository.Reach{}, nil
+	}
+
+	followed, err := repos.Follows.FollowingIDs(ctx, viewerID)
+	if err != nil {
+		return repository.Reach{}, err
+	}
+	closeFriendOf, err := repos.CloseFriends.ListedBy(ctx, viewerID)
+	if err != nil {
+		return repository.Reach{}, err
+	}
+
+	return repository.Reach{ViewerID: viewerID, Followed: followed, CloseFriendOf: closeFriendOf}, nil
+}
+
+// CanReach reports whether viewerID may read post by its ID: they may see the
+// profile of its author and the post is within their reach.
+func CanReach(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, post types.Post) (bool, error) {
+	if !viewerID.IsZero() && post.UserID == viewerID {
+		return true, nil
+	}
+
+	visible, err := CanSeeProfile(ctx, repos, viewerID, post.UserID)
+	if err != nil || !visible {
+		return false, err
+	}
+
+	reach, err := Reach(ctx, repos, viewerID)
+	if err != nil {
+		return false, err
+	}
+	return reach.Allows(post, true), nil
+}
+
+// CanSeeProfile reports whether viewerID may see the posts of ownerID: neither
+// blocked the other, and ownerID is public or followed by viewerID.
+func CanSeeProfile(ctx context.Context, repos *repository.Repositories, viewerID, ownerID primitive.ObjectID) (bool, error) {
+	if !viewerID.IsZero() && ownerID == viewerID {
+		return true, nil
+	}
+
+	blocked, err := BlockedIDs(ctx, repos, viewerID)
+	if err != nil || slices.Contains(blocked, ownerID) {
+		return false, err
+	}
+
+	private, err := IsPrivate(ctx, repos, ownerID)
+	if err != nil || !private {
+		return err == nil, err
+	}
+	if viewerID.IsZero() {
+		return false, nil
+	}
+
+	return repos.Follows.IsFollowing(ctx, viewerID, ownerID)
+}
+
+// IsPrivate reports whether userID has a private profile. Users without
+// settings have the default public profile.
+func IsPrivate(ctx context.Context, repos *repository.Repositories, userID primitive.ObjectID) (bool, error) {
+	settings, err := repos.Settings.FindByUser(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return settings.ProfileVisibility == types.VisibilityPrivate, nil
+}
+
+// Notify sends the notification unless the recipient muted the notifications
+// of the actor. It's sent when the mutes can't be checked.
+func Notify(ctx context.Context, repos *repository.Repositories, notification types.Notification) {
+	muted, err := repos.Mutes.IsMuted(ctx, notification.RecipientID, notification.ActorID, types.MuteScopeNotifications)
+	if err != nil {
+		log.Println("Error checking mutes for notification: ", err)
+	}
+	if muted {
+		return
+	}
 
+	notify.Send(ctx, notification)
 }
@@ -0,0 +1,94 @@
+package helpers_test
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestCanReach(t *testing.T) {
+	tests := []struct {
+		name     string
+		audience string
+		private  bool
+		follows  bool
+		// closeFriend puts the viewer on the author's close friends list.
+		closeFriend bool
+		blocks      bool
+		blockedBy   bool
+		anonymous   bool
+		byViewer    bool
+		want        bool
+	}{
+		{name: "public", audience: types.AudiencePublic, want: true},
+		{name: "public to anonymous", audience: types.AudiencePublic, anonymous: true, want: true},
+		{name: "unlisted by link", audience: types.AudienceUnlisted, want: true},
+		{name: "followers only", audience: types.AudienceFollowers},
+		{name: "followers only to follower", audience: types.AudienceFollowers, follows: true, want: true},
+		{name: "close friends to follower", audience: types.AudienceCloseFriends, follows: true},
+		{name: "close friends to close friend", audience: types.AudienceCloseFriends, closeFriend: true, want: true},
+		{name: "private author", audience: types.AudiencePublic, private: true},
+		{name: "private author to anonymous", audience: types.AudiencePublic, private: true, anonymous: true},
+		{name: "private author to follower", audience: types.AudiencePublic, private: true, follows: true, want: true},
+		{name: "viewer blocked author", audience: types.AudiencePublic, blocks: true},
+		{name: "author blocked viewer", audience: types.AudiencePublic, blockedBy: true},
+		{name: "own private close friends post", audience: types.AudienceCloseFriends, private: true, byViewer: true, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			author := createUser(t, repos, "author")
+			viewer := createUser(t, repos, "viewer")
+
+			if tt.private {
+				if err := repos.Settings.Set(ctx, author, bson.M{"profileVisibility": types.VisibilityPrivate}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.follows {
+				if err := repos.Follows.Follow(ctx, viewer, author); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.closeFriend {
+				if err := repos.CloseFriends.Add(ctx, &types.CloseFriend{UserID: author, FriendID: viewer, CreatedAt: time.Now()}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.blocks {
+				if err := repos.Blocks.Create(ctx, &types.Block{UserID: viewer, BlockedID: author, CreatedAt: time.Now()}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.blockedBy {
+				if err := repos.Blocks.Create(ctx, &types.Block{UserID: author, BlockedID: viewer, CreatedAt: time.Now()}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			post := types.Post{ID: primitive.NewObjectID(), UserID: author, Audience: tt.audience}
+			if tt.byViewer {
+				post.UserID = viewer
+			}
+			viewerID := viewer
+			if tt.anonymous {
+				viewerID = primitive.NilObjectID
+			}
+
+			got, err := helpers.CanReach(ctx, repos, viewerID, post)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("CanReach = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package admin
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"strings"
+)
+
+// ConversationDump is a conversation with its participants and every message.
+type ConversationDump struct {
+	Conversation types.Conversation `json:"conversation"`
+	Messages     []types.Message    `json:"messages"`
+}
+
+// InspectConversation returns the conversation id with its messages oldest
+// first.
+func InspectConversation(ctx context.Context, repos *repository.Repositories, id primitive.ObjectID) (ConversationDump, error) {
+	conversation, err := repos.Conversations.FindByID(ctx, id)
+	if err != nil {
+		return ConversationDump{}, err
+	}
+
+	users, err := repos.Users.FindByIDs(ctx, conversation.ParticipantsIds)
+	if err != nil {
+		return ConversationDump{}, err
+	}
+	conversation.Participants = make([]types.Participant, 0, len(users))
+	for _, user := range users {
+		conversation.Participants = append(conversation.Participants, types.Participant{
+			ID:       user.ID,
+			UserName: strings.Join([]string{user.FirstName, user.LastName}, " "),
+			PhotoURL: user.PhotoURL,
+			IsOnline: user.IsOnline,
+			LastSeen: user.LastSeen,
+		})
+	}
+
+	messages, err := repos.Messages.ListByConversation(ctx, id)
+	if err != nil {
+		return ConversationDump{}, err
+	}
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].CreatedAt.Before(messages[j].CreatedAt) })
+
+	return ConversationDump{Conversation: conversation, Messages: messages}, nil
+}
@@ -0,0 +1,273 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DeleteReport counts what DeleteUser removed, or would remove in a dry run.
+type DeleteReport struct {
+	UserID    primitive.ObjectID `json:"userID"`
+	Posts     int                `json:"posts"`
+	Comments  int                `json:"comments"`
+	Likes     int                `json:"likes"`
+	Saves     int                `json:"saves"`
+	Reposts   int                `json:"reposts"`
+	Following int                `json:"following"`
+	Followers int                `json:"followers"`
+	Blocks    int                `json:"blocks"`
+	// Interactions counts the likes, comments, reposts and saves other users
+	// made on the posts and the collection items of them. They are only counted
+	// when they are deleted, like Revisions, Drafts, Collections,
+	// FollowRequests, CloseFriends, Mutes, Dismissals and TimelineEntries.
+	Interactions    int64 `json:"interactions"`
+	Revisions       int64 `json:"revisions"`
+	Drafts          int64 `json:"drafts"`
+	Collections     int64 `json:"collections"`
+	FollowRequests  int64 `json:"followRequests"`
+	CloseFriends    int64 `json:"closeFriends"`
+	Mutes           int64 `json:"mutes"`
+	Dismissals      int64 `json:"dismissals"`
+	TimelineEntries int64 `json:"timelineEntries"`
+	DryRun          bool  `json:"dryRun"`
+}
+
+// DeleteUser deletes the user together with their posts and everything on them
+// including revisions, their drafts, comments, likes, saves, collections,
+// reposts, follows, follow requests, close friends, blocks, mutes, dismissed
+// suggestions, timeline entries, settings and portfolio, and adjusts the
+// counters of the posts and users they interacted with. Their uploads are left
+// to PurgeMedia.
+//
+// Every step can be repeated, so a failed deletion is finished by running it
+// again.
+func DeleteUser(ctx context.Context, repos *repository.Repositories, user types.User, dryRun bool) (DeleteReport, error) {
+	report := DeleteReport{UserID: user.ID, DryRun: dryRun}
+
+	posts, err := repos.Posts.FindByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	comments, err := repos.Comments.FindByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	likes, err := repos.Likes.FindByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	saves, err := repos.Saves.FindByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	reposts, err := repos.Reposts.FindByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	following, err := repos.Follows.FollowingIDs(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	followers, err := repos.Follows.FollowerIDs(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	blocks, err := repos.Blocks.ListByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+
+	if dryRun {
+		report.Posts, report.Comments, report.Likes, report.Saves, report.Reposts = len(posts), len(comments), len(likes), len(saves), len(reposts)
+		report.Following, report.Followers, report.Blocks = len(following), len(followers), len(blocks)
+		return report, nil
+	}
+
+	for _, comment := range comments {
+		err := decrementing(ctx, repos, func(ctx context.Context) error {
+			return repos.Comments.Delete(ctx, comment.ID)
+		}, repos.Posts, comment.PostID, repository.CommentsCount)
+		if err != nil {
+			return report, err
+		}
+		report.Comments++
+	}
+
+	for _, like := range likes {
+		err := decrementing(ctx, repos, func(ctx context.Context) error {
+			return repos.Likes.Delete(ctx, like.PostID, user.ID)
+		}, repos.Posts, like.PostID, repository.LikesCount)
+		if err != nil {
+			return report, err
+		}
+		report.Likes++
+	}
+
+	for _, save := range saves {
+		err := decrementing(ctx, repos, func(ctx context.Context) error {
+			return repos.Saves.Delete(ctx, save.PostID, user.ID)
+		}, repos.Posts, save.PostID, repository.SavesCount)
+		if err != nil {
+			return report, err
+		}
+		report.Saves++
+	}
+
+	for _, repost := range reposts {
+		err := decrementing(ctx, repos, func(ctx context.Context) error {
+			return repos.Reposts.Delete(ctx, repost.ID)
+		}, repos.Posts, repost.PostID, repository.RepostCount)
+		if err != nil {
+			return report, err
+		}
+		report.Reposts++
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	for _, interactions := range []interface {
+		DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+	}{repos.Likes, repos.Comments, repos.Reposts, repos.Saves} {
+		deleted, err := interactions.DeleteByPosts(ctx, postIDs)
+		if err != nil {
+			return report, err
+		}
+		report.Interactions += deleted
+	}
+	items, err := repos.Collections.DeleteItemsByPosts(ctx, postIDs)
+	if err != nil {
+		return report, err
+	}
+	report.Interactions += items
+	report.Revisions, err = repos.Revisions.DeleteByPosts(ctx, postIDs)
+	if err != nil {
+		return report, err
+	}
+
+	for _, post := range posts {
+		deleted := false
+		remove := func(ctx context.Context) error {
+			err := repos.Posts.Delete(ctx, post.ID)
+			deleted = err == nil
+			return err
+		}
+
+		var err error
+		if post.QuotedPostID != nil {
+			err = decrementing(ctx, repos, remove, repos.Posts, *post.QuotedPostID, repository.QuotesCount)
+		} else {
+			err = ignoreNotFound(remove(ctx))
+		}
+		if err == nil && deleted {
+			err = hashtag.Count(ctx, repos, post.Tags, nil)
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Posts++
+	}
+
+	for _, followingID := range following {
+		err := decrementing(ctx, repos, func(ctx context.Context) error {
+			return repos.Follows.Unfollow(ctx, user.ID, followingID)
+		}, repos.Users, followingID, repository.FollowersCount)
+		if err != nil {
+			return report, err
+		}
+		report.Following++
+	}
+
+	for _, followerID := range followers {
+		err := decrementing(ctx, repos, func(ctx context.Context) error {
+			return repos.Follows.Unfollow(ctx, followerID, user.ID)
+		}, repos.Users, followerID, repository.FollowingCount)
+		if err != nil {
+			return report, err
+		}
+		report.Followers++
+	}
+
+	report.Drafts, err = repos.Drafts.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	report.Collections, err = repos.Collections.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	report.FollowRequests, err = repos.FollowRequests.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	report.CloseFriends, err = repos.CloseFriends.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	report.Mutes, err = repos.Mutes.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	report.Dismissals, err = repos.Suggestions.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+	report.TimelineEntries, err = repos.Timelines.DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return report, err
+	}
+
+	for _, block := range blocks {
+		if err := ignoreNotFound(repos.Blocks.Delete(ctx, user.ID, block.BlockedID)); err != nil {
+			return report, err
+		}
+		report.Blocks++
+	}
+
+	if err := ignoreNotFound(repos.Settings.Delete(ctx, user.ID)); err != nil {
+		return report, err
+	}
+	if err := ignoreNotFound(repos.Portfolios.Delete(ctx, user.ID.Hex())); err != nil {
+		return report, err
+	}
+
+	return report, ignoreNotFound(repos.Users.Delete(ctx, user.ID))
+}
+
+// counted is implemented by the repositories of documents with counters.
+type counted interface {
+	IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error
+}
+
+// decrementing runs remove and decrements field of the document id in one
+// transaction. Nothing changes when remove finds nothing to remove, so every
+// call can be repeated.
+func decrementing(ctx context.Context, repos *repository.Repositories, remove func(ctx context.Context) error, counters counted, id primitive.ObjectID, field string) error {
+	err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		// The document may have been deleted with the user, like their own posts.
+		err := counters.IncrementCounter(ctx, id, field, -1)
+		if err == nil {
+			repository.OnRollback(ctx, func(ctx context.Context) error {
+				return counters.IncrementCounter(ctx, id, field, 1)
+			})
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		return remove(ctx)
+	})
+
+	return ignoreNotFound(err)
+}
+
+func ignoreNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,431 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"time"
+)
+
+const exportVersion = 2
+
+// Export is everything a user created, in the format written by ExportUser and
+// read by ImportUser. The password is included as its hash so that an imported
+// user can log in as before.
+type Export struct {
+	Version      int                  `json:"version"`
+	ExportedAt   time.Time            `json:"exportedAt"`
+	User         types.User           `json:"user"`
+	Settings     *types.Settings      `json:"settings,omitempty"`
+	Portfolio    *types.Portfolio     `json:"portfolio,omitempty"`
+	Posts        []types.Post         `json:"posts"`
+	Drafts       []types.Draft        `json:"drafts"`
+	Uploads      []Upload             `json:"uploads"`
+	Comments     []types.Comment      `json:"comments"`
+	Likes        []types.Like         `json:"likes"`
+	Saves        []types.Save         `json:"saves"`
+	Collections  []CollectionExport   `json:"collections"`
+	Reposts      []types.Repost       `json:"reposts"`
+	Blocks       []types.Block        `json:"blocks"`
+	Mutes        []types.Mute         `json:"mutes"`
+	Following    []primitive.ObjectID `json:"following"`
+	CloseFriends []primitive.ObjectID `json:"closeFriends"`
+	// FollowRequests are the requests by and to the user.
+	FollowRequests []types.FollowRequest `json:"followRequests"`
+}
+
+// Upload is a file uploaded for a draft. Published posts keep their files, but
+// those of drafts are purged once the user is deleted, so they are exported
+// with their contents.
+type Upload struct {
+	repository.MediaFile
+	Data []byte `json:"data"`
+}
+
+// CollectionExport is a collection with its items, by position.
+type CollectionExport struct {
+	types.Collection
+	Items []types.CollectionItem `json:"items"`
+}
+
+// ExportUser collects the data of the user.
+func ExportUser(ctx context.Context, repos *repository.Repositories, user types.User) (Export, error) {
+	export := Export{Version: exportVersion, ExportedAt: time.Now(), User: user}
+	var err error
+
+	if export.Posts, err = repos.Posts.FindByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Comments, err = repos.Comments.FindByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Likes, err = repos.Likes.FindByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Reposts, err = repos.Reposts.FindByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Blocks, err = repos.Blocks.ListByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Following, err = repos.Follows.FollowingIDs(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Saves, err = repos.Saves.FindByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Mutes, err = repos.Mutes.ListByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.CloseFriends, err = repos.CloseFriends.FriendIDs(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.FollowRequests, err = repos.FollowRequests.FindOutgoing(ctx, user.ID); err != nil {
+		return export, err
+	}
+	incoming, err := repos.FollowRequests.FindIncoming(ctx, user.ID)
+	if err != nil {
+		return export, err
+	}
+	export.FollowRequests = append(export.FollowRequests, incoming...)
+
+	if export.Drafts, err = repos.Drafts.FindByUser(ctx, user.ID); err != nil {
+		return export, err
+	}
+	if export.Uploads, err = exportUploads(ctx, repos, export.Drafts); err != nil {
+		return export, err
+	}
+
+	collections, err := repos.Collections.ListByUser(ctx, user.ID, false)
+	if err != nil {
+		return export, err
+	}
+	for _, collection := range collections {
+		items, err := repos.Collections.ListItems(ctx, collection.ID, 0, 0)
+		if err != nil {
+			return export, err
+		}
+		export.Collections = append(export.Collections, CollectionExport{Collection: collection, Items: items})
+	}
+
+	settings, err := repos.Settings.FindByUser(ctx, user.ID)
+	if err == nil {
+		export.Settings = &settings
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return export, err
+	}
+
+	portfolio, err := repos.Portfolios.FindByUser(ctx, user.ID.Hex())
+	if err == nil {
+		export.Portfolio = &portfolio
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return export, err
+	}
+
+	return export, nil
+}
+
+// exportUploads reads the files of the drafts. Files that are gone already are
+// left out.
+func exportUploads(ctx context.Context, repos *repository.Repositories, drafts []types.Draft) ([]Upload, error) {
+	var uploads []Upload
+	for _, draft := range drafts {
+		for _, hexID := range append(slices.Clone(draft.Images), draft.Files...) {
+			id, err := primitive.ObjectIDFromHex(hexID)
+			if err != nil {
+				continue
+			}
+
+			file, err := repos.Media.Find(ctx, id)
+			if errors.Is(err, repository.ErrNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			var data bytes.Buffer
+			if err := repos.Media.Download(ctx, id, &data); err != nil {
+				return nil, err
+			}
+			uploads = append(uploads, Upload{MediaFile: file, Data: data.Bytes()})
+		}
+	}
+
+	return uploads, nil
+}
+
+// ImportReport counts what ImportUser created, or would create in a dry run.
+// Likes, comments, saves, collection items, reposts, follows, close friends,
+// mutes and follow requests of posts and users that don't exist are skipped.
+type ImportReport struct {
+	UserID          primitive.ObjectID `json:"userID"`
+	Posts           int                `json:"posts"`
+	Drafts          int                `json:"drafts"`
+	Uploads         int                `json:"uploads"`
+	Comments        int                `json:"comments"`
+	Likes           int                `json:"likes"`
+	Saves           int                `json:"saves"`
+	Collections     int                `json:"collections"`
+	CollectionItems int                `json:"collectionItems"`
+	Reposts         int                `json:"reposts"`
+	Blocks          int                `json:"blocks"`
+	Mutes           int                `json:"mutes"`
+	Following       int                `json:"following"`
+	CloseFriends    int                `json:"closeFriends"`
+	FollowRequests  int                `json:"followRequests"`
+	Skipped         int                `json:"skipped"`
+	DryRun          bool               `json:"dryRun"`
+}
+
+// ImportUser recreates an exported user with their original IDs, for example
+// after moving them between databases or deleting them by mistake. The user
+// must not exist yet. The counters are rebuilt from what is imported, so likes
+// and comments other users made on the posts are not restored.
+func ImportUser(ctx context.Context, repos *repository.Repositories, export Export, dryRun bool) (ImportReport, error) {
+	report := ImportReport{UserID: export.User.ID, DryRun: dryRun}
+
+	if export.Version != exportVersion {
+		return report, fmt.Errorf("unsupported export version %d", export.Version)
+	}
+	if export.User.ID.IsZero() {
+		return report, errors.New("export has no user ID")
+	}
+	if _, err := repos.Users.FindByID(ctx, export.User.ID); err == nil {
+		return report, fmt.Errorf("user %s: %w", export.User.ID.Hex(), repository.ErrDuplicate)
+	}
+
+	user := export.User
+	user.FollowersCount, user.FollowingCount = 0, 0
+	user.IsOnline = false
+
+	if !dryRun {
+		if err := repos.Users.Create(ctx, &user); err != nil {
+			return report, err
+		}
+		if export.Settings != nil {
+			if err := repos.Settings.Create(ctx, export.Settings); err != nil {
+				return report, err
+			}
+		}
+		if export.Portfolio != nil {
+			if err := repos.Portfolios.Create(ctx, export.Portfolio); err != nil {
+				return report, err
+			}
+		}
+	}
+
+	for _, post := range export.Posts {
+		post.LikesCount, post.CommentsCount, post.RepostCount, post.SavesCount = 0, 0, 0, 0
+		if !dryRun {
+			if err := repos.Posts.Create(ctx, &post); err != nil {
+				return report, err
+			}
+			if err := hashtag.Count(ctx, repos, nil, post.Tags); err != nil {
+				return report, err
+			}
+		}
+		report.Posts++
+	}
+
+	for _, upload := range export.Uploads {
+		if !dryRun {
+			if err := repos.Media.Restore(ctx, upload.MediaFile, bytes.NewReader(upload.Data)); err != nil && !errors.Is(err, repository.ErrDuplicate) {
+				return report, err
+			}
+		}
+		report.Uploads++
+	}
+
+	for _, draft := range export.Drafts {
+		if !dryRun {
+			if err := repos.Drafts.Create(ctx, &draft); err != nil {
+				return report, err
+			}
+		}
+		report.Drafts++
+	}
+
+	for _, comment := range export.Comments {
+		created, err := importing(ctx, repos, dryRun, repos.Posts, comment.PostID, repository.CommentsCount, func(ctx context.Context) error {
+			return repos.Comments.Create(ctx, &comment)
+		})
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.Comments)
+	}
+
+	for _, like := range export.Likes {
+		created, err := importing(ctx, repos, dryRun, repos.Posts, like.PostID, repository.LikesCount, func(ctx context.Context) error {
+			return repos.Likes.Create(ctx, &like)
+		})
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.Likes)
+	}
+
+	for _, save := range export.Saves {
+		created, err := importing(ctx, repos, dryRun, repos.Posts, save.PostID, repository.SavesCount, func(ctx context.Context) error {
+			return repos.Saves.Create(ctx, &save)
+		})
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.Saves)
+	}
+
+	for _, collection := range export.Collections {
+		if !dryRun {
+			if err := repos.Collections.Create(ctx, &collection.Collection); err != nil {
+				return report, err
+			}
+		}
+		report.Collections++
+
+		// AddItem puts every item first, so they are added from the last.
+		for i := len(collection.Items) - 1; i >= 0; i-- {
+			item := collection.Items[i]
+			created, err := exists(ctx, repos.Posts, item.PostID)
+			if err == nil && created && !dryRun {
+				err = repos.Collections.AddItem(ctx, &item)
+			}
+			if err != nil {
+				return report, err
+			}
+			report.count(created, &report.CollectionItems)
+		}
+	}
+
+	for _, repost := range export.Reposts {
+		created, err := importing(ctx, repos, dryRun, repos.Posts, repost.PostID, repository.RepostCount, func(ctx context.Context) error {
+			return repos.Reposts.Create(ctx, &repost)
+		})
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.Reposts)
+	}
+
+	for _, followingID := range export.Following {
+		created, err := importing(ctx, repos, dryRun, repos.Users, followingID, repository.FollowersCount, func(ctx context.Context) error {
+			if err := repos.Follows.Follow(ctx, user.ID, followingID); err != nil {
+				return err
+			}
+			repository.OnRollback(ctx, func(ctx context.Context) error {
+				return repos.Follows.Unfollow(ctx, user.ID, followingID)
+			})
+
+			return repos.Users.IncrementCounter(ctx, user.ID, repository.FollowingCount, 1)
+		})
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.Following)
+	}
+
+	for _, friendID := range export.CloseFriends {
+		created, err := exists(ctx, repos.Users, friendID)
+		if err == nil && created && !dryRun {
+			err = repos.CloseFriends.Add(ctx, &types.CloseFriend{UserID: user.ID, FriendID: friendID, CreatedAt: time.Now()})
+		}
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.CloseFriends)
+	}
+
+	for _, request := range export.FollowRequests {
+		other := request.TargetID
+		if other == user.ID {
+			other = request.RequesterID
+		}
+		created, err := exists(ctx, repos.Users, other)
+		if err == nil && created && !dryRun {
+			err = repos.FollowRequests.Create(ctx, &request)
+		}
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.FollowRequests)
+	}
+
+	for _, mute := range export.Mutes {
+		created, err := exists(ctx, repos.Users, mute.MutedID)
+		if err == nil && created && !dryRun {
+			err = repos.Mutes.Set(ctx, &mute)
+		}
+		if err != nil {
+			return report, err
+		}
+		report.count(created, &report.Mutes)
+	}
+
+	for _, block := range export.Blocks {
+		if !dryRun {
+			if err := repos.Blocks.Create(ctx, &block); err != nil {
+				return report, err
+			}
+		}
+		report.Blocks++
+	}
+
+	return report, nil
+}
+
+func (r *ImportReport) count(created bool, n *int) {
+	if created {
+		*n++
+	} else {
+		r.Skipped++
+	}
+}
+
+var errSkipped = errors.New("skipped")
+
+// exists reports whether the document id exists.
+func exists[T any](ctx context.Context, repo interface {
+	FindByID(ctx context.Context, id primitive.ObjectID) (T, error)
+}, id primitive.ObjectID) (bool, error) {
+	_, err := repo.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// importing runs create and increments field of the document id in one
+// transaction. It reports false and creates nothing when the document doesn't
+// exist.
+func importing(ctx context.Context, repos *repository.Repositories, dryRun bool, counters counted, id primitive.ObjectID, field string, create func(ctx context.Context) error) (bool, error) {
+	if dryRun {
+		return true, nil
+	}
+
+	err := repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		err := counters.IncrementCounter(ctx, id, field, 1)
+		if errors.Is(err, repository.ErrNotFound) {
+			return errSkipped
+		}
+		if err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return counters.IncrementCounter(ctx, id, field, -1)
+		})
+
+		return create(ctx)
+	})
+	if errors.Is(err, errSkipped) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+const mediaBatchSize = 500
+
+// PurgeReport lists the orphaned files PurgeMedia found.
+type PurgeReport struct {
+	Checked int                    `json:"checked"`
+	Orphans []repository.MediaFile `json:"orphans"`
+	Bytes   int64                  `json:"bytes"`
+	DryRun  bool                   `json:"dryRun"`
+}
+
+// PurgeMedia deletes the uploaded files that no user, post, message or
+// portfolio refers to. Files uploaded within minAge are kept because the
+// document referring to them may not have been saved yet.
+func PurgeMedia(ctx context.Context, repos *repository.Repositories, minAge time.Duration, dryRun bool) (PurgeReport, error) {
+	report := PurgeReport{Orphans: []repository.MediaFile{}, DryRun: dryRun}
+	cutoff := time.Now().Add(-minAge)
+
+	after := primitive.NilObjectID
+	for {
+		files, err := repos.Media.List(ctx, after, mediaBatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(files) == 0 {
+			return report, nil
+		}
+		after = files[len(files)-1].ID
+		report.Checked += len(files)
+
+		ids := make([]primitive.ObjectID, 0, len(files))
+		for _, file := range files {
+			ids = append(ids, file.ID)
+		}
+		referenced, err := repos.Media.Referenced(ctx, ids)
+		if err != nil {
+			return report, err
+		}
+
+		for _, file := range files {
+			if referenced[file.ID] || file.UploadedAt.After(cutoff) {
+				continue
+			}
+			if !dryRun {
+				if err := ignoreNotFound(repos.Media.Delete(ctx, file.ID)); err != nil {
+					return report, err
+				}
+			}
+			report.Orphans = append(report.Orphans, file)
+			report.Bytes += file.Length
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package admin implements the operational tasks run by cmd/fiabesco-admin. Every
+// task works through the repositories, so it behaves the same as the API, and
+// supports a dry run that only reports what would change.
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/handlers/auth"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRole = errors.New("invalid role")
+
+// FindUser looks a user up by ID, email or handle.
+func FindUser(ctx context.Context, repos *repository.Repositories, ref string) (types.User, error) {
+	if id, err := utils.ParseHexID(ref); err == nil {
+		return repos.Users.FindByID(ctx, id)
+	}
+	if strings.Contains(ref, "@") && !strings.HasPrefix(ref, "@") {
+		return repos.Users.FindByEmail(ctx, ref)
+	}
+
+	return repos.Users.FindByHandle(ctx, strings.TrimPrefix(ref, "@"))
+}
+
+// NewUser holds the fields of a user created by CreateUser. A handle is
+// generated when Handle is empty.
+type NewUser struct {
+	Email     string
+	Password  string
+	FirstName string
+	LastName  string
+	Handle    string
+	Roles     []string
+}
+
+// CreateUser creates the user with the default settings, like signing up does.
+func CreateUser(ctx context.Context, repos *repository.Repositories, input NewUser, dryRun bool) (types.User, error) {
+	for _, role := range input.Roles {
+		if !slices.Contains(types.Roles, role) {
+			return types.User{}, fmt.Errorf("%w %q", ErrInvalidRole, role)
+		}
+	}
+	if input.Handle == "" {
+		input.Handle = utils.GenerateHandle(24)
+	}
+
+	user := types.User{
+		Email:     input.Email,
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Handle:    input.Handle,
+		Roles:     input.Roles,
+		CreatedAt: time.Now(),
+	}
+
+	if _, err := repos.Users.FindByEmail(ctx, user.Email); err == nil {
+		return user, fmt.Errorf("email %s: %w", user.Email, repository.ErrDuplicate)
+	}
+	if _, err := repos.Users.FindByHandle(ctx, user.Handle); err == nil {
+		return user, fmt.Errorf("handle %s: %w", user.Handle, repository.ErrDuplicate)
+	}
+	if dryRun {
+		return user, nil
+	}
+
+	user.Password = auth.HashPassword(input.Password)
+	if err := repos.Users.Create(ctx, &user); err != nil {
+		return user, err
+	}
+
+	settings := types.DefaultSettings(user.ID)
+	if err := repos.Settings.Create(ctx, &settings); err != nil {
+		return user, err
+	}
+
+	user.Password = ""
+	return user, nil
+}
+
+// Suspend suspends the user, or lifts the suspension when suspended is false.
+// Suspended users can't log in.
+func Suspend(ctx context.Context, repos *repository.Repositories, user types.User, suspended, dryRun bool) (types.User, error) {
+	var fields bson.M
+	if suspended {
+		now := time.Now()
+		user.SuspendedAt = &now
+		fields = bson.M{"suspendedAt": now}
+	} else {
+		user.SuspendedAt = nil
+		fields = bson.M{"suspendedAt": nil}
+	}
+
+	user.Password = ""
+	if dryRun {
+		return user, nil
+	}
+
+	return user, repos.Users.Update(ctx, user.ID, fields)
+}
+
+// ResetPassword sets a new password for the user and returns it. A random
+// password is generated when password is empty.
+func ResetPassword(ctx context.Context, repos *repository.Repositories, user types.User, password string, dryRun bool) (string, error) {
+	if password == "" {
+		raw := make([]byte, 12)
+		if _, err := rand.Read(raw); err != nil {
+			return "", err
+		}
+		password = base64.RawURLEncoding.EncodeToString(raw)
+	}
+	if dryRun {
+		return password, nil
+	}
+
+	return password, repos.Users.Update(ctx, user.ID, bson.M{"password": auth.HashPassword(password)})
+}
+
+// GrantRole adds role to the user, or removes it when revoke is set, and
+// returns the resulting roles.
+func GrantRole(ctx context.Context, repos *repository.Repositories, user types.User, role string, revoke, dryRun bool) ([]string, error) {
+	if !slices.Contains(types.Roles, role) {
+		return nil, fmt.Errorf("%w %q, expected one of %s", ErrInvalidRole, role, strings.Join(types.Roles, ", "))
+	}
+
+	roles := slices.DeleteFunc(slices.Clone(user.Roles), func(r string) bool { return r == role })
+	if !revoke {
+		roles = append(roles, role)
+	}
+	slices.Sort(roles)
+
+	if dryRun {
+		return roles, nil
+	}
+
+	return roles, repos.Users.Update(ctx, user.ID, bson.M{"roles": roles})
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"github.com/edisss1/fiabesco-backend/cache"
 	"github.com/edisss1/fiabesco-backend/db"
+	"github.com/edisss1/fiabesco-backend/internal/ranking"
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func LoadEnv() {
@@ -25,3 +30,144 @@ func GetPort() string {
 
 	return port
 }
+
+// MigrateOnStart reports whether pending migrations are applied when the server starts.
+func MigrateOnStart() bool {
+	return os.Getenv("MIGRATE_ON_START") == "true"
+}
+
+// GetReconcileInterval returns how often the counter reconciliation job runs in
+// the server. It is disabled when RECONCILE_INTERVAL is unset.
+func GetReconcileInterval() time.Duration {
+	value := os.Getenv("RECONCILE_INTERVAL")
+	if value == "" {
+		return 0
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < 0 {
+		log.Printf("Invalid RECONCILE_INTERVAL %q, reconciliation is disabled", value)
+		return 0
+	}
+
+	return interval
+}
+
+// GetPublishInterval returns how often scheduled drafts are published, every
+// minute unless PUBLISH_INTERVAL says otherwise.
+func GetPublishInterval() time.Duration {
+	value := os.Getenv("PUBLISH_INTERVAL")
+	if value == "" {
+		return time.Minute
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid PUBLISH_INTERVAL %q, using 1m", value)
+		return time.Minute
+	}
+
+	return interval
+}
+
+// GetTimelinePrecompute returns how many accounts a user has to follow for their
+// home timeline to be precomputed. It is disabled when TIMELINE_PRECOMPUTE_FOLLOWING
+// is unset.
+func GetTimelinePrecompute() int {
+	return getInt("TIMELINE_PRECOMPUTE_FOLLOWING", 0)
+}
+
+// GetRankingOptions returns the For You ranking options. RANKING_WEIGHTS
+// overrides some of the weights, e.g. "engagement=1.5,freshness=0.5", and
+// RANKING_MAX_CONSECUTIVE how many posts of one author may follow each other.
+func GetRankingOptions() ranking.Options {
+	opts := ranking.DefaultOptions
+	opts.MaxConsecutive = getInt("RANKING_MAX_CONSECUTIVE", opts.MaxConsecutive)
+
+	value := os.Getenv("RANKING_WEIGHTS")
+	if value == "" {
+		return opts
+	}
+
+	weights := map[string]*float64{
+		"engagement": &opts.Weights.Engagement,
+		"tags":       &opts.Weights.TagAffinity,
+		"authors":    &opts.Weights.AuthorAffinity,
+		"freshness":  &opts.Weights.Freshness,
+	}
+	for _, pair := range strings.Split(value, ",") {
+		name, raw, _ := strings.Cut(strings.TrimSpace(pair), "=")
+		weight, ok := weights[name]
+		n, err := strconv.ParseFloat(raw, 64)
+		if !ok || err != nil || n < 0 {
+			log.Printf("Invalid RANKING_WEIGHTS entry %q, ignoring it", pair)
+			continue
+		}
+		*weight = n
+	}
+
+	return opts
+}
+
+// GetCache returns the cache selected by CACHE_BACKEND: none (the default),
+// Redis at REDIS_ADDR, or an in-process LRU of CACHE_SIZE entries. The LRU is
+// only invalidated by writes of its own process, so it's only safe with a
+// single server and no other process writing, like the admin commands.
+func GetCache() cache.Cache {
+	switch backend := os.Getenv("CACHE_BACKEND"); backend {
+	case "", "none":
+		return cache.Nop{}
+	case "memory":
+		log.Println("CACHE_BACKEND=memory is only invalidated by this process, so it needs a single server and no other writers")
+		return cache.NewLRU(getInt("CACHE_SIZE", 10000))
+	case "redis":
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		return cache.NewRedis(addr, os.Getenv("REDIS_PASSWORD"), getInt("REDIS_DB", 0), getInt("REDIS_POOL_SIZE", 10))
+	default:
+		log.Printf("Invalid CACHE_BACKEND %q, caching is disabled", backend)
+		return cache.Nop{}
+	}
+}
+
+// GetLegacyDeprecation returns when the unversioned routes were deprecated.
+func GetLegacyDeprecation() time.Time {
+	return getDate("LEGACY_DEPRECATED_AT", time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC))
+}
+
+// GetLegacySunset returns when the unversioned routes stop being served.
+func GetLegacySunset() time.Time {
+	return getDate("LEGACY_SUNSET", time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC))
+}
+
+func getDate(key string, fallback time.Time) time.Time {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	date, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using %s", key, value, fallback.Format(time.DateOnly))
+		return fallback
+	}
+
+	return date
+}
+
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		log.Printf("Invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+
+	return n
+}
@@ -0,0 +1,156 @@
+// Package hashtag normalizes the tags of posts, finds the hashtags written in
+// captions and keeps the usage counts of the tags collection up to date.
+//
+// Tags are stored without "#", in lower case and without the diacritics of
+// Latin, Greek and Cyrillic letters, so "#Café" and "cafe" are the same tag.
+// Only letters, digits and "_" are kept, and a tag needs at least one letter.
+package hashtag
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"golang.org/x/text/unicode/norm"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxLength is the most characters a tag may have.
+	MaxLength = 50
+	// MaxPerPost is the most tags a post may have, its caption's included.
+	MaxPerPost = 30
+
+	// TrendingWindow is how far back Trending looks.
+	TrendingWindow = 72 * time.Hour
+	// TrendingHalfLife is how long it takes a post to count half as much
+	// towards its tags trending.
+	TrendingHalfLife = 12 * time.Hour
+)
+
+// ErrInvalid means a post has too many tags or a tag that is too long.
+var ErrInvalid = errors.New("invalid tags")
+
+// inline matches a hashtag in a caption. It has to start the caption or follow
+// a character that can't be part of a word, so "a#b" and "&#39;" aren't tags.
+var inline = regexp.MustCompile(`(?:^|[^\p{L}\p{N}\p{M}_&/#])#([\p{L}\p{N}\p{M}_]+)`)
+
+// Normalize returns tag as it is stored, or "" if nothing of it is left.
+func Normalize(tag string) string {
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+
+	var b strings.Builder
+	var base rune
+	letter := false
+	for _, r := range norm.NFD.String(tag) {
+		switch {
+		case unicode.IsMark(r):
+			if !unicode.In(base, unicode.Latin, unicode.Greek, unicode.Cyrillic) {
+				b.WriteRune(r)
+			}
+		case unicode.IsLetter(r):
+			b.WriteRune(unicode.ToLower(r))
+			base, letter = r, true
+		case unicode.IsDigit(r) || r == '_':
+			b.WriteRune(r)
+			base = r
+		}
+	}
+	if !letter {
+		return ""
+	}
+
+	return norm.NFC.String(b.String())
+}
+
+// Extract returns the hashtags written in caption, as they are written.
+func Extract(caption string) []string {
+	var tags []string
+	for _, match := range inline.FindAllStringSubmatch(caption, -1) {
+		tags = append(tags, match[1])
+	}
+	return tags
+}
+
+// ForPost returns the normalized tags of a post: tags followed by the hashtags
+// of caption, without duplicates. It returns ErrInvalid when they break the
+// limits.
+func ForPost(tags []string, caption string) ([]string, error) {
+	var result []string
+	for _, tag := range append(slices.Clone(tags), Extract(caption)...) {
+		name := Normalize(tag)
+		if name == "" || slices.Contains(result, name) {
+			continue
+		}
+		if utf8.RuneCountInString(name) > MaxLength {
+			return nil, fmt.Errorf("%w: tags can't be longer than %d characters", ErrInvalid, MaxLength)
+		}
+		result = append(result, name)
+	}
+
+	if len(result) > MaxPerPost {
+		return nil, fmt.Errorf("%w: posts can't have more than %d tags", ErrInvalid, MaxPerPost)
+	}
+
+	return result, nil
+}
+
+// Explicit returns the tags of a post that don't come from the hashtags of its
+// caption, so that they survive when the caption changes.
+func Explicit(tags []string, caption string) []string {
+	var inCaption []string
+	for _, tag := range Extract(caption) {
+		inCaption = append(inCaption, Normalize(tag))
+	}
+
+	var result []string
+	for _, tag := range tags {
+		if !slices.Contains(inCaption, tag) {
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// Count updates the usage counts of the tags that a post gained or lost when
+// its tags went from before to after. A new post has no tags before, and a
+// deleted one none after.
+func Count(ctx context.Context, repos *repository.Repositories, before, after []string) error {
+	var added, removed []string
+	for _, tag := range after {
+		if !slices.Contains(before, tag) {
+			added = append(added, tag)
+		}
+	}
+	for _, tag := range before {
+		if !slices.Contains(after, tag) {
+			removed = append(removed, tag)
+		}
+	}
+
+	if len(added) > 0 {
+		if err := repos.Tags.Increment(ctx, added, 1); err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		return repos.Tags.Increment(ctx, removed, -1)
+	}
+	return nil
+}
+
+// Trending returns the limit tags used most in the posts of the past
+// TrendingWindow, each post counting half as much every TrendingHalfLife.
+func Trending(ctx context.Context, repos *repository.Repositories, limit int64) ([]types.TrendingTag, error) {
+	return repos.Posts.TrendingTags(ctx, repository.TrendingQuery{
+		Since:    time.Now().Add(-TrendingWindow),
+		HalfLife: TrendingHalfLife,
+		Limit:    limit,
+	})
+}
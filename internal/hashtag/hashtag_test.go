@@ -0,0 +1,101 @@
+package hashtag
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"Café", "cafe"},
+		{"#Café", "cafe"},
+		{"##go", "go"},
+		{"  #Go_Lang  ", "go_lang"},
+		{"Ελληνικά", "ελληνικα"},
+		{"Ёлка", "елка"},
+		{"straße", "straße"},
+		{"2024", ""},
+		{"#2024", ""},
+		{"2024Olympics", "2024olympics"},
+		{"___", ""},
+		{"", ""},
+		{"#", ""},
+		{"hello-world!", "helloworld"},
+		{"日本語", "日本語"},
+		{"हिन्दी", "हिन्दी"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := Normalize(tt.tag); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.tag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestForPost(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		caption string
+		want    []string
+		wantErr error
+	}{
+		{name: "tags then caption", tags: []string{"Art"}, caption: "new #Painting and #café", want: []string{"art", "painting", "cafe"}},
+		{name: "duplicates after normalizing", tags: []string{"#Café", "cafe"}, caption: "#CAFÉ", want: []string{"cafe"}},
+		{name: "digits only are dropped", tags: []string{"2024"}, caption: "#2024 #y2024", want: []string{"y2024"}},
+		{name: "not hashtags", caption: "a#b &#39; mail#me /#path", want: nil},
+		{name: "too long", tags: []string{strings.Repeat("a", MaxLength+1)}, wantErr: ErrInvalid},
+		{name: "longest", tags: []string{strings.Repeat("a", MaxLength)}, want: []string{strings.Repeat("a", MaxLength)}},
+		{name: "too many", caption: hashtags(MaxPerPost + 1), wantErr: ErrInvalid},
+		{name: "most", caption: hashtags(MaxPerPost), want: strings.Fields(strings.ReplaceAll(hashtags(MaxPerPost), "#", ""))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ForPost(tt.tags, tt.caption)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplicit(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		caption string
+		want    []string
+	}{
+		{name: "no caption hashtags", tags: []string{"art", "cafe"}, caption: "hello", want: []string{"art", "cafe"}},
+		{name: "caption hashtags are left out", tags: []string{"art", "cafe"}, caption: "at the #Café", want: []string{"art"}},
+		{name: "all from the caption", tags: []string{"art"}, caption: "#ART", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Explicit(tt.tags, tt.caption); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// hashtags returns a caption with n different hashtags.
+func hashtags(n int) string {
+	tags := make([]string, n)
+	for i := range tags {
+		tags[i] = "#tag" + strings.Repeat("x", i)
+	}
+	return strings.Join(tags, " ")
+}
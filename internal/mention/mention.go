@@ -0,0 +1,169 @@
+// Package mention finds the @handle mentions written in captions, comments and
+// messages, resolves them to users and notifies the users mentioned.
+//
+// A mention is an "@" that doesn't follow a word character, so e-mail
+// addresses aren't mentions, followed by letters, digits, "_" and "." up to
+// the last letter, digit or "_". Handles are matched exactly. Mentions of
+// unknown handles and of users the author blocked or was blocked by stay
+// plain text.
+package mention
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxPerText is the most different handles resolved in one text.
+const MaxPerText = 20
+
+var inline = regexp.MustCompile(`(?:^|[^\p{L}\p{N}\p{M}_.@])@([\p{L}\p{N}\p{M}_.]+)`)
+
+// Parse returns the mentions written in text in order, without their users.
+func Parse(text string) []types.Mention {
+	var mentions []types.Mention
+	for _, match := range inline.FindAllStringSubmatchIndex(text, -1) {
+		handle := strings.TrimRight(text[match[2]:match[3]], ".")
+		if handle == "" {
+			continue
+		}
+
+		start := utf8.RuneCountInString(text[:match[2]-1])
+		mentions = append(mentions, types.Mention{
+			Handle: handle,
+			Start:  start,
+			End:    start + 1 + utf8.RuneCountInString(handle),
+		})
+	}
+
+	return mentions
+}
+
+// Resolve returns the mentions in text authorID may make, with their users.
+func Resolve(ctx context.Context, repos *repository.Repositories, authorID primitive.ObjectID, text string) ([]types.Mention, error) {
+	parsed := Parse(text)
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	var handles []string
+	for _, mention := range parsed {
+		if len(handles) < MaxPerText && !slices.Contains(handles, mention.Handle) {
+			handles = append(handles, mention.Handle)
+		}
+	}
+
+	users, err := repos.Users.FindByHandles(ctx, handles)
+	if err != nil {
+		return nil, err
+	}
+
+	blocked, err := helpers.BlockedIDs(ctx, repos, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	byHandle := make(map[string]primitive.ObjectID, len(users))
+	for _, user := range users {
+		if !slices.Contains(blocked, user.ID) {
+			byHandle[user.Handle] = user.ID
+		}
+	}
+
+	var mentions []types.Mention
+	for _, mention := range parsed {
+		if userID, ok := byHandle[mention.Handle]; ok {
+			mention.UserID = userID
+			mentions = append(mentions, mention)
+		}
+	}
+
+	return mentions, nil
+}
+
+// Notify sends a notification of notificationType about subjectID to the users
+// in mentions that weren't in previous yet. The author isn't notified, and
+// neither is anyone canSee reports can't see the subject.
+func Notify(ctx context.Context, repos *repository.Repositories, notificationType string, authorID, subjectID primitive.ObjectID, mentions, previous []types.Mention, canSee func(ctx context.Context, userID primitive.ObjectID) (bool, error)) {
+	notified := []primitive.ObjectID{authorID}
+	for _, mention := range previous {
+		notified = append(notified, mention.UserID)
+	}
+
+	for _, mention := range mentions {
+		if slices.Contains(notified, mention.UserID) {
+			continue
+		}
+		notified = append(notified, mention.UserID)
+
+		visible, err := canSee(ctx, mention.UserID)
+		if err != nil {
+			log.Println("Error checking who can see mention: ", err)
+			continue
+		}
+		if !visible {
+			continue
+		}
+
+		helpers.Notify(ctx, repos, types.Notification{
+			Type:        notificationType,
+			RecipientID: mention.UserID,
+			ActorID:     authorID,
+			SubjectID:   subjectID,
+		})
+	}
+}
+
+// NotifyPost notifies the users newly mentioned in post who can see it.
+func NotifyPost(ctx context.Context, repos *repository.Repositories, post types.Post, previous []types.Mention) {
+	Notify(ctx, repos, types.NotificationPostMention, post.UserID, post.ID, post.Mentions, previous, postVisible(repos, post.ID))
+}
+
+// NotifyComment notifies the users newly mentioned in comment who can see the
+// post it was made on.
+func NotifyComment(ctx context.Context, repos *repository.Repositories, comment types.Comment, previous []types.Mention) {
+	Notify(ctx, repos, types.NotificationCommentMention, comment.UserID, comment.ID, comment.Mentions, previous, postVisible(repos, comment.PostID))
+}
+
+// NotifyMessage notifies the users newly mentioned in message who take part in
+// its conversation.
+func NotifyMessage(ctx context.Context, repos *repository.Repositories, message types.Message, previous []types.Mention) {
+	var participants []primitive.ObjectID
+	Notify(ctx, repos, types.NotificationMessageMention, message.SenderID, message.ID, message.Mentions, previous, func(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+		if participants == nil {
+			conversation, err := repos.Conversations.FindByID(ctx, message.ConversationID)
+			if err != nil {
+				return false, err
+			}
+			participants = conversation.ParticipantsIds
+		}
+		return slices.Contains(participants, userID), nil
+	})
+}
+
+// postVisible reports whether a mentioned user may read postID, looking the
+// post up once.
+func postVisible(repos *repository.Repositories, postID primitive.ObjectID) func(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	var post *types.Post
+	return func(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+		if post == nil {
+			found, err := repos.Posts.FindByID(ctx, postID)
+			if errors.Is(err, repository.ErrNotFound) {
+				return false, nil
+			}
+			if err != nil {
+				return false, err
+			}
+			post = &found
+		}
+		return helpers.CanReach(ctx, repos, userID, *post)
+	}
+}
@@ -0,0 +1,36 @@
+package mention
+
+import (
+	"github.com/edisss1/fiabesco-backend/types"
+	"slices"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []types.Mention
+	}{
+		{name: "start", text: "@alice hi", want: []types.Mention{{Handle: "alice", Start: 0, End: 6}}},
+		{name: "several", text: "hi @alice and @bob_2", want: []types.Mention{{Handle: "alice", Start: 3, End: 9}, {Handle: "bob_2", Start: 14, End: 20}}},
+		{name: "trailing dots", text: "thanks @alice.", want: []types.Mention{{Handle: "alice", Start: 7, End: 13}}},
+		{name: "inner dots", text: "@a.b.c, ok", want: []types.Mention{{Handle: "a.b.c", Start: 0, End: 6}}},
+		{name: "multibyte text before", text: "café ☕ @zoë", want: []types.Mention{{Handle: "zoë", Start: 7, End: 11}}},
+		{name: "emoji before", text: "🎉🎉 @bob", want: []types.Mention{{Handle: "bob", Start: 3, End: 7}}},
+		{name: "email", text: "mail alice@example.com", want: nil},
+		{name: "email with dotted user", text: "a.b@example.com", want: nil},
+		{name: "after punctuation", text: "(@alice)", want: []types.Mention{{Handle: "alice", Start: 1, End: 7}}},
+		{name: "double at", text: "@@alice", want: nil},
+		{name: "lone at", text: "@ alice", want: nil},
+		{name: "only dots", text: "@...", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package migrations applies versioned schema changes to the database. Applied
+// versions are recorded in the migrations collection so every migration runs once.
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, database *mongo.Database) error
+}
+
+type Record struct {
+	Version     int       `json:"version" bson:"_id"`
+	Description string    `json:"description" bson:"description"`
+	AppliedAt   time.Time `json:"appliedAt" bson:"appliedAt"`
+}
+
+type Status struct {
+	Migration Migration
+	Applied   *Record
+}
+
+const (
+	collectionName = "migrations"
+	lockCollection = "migrations_lock"
+	lockTTL        = 10 * time.Minute
+)
+
+var ErrLocked = errors.New("migrations are already running")
+
+// All returns every known migration ordered by version.
+func All() []Migration {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
+
+// Up applies the pending migrations in order and returns the ones it applied.
+// With dryRun set it only returns what would be applied.
+func Up(ctx context.Context, database *mongo.Database, dryRun bool) ([]Migration, error) {
+	pending, err := Pending(ctx, database)
+	if err != nil || dryRun || len(pending) == 0 {
+		return pending, err
+	}
+
+	release, err := lock(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	// Another instance may have finished while we were waiting for the lock.
+	pending, err = Pending(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	records := database.Collection(collectionName)
+	var applied []Migration
+	for _, migration := range pending {
+		log.Printf("Applying migration %d: %s", migration.Version, migration.Description)
+
+		if err := migration.Up(ctx, database); err != nil {
+			return applied, fmt.Errorf("migration %d failed: %w", migration.Version, err)
+		}
+
+		record := Record{Version: migration.Version, Description: migration.Description, AppliedAt: time.Now()}
+		if _, err := records.InsertOne(ctx, record); err != nil {
+			return applied, fmt.Errorf("recording migration %d: %w", migration.Version, err)
+		}
+
+		applied = append(applied, migration)
+	}
+
+	return applied, nil
+}
+
+func Pending(ctx context.Context, database *mongo.Database) ([]Migration, error) {
+	statuses, err := List(ctx, database)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, status := range statuses {
+		if status.Applied == nil {
+			pending = append(pending, status.Migration)
+		}
+	}
+
+	return pending, nil
+}
+
+// List returns every migration together with its record if it was applied.
+func List(ctx context.Context, database *mongo.Database) ([]Status, error) {
+	cursor, err := database.Collection(collectionName).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]Record, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+
+	var statuses []Status
+	for _, migration := range All() {
+		status := Status{Migration: migration}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = &record
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// lock makes sure only one process migrates at a time. The lock document expires
+// through a TTL index so a crashed process can't hold it forever.
+func lock(ctx context.Context, database *mongo.Database) (func(), error) {
+	locks := database.Collection(lockCollection)
+	host, _ := os.Hostname()
+
+	_, err := locks.InsertOne(ctx, bson.M{
+		"_id":       "lock",
+		"owner":     fmt.Sprintf("%s:%d", host, os.Getpid()),
+		"expiresAt": time.Now().Add(lockTTL),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// The TTL monitor only runs once a minute, so clear expired locks ourselves.
+		res, err := locks.DeleteOne(ctx, bson.M{"_id": "lock", "expiresAt": bson.M{"$lt": time.Now()}})
+		if err != nil || res.DeletedCount == 0 {
+			return nil, ErrLocked
+		}
+		return lock(ctx, database)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if _, err := locks.DeleteOne(context.Background(), bson.M{"_id": "lock"}); err != nil {
+			log.Printf("Failed to release migrations lock: %v", err)
+		}
+	}, nil
+}
+
+// createIndexes creates the indexes on collection. Creating an index that already
+// exists with the same options is a no-op, so migrations stay rerunnable.
+func createIndexes(ctx context.Context, database *mongo.Database, collection string, indexes ...mongo.IndexModel) error {
+	_, err := database.Collection(collection).Indexes().CreateMany(ctx, indexes)
+	if err != nil {
+		return fmt.Errorf("creating indexes on %s: %w", collection, err)
+	}
+	return nil
+}
+
+func index(keys bson.D, opts ...*options.IndexOptions) mongo.IndexModel {
+	model := mongo.IndexModel{Keys: keys}
+	if len(opts) > 0 {
+		model.Options = opts[0]
+	}
+	return model
+}
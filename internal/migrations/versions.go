@@ -0,0 +1,515 @@
+package migrations
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"slices"
+	"time"
+	"unicode/utf8"
+)
+
+// migrations must only ever be appended to; never change a released one.
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "rename the capitalised Handle field to handle",
+		Up:          renameHandle,
+	},
+	{
+		Version:     2,
+		Description: "unique email and handle on users",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "users",
+				index(bson.D{{"email", 1}}, options.Index().SetName("email_unique").SetUnique(true)),
+				index(bson.D{{"handle", 1}}, options.Index().
+					SetName("handle_unique").
+					SetUnique(true).
+					SetPartialFilterExpression(bson.M{"handle": bson.M{"$type": "string"}})),
+			)
+		},
+	},
+	{
+		Version:     3,
+		Description: "post, like and comment indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			err := createIndexes(ctx, database, "posts",
+				index(bson.D{{"createdAt", -1}, {"_id", -1}}, options.Index().SetName("feed")),
+				index(bson.D{{"userID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("user_posts")),
+				index(bson.D{{"caption", "text"}, {"tags", "text"}}, options.Index().
+					SetName("posts_text").
+					SetWeights(bson.D{{"tags", 5}, {"caption", 1}})),
+			)
+			if err != nil {
+				return err
+			}
+
+			err = createIndexes(ctx, database, "likes",
+				index(bson.D{{"postID", 1}, {"userID", 1}}, options.Index().SetName("like_unique").SetUnique(true)),
+				index(bson.D{{"userID", 1}, {"createdAt", -1}}, options.Index().SetName("user_likes")),
+			)
+			if err != nil {
+				return err
+			}
+
+			return createIndexes(ctx, database, "comments",
+				index(bson.D{{"postID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("post_comments")),
+				index(bson.D{{"userID", 1}}, options.Index().SetName("user_comments")),
+			)
+		},
+	},
+	{
+		Version:     4,
+		Description: "user text index",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "users",
+				index(bson.D{{"firstName", "text"}, {"lastName", "text"}, {"handle", "text"}}, options.Index().
+					SetName("users_text").
+					SetWeights(bson.D{{"handle", 3}, {"firstName", 2}, {"lastName", 2}})),
+			)
+		},
+	},
+	{
+		Version:     5,
+		Description: "repost, block, messaging, settings and portfolio indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			indexes := map[string][]mongo.IndexModel{
+				"reposts": {
+					index(bson.D{{"postID", 1}}, options.Index().SetName("post_reposts")),
+					index(bson.D{{"repostedBy", 1}, {"createdAt", -1}}, options.Index().SetName("user_reposts")),
+				},
+				"blocked_users": {
+					index(bson.D{{"userID", 1}, {"blockedID", 1}}, options.Index().SetName("block_unique").SetUnique(true)),
+					index(bson.D{{"blockedID", 1}}, options.Index().SetName("blocked_by")),
+				},
+				"conversations": {
+					index(bson.D{{"participantsIds", 1}}, options.Index().SetName("participants")),
+				},
+				"messages": {
+					index(bson.D{{"conversationID", 1}, {"createdAt", 1}}, options.Index().SetName("conversation_messages")),
+				},
+				"settings": {
+					index(bson.D{{"userID", 1}}, options.Index().SetName("settings_user_unique").SetUnique(true)),
+				},
+				"portfolios": {
+					index(bson.D{{"userID", 1}}, options.Index().SetName("portfolio_user_unique").SetUnique(true)),
+				},
+			}
+
+			for _, collection := range []string{"reposts", "blocked_users", "conversations", "messages", "settings", "portfolios"} {
+				if err := createIndexes(ctx, database, collection, indexes[collection]...); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	},
+	{
+		Version:     6,
+		Description: "expire stale migration locks",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, lockCollection,
+				index(bson.D{{"expiresAt", 1}}, options.Index().SetName("lock_ttl").SetExpireAfterSeconds(0)),
+			)
+		},
+	},
+	{
+		Version:     7,
+		Description: "move follows from the followedUsers arrays to the follows collection",
+		Up:          moveFollows,
+	},
+	{
+		Version:     8,
+		Description: "follow request indexes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "follow_requests",
+				index(bson.D{{"requesterID", 1}, {"targetID", 1}}, options.Index().SetName("follow_request_unique").SetUnique(true)),
+				index(bson.D{{"targetID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("incoming_requests")),
+				index(bson.D{{"requesterID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("outgoing_requests")),
+			)
+		},
+	},
+	{
+		Version:     9,
+		Description: "mute indexes, expiring mutes",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "mutes",
+				index(bson.D{{"userID", 1}, {"mutedID", 1}}, options.Index().SetName("mute_unique").SetUnique(true)),
+				index(bson.D{{"mutedID", 1}}, options.Index().SetName("muted_by")),
+				index(bson.D{{"expiresAt", 1}}, options.Index().SetName("mute_ttl").SetExpireAfterSeconds(0)),
+			)
+		},
+	},
+	{
+		Version:     10,
+		Description: "dismissed suggestion indexes, recent posts by tag",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			err := createIndexes(ctx, database, "dismissed_suggestions",
+				index(bson.D{{"userID", 1}, {"dismissedID", 1}}, options.Index().SetName("dismissal_unique").SetUnique(true)),
+				index(bson.D{{"dismissedID", 1}}, options.Index().SetName("dismissed_by")),
+			)
+			if err != nil {
+				return err
+			}
+
+			return createIndexes(ctx, database, "posts",
+				index(bson.D{{"tags", 1}, {"createdAt", -1}}, options.Index().SetName("tags_recent")),
+			)
+		},
+	},
+	{
+		Version:     11,
+		Description: "precomputed timelines, dropped after 30 days",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "timelines",
+				index(bson.D{{"ownerID", 1}, {"postID", 1}, {"repostID", 1}}, options.Index().SetName("timeline_unique").SetUnique(true)),
+				index(bson.D{{"ownerID", 1}, {"createdAt", -1}}, options.Index().SetName("timeline")),
+				index(bson.D{{"createdAt", 1}}, options.Index().SetName("timeline_ttl").SetExpireAfterSeconds(30*24*60*60)),
+			)
+		},
+	},
+	{
+		Version:     12,
+		Description: "one repost per user and post, reposts by post",
+		Up:          uniqueReposts,
+	},
+	{
+		Version:     13,
+		Description: "quotes by post",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "posts",
+				index(bson.D{{"quotedPostID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().
+					SetName("post_quotes").
+					SetPartialFilterExpression(bson.M{"quotedPostID": bson.M{"$exists": true}})),
+			)
+		},
+	},
+	{
+		Version:     14,
+		Description: "drafts by user, scheduled drafts",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "drafts",
+				index(bson.D{{"userID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("user_drafts_recent")),
+				index(bson.D{{"publishAt", 1}}, options.Index().
+					SetName("drafts_due").
+					SetPartialFilterExpression(bson.M{"publishAt": bson.M{"$type": "date"}})),
+			)
+		},
+	},
+	{
+		Version:     15,
+		Description: "post revisions by post",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "post_revisions",
+				index(bson.D{{"postID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("post_revisions_recent")),
+			)
+		},
+	},
+	{
+		Version:     16,
+		Description: "normalized tags, tag counts and posts by tag",
+		Up:          normalizeTags,
+	},
+	{
+		Version:     17,
+		Description: "mentions by user",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			for _, name := range []string{"posts", "comments", "messages"} {
+				err := createIndexes(ctx, database, name,
+					index(bson.D{{"mentions.userID", 1}}, options.Index().SetName(name+"_mentions")),
+				)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// The posts text index of version 18 duplicated the one of version 3,
+		// which search uses. Version 18 is kept so databases that applied it
+		// and new ones agree on the numbering.
+		Version:     18,
+		Description: "post text index (superseded by version 3, does nothing)",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return nil
+		},
+	},
+	{
+		Version:     19,
+		Description: "saves and collections",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			err := createIndexes(ctx, database, "saves",
+				index(bson.D{{"postID", 1}, {"userID", 1}}, options.Index().SetName("save_unique").SetUnique(true)),
+				index(bson.D{{"userID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("user_saves_recent")),
+			)
+			if err != nil {
+				return err
+			}
+
+			err = createIndexes(ctx, database, "collections",
+				index(bson.D{{"userID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("user_collections_recent")),
+			)
+			if err != nil {
+				return err
+			}
+
+			return createIndexes(ctx, database, "collection_items",
+				index(bson.D{{"collectionID", 1}, {"postID", 1}}, options.Index().SetName("collection_item_unique").SetUnique(true)),
+				index(bson.D{{"collectionID", 1}, {"position", 1}, {"_id", -1}}, options.Index().SetName("collection_items_order")),
+				index(bson.D{{"userID", 1}, {"postID", 1}}, options.Index().SetName("user_collection_items")),
+				index(bson.D{{"postID", 1}}, options.Index().SetName("collection_items_by_post")),
+			)
+		},
+	},
+	{
+		Version:     20,
+		Description: "close friends",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "close_friends",
+				index(bson.D{{"userID", 1}, {"friendID", 1}}, options.Index().SetName("close_friend_unique").SetUnique(true)),
+				index(bson.D{{"friendID", 1}}, options.Index().SetName("close_friends_by_friend")),
+			)
+		},
+	},
+	{
+		Version:     21,
+		Description: "private profiles",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			return createIndexes(ctx, database, "settings",
+				index(bson.D{{"profileVisibility", 1}, {"userID", 1}}, options.Index().SetName("settings_by_visibility")),
+			)
+		},
+	},
+	{
+		Version:     22,
+		Description: "notifications mute scope for mutes of every scope",
+		Up: func(ctx context.Context, database *mongo.Database) error {
+			// Mutes of every scope used to stop notifications as well.
+			_, err := database.Collection("mutes").UpdateMany(ctx,
+				bson.M{"scopes": bson.M{"$all": bson.A{"posts", "reposts", "comments", "messages"}}},
+				bson.M{"$addToSet": bson.M{"scopes": "notifications"}},
+			)
+			return err
+		},
+	},
+}
+
+// renameHandle moves handles written under "Handle" to "handle". Users that have
+// both keep the lowercase one.
+func renameHandle(ctx context.Context, database *mongo.Database) error {
+	users := database.Collection("users")
+
+	_, err := users.UpdateMany(ctx,
+		bson.M{"Handle": bson.M{"$exists": true}, "handle": bson.M{"$exists": false}},
+		bson.M{"$rename": bson.M{"Handle": "handle"}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = users.UpdateMany(ctx,
+		bson.M{"Handle": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"Handle": ""}},
+	)
+	return err
+}
+
+// moveFollows turns every hex ID in a user's followedUsers into a follow. Upserting
+// the follows lets it resume after a failure; IDs that don't parse are dropped.
+// followedBy was never written to and goes away too.
+func moveFollows(ctx context.Context, database *mongo.Database) error {
+	err := createIndexes(ctx, database, "follows",
+		index(bson.D{{"followerID", 1}, {"followedID", 1}}, options.Index().SetName("follow_unique").SetUnique(true)),
+		index(bson.D{{"followedID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("followers")),
+		index(bson.D{{"followerID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("following")),
+	)
+	if err != nil {
+		return err
+	}
+
+	users := database.Collection("users")
+	follows := database.Collection("follows")
+
+	cursor, err := users.Find(ctx,
+		bson.M{"followedUsers.0": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"followedUsers": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	now := time.Now()
+	for cursor.Next(ctx) {
+		var user struct {
+			ID            primitive.ObjectID `bson:"_id"`
+			FollowedUsers []string           `bson:"followedUsers"`
+		}
+		if err := cursor.Decode(&user); err != nil {
+			return err
+		}
+
+		var writes []mongo.WriteModel
+		for _, hexID := range user.FollowedUsers {
+			followedID, err := primitive.ObjectIDFromHex(hexID)
+			if err != nil || followedID == user.ID {
+				continue
+			}
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"followerID": user.ID, "followedID": followedID}).
+				SetUpdate(bson.M{"$setOnInsert": bson.M{"createdAt": now}}).
+				SetUpsert(true))
+		}
+		if len(writes) == 0 {
+			continue
+		}
+
+		if _, err := follows.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	_, err = users.UpdateMany(ctx,
+		bson.M{"$or": bson.A{
+			bson.M{"followedUsers": bson.M{"$exists": true}},
+			bson.M{"followedBy": bson.M{"$exists": true}},
+		}},
+		bson.M{"$unset": bson.M{"followedUsers": "", "followedBy": ""}},
+	)
+	return err
+}
+
+// uniqueReposts keeps the first repost of each user and post before making them
+// unique. The reconciliation job fixes the repost counters afterwards.
+func uniqueReposts(ctx context.Context, database *mongo.Database) error {
+	reposts := database.Collection("reposts")
+
+	cursor, err := reposts.Aggregate(ctx, mongo.Pipeline{
+		{{"$sort", bson.D{{"createdAt", 1}, {"_id", 1}}}},
+		{{"$group", bson.D{
+			{"_id", bson.D{{"repostedBy", "$repostedBy"}, {"postID", "$postID"}}},
+			{"ids", bson.D{{"$push", "$_id"}}},
+		}}},
+		{{"$match", bson.D{{"ids.1", bson.D{{"$exists", true}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var duplicates struct {
+			IDs []primitive.ObjectID `bson:"ids"`
+		}
+		if err := cursor.Decode(&duplicates); err != nil {
+			return err
+		}
+
+		if _, err := reposts.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": duplicates.IDs[1:]}}); err != nil {
+			return err
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, database, "reposts",
+		index(bson.D{{"repostedBy", 1}, {"postID", 1}}, options.Index().SetName("repost_unique").SetUnique(true)),
+		index(bson.D{{"postID", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("post_reposts_recent")),
+	)
+}
+
+// normalizeTags normalizes the tags of existing posts and adds the hashtags of
+// their captions, then counts how many posts use each tag. Posts over the tag
+// limits keep all their tags; only tags that are too long are dropped.
+func normalizeTags(ctx context.Context, database *mongo.Database) error {
+	posts := database.Collection("posts")
+
+	cursor, err := posts.Find(ctx,
+		bson.M{"$or": bson.A{
+			bson.M{"tags.0": bson.M{"$exists": true}},
+			bson.M{"caption": bson.M{"$regex": "#"}},
+		}},
+		options.Find().SetProjection(bson.M{"tags": 1, "caption": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	var writes []mongo.WriteModel
+	flush := func() error {
+		if len(writes) == 0 {
+			return nil
+		}
+		_, err := posts.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+		writes = writes[:0]
+		return err
+	}
+
+	for cursor.Next(ctx) {
+		var post struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			Tags    []string           `bson:"tags"`
+			Caption string             `bson:"caption"`
+		}
+		if err := cursor.Decode(&post); err != nil {
+			return err
+		}
+
+		var tags []string
+		for _, tag := range append(post.Tags, hashtag.Extract(post.Caption)...) {
+			name := hashtag.Normalize(tag)
+			if name != "" && utf8.RuneCountInString(name) <= hashtag.MaxLength && !slices.Contains(tags, name) {
+				tags = append(tags, name)
+			}
+		}
+		if slices.Equal(tags, post.Tags) {
+			continue
+		}
+
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": post.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"tags": tags}}))
+		if len(writes) == 500 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	counts, err := posts.Aggregate(ctx, mongo.Pipeline{
+		{{"$match", bson.D{{"tags.0", bson.D{{"$exists", true}}}}}},
+		{{"$unwind", "$tags"}},
+		{{"$group", bson.D{
+			{"_id", "$tags"},
+			{"postsCount", bson.D{{"$sum", 1}}},
+			{"lastUsedAt", bson.D{{"$max", "$createdAt"}}},
+		}}},
+		{{"$merge", bson.D{{"into", "tags"}, {"whenMatched", "replace"}, {"whenNotMatched", "insert"}}}},
+	})
+	if err != nil {
+		return err
+	}
+	if err := counts.Close(ctx); err != nil {
+		return err
+	}
+
+	return createIndexes(ctx, database, "posts",
+		index(bson.D{{"tags", 1}, {"createdAt", -1}, {"_id", -1}}, options.Index().SetName("tag_posts_recent")),
+	)
+}
@@ -0,0 +1,220 @@
+// Package publish creates posts: right away for new posts, and from drafts when
+// their author publishes them or their scheduled time comes.
+package publish
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/internal/hashtag"
+	"github.com/edisss1/fiabesco-backend/internal/mention"
+	"github.com/edisss1/fiabesco-backend/internal/quote"
+	"github.com/edisss1/fiabesco-backend/internal/timeline"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"log"
+	"time"
+)
+
+// dueBatch is how many due drafts Due publishes per query.
+const dueBatch = 100
+
+// ErrQuotedNotFound means the post being published quotes a post that is gone
+// or that its author can't see.
+var ErrQuotedNotFound = errors.New("quoted post not found")
+
+// Post normalizes the tags of post, resolves its mentions and creates it,
+// counts it as a quote of the post it quotes and adds it to the timelines. It
+// returns hashtag.ErrInvalid when its tags break the limits.
+func Post(ctx context.Context, repos *repository.Repositories, post *types.Post) error {
+	tags, err := hashtag.ForPost(post.Tags, post.Caption)
+	if err != nil {
+		return err
+	}
+	post.Tags = tags
+
+	if err := checkQuoted(ctx, repos, post); err != nil {
+		return err
+	}
+
+	if post.Mentions, err = mention.Resolve(ctx, repos, post.UserID, post.Caption); err != nil {
+		return err
+	}
+
+	err = repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		return create(ctx, repos, post)
+	})
+	if err != nil {
+		return err
+	}
+
+	published(ctx, repos, *post)
+	return nil
+}
+
+// Draft publishes draft as a new post and deletes it. Its tags were normalized
+// when it was saved, its mentions are resolved now. It returns repository.ErrNotFound when the draft was
+// published or deleted meanwhile.
+func Draft(ctx context.Context, repos *repository.Repositories, draft types.Draft) (types.Post, error) {
+	now := time.Now()
+	post := types.Post{
+		UserID:       draft.UserID,
+		Caption:      draft.Caption,
+		Images:       draft.Images,
+		Files:        draft.Files,
+		Tags:         draft.Tags,
+		QuotedPostID: draft.QuotedPostID,
+		Audience:     draft.Audience,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+
+	if post.Audience == "" {
+		post.Audience = types.AudiencePublic
+	}
+
+	if err := checkQuoted(ctx, repos, &post); err != nil {
+		return post, err
+	}
+
+	mentions, err := mention.Resolve(ctx, repos, post.UserID, post.Caption)
+	if err != nil {
+		return post, err
+	}
+	post.Mentions = mentions
+
+	// Deleting the draft first makes sure only one request or scheduler
+	// publishes it.
+	err = repos.Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := repos.Drafts.Delete(ctx, draft.ID); err != nil {
+			return err
+		}
+		repository.OnRollback(ctx, func(ctx context.Context) error {
+			return repos.Drafts.Create(ctx, &draft)
+		})
+
+		return create(ctx, repos, &post)
+	})
+	if err != nil {
+		return post, err
+	}
+
+	published(ctx, repos, post)
+	return post, nil
+}
+
+// Due publishes the drafts whose time has come and returns how many it
+// published. Drafts that can't be published are unscheduled and stay drafts,
+// with the reason recorded on them, so one failing draft doesn't hold up the
+// ones after it.
+func Due(ctx context.Context, repos *repository.Repositories, now time.Time) (int, error) {
+	count := 0
+	for {
+		drafts, err := repos.Drafts.ListDue(ctx, now, dueBatch)
+		if err != nil || len(drafts) == 0 {
+			return count, err
+		}
+
+		for _, draft := range drafts {
+			_, err := Draft(ctx, repos, draft)
+			switch {
+			case err == nil:
+				count++
+			case errors.Is(err, repository.ErrNotFound):
+				// Someone else published or deleted it.
+			default:
+				log.Printf("Unscheduling draft %s, publishing it failed: %v", draft.ID.Hex(), err)
+				if err := unschedule(ctx, repos, draft, err); err != nil {
+					return count, err
+				}
+			}
+		}
+
+		if len(drafts) < dueBatch {
+			return count, nil
+		}
+	}
+}
+
+// unschedule takes draft off the schedule after publishing it failed with
+// cause and records why for its author.
+func unschedule(ctx context.Context, repos *repository.Repositories, draft types.Draft, cause error) error {
+	reason := "Publishing failed, schedule the draft again to retry"
+	if errors.Is(cause, ErrQuotedNotFound) {
+		reason = "The quoted post is gone or hidden"
+	}
+
+	err := repos.Drafts.Update(ctx, draft.ID, bson.M{"publishAt": nil, "timezone": "", "publishError": reason})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// Schedule publishes the due drafts every interval until ctx is cancelled.
+func Schedule(ctx context.Context, repos *repository.Repositories, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := Due(ctx, repos, now)
+			if err != nil {
+				log.Printf("Publishing scheduled drafts failed: %v", err)
+			}
+			if count > 0 {
+				log.Printf("Published %d scheduled drafts", count)
+			}
+		}
+	}
+}
+
+// checkQuoted returns ErrQuotedNotFound when post quotes a post its author
+// can't see.
+func checkQuoted(ctx context.Context, repos *repository.Repositories, post *types.Post) error {
+	if post.QuotedPostID == nil {
+		return nil
+	}
+
+	visible, err := quote.Visible(ctx, repos, post.UserID, *post.QuotedPostID)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return ErrQuotedNotFound
+	}
+
+	return nil
+}
+
+// create inserts post and counts it on the post it quotes inside a
+// transaction.
+func create(ctx context.Context, repos *repository.Repositories, post *types.Post) error {
+	if err := repos.Posts.Create(ctx, post); err != nil || post.QuotedPostID == nil {
+		return err
+	}
+	repository.OnRollback(ctx, func(ctx context.Context) error {
+		return repos.Posts.Delete(ctx, post.ID)
+	})
+
+	err := repos.Posts.IncrementCounter(ctx, *post.QuotedPostID, repository.QuotesCount, 1)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrQuotedNotFound
+	}
+	return err
+}
+
+// published adds post to the timelines, counts its tags and notifies the users
+// it mentions. Failing to do so doesn't undo it.
+func published(ctx context.Context, repos *repository.Repositories, post types.Post) {
+	if err := timeline.Publish(ctx, repos, timeline.PostEntry(post)); err != nil {
+		log.Println("Error publishing post to timelines: ", err)
+	}
+	if err := hashtag.Count(ctx, repos, nil, post.Tags); err != nil {
+		log.Println("Error counting post tags: ", err)
+	}
+	mention.NotifyPost(ctx, repos, post, nil)
+}
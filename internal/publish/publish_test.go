@@ -0,0 +1,168 @@
+package publish_test
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/internal/publish"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestDraft(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.New()
+	author := createUser(t, repos, "author")
+	quoted := createPost(t, repos, author)
+
+	draft := createDraft(t, repos, types.Draft{UserID: author, Caption: "quoting #Go", Tags: []string{"go"}, QuotedPostID: &quoted.ID})
+
+	post, err := publish.Draft(ctx, repos, draft)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Caption != draft.Caption || post.Audience != types.AudiencePublic {
+		t.Errorf("got post %q for %s, want %q for %s", post.Caption, post.Audience, draft.Caption, types.AudiencePublic)
+	}
+	if _, err := repos.Drafts.FindByID(ctx, draft.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("draft still found, error %v", err)
+	}
+
+	quoted, err = repos.Posts.FindByID(ctx, quoted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quoted.QuotesCount != 1 {
+		t.Errorf("quotes count = %d, want 1", quoted.QuotesCount)
+	}
+
+	if _, err := publish.Draft(ctx, repos, draft); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("publishing twice got error %v, want %v", err, repository.ErrNotFound)
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		publishAt *time.Time
+		// quoteGone quotes a post that is deleted before the drafts are due.
+		quoteGone        bool
+		wantPublished    bool
+		wantScheduled    bool
+		wantPublishError bool
+	}{
+		{name: "due", publishAt: &past, wantPublished: true},
+		{name: "not due yet", publishAt: &future, wantScheduled: true},
+		{name: "not scheduled", publishAt: nil},
+		{name: "quoted post gone", publishAt: &past, quoteGone: true, wantPublishError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			author := createUser(t, repos, "author")
+
+			draft := types.Draft{UserID: author, Caption: tt.name, PublishAt: tt.publishAt}
+			if tt.quoteGone {
+				quoted := createPost(t, repos, author)
+				draft.QuotedPostID = &quoted.ID
+				if err := repos.Posts.Delete(ctx, quoted.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+			draft = createDraft(t, repos, draft)
+
+			count, err := publish.Due(ctx, repos, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if published := count == 1; published != tt.wantPublished {
+				t.Errorf("published %d drafts, want published %v", count, tt.wantPublished)
+			}
+
+			got, err := repos.Drafts.FindByID(ctx, draft.ID)
+			if tt.wantPublished {
+				if !errors.Is(err, repository.ErrNotFound) {
+					t.Errorf("published draft still found, error %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if scheduled := got.PublishAt != nil; scheduled != tt.wantScheduled {
+				t.Errorf("scheduled = %v, want %v", scheduled, tt.wantScheduled)
+			}
+			if hasError := got.PublishError != ""; hasError != tt.wantPublishError {
+				t.Errorf("publishError = %q, want set %v", got.PublishError, tt.wantPublishError)
+			}
+		})
+	}
+}
+
+func TestDueContinuesAfterFailure(t *testing.T) {
+	ctx := context.Background()
+	repos := memory.New()
+	author := createUser(t, repos, "author")
+	quoted := createPost(t, repos, author)
+
+	first, second := time.Now().Add(-2*time.Minute), time.Now().Add(-time.Minute)
+	failing := createDraft(t, repos, types.Draft{UserID: author, QuotedPostID: &quoted.ID, PublishAt: &first})
+	createDraft(t, repos, types.Draft{UserID: author, PublishAt: &second})
+	if err := repos.Posts.Delete(ctx, quoted.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := publish.Due(ctx, repos, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("published %d drafts, want 1", count)
+	}
+
+	draft, err := repos.Drafts.FindByID(ctx, failing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if draft.PublishAt != nil || draft.PublishError == "" {
+		t.Errorf("failing draft has publishAt %v and publishError %q, want it unscheduled with an error", draft.PublishAt, draft.PublishError)
+	}
+}
+
+func createUser(t *testing.T, repos *repository.Repositories, handle string) primitive.ObjectID {
+	t.Helper()
+
+	user := types.User{Email: handle + "@example.com", Handle: handle}
+	if err := repos.Users.Create(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user.ID
+}
+
+func createPost(t *testing.T, repos *repository.Repositories, userID primitive.ObjectID) types.Post {
+	t.Helper()
+
+	post := types.Post{UserID: userID, Caption: "quoted", Audience: types.AudiencePublic, CreatedAt: time.Now()}
+	if err := repos.Posts.Create(context.Background(), &post); err != nil {
+		t.Fatal(err)
+	}
+	return post
+}
+
+func createDraft(t *testing.T, repos *repository.Repositories, draft types.Draft) types.Draft {
+	t.Helper()
+
+	draft.CreatedAt, draft.UpdatedAt = time.Now(), time.Now()
+	if err := repos.Drafts.Create(context.Background(), &draft); err != nil {
+		t.Fatal(err)
+	}
+	return draft
+}
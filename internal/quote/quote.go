@@ -0,0 +1,89 @@
+// Package quote embeds quoted posts into the feed items of quote posts. A
+// quoted post only shows up for viewers who may see it: it is left out once
+// it's deleted, when its author and the viewer blocked each other, when its
+// author went private and the viewer doesn't follow them, or when its audience
+// leaves the viewer out.
+package quote
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Embed fills in the quoted post of the items that quote one as viewerID sees
+// it.
+func Embed(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, items []*types.FeedItem) error {
+	var ids []primitive.ObjectID
+	for _, item := range items {
+		if item.Post.QuotedPostID != nil {
+			ids = append(ids, *item.Post.QuotedPostID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	visible, err := Find(ctx, repos, viewerID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		if item.Post.QuotedPostID == nil {
+			continue
+		}
+
+		item.Quoted = &types.QuotedPost{PostID: *item.Post.QuotedPostID}
+		if quoted, ok := visible[*item.Post.QuotedPostID]; ok {
+			item.Quoted.Available = true
+			item.Quoted.Item = &quoted
+		}
+	}
+
+	return nil
+}
+
+// Visible reports whether userID may see postID and so quote it.
+func Visible(ctx context.Context, repos *repository.Repositories, userID, postID primitive.ObjectID) (bool, error) {
+	visible, err := Find(ctx, repos, userID, []primitive.ObjectID{postID})
+	if err != nil {
+		return false, err
+	}
+
+	_, ok := visible[postID]
+	return ok, nil
+}
+
+// Find returns the posts among ids viewerID may see by ID, like the ones
+// quote posts embed.
+func Find(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, ids []primitive.ObjectID) (map[primitive.ObjectID]types.FeedItem, error) {
+	query := repository.QuotedQuery{ViewerID: viewerID, IDs: ids, Following: []primitive.ObjectID{viewerID}}
+
+	if !viewerID.IsZero() {
+		reach, err := helpers.Reach(ctx, repos, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		query.Reach = reach
+		query.Following = append(query.Following, reach.Followed...)
+
+		if query.Exclude, err = helpers.BlockedIDs(ctx, repos, viewerID); err != nil {
+			return nil, err
+		}
+	}
+
+	items, err := repos.Posts.FindQuoted(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := make(map[primitive.ObjectID]types.FeedItem, len(items))
+	for _, item := range items {
+		visible[item.Post.ID] = item
+	}
+
+	return visible, nil
+}
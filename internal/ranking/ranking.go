@@ -0,0 +1,292 @@
+// Package ranking builds the For You feed. Recent posts are scored by how fast
+// they are picking up likes, comments and reposts, how well their tags and
+// authors match what the viewer liked before, and how fresh they are. The
+// ranked posts are then spread out so no author takes over the feed.
+package ranking
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sort"
+	"time"
+)
+
+// Weights scale the signals of the score.
+type Weights struct {
+	Engagement     float64
+	TagAffinity    float64
+	AuthorAffinity float64
+	Freshness      float64
+}
+
+type Options struct {
+	Weights Weights
+	// MaxConsecutive is how many posts of one author may follow each other.
+	// Zero turns the rule off.
+	MaxConsecutive int
+	// EngagementHalfLife is after how long a like, comment or repost counts
+	// half as much.
+	EngagementHalfLife time.Duration
+	// FreshnessHalfLife is after how long a post is half as fresh.
+	FreshnessHalfLife time.Duration
+	// Window is how old the candidate posts may be.
+	Window time.Duration
+	// Candidates is how many of the newest posts are ranked.
+	Candidates int64
+}
+
+var DefaultOptions = Options{
+	Weights: Weights{
+		Engagement:     1,
+		TagAffinity:    2,
+		AuthorAffinity: 1,
+		Freshness:      2,
+	},
+	MaxConsecutive:     2,
+	EngagementHalfLife: 6 * time.Hour,
+	FreshnessHalfLife:  24 * time.Hour,
+	Window:             7 * 24 * time.Hour,
+	Candidates:         500,
+}
+
+const (
+	// recentLikes is how many of the viewer's latest likes the affinities are
+	// computed from.
+	recentLikes = 200
+
+	repostWeight  = 2
+	commentWeight = 1.5
+	// followBonus is the author affinity of followed users on top of the one
+	// from likes.
+	followBonus = 1
+)
+
+var options = DefaultOptions
+
+// Configure replaces DefaultOptions. It must be called before the server
+// starts.
+func Configure(opts Options) {
+	options = opts
+}
+
+// Rank returns the ranked For You feed of viewerID, best first. Posts of the
+// viewer, of blocked users in either direction, of users whose posts they
+// muted, of private users they don't follow and out of their reach are left
+// out. explain adds a breakdown of each score.
+func Rank(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, explain bool) ([]types.RankedItem, error) {
+	reach, err := helpers.Reach(ctx, repos, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	following := reach.Followed
+
+	exclude, err := excluded(ctx, repos, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := repos.Posts.ListCandidates(ctx, repository.CandidateQuery{
+		ViewerID:  viewerID,
+		Since:     time.Now().Add(-options.Window),
+		Exclude:   exclude,
+		Following: following,
+		Reach:     reach,
+		Limit:     options.Candidates,
+	})
+	if err != nil || len(candidates) == 0 {
+		return []types.RankedItem{}, err
+	}
+
+	s, err := newScorer(ctx, repos, viewerID, following, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]types.RankedItem, 0, len(candidates))
+	for _, candidate := range candidates {
+		items = append(items, s.score(candidate))
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return items[i].Post.CreatedAt.After(items[j].Post.CreatedAt)
+	})
+	for i := range items {
+		items[i].Explanation.Rank = i + 1
+	}
+
+	items = diversify(items, options.MaxConsecutive)
+
+	if !explain {
+		for i := range items {
+			items[i].Explanation = nil
+		}
+	}
+
+	return items, nil
+}
+
+// excluded returns viewerID and the users whose posts they mustn't see.
+func excluded(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	exclude := []primitive.ObjectID{viewerID}
+
+	blocks, err := repos.Blocks.ListByUser(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		exclude = append(exclude, block.BlockedID)
+	}
+
+	blockers, err := repos.Blocks.BlockerIDs(ctx, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	exclude = append(exclude, blockers...)
+
+	muted, err := repos.Mutes.MutedIDs(ctx, viewerID, types.MuteScopePosts)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(exclude, muted...), nil
+}
+
+// scorer holds what the scores of the candidates are computed from.
+type scorer struct {
+	now       time.Time
+	likes     map[primitive.ObjectID]float64
+	reposts   map[primitive.ObjectID]float64
+	tags      map[string]int64
+	tagLikes  int64
+	authors   map[primitive.ObjectID]int64
+	following map[primitive.ObjectID]bool
+}
+
+func newScorer(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, following []primitive.ObjectID, candidates []types.FeedItem) (*scorer, error) {
+	s := &scorer{now: time.Now(), following: make(map[primitive.ObjectID]bool, len(following))}
+	for _, id := range following {
+		s.following[id] = true
+	}
+
+	postIDs := make([]primitive.ObjectID, 0, len(candidates))
+	for _, candidate := range candidates {
+		postIDs = append(postIDs, candidate.Post.ID)
+	}
+
+	var err error
+	if s.likes, err = repos.Likes.DecayedCountByPosts(ctx, postIDs, options.EngagementHalfLife); err != nil {
+		return nil, err
+	}
+	if s.reposts, err = repos.Reposts.DecayedCountByPosts(ctx, postIDs, options.EngagementHalfLife); err != nil {
+		return nil, err
+	}
+	if s.tags, err = repos.Likes.LikedTags(ctx, viewerID, recentLikes); err != nil {
+		return nil, err
+	}
+	if s.authors, err = repos.Likes.LikedAuthors(ctx, viewerID, recentLikes); err != nil {
+		return nil, err
+	}
+
+	for _, count := range s.tags {
+		s.tagLikes += count
+	}
+
+	return s, nil
+}
+
+func (s *scorer) score(item types.FeedItem) types.RankedItem {
+	post := item.Post
+	age := s.now.Sub(post.CreatedAt)
+
+	// Comments only have a counter, so they decay with the age of the post.
+	engagement := s.likes[post.ID] + repostWeight*s.reposts[post.ID] +
+		commentWeight*float64(post.CommentsCount)*halve(age, options.EngagementHalfLife)
+
+	var tagAffinity float64
+	if s.tagLikes > 0 {
+		for _, tag := range post.Tags {
+			tagAffinity += float64(s.tags[tag])
+		}
+		tagAffinity /= float64(s.tagLikes)
+	}
+
+	authorAffinity := math.Log1p(float64(s.authors[post.UserID]))
+	if s.following[post.UserID] {
+		authorAffinity += followBonus
+	}
+
+	explanation := &types.ScoreExplanation{
+		Engagement:     signal(engagement, math.Log1p(engagement), options.Weights.Engagement),
+		TagAffinity:    signal(tagAffinity, tagAffinity, options.Weights.TagAffinity),
+		AuthorAffinity: signal(authorAffinity, authorAffinity, options.Weights.AuthorAffinity),
+		Freshness:      signal(age.Hours(), halve(age, options.FreshnessHalfLife), options.Weights.Freshness),
+	}
+
+	return types.RankedItem{
+		FeedItem: item,
+		Score: explanation.Engagement.Score + explanation.TagAffinity.Score +
+			explanation.AuthorAffinity.Score + explanation.Freshness.Score,
+		Explanation: explanation,
+	}
+}
+
+// signal weighs the scaled value of a signal.
+func signal(value, scaled, weight float64) types.Signal {
+	return types.Signal{Value: value, Weight: weight, Score: scaled * weight}
+}
+
+// halve returns how much is left of one after age with halfLife.
+func halve(age, halfLife time.Duration) float64 {
+	return math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// diversify moves posts down so no more than max posts of the same author
+// follow each other, keeping the order otherwise. When only one author is left
+// their posts stay together.
+func diversify(items []types.RankedItem, max int) []types.RankedItem {
+	if max <= 0 {
+		return items
+	}
+
+	result := make([]types.RankedItem, 0, len(items))
+	pending := items
+	for len(pending) > 0 {
+		next := 0
+		if run(result) >= max {
+			last := result[len(result)-1].Post.UserID
+			for i, item := range pending {
+				if item.Post.UserID != last {
+					next = i
+					break
+				}
+			}
+		}
+
+		result = append(result, pending[next])
+		pending = append(pending[:next:next], pending[next+1:]...)
+	}
+
+	return result
+}
+
+// run returns how many posts at the end of items are by the same author.
+func run(items []types.RankedItem) int {
+	if len(items) == 0 {
+		return 0
+	}
+
+	last := items[len(items)-1].Post.UserID
+	n := 0
+	for i := len(items) - 1; i >= 0 && items[i].Post.UserID == last; i-- {
+		n++
+	}
+
+	return n
+}
@@ -0,0 +1,64 @@
+package ranking
+
+import (
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+	"testing"
+)
+
+func TestDiversify(t *testing.T) {
+	tests := []struct {
+		name string
+		// authors has one letter per post, best first.
+		authors string
+		max     int
+		want    string
+	}{
+		{name: "empty", authors: "", max: 2, want: ""},
+		{name: "already diverse", authors: "abab", max: 1, want: "abab"},
+		{name: "run at the limit", authors: "aab", max: 2, want: "aab"},
+		{name: "run longer than max", authors: "aaaab", max: 2, want: "aabaa"},
+		{name: "two long runs", authors: "aaaabbbb", max: 2, want: "aabaabbb"},
+		{name: "several authors", authors: "aaaaabc", max: 2, want: "aabaaca"},
+		{name: "max one", authors: "aaabbc", max: 1, want: "ababac"},
+		{name: "single author", authors: "aaaaa", max: 2, want: "aaaaa"},
+		{name: "off", authors: "aaaab", max: 0, want: "aaaab"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := map[rune]primitive.ObjectID{}
+			var items []types.RankedItem
+			for i, author := range tt.authors {
+				if _, ok := users[author]; !ok {
+					users[author] = primitive.NewObjectID()
+				}
+				item := types.RankedItem{Score: float64(len(tt.authors) - i)}
+				item.Post.ID = primitive.NewObjectID()
+				item.Post.UserID = users[author]
+				items = append(items, item)
+			}
+
+			got := diversify(items, tt.max)
+
+			var b strings.Builder
+			scores := map[primitive.ObjectID]float64{}
+			for _, item := range got {
+				for author, id := range users {
+					if id == item.Post.UserID {
+						b.WriteRune(author)
+					}
+				}
+				// The posts of each author keep their order.
+				if score, ok := scores[item.Post.UserID]; ok && item.Score > score {
+					t.Errorf("posts of one author reordered: %v", got)
+				}
+				scores[item.Post.UserID] = item.Score
+			}
+			if b.String() != tt.want {
+				t.Errorf("diversify(%q, %d) = %q, want %q", tt.authors, tt.max, b.String(), tt.want)
+			}
+		})
+	}
+}
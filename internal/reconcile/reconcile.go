@@ -0,0 +1,205 @@
+// Package reconcile recomputes the denormalized counters on posts and users from
+// the collections they summarise and fixes the ones that drifted.
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"time"
+)
+
+const DefaultBatchSize = 500
+
+type Options struct {
+	// BatchSize is how many posts or users are checked per query.
+	BatchSize int64
+	// DryRun reports the discrepancies without fixing them.
+	DryRun bool
+}
+
+type Discrepancy struct {
+	Collection string             `json:"collection"`
+	ID         primitive.ObjectID `json:"id"`
+	Field      string             `json:"field"`
+	Stored     int64              `json:"stored"`
+	Actual     int64              `json:"actual"`
+	// Fixed is false in dry-run mode and when the counter changed while it was
+	// being checked. The next run picks those up again.
+	Fixed bool `json:"fixed"`
+}
+
+type Report struct {
+	PostsChecked  int           `json:"postsChecked"`
+	UsersChecked  int           `json:"usersChecked"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	StartedAt     time.Time     `json:"startedAt"`
+	FinishedAt    time.Time     `json:"finishedAt"`
+}
+
+// Fixed returns how many of the discrepancies were fixed.
+func (r Report) Fixed() int {
+	fixed := 0
+	for _, d := range r.Discrepancies {
+		if d.Fixed {
+			fixed++
+		}
+	}
+	return fixed
+}
+
+// Run checks every post and user counter.
+func Run(ctx context.Context, repos *repository.Repositories, opts Options) (Report, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	report := Report{StartedAt: time.Now(), Discrepancies: []Discrepancy{}}
+
+	err := reconcilePosts(ctx, repos, opts, &report)
+	if err == nil {
+		err = reconcileUsers(ctx, repos, opts, &report)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, err
+}
+
+// Schedule runs the job every interval until ctx is cancelled and logs what it
+// found.
+func Schedule(ctx context.Context, repos *repository.Repositories, interval time.Duration, opts Options) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := Run(ctx, repos, opts)
+			if err != nil {
+				log.Printf("Counter reconciliation failed: %v", err)
+				continue
+			}
+			log.Printf("Counter reconciliation checked %d posts and %d users, found %d discrepancies and fixed %d",
+				report.PostsChecked, report.UsersChecked, len(report.Discrepancies), report.Fixed())
+		}
+	}
+}
+
+func reconcilePosts(ctx context.Context, repos *repository.Repositories, opts Options, report *Report) error {
+	var after primitive.ObjectID
+	for {
+		batch, err := repos.Posts.ListCounters(ctx, after, opts.BatchSize)
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		ids := idsOf(batch)
+		likes, err := repos.Likes.CountByPosts(ctx, ids)
+		if err != nil {
+			return err
+		}
+		comments, err := repos.Comments.CountByPosts(ctx, ids)
+		if err != nil {
+			return err
+		}
+		reposts, err := repos.Reposts.CountByPosts(ctx, ids)
+		if err != nil {
+			return err
+		}
+		quotes, err := repos.Posts.CountQuotes(ctx, ids)
+		if err != nil {
+			return err
+		}
+		saves, err := repos.Saves.CountByPosts(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		actual := []counts{
+			{repository.LikesCount, likes},
+			{repository.CommentsCount, comments},
+			{repository.RepostCount, reposts},
+			{repository.QuotesCount, quotes},
+			{repository.SavesCount, saves},
+		}
+		if err := check(ctx, "posts", repos.Posts.SetCounter, batch, actual, opts, report); err != nil {
+			return err
+		}
+
+		report.PostsChecked += len(batch)
+		after = batch[len(batch)-1].ID
+	}
+}
+
+func reconcileUsers(ctx context.Context, repos *repository.Repositories, opts Options, report *Report) error {
+	var after primitive.ObjectID
+	for {
+		batch, err := repos.Users.ListCounters(ctx, after, opts.BatchSize)
+		if err != nil || len(batch) == 0 {
+			return err
+		}
+
+		followers, following, err := repos.Follows.Counts(ctx, idsOf(batch))
+		if err != nil {
+			return err
+		}
+
+		actual := []counts{
+			{repository.FollowersCount, followers},
+			{repository.FollowingCount, following},
+		}
+		if err := check(ctx, "users", repos.Users.SetCounter, batch, actual, opts, report); err != nil {
+			return err
+		}
+
+		report.UsersChecked += len(batch)
+		after = batch[len(batch)-1].ID
+	}
+}
+
+// counts holds the actual value of a counter field per document.
+type counts struct {
+	field  string
+	values map[primitive.ObjectID]int64
+}
+
+type setCounter func(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error
+
+// check compares the stored counters of batch with the actual ones and fixes
+// those that differ unless opts.DryRun is set.
+func check(ctx context.Context, collection string, set setCounter, batch []repository.Counters, actual []counts, opts Options, report *Report) error {
+	for _, counters := range batch {
+		for _, c := range actual {
+			field := c.field
+			stored, want := counters.Values[field], c.values[counters.ID]
+			if stored == want {
+				continue
+			}
+
+			d := Discrepancy{Collection: collection, ID: counters.ID, Field: field, Stored: stored, Actual: want}
+			if !opts.DryRun {
+				err := set(ctx, counters.ID, field, stored, want)
+				if err != nil && !errors.Is(err, repository.ErrNotFound) {
+					return err
+				}
+				d.Fixed = err == nil
+			}
+
+			report.Discrepancies = append(report.Discrepancies, d)
+		}
+	}
+
+	return nil
+}
+
+func idsOf(batch []repository.Counters) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(batch))
+	for _, counters := range batch {
+		ids = append(ids, counters.ID)
+	}
+	return ids
+}
@@ -2,118 +2,300 @@ package routes
 
 import (
 	"github.com/edisss1/fiabesco-backend/handlers/auth"
+	"github.com/edisss1/fiabesco-backend/handlers/collections"
 	"github.com/edisss1/fiabesco-backend/handlers/comments"
 	"github.com/edisss1/fiabesco-backend/handlers/mail"
 	"github.com/edisss1/fiabesco-backend/handlers/messages"
+	"github.com/edisss1/fiabesco-backend/handlers/metrics"
 	"github.com/edisss1/fiabesco-backend/handlers/portfolio"
 	"github.com/edisss1/fiabesco-backend/handlers/post"
 	"github.com/edisss1/fiabesco-backend/handlers/repost"
+	"github.com/edisss1/fiabesco-backend/handlers/search"
 	"github.com/edisss1/fiabesco-backend/handlers/settings"
 	"github.com/edisss1/fiabesco-backend/handlers/social"
 	"github.com/edisss1/fiabesco-backend/handlers/uploads"
 	"github.com/edisss1/fiabesco-backend/handlers/user"
+	"github.com/edisss1/fiabesco-backend/handlers/ws"
+	"github.com/edisss1/fiabesco-backend/internal/config"
 	"github.com/edisss1/fiabesco-backend/middleware"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
 )
 
-func Setup(app *fiber.App) {
-	authRoutes(app)
-	userRoutes(app)
-	postRoutes(app)
-	repostRoutes(app)
-	messageRoutes(app)
-	settingsRoutes(app)
-	portfolioRoutes(app)
-	servingRoutes(app)
-	emailRoutes(app)
+type handlers struct {
+	repos *repository.Repositories
+
+	auth        *auth.Handler
+	collections *collections.Handler
+	comments    *comments.Handler
+	messages    *messages.Handler
+	portfolio   *portfolio.Handler
+	post        *post.Handler
+	repost      *repost.Handler
+	search      *search.Handler
+	settings    *settings.Handler
+	social      *social.Handler
+	uploads     *uploads.Handler
+	user        *user.Handler
+	ws          *ws.Handler
+}
+
+func newHandlers(repos *repository.Repositories) *handlers {
+	return &handlers{
+		repos: repos,
+
+		auth:        auth.NewHandler(repos),
+		collections: collections.NewHandler(repos),
+		comments:    comments.NewHandler(repos),
+		messages:    messages.NewHandler(repos),
+		portfolio:   portfolio.NewHandler(repos),
+		post:        post.NewHandler(repos),
+		repost:      repost.NewHandler(repos),
+		search:      search.NewHandler(repos),
+		settings:    settings.NewHandler(repos),
+		social:      social.NewHandler(repos),
+		uploads:     uploads.NewHandler(repos),
+		user:        user.NewHandler(repos),
+		ws:          ws.NewHandler(repos),
+	}
+}
+
+// Setup mounts every API version. The unversioned routes are the v1 API kept for
+// older mobile builds and must be registered last: their deprecation middleware is
+// mounted on "/" and would otherwise run for the versioned routes as well.
+func Setup(app *fiber.App, repos *repository.Repositories) {
+	h := newHandlers(repos)
+
+	v1(app.Group("/v1", middleware.APIVersion(1)), h)
+	v2(app.Group("/v2", middleware.APIVersion(2)), h)
+
+	legacy := app.Group("/", middleware.Deprecated(config.GetLegacyDeprecation(), config.GetLegacySunset(), "/v1"))
+	v1(legacy, h)
+}
+
+func v1(router fiber.Router, h *handlers) {
+	authRoutes(router, h)
+	userRoutes(router, h)
+	followRequestRoutes(router, h)
+	muteRoutes(router, h)
+	closeFriendRoutes(router, h)
+	postRoutes(router, h)
+	repostRoutes(router, h)
+	draftRoutes(router, h)
+	collectionRoutes(router, h)
+	tagRoutes(router, h)
+	searchRoutes(router, h)
+	messageRoutes(router, h)
+	settingsRoutes(router, h)
+	portfolioRoutes(router, h)
+	servingRoutes(router, h)
+	emailRoutes(router)
+	metricsRoutes(router, h)
+	wsRoutes(router, h)
 }
 
-func authRoutes(app *fiber.App) {
-	app.Post("/auth/signup", auth.SignUp)
-	app.Post("/auth/login", auth.Login)
+// v2 registers the handlers that changed in v2 first so they take precedence, and
+// falls back to the v1 handlers for everything else.
+func v2(router fiber.Router, h *handlers) {
+	router.Post("/posts/:postID/like", middleware.RequireJWT, h.post.ToggleLike)
+
+	v1(router, h)
+}
+
+func authRoutes(router fiber.Router, h *handlers) {
+	router.Post("/auth/signup", h.auth.SignUp)
+	router.Post("/auth/login", h.auth.Login)
+}
+
+func userRoutes(router fiber.Router, h *handlers) {
+	users := router.Group("/users", middleware.RequireJWT)
+
+	users.Get("/me", h.user.GetUserData)
+	users.Get("/profile/:_id", h.user.GetProfileData)
+	users.Get("/suggestions", h.social.GetSuggestions)
+	users.Post("/suggestions/:userID/dismiss", h.social.DismissSuggestion)
+	users.Post("/:userID/block", h.social.BlockUser)
+	users.Delete("/:userID/unblock", h.social.UnblockUser)
+	users.Put("/:_id/bio", h.user.EditBio)
+	users.Get("/:_id/following", h.social.GetFollowing)
+	users.Get("/:_id/followers", h.social.GetFollowers)
+	users.Post("/:_id/follow", h.social.FollowUser)
+	users.Delete("/:_id/unfollow", h.social.UnfollowUser)
+	users.Get("/:userID/blocked", h.social.GetBlockedUsers)
+	users.Put("/:userID/pfp", h.user.ChangePFP)
+	users.Put("/:userID/banner", h.user.UploadBanner)
+
 }
 
-func userRoutes(app *fiber.App) {
-	users := app.Group("/users", middleware.RequireJWT)
+func followRequestRoutes(router fiber.Router, h *handlers) {
+	requests := router.Group("/follow-requests", middleware.RequireJWT)
 
-	users.Get("/me", user.GetUserData)
-	users.Get("/profile/:_id", user.GetProfileData)
-	users.Post("/:userID/block", social.BlockUser)
-	users.Delete("/:userID/unblock", social.UnblockUser)
-	users.Put("/:_id/bio", user.EditBio)
-	users.Get("/:_id/following", social.GetFollowing)
-	users.Post("/:_id/follow", social.FollowUser)
-	users.Get("/:userID/blocked", social.GetBlockedUsers)
-	users.Put("/:userID/pfp", user.ChangePFP)
-	users.Put("/:userID/banner", user.UploadBanner)
+	requests.Get("/incoming", h.social.GetIncomingFollowRequests)
+	requests.Get("/outgoing", h.social.GetOutgoingFollowRequests)
+	requests.Post("/:requestID/accept", h.social.AcceptFollowRequest)
+	requests.Post("/:requestID/decline", h.social.DeclineFollowRequest)
+	requests.Delete("/:requestID", h.social.CancelFollowRequest)
+}
 
+func muteRoutes(router fiber.Router, h *handlers) {
+	mutes := router.Group("/mutes", middleware.RequireJWT)
+
+	mutes.Post("/", h.social.MuteUser)
+	mutes.Get("/", h.social.GetMutedUsers)
+	mutes.Delete("/:userID", h.social.UnmuteUser)
 }
 
-func postRoutes(app *fiber.App) {
-	users := app.Group("/users", middleware.RequireJWT)
-	posts := app.Group("/posts", middleware.RequireJWT)
+func closeFriendRoutes(router fiber.Router, h *handlers) {
+	closeFriends := router.Group("/close-friends", middleware.RequireJWT)
 
-	users.Post("/:userID/posts", post.CreatePost)
-	users.Get("/:userID/post", post.GetPostsByUser)
-	users.Delete("/:_id/posts/:postID", post.DeletePost)
-	posts.Get("/feed", post.GetFeedPosts)
-	posts.Patch("/:_id/caption", post.UpdatePostCaption)
-	posts.Post("/like", post.LikePost)
-	posts.Get("/:postID", post.GetPost)
-	posts.Post("/:postID/comment", comments.CommentPost)
-	posts.Get("/:postID/comments", comments.GetComments)
-	posts.Patch("/:commentID/edit", comments.EditComment)
-	posts.Delete("/:commentID", comments.DeleteComment)
+	closeFriends.Get("/", h.social.GetCloseFriends)
+	closeFriends.Put("/:userID", h.social.AddCloseFriend)
+	closeFriends.Delete("/:userID", h.social.RemoveCloseFriend)
 }
 
-func repostRoutes(app *fiber.App) {
-	reposts := app.Group("/reposts", middleware.RequireJWT)
+func postRoutes(router fiber.Router, h *handlers) {
+	users := router.Group("/users", middleware.RequireJWT)
+	posts := router.Group("/posts", middleware.RequireJWT)
 
-	reposts.Post("/", repost.Repost)
+	users.Post("/:userID/posts", h.post.CreatePost)
+	users.Get("/:userID/post", h.post.GetPostsByUser)
+	users.Delete("/:_id/posts/:postID", h.post.DeletePost)
+	posts.Get("/feed", h.post.GetFeedPosts)
+	posts.Get("/timeline", h.post.GetTimeline)
+	posts.Get("/for-you", h.post.GetForYouFeed)
+	posts.Patch("/:_id/caption", h.post.UpdatePostCaption)
+	posts.Patch("/:postID", h.post.EditPost)
+	posts.Put("/:postID/audience", h.post.UpdatePostAudience)
+	posts.Post("/like", h.post.LikePost)
+	posts.Get("/:postID", h.post.GetPost)
+	posts.Post("/:postID/comment", h.comments.CommentPost)
+	posts.Get("/:postID/comments", h.comments.GetComments)
+	posts.Get("/:postID/reposts", h.repost.GetReposts)
+	posts.Get("/:postID/quotes", h.post.GetQuotes)
+	posts.Get("/:postID/revisions", h.post.GetPostRevisions)
+	posts.Post("/:postID/revisions/:revisionID/revert", h.post.RevertPost)
+	posts.Patch("/:commentID/edit", h.comments.EditComment)
+	posts.Delete("/:commentID", h.comments.DeleteComment)
 }
 
-func messageRoutes(app *fiber.App) {
-	conversations := app.Group("/conversations", middleware.RequireJWT)
-	message := app.Group("/messages", middleware.RequireJWT)
+func repostRoutes(router fiber.Router, h *handlers) {
+	reposts := router.Group("/reposts", middleware.RequireJWT)
 
-	conversations.Post("/start", messages.StartConversation)
-	conversations.Post("/:conversationID/messages/:senderID", messages.SendMessage)
-	conversations.Delete("/:conversationID", messages.DeleteConversation)
-	conversations.Get("/conversation/:conversationID", messages.GetConversation)
-	conversations.Get("/all", messages.GetConversations)
-	message.Patch("/:_id", messages.EditMessage)
-	message.Delete("/delete", messages.DeleteMessage)
-	message.Post("/reply/:conversationID", messages.SendReply)
+	reposts.Post("/", h.repost.Repost)
+	reposts.Patch("/", h.repost.EditRepostCaption)
+	reposts.Delete("/", h.repost.DeleteRepost)
 }
 
-func settingsRoutes(app *fiber.App) {
-	setting := app.Group("/settings", middleware.RequireJWT)
+func draftRoutes(router fiber.Router, h *handlers) {
+	drafts := router.Group("/drafts", middleware.RequireJWT)
 
-	setting.Put("/firstname", settings.ChangeFirstName)
-	setting.Put("/lastname", settings.ChangeLastName)
-	setting.Put("/email", settings.ChangeEmail)
-	setting.Put("/handle", settings.ChangeHandle)
-	setting.Put("/password", settings.ChangePassword)
-	setting.Put("/theme", settings.ChangeTheme)
-	setting.Put("/language", settings.ChangeLanguage)
-	setting.Put("/visibility", settings.ChangeProfileVisibility)
-	setting.Get("/data", settings.DownloadUserData)
+	drafts.Post("/", h.post.CreateDraft)
+	drafts.Get("/", h.post.GetDrafts)
+	drafts.Get("/:draftID", h.post.GetDraft)
+	drafts.Patch("/:draftID", h.post.UpdateDraft)
+	drafts.Delete("/:draftID", h.post.DeleteDraft)
+	drafts.Post("/:draftID/publish", h.post.PublishDraft)
+	drafts.Put("/:draftID/schedule", h.post.ScheduleDraft)
+	drafts.Delete("/:draftID/schedule", h.post.UnscheduleDraft)
 }
 
-func portfolioRoutes(app *fiber.App) {
-	portfolios := app.Group("/portfolios/:userID", middleware.RequireJWT)
+func collectionRoutes(router fiber.Router, h *handlers) {
+	users := router.Group("/users", middleware.RequireJWT)
+	posts := router.Group("/posts", middleware.RequireJWT)
+	saves := router.Group("/saves", middleware.RequireJWT)
+	collections := router.Group("/collections", middleware.RequireJWT)
 
-	portfolios.Post("/create/", portfolio.CreatePortfolio)
-	portfolios.Get("/", portfolio.GetPortfolio)
+	users.Get("/:userID/collections", h.collections.GetUserCollections)
+	posts.Post("/:postID/save", h.collections.SavePost)
+	posts.Delete("/:postID/save", h.collections.UnsavePost)
+	posts.Get("/:postID/collections", h.collections.GetPostCollections)
+	saves.Get("/", h.collections.GetSavedPosts)
+	collections.Post("/", h.collections.CreateCollection)
+	collections.Get("/:collectionID", h.collections.GetCollection)
+	collections.Patch("/:collectionID", h.collections.UpdateCollection)
+	collections.Delete("/:collectionID", h.collections.DeleteCollection)
+	collections.Get("/:collectionID/items", h.collections.GetCollectionItems)
+	collections.Post("/:collectionID/items", h.collections.AddToCollection)
+	collections.Put("/:collectionID/items", h.collections.ReorderCollection)
+	collections.Delete("/:collectionID/items/:postID", h.collections.RemoveFromCollection)
+	collections.Put("/:collectionID/cover", h.collections.SetCollectionCover)
 }
 
-func servingRoutes(app *fiber.App) {
-	images := app.Group("/images")
+func tagRoutes(router fiber.Router, h *handlers) {
+	tags := router.Group("/tags", middleware.RequireJWT)
 
-	images.Get("/:imageID", uploads.ServeImage)
+	tags.Get("/autocomplete", h.post.GetTagSuggestions)
+	tags.Get("/trending", h.post.GetTrendingTags)
+	tags.Get("/:tag/posts", h.post.GetTagPosts)
 }
 
-func emailRoutes(app *fiber.App) {
-	emails := app.Group("/emails", middleware.RequireJWT)
+func searchRoutes(router fiber.Router, h *handlers) {
+	searches := router.Group("/search", middleware.RequireJWT)
+
+	searches.Get("/", h.search.Search)
+	searches.Get("/handles", h.search.GetHandleSuggestions)
+}
+
+func messageRoutes(router fiber.Router, h *handlers) {
+	conversations := router.Group("/conversations", middleware.RequireJWT)
+	message := router.Group("/messages", middleware.RequireJWT)
+
+	conversations.Post("/start", h.messages.StartConversation)
+	conversations.Post("/:conversationID/messages/:senderID", h.messages.SendMessage)
+	conversations.Delete("/:conversationID", h.messages.DeleteConversation)
+	conversations.Get("/conversation/:conversationID", h.messages.GetConversation)
+	conversations.Get("/all", h.messages.GetConversations)
+	message.Patch("/:_id", h.messages.EditMessage)
+	message.Delete("/delete", h.messages.DeleteMessage)
+	message.Post("/reply/:conversationID", h.messages.SendReply)
+}
+
+func settingsRoutes(router fiber.Router, h *handlers) {
+	setting := router.Group("/settings", middleware.RequireJWT)
+
+	setting.Put("/firstname", h.settings.ChangeFirstName)
+	setting.Put("/lastname", h.settings.ChangeLastName)
+	setting.Put("/email", h.settings.ChangeEmail)
+	setting.Put("/handle", h.settings.ChangeHandle)
+	setting.Put("/password", h.settings.ChangePassword)
+	setting.Put("/theme", h.settings.ChangeTheme)
+	setting.Put("/language", h.settings.ChangeLanguage)
+	setting.Put("/visibility", h.settings.ChangeProfileVisibility)
+	setting.Get("/data", h.settings.DownloadUserData)
+}
+
+func portfolioRoutes(router fiber.Router, h *handlers) {
+	portfolios := router.Group("/portfolios/:userID", middleware.RequireJWT)
+
+	portfolios.Post("/create/", h.portfolio.CreatePortfolio)
+	portfolios.Get("/", h.portfolio.GetPortfolio)
+}
+
+func servingRoutes(router fiber.Router, h *handlers) {
+	images := router.Group("/images")
+
+	images.Get("/:imageID", h.uploads.ServeImage)
+}
+
+func emailRoutes(router fiber.Router) {
+	emails := router.Group("/emails", middleware.RequireJWT)
 	emails.Post("/send", mail.SendEmail)
 }
+
+func metricsRoutes(router fiber.Router, h *handlers) {
+	metric := router.Group("/metrics", middleware.RequireJWT, middleware.RequireRole(h.repos, types.RoleAdmin))
+	metric.Get("/cache", metrics.CacheStats)
+}
+
+func wsRoutes(router fiber.Router, h *handlers) {
+	router.Use("/ws", func(c *fiber.Ctx) error {
+		if websocket.IsWebSocketUpgrade(c) {
+			return c.Next()
+		}
+		return c.SendStatus(fiber.StatusUpgradeRequired)
+	})
+
+	router.Get("/ws", websocket.New(h.ws.HandleWS))
+}
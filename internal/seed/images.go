@@ -0,0 +1,47 @@
+package seed
+
+import (
+	"bytes"
+	stdimage "image"
+	"image/color"
+	"image/png"
+)
+
+// render draws the placeholder as a PNG: a gradient between its two colours,
+// optionally overlaid with stripes or a checkerboard.
+func (img image) render() ([]byte, error) {
+	canvas := stdimage.NewRGBA(stdimage.Rect(0, 0, img.width, img.height))
+
+	for y := 0; y < img.height; y++ {
+		for x := 0; x < img.width; x++ {
+			t := float64(x+y) / float64(img.width+img.height)
+			c := color.RGBA{
+				R: blend(img.from[0], img.to[0], t),
+				G: blend(img.from[1], img.to[1], t),
+				B: blend(img.from[2], img.to[2], t),
+				A: 255,
+			}
+
+			var shaded bool
+			switch img.pattern {
+			case 1:
+				shaded = (x+y)/40%2 == 0
+			case 2:
+				shaded = (x/60+y/60)%2 == 0
+			}
+			if shaded {
+				c.R, c.G, c.B = c.R/10*9, c.G/10*9, c.B/10*9
+			}
+
+			canvas.SetRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, canvas)
+	return buf.Bytes(), err
+}
+
+func blend(from, to uint8, t float64) uint8 {
+	return uint8(float64(from) + (float64(to)-float64(from))*t)
+}
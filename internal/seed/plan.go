@@ -0,0 +1,341 @@
+package seed
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// epoch is when the first seeded user signed up. Timestamps are derived from it
+// instead of the current time so that every run generates the same documents.
+var epoch = time.Date(2025, time.January, 1, 9, 0, 0, 0, time.UTC)
+
+const (
+	maxFollows       = 15
+	maxLikes         = 12
+	maxComments      = 4
+	maxMessages      = 12
+	repostChance     = 0.1
+	directChance     = 0.3
+	portfolioChance  = 0.3
+	maxImagesPerPost = 2
+)
+
+// image describes a placeholder image, which is only rendered when it is
+// uploaded.
+type image struct {
+	name    string
+	width   int
+	height  int
+	from    [3]uint8
+	to      [3]uint8
+	pattern int
+}
+
+type seededUser struct {
+	user      types.User
+	avatar    image
+	settings  types.Settings
+	following []primitive.ObjectID
+}
+
+type seededPost struct {
+	post   types.Post
+	images []image
+}
+
+type seededConversation struct {
+	conversation types.Conversation
+	messages     []types.Message
+}
+
+type seededPortfolio struct {
+	portfolio types.Portfolio
+	images    []image
+}
+
+// plan is the complete data set for one seed value.
+type plan struct {
+	users         []seededUser
+	posts         []seededPost
+	likes         []types.Like
+	comments      []types.Comment
+	reposts       []types.Repost
+	conversations []seededConversation
+	portfolios    []seededPortfolio
+}
+
+// generator derives everything from a single random source, so the order of the
+// calls to it must not change or existing seeds generate different data.
+type generator struct {
+	seed int64
+	r    *rand.Rand
+}
+
+// id returns the ObjectID of the index-th document of kind. It starts with
+// createdAt like a generated ObjectID, so sorting by ID still sorts by time.
+func (g *generator) id(kind string, index int, createdAt time.Time) primitive.ObjectID {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d/%s/%d", g.seed, kind, index)))
+
+	var id primitive.ObjectID
+	binary.BigEndian.PutUint32(id[0:4], uint32(createdAt.Unix()))
+	copy(id[4:], sum[:8])
+
+	return id
+}
+
+// after returns a time up to limit after t, truncated to the millisecond that
+// MongoDB stores.
+func (g *generator) after(t time.Time, limit time.Duration) time.Time {
+	return t.Add(time.Duration(g.r.Int63n(int64(limit)))).Truncate(time.Millisecond)
+}
+
+func (g *generator) pick(words []string) string {
+	return words[g.r.Intn(len(words))]
+}
+
+func (g *generator) sentence(minWords, maxWords int) string {
+	n := minWords + g.r.Intn(maxWords-minWords+1)
+	if n == 0 {
+		return ""
+	}
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = g.pick(vocabulary)
+	}
+	text := strings.Join(parts, " ")
+	return strings.ToUpper(text[:1]) + text[1:] + "."
+}
+
+func (g *generator) image(name string) image {
+	color := func() [3]uint8 {
+		return [3]uint8{uint8(g.r.Intn(256)), uint8(g.r.Intn(256)), uint8(g.r.Intn(256))}
+	}
+	sizes := [][2]int{{640, 480}, {480, 640}, {600, 600}}
+	size := sizes[g.r.Intn(len(sizes))]
+
+	return image{name: name, width: size[0], height: size[1], from: color(), to: color(), pattern: g.r.Intn(3)}
+}
+
+// others returns up to n distinct indexes below total other than self.
+func (g *generator) others(total, self, n int) []int {
+	var picked []int
+	for _, i := range g.r.Perm(total) {
+		if len(picked) == n {
+			break
+		}
+		if i != self {
+			picked = append(picked, i)
+		}
+	}
+	return picked
+}
+
+func newPlan(opts Options) plan {
+	g := &generator{seed: opts.Seed, r: rand.New(rand.NewSource(opts.Seed))}
+	var p plan
+
+	for i := 0; i < opts.Users; i++ {
+		createdAt := epoch.Add(time.Duration(i) * time.Hour)
+		first, last := g.pick(firstNames), g.pick(lastNames)
+		id := g.id("user", i, createdAt)
+
+		settings := types.DefaultSettings(id)
+		settings.ID = g.id("settings", i, createdAt)
+		settings.Theme = g.pick([]string{"light", "dark"})
+
+		p.users = append(p.users, seededUser{
+			user: types.User{
+				ID:        id,
+				FirstName: first,
+				LastName:  last,
+				Email:     fmt.Sprintf("seed%d.user%d@example.com", opts.Seed, i),
+				Handle:    handle(first, last, opts.Seed, i),
+				Bio:       g.sentence(4, 12),
+				CreatedAt: createdAt,
+				LastSeen:  createdAt,
+			},
+			avatar:   g.image(fmt.Sprintf("avatar-%d.png", i)),
+			settings: settings,
+		})
+	}
+
+	followers := make([]uint32, opts.Users)
+	for i := range p.users {
+		for _, j := range g.others(opts.Users, i, g.r.Intn(min(maxFollows, opts.Users-1)+1)) {
+			p.users[i].following = append(p.users[i].following, p.users[j].user.ID)
+			followers[j]++
+		}
+		p.users[i].user.FollowingCount = uint32(len(p.users[i].following))
+	}
+	for i := range p.users {
+		p.users[i].user.FollowersCount = followers[i]
+	}
+
+	for i, u := range p.users {
+		for j := 0; j < opts.PostsPerUser; j++ {
+			index := i*opts.PostsPerUser + j
+			createdAt := g.after(u.user.CreatedAt, 180*24*time.Hour)
+
+			var tags []string
+			for k := g.r.Intn(4); k > 0; k-- {
+				tags = append(tags, g.pick(tagWords))
+			}
+			var images []image
+			for k := g.r.Intn(maxImagesPerPost + 1); k > 0; k-- {
+				images = append(images, g.image(fmt.Sprintf("post-%d-%d.png", index, len(images))))
+			}
+
+			p.posts = append(p.posts, seededPost{
+				post: types.Post{
+					ID:        g.id("post", index, createdAt),
+					UserID:    u.user.ID,
+					Caption:   g.sentence(3, 20),
+					Tags:      tags,
+					CreatedAt: createdAt,
+					UpdatedAt: createdAt,
+				},
+				images: images,
+			})
+		}
+	}
+
+	for i := range p.posts {
+		post := &p.posts[i].post
+		author := indexOf(p.users, post.UserID)
+
+		for _, j := range g.others(opts.Users, author, g.r.Intn(min(maxLikes, opts.Users)+1)) {
+			liker := p.users[j].user
+			createdAt := g.after(post.CreatedAt, 7*24*time.Hour)
+			p.likes = append(p.likes, types.Like{
+				ID:        g.id("like", len(p.likes), createdAt),
+				PostID:    post.ID,
+				UserID:    liker.ID,
+				UserName:  liker.FirstName + " " + liker.LastName,
+				CreatedAt: createdAt,
+			})
+			post.LikesCount++
+		}
+
+		for k := g.r.Intn(maxComments + 1); k > 0; k-- {
+			createdAt := g.after(post.CreatedAt, 7*24*time.Hour)
+			p.comments = append(p.comments, types.Comment{
+				ID:        g.id("comment", len(p.comments), createdAt),
+				PostID:    post.ID,
+				UserID:    p.users[g.r.Intn(opts.Users)].user.ID,
+				Content:   g.sentence(2, 15),
+				CreatedAt: createdAt,
+			})
+			post.CommentsCount++
+		}
+
+		if opts.Users > 1 && g.r.Float64() < repostChance {
+			createdAt := g.after(post.CreatedAt, 7*24*time.Hour)
+			reposter := g.others(opts.Users, author, 1)[0]
+			p.reposts = append(p.reposts, types.Repost{
+				ID:            g.id("repost", len(p.reposts), createdAt),
+				RepostedBy:    p.users[reposter].user.ID,
+				PostID:        post.ID,
+				RepostCaption: g.sentence(0, 8),
+				CreatedAt:     createdAt,
+				UpdatedAt:     createdAt,
+			})
+			post.RepostCount++
+		}
+	}
+
+	paired := map[[2]int]bool{}
+	for i, u := range p.users {
+		if opts.Users < 2 || g.r.Float64() >= directChance {
+			continue
+		}
+		j := g.others(opts.Users, i, 1)[0]
+		pair := [2]int{min(i, j), max(i, j)}
+		if paired[pair] {
+			continue
+		}
+		paired[pair] = true
+
+		other := p.users[j].user
+		start := u.user.CreatedAt
+		if other.CreatedAt.After(start) {
+			start = other.CreatedAt
+		}
+		createdAt := g.after(start, 30*24*time.Hour)
+		conversation := types.Conversation{
+			ID:              g.id("conversation", len(p.conversations), createdAt),
+			ParticipantsIds: []primitive.ObjectID{u.user.ID, other.ID},
+			CreatedAt:       createdAt,
+		}
+
+		var messages []types.Message
+		sentAt := createdAt
+		for k := 2 + g.r.Intn(maxMessages-1); k > 0; k-- {
+			sentAt = g.after(sentAt, 6*time.Hour)
+			messages = append(messages, types.Message{
+				ID:             g.id(fmt.Sprintf("message/%d", len(p.conversations)), len(messages), sentAt),
+				ConversationID: conversation.ID,
+				SenderID:       conversation.ParticipantsIds[g.r.Intn(2)],
+				Content:        g.sentence(1, 18),
+				Read:           k > 1,
+				CreatedAt:      sentAt,
+				UpdatedAt:      sentAt,
+			})
+		}
+		conversation.LastMessage = messages[len(messages)-1]
+		conversation.UpdatedAt = sentAt
+
+		p.conversations = append(p.conversations, seededConversation{conversation: conversation, messages: messages})
+	}
+
+	for i, u := range p.users {
+		if g.r.Float64() >= portfolioChance {
+			continue
+		}
+
+		portfolio := types.Portfolio{
+			ID:          g.id("portfolio", i, u.user.CreatedAt).Hex(),
+			UserID:      u.user.ID.Hex(),
+			AllowEmails: g.r.Intn(2) == 0,
+			About:       g.sentence(10, 30),
+			Appearance: types.PortfolioAppearance{
+				TextColor:    "#1f1f1f",
+				BgColor:      "#ffffff",
+				PrimaryColor: fmt.Sprintf("#%06x", g.r.Intn(1<<24)),
+			},
+			ContactInfo: types.PortfolioContactInfo{Email: u.user.Email},
+		}
+
+		var images []image
+		for k := 1 + g.r.Intn(3); k > 0; k-- {
+			portfolio.Projects = append(portfolio.Projects, types.PortfolioProject{
+				Title: g.sentence(1, 4),
+				Link:  fmt.Sprintf("https://example.com/%s/%d", u.user.Handle, len(images)),
+			})
+			images = append(images, g.image(fmt.Sprintf("project-%d-%d.png", i, len(images))))
+		}
+
+		p.portfolios = append(p.portfolios, seededPortfolio{portfolio: portfolio, images: images})
+	}
+
+	return p
+}
+
+func handle(first, last string, seed int64, index int) string {
+	name := strings.ToLower(strings.ReplaceAll(first+"_"+last, " ", ""))
+	return fmt.Sprintf("%s_%d_%d", name, seed, index)
+}
+
+func indexOf(users []seededUser, id primitive.ObjectID) int {
+	for i, u := range users {
+		if u.user.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,334 @@
+// Package seed fills a database with generated users, posts and conversations
+// for local development. The data is derived from a seed value and the documents
+// get fixed IDs, so a run creates only what earlier runs with the same options
+// haven't created yet.
+package seed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/handlers/auth"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultUsers        = 50
+	DefaultPostsPerUser = 5
+	DefaultPassword     = "password"
+)
+
+type Options struct {
+	// Seed selects the data set. Runs with the same seed and sizes generate the
+	// same documents.
+	Seed         int64
+	Users        int
+	PostsPerUser int
+	// Password is the password of every seeded user.
+	Password string
+	// DryRun only counts what would be created.
+	DryRun bool
+}
+
+// Count tells how many documents of a kind were created and how many existed
+// from an earlier run.
+type Count struct {
+	Created  int `json:"created"`
+	Existing int `json:"existing"`
+}
+
+func (c *Count) add(created bool) {
+	if created {
+		c.Created++
+	} else {
+		c.Existing++
+	}
+}
+
+type Report struct {
+	Users         Count `json:"users"`
+	Follows       Count `json:"follows"`
+	Posts         Count `json:"posts"`
+	Likes         Count `json:"likes"`
+	Comments      Count `json:"comments"`
+	Reposts       Count `json:"reposts"`
+	Conversations Count `json:"conversations"`
+	Messages      Count `json:"messages"`
+	Portfolios    Count `json:"portfolios"`
+	Images        int   `json:"images"`
+}
+
+// Run creates the data set. Every seeded user can log in with their email
+// (seed<seed>.user<n>@example.com) and opts.Password.
+//
+// The counters of seeded posts and users are set for the seeded data only.
+// Run the counter reconciliation when real accounts interact with them.
+func Run(ctx context.Context, repos *repository.Repositories, opts Options) (Report, error) {
+	if opts.Users <= 0 {
+		opts.Users = DefaultUsers
+	}
+	if opts.PostsPerUser < 0 {
+		opts.PostsPerUser = DefaultPostsPerUser
+	}
+	if opts.Password == "" {
+		opts.Password = DefaultPassword
+	}
+
+	s := &seeder{repos: repos, dryRun: opts.DryRun}
+	p := newPlan(opts)
+	// Hashing is slow on purpose, so every user shares one hash.
+	password := auth.HashPassword(opts.Password)
+
+	for _, u := range p.users {
+		if err := s.user(ctx, u, password); err != nil {
+			return s.report, err
+		}
+	}
+	for _, u := range p.users {
+		for _, followingID := range u.following {
+			if err := s.follow(ctx, u.user.ID, followingID); err != nil {
+				return s.report, err
+			}
+		}
+	}
+	for _, post := range p.posts {
+		if err := s.post(ctx, post); err != nil {
+			return s.report, err
+		}
+	}
+	for _, like := range p.likes {
+		if err := s.like(ctx, like); err != nil {
+			return s.report, err
+		}
+	}
+	for _, comment := range p.comments {
+		if err := s.comment(ctx, comment); err != nil {
+			return s.report, err
+		}
+	}
+	for _, repost := range p.reposts {
+		if err := s.repost(ctx, repost); err != nil {
+			return s.report, err
+		}
+	}
+	for _, conversation := range p.conversations {
+		if err := s.conversation(ctx, conversation); err != nil {
+			return s.report, err
+		}
+	}
+	for _, portfolio := range p.portfolios {
+		if err := s.portfolio(ctx, portfolio); err != nil {
+			return s.report, err
+		}
+	}
+
+	return s.report, nil
+}
+
+type seeder struct {
+	repos  *repository.Repositories
+	dryRun bool
+	report Report
+}
+
+// missing reports whether find returned repository.ErrNotFound.
+func missing(err error) (bool, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return true, nil
+	}
+	return false, err
+}
+
+// created reports whether create succeeded, treating repository.ErrDuplicate as
+// created by an earlier run.
+func created(err error) (bool, error) {
+	if errors.Is(err, repository.ErrDuplicate) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *seeder) upload(ctx context.Context, img image) (string, error) {
+	s.report.Images++
+	if s.dryRun {
+		return "", nil
+	}
+
+	data, err := img.render()
+	if err != nil {
+		return "", err
+	}
+	id, err := s.repos.Media.Upload(ctx, img.name, bytes.NewReader(data))
+	return id.Hex(), err
+}
+
+func (s *seeder) user(ctx context.Context, u seededUser, password string) error {
+	_, err := s.repos.Users.FindByID(ctx, u.user.ID)
+	isNew, err := missing(err)
+	if err != nil {
+		return err
+	}
+	s.report.Users.add(isNew)
+
+	if isNew {
+		user := u.user
+		user.Password = password
+		if user.PhotoURL, err = s.upload(ctx, u.avatar); err != nil {
+			return err
+		}
+		if !s.dryRun {
+			if err := s.repos.Users.Create(ctx, &user); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = s.repos.Settings.FindByUser(ctx, u.user.ID)
+	if isNew, err := missing(err); err != nil || !isNew || s.dryRun {
+		return err
+	}
+	settings := u.settings
+	return s.repos.Settings.Create(ctx, &settings)
+}
+
+func (s *seeder) follow(ctx context.Context, followerID, followingID primitive.ObjectID) error {
+	if s.dryRun {
+		following, err := s.repos.Follows.IsFollowing(ctx, followerID, followingID)
+		s.report.Follows.add(!following)
+		return err
+	}
+
+	isNew, err := created(s.repos.Follows.Follow(ctx, followerID, followingID))
+	s.report.Follows.add(isNew)
+	return err
+}
+
+func (s *seeder) post(ctx context.Context, p seededPost) error {
+	_, err := s.repos.Posts.FindByID(ctx, p.post.ID)
+	isNew, err := missing(err)
+	if err != nil {
+		return err
+	}
+	s.report.Posts.add(isNew)
+	if !isNew {
+		return nil
+	}
+
+	post := p.post
+	post.Images = make([]string, 0, len(p.images))
+	for _, img := range p.images {
+		id, err := s.upload(ctx, img)
+		if err != nil {
+			return err
+		}
+		post.Images = append(post.Images, id)
+	}
+	if s.dryRun {
+		return nil
+	}
+
+	return s.repos.Posts.Create(ctx, &post)
+}
+
+func (s *seeder) like(ctx context.Context, like types.Like) error {
+	if s.dryRun {
+		liked, err := s.repos.Likes.Exists(ctx, like.PostID, like.UserID)
+		s.report.Likes.add(!liked)
+		return err
+	}
+
+	isNew, err := created(s.repos.Likes.Create(ctx, &like))
+	s.report.Likes.add(isNew)
+	return err
+}
+
+func (s *seeder) comment(ctx context.Context, comment types.Comment) error {
+	_, err := s.repos.Comments.FindByID(ctx, comment.ID)
+	isNew, err := missing(err)
+	if err != nil {
+		return err
+	}
+	s.report.Comments.add(isNew)
+	if !isNew || s.dryRun {
+		return nil
+	}
+
+	return s.repos.Comments.Create(ctx, &comment)
+}
+
+func (s *seeder) repost(ctx context.Context, repost types.Repost) error {
+	_, err := s.repos.Reposts.FindByID(ctx, repost.ID)
+	isNew, err := missing(err)
+	if err != nil {
+		return err
+	}
+	s.report.Reposts.add(isNew)
+	if !isNew || s.dryRun {
+		return nil
+	}
+
+	return s.repos.Reposts.Create(ctx, &repost)
+}
+
+func (s *seeder) conversation(ctx context.Context, c seededConversation) error {
+	_, err := s.repos.Conversations.FindByID(ctx, c.conversation.ID)
+	isNew, err := missing(err)
+	if err != nil {
+		return err
+	}
+	s.report.Conversations.add(isNew)
+	if isNew && !s.dryRun {
+		conversation := c.conversation
+		if err := s.repos.Conversations.Create(ctx, &conversation); err != nil {
+			return err
+		}
+	}
+
+	// The messages are checked even for existing conversations in case an
+	// earlier run stopped halfway through them.
+	for _, message := range c.messages {
+		_, err := s.repos.Messages.FindByID(ctx, message.ID)
+		isNew, err := missing(err)
+		if err != nil {
+			return err
+		}
+		s.report.Messages.add(isNew)
+		if !isNew || s.dryRun {
+			continue
+		}
+		if err := s.repos.Messages.Create(ctx, &message); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *seeder) portfolio(ctx context.Context, p seededPortfolio) error {
+	_, err := s.repos.Portfolios.FindByUser(ctx, p.portfolio.UserID)
+	isNew, err := missing(err)
+	if err != nil {
+		return err
+	}
+	s.report.Portfolios.add(isNew)
+	if !isNew {
+		return nil
+	}
+
+	portfolio := p.portfolio
+	portfolio.Projects = append([]types.PortfolioProject(nil), p.portfolio.Projects...)
+	for i, img := range p.images {
+		id, err := s.upload(ctx, img)
+		if err != nil {
+			return err
+		}
+		portfolio.Projects[i].Img = id
+	}
+	if s.dryRun {
+		return nil
+	}
+
+	return s.repos.Portfolios.Create(ctx, &portfolio)
+}
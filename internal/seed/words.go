@@ -0,0 +1,24 @@
+package seed
+
+var firstNames = []string{
+	"Ada", "Bruno", "Chiara", "Dario", "Elena", "Fabio", "Giulia", "Hana", "Ivan", "Jonas",
+	"Kira", "Luca", "Marta", "Nico", "Olga", "Paolo", "Quinn", "Rosa", "Sami", "Teo",
+	"Uma", "Vera", "Wim", "Xenia", "Yara", "Zeno",
+}
+
+var lastNames = []string{
+	"Bianchi", "Costa", "De Luca", "Esposito", "Ferrari", "Gallo", "Greco", "Lombardi", "Marino", "Moretti",
+	"Novak", "Ricci", "Romano", "Russo", "Santoro", "Silva", "Conti", "Weber", "Fontana", "Rinaldi",
+}
+
+var vocabulary = []string{
+	"sketch", "palette", "light", "shadow", "texture", "canvas", "study", "portrait", "landscape", "ink",
+	"colour", "layer", "brush", "line", "form", "morning", "evening", "city", "forest", "sea",
+	"new", "quick", "final", "rough", "soft", "bold", "quiet", "warm", "cold", "bright",
+	"working", "on", "with", "for", "the", "a", "my", "this", "some", "more",
+	"draft", "render", "poster", "logo", "type", "grid", "photo", "frame", "print", "series",
+}
+
+var tagWords = []string{
+	"illustration", "design", "photography", "typography", "branding", "sketch", "3d", "ui", "painting", "animation",
+}
@@ -2,11 +2,12 @@ package server
 
 import (
 	"github.com/edisss1/fiabesco-backend/internal/routes"
+	"github.com/edisss1/fiabesco-backend/repository"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
-func Setup() *fiber.App {
+func Setup(repos *repository.Repositories) *fiber.App {
 	app := fiber.New()
 
 	app.Use(cors.New(cors.Config{
@@ -14,7 +15,7 @@ func Setup() *fiber.App {
 		AllowHeaders: "Origin,Content-Type,Accept,Authorization",
 	}))
 
-	routes.Setup(app)
+	routes.Setup(app, repos)
 
 	return app
 
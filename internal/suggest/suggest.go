@@ -0,0 +1,205 @@
+// Package suggest ranks the users someone might want to follow. Candidates
+// come from three aggregations: the users followed by the people they follow,
+// the authors of posts tagged like the posts they like, and the most active
+// authors for new accounts that have neither.
+package suggest
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// candidateLimit caps how many users each aggregation returns.
+	candidateLimit = 200
+	// recentLikes is how many of the latest likes the liked tags come from.
+	recentLikes = 200
+	// topTags is how many of the most liked tags are matched.
+	topTags = 20
+
+	tagWindow      = 90 * 24 * time.Hour
+	activityWindow = 30 * 24 * time.Hour
+
+	mutualWeight   = 3
+	tagWeight      = 2
+	activityWeight = 1
+)
+
+// Suggest returns up to limit users for userID to follow, best first. Users
+// they already follow, blocked in either direction, muted, dismissed or
+// suspended are never suggested.
+func Suggest(ctx context.Context, repos *repository.Repositories, userID primitive.ObjectID, limit int) ([]types.Suggestion, error) {
+	following, err := repos.Follows.FollowingIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := excluded(ctx, repos, userID, following)
+	if err != nil {
+		return nil, err
+	}
+
+	mutual := map[primitive.ObjectID]int64{}
+	if len(following) > 0 {
+		mutual, err = repos.Follows.CountFollowedBy(ctx, following, exclude, candidateLimit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tagMatches := map[primitive.ObjectID]int64{}
+	tags, err := likedTags(ctx, repos, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tags) > 0 {
+		tagMatches, err = repos.Posts.AuthorActivity(ctx, repository.ActivityQuery{
+			Since:   time.Now().Add(-tagWindow),
+			Exclude: exclude,
+			Tags:    tags,
+			Limit:   candidateLimit,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := make([]primitive.ObjectID, 0, len(mutual)+len(tagMatches))
+	for id := range mutual {
+		candidates = append(candidates, id)
+	}
+	for id := range tagMatches {
+		if _, ok := mutual[id]; !ok {
+			candidates = append(candidates, id)
+		}
+	}
+
+	// Without enough candidates, which is the case for new accounts, the
+	// most active authors fill up the list. Otherwise only the activity of
+	// the candidates is needed.
+	query := repository.ActivityQuery{Since: time.Now().Add(-activityWindow), Exclude: exclude}
+	if len(candidates) < limit {
+		query.Limit = candidateLimit
+	} else {
+		query.Authors = candidates
+	}
+	activity, err := repos.Posts.AuthorActivity(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for id := range activity {
+		if _, ok := mutual[id]; !ok {
+			if _, ok := tagMatches[id]; !ok {
+				candidates = append(candidates, id)
+			}
+		}
+	}
+
+	users, err := repos.Users.FindByIDs(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]types.Suggestion, 0, len(users))
+	for _, user := range users {
+		if user.SuspendedAt != nil {
+			continue
+		}
+
+		suggestion := types.Suggestion{
+			ID:            user.ID,
+			FirstName:     user.FirstName,
+			LastName:      user.LastName,
+			Handle:        user.Handle,
+			PhotoURL:      utils.MediaURL(user.PhotoURL),
+			Bio:           user.Bio,
+			MutualFollows: mutual[user.ID],
+			TagMatches:    tagMatches[user.ID],
+			RecentPosts:   activity[user.ID],
+		}
+		suggestion.Score = score(suggestion)
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].ID.Hex() < suggestions[j].ID.Hex()
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
+
+// score weighs the signals on a log scale so a single very active author
+// can't outrank users with mutual follows.
+func score(s types.Suggestion) float64 {
+	return mutualWeight*math.Log1p(float64(s.MutualFollows)) +
+		tagWeight*math.Log1p(float64(s.TagMatches)) +
+		activityWeight*math.Log1p(float64(s.RecentPosts))
+}
+
+// excluded returns userID and the users that mustn't be suggested to them.
+func excluded(ctx context.Context, repos *repository.Repositories, userID primitive.ObjectID, following []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	exclude := append([]primitive.ObjectID{userID}, following...)
+
+	blocks, err := repos.Blocks.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		exclude = append(exclude, block.BlockedID)
+	}
+
+	lists := []func(context.Context, primitive.ObjectID) ([]primitive.ObjectID, error){
+		repos.Blocks.BlockerIDs,
+		func(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+			return repos.Mutes.MutedIDs(ctx, userID, "")
+		},
+		repos.Suggestions.DismissedIDs,
+	}
+	for _, list := range lists {
+		ids, err := list(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		exclude = append(exclude, ids...)
+	}
+
+	return exclude, nil
+}
+
+// likedTags returns the tags userID liked most recently, most liked first.
+func likedTags(ctx context.Context, repos *repository.Repositories, userID primitive.ObjectID) ([]string, error) {
+	counts, err := repos.Likes.LikedTags(ctx, userID, recentLikes)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	if len(tags) > topTags {
+		tags = tags[:topTags]
+	}
+
+	return tags, nil
+}
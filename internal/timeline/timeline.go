@@ -0,0 +1,201 @@
+// Package timeline builds home timelines: the posts and reposts of the users
+// someone follows together with their own posts. Profiles are built the same
+// way from a single user's posts and reposts. Timelines are merged from the
+// posts and reposts on read, except for users who follow at least as many
+// accounts as the precompute threshold. Theirs are precomputed on first read
+// and kept up to date as posts and reposts are published.
+package timeline
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/helpers"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+)
+
+// storedEntries is how many entries a precomputed timeline starts with. Older
+// pages are merged on read.
+const storedEntries = 500
+
+var threshold int
+
+// Precompute makes the timelines of users who follow at least following
+// accounts precomputed. Zero, the default, merges every timeline on read. It
+// must be called before the server starts.
+func Precompute(following int) {
+	threshold = following
+}
+
+// Read returns page of viewerID's timeline, leaving out blocked users in
+// either direction, muted posts and reposts, posts of private users the viewer
+// doesn't follow and posts out of their reach.
+func Read(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, page utils.Page) ([]types.TimelineItem, error) {
+	reach, err := helpers.Reach(ctx, repos, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := queryFor(ctx, repos, viewerID, reach)
+	if err != nil {
+		return nil, err
+	}
+
+	if threshold == 0 || len(reach.Followed) < threshold || page.Skip > 0 {
+		return repos.Timelines.List(ctx, query, page)
+	}
+
+	if err := build(ctx, repos, query); err != nil {
+		return nil, err
+	}
+
+	items, err := repos.Timelines.ListStored(ctx, query, page)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(items)) > page.Limit {
+		return items, nil
+	}
+
+	// The precomputed timeline ends here, the rest is merged on read.
+	next := utils.Page{After: page.After, Limit: page.Limit - int64(len(items))}
+	if len(items) > 0 {
+		last := items[len(items)-1]
+		next.After = &utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+
+	older, err := repos.Timelines.List(ctx, query, next)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(items, older...), nil
+}
+
+// Profile returns page of userID's posts and reposts as viewerID sees them:
+// reposts of blocked users and of private users the viewer doesn't follow are
+// left out, so are posts out of the viewer's reach, and nothing is shown when
+// either blocked the other or userID is private and not followed by the
+// viewer.
+func Profile(ctx context.Context, repos *repository.Repositories, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.TimelineItem, error) {
+	blocked, err := helpers.BlockedIDs(ctx, repos, viewerID)
+	if err != nil || slices.Contains(blocked, userID) {
+		return nil, err
+	}
+
+	reach, err := helpers.Reach(ctx, repos, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	if userID != viewerID && !slices.Contains(reach.Followed, userID) {
+		private, err := helpers.IsPrivate(ctx, repos, userID)
+		if err != nil || private {
+			return nil, err
+		}
+	}
+
+	query := repository.TimelineQuery{
+		ViewerID:     viewerID,
+		Authors:      []primitive.ObjectID{userID},
+		Following:    append(slices.Clone(reach.Followed), viewerID),
+		ExcludePosts: blocked,
+		Reach:        reach,
+	}
+
+	return repos.Timelines.List(ctx, query, page)
+}
+
+// Publish adds the entry to the precomputed timelines of its author and their
+// followers. Those that aren't precomputed pick it up when they are read.
+func Publish(ctx context.Context, repos *repository.Repositories, entry types.TimelineEntry) error {
+	if threshold == 0 {
+		return nil
+	}
+
+	followers, err := repos.Follows.FollowerIDs(ctx, entry.AuthorID)
+	if err != nil {
+		return err
+	}
+
+	owners, err := repos.Timelines.Stored(ctx, append(followers, entry.AuthorID))
+	if err != nil {
+		return err
+	}
+
+	entries := make([]types.TimelineEntry, 0, len(owners))
+	for _, ownerID := range owners {
+		entry.OwnerID = ownerID
+		entries = append(entries, entry)
+	}
+
+	return repos.Timelines.Store(ctx, entries)
+}
+
+// PostEntry returns the timeline entry of a new post.
+func PostEntry(post types.Post) types.TimelineEntry {
+	return types.TimelineEntry{
+		PostID:       post.ID,
+		AuthorID:     post.UserID,
+		PostAuthorID: post.UserID,
+		CreatedAt:    post.CreatedAt,
+	}
+}
+
+// RepostEntry returns the timeline entry of a new repost of post.
+func RepostEntry(repost types.Repost, post types.Post) types.TimelineEntry {
+	return types.TimelineEntry{
+		PostID:       post.ID,
+		RepostID:     repost.ID,
+		AuthorID:     repost.RepostedBy,
+		PostAuthorID: post.UserID,
+		CreatedAt:    repost.CreatedAt,
+	}
+}
+
+// build precomputes the timeline of query.ViewerID unless it already is.
+func build(ctx context.Context, repos *repository.Repositories, query repository.TimelineQuery) error {
+	stored, err := repos.Timelines.Stored(ctx, []primitive.ObjectID{query.ViewerID})
+	if err != nil || len(stored) > 0 {
+		return err
+	}
+
+	entries, err := repos.Timelines.Entries(ctx, query.Authors, storedEntries)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		entries[i].OwnerID = query.ViewerID
+	}
+
+	return repos.Timelines.Store(ctx, entries)
+}
+
+// queryFor returns what viewerID's timeline shows.
+func queryFor(ctx context.Context, repos *repository.Repositories, viewerID primitive.ObjectID, reach repository.Reach) (repository.TimelineQuery, error) {
+	query := repository.TimelineQuery{ViewerID: viewerID, Reach: reach}
+
+	blocked, err := helpers.BlockedIDs(ctx, repos, viewerID)
+	if err != nil {
+		return query, err
+	}
+	query.ExcludePosts = blocked
+
+	for _, authorID := range append(slices.Clone(reach.Followed), viewerID) {
+		if !slices.Contains(query.ExcludePosts, authorID) {
+			query.Authors = append(query.Authors, authorID)
+		}
+	}
+	query.Following = query.Authors
+
+	muted, err := repos.Mutes.MutedIDs(ctx, viewerID, types.MuteScopePosts)
+	if err != nil {
+		return query, err
+	}
+	query.ExcludePosts = append(query.ExcludePosts, muted...)
+
+	query.ExcludeReposts, err = repos.Mutes.MutedIDs(ctx, viewerID, types.MuteScopeReposts)
+	return query, err
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"net/http"
+	"time"
+)
+
+// Deprecated marks responses of legacy routes with the Deprecation (RFC 9745) and
+// Sunset (RFC 8594) headers and points clients to the same path under successor.
+func Deprecated(deprecatedAt, sunset time.Time, successor string) fiber.Handler {
+	deprecation := fmt.Sprintf("@%d", deprecatedAt.Unix())
+	sunsetDate := sunset.UTC().Format(http.TimeFormat)
+
+	return func(c *fiber.Ctx) error {
+		c.Set("Deprecation", deprecation)
+		c.Set("Sunset", sunsetDate)
+		c.Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", successor, c.Path()))
+		return c.Next()
+	}
+}
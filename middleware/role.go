@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"slices"
+)
+
+// RequireRole lets through the users that have role. It must run after
+// RequireJWT. The role is looked up for every request, so revoking it takes
+// effect right away.
+func RequireRole(repos *repository.Repositories, role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, err := utils.GetUserID(c)
+		if err != nil {
+			return utils.RespondWithError(c, fiber.StatusUnauthorized, "Invalid user ID")
+		}
+
+		user, err := repos.Users.FindByID(c.UserContext(), userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return utils.RespondWithError(c, fiber.StatusUnauthorized, "User not found")
+		}
+		if err != nil {
+			return utils.RespondWithError(c, fiber.StatusInternalServerError, "Failed to check role "+err.Error())
+		}
+
+		if !slices.Contains(user.Roles, role) {
+			return utils.RespondWithError(c, fiber.StatusForbidden, "Forbidden")
+		}
+
+		return c.Next()
+	}
+}
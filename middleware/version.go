@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/edisss1/fiabesco-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIVersion records which API version a route was mounted under so shared
+// handlers can shape their responses accordingly.
+func APIVersion(version int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Locals(utils.APIVersionKey, version)
+		return c.Next()
+	}
+}
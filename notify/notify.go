@@ -0,0 +1,46 @@
+// Package notify hands notifications to the hooks registered for them, such as
+// the websocket push. Handlers call Send once the change a notification is about
+// has been committed.
+package notify
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"sync"
+	"time"
+)
+
+// Hook receives every notification sent. Hooks run in the request that sent the
+// notification, so slow work belongs in a goroutine.
+type Hook func(ctx context.Context, notification types.Notification)
+
+var (
+	mu    sync.RWMutex
+	hooks []Hook
+)
+
+// Register adds hook to the hooks that receive notifications.
+func Register(hook Hook) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	hooks = append(hooks, hook)
+}
+
+// Send passes notification to every hook. Notifications users would send to
+// themselves are dropped.
+func Send(ctx context.Context, notification types.Notification) {
+	if notification.RecipientID == notification.ActorID {
+		return
+	}
+	if notification.CreatedAt.IsZero() {
+		notification.CreatedAt = time.Now()
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, notification)
+	}
+}
@@ -0,0 +1,104 @@
+// Package cached decorates the repositories with a cache for public profiles,
+// single posts and the first feed page. The decorators invalidate the cached
+// values from every write that changes them, so handlers don't need to know
+// about the cache.
+package cached
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/cache"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sync"
+	"time"
+)
+
+const (
+	profileTTL = 10 * time.Minute
+	postTTL    = 5 * time.Minute
+	// The first feed page changes with every new post and like, so it is only
+	// cached long enough to absorb bursts of requests.
+	feedTTL = 30 * time.Second
+
+	firstFeedPage = "first"
+)
+
+// Wrap returns repos with the user, post, mention and transaction repositories
+// decorated to use c.
+func Wrap(repos *repository.Repositories, c cache.Cache) *repository.Repositories {
+	inv := &invalidator{
+		profiles: cache.NewStore[types.User](c, "profiles", profileTTL),
+		posts:    cache.NewStore[types.FeedItem](c, "posts", postTTL),
+		feed:     cache.NewStore[[]types.FeedItem](c, "feed", feedTTL),
+	}
+
+	wrapped := *repos
+	wrapped.Users = &users{UserRepository: repos.Users, inv: inv}
+	wrapped.Posts = &posts{PostRepository: repos.Posts, users: repos.Users, likes: repos.Likes, inv: inv}
+	wrapped.Mentions = &mentions{MentionRepository: repos.Mentions, inv: inv}
+	wrapped.Transactions = &transactions{inner: repos.Transactions, inv: inv}
+
+	return &wrapped
+}
+
+type invalidator struct {
+	profiles *cache.Store[types.User]
+	posts    *cache.Store[types.FeedItem]
+	feed     *cache.Store[[]types.FeedItem]
+}
+
+type pendingKey struct{}
+
+// pending collects the invalidations made inside a transaction. Until it
+// commits, concurrent readers may cache the old values again, so they are
+// invalidated once more afterwards.
+type pending struct {
+	mu    sync.Mutex
+	steps []func(ctx context.Context)
+}
+
+func (i *invalidator) profile(ctx context.Context, userID primitive.ObjectID) {
+	i.run(ctx, func(ctx context.Context) {
+		i.profiles.Invalidate(ctx, userID.Hex())
+	})
+}
+
+func (i *invalidator) post(ctx context.Context, postID primitive.ObjectID) {
+	i.run(ctx, func(ctx context.Context) {
+		i.posts.Invalidate(ctx, postID.Hex())
+		i.feed.Invalidate(ctx, firstFeedPage)
+	})
+}
+
+func (i *invalidator) firstFeedPage(ctx context.Context) {
+	i.run(ctx, func(ctx context.Context) {
+		i.feed.Invalidate(ctx, firstFeedPage)
+	})
+}
+
+func (i *invalidator) run(ctx context.Context, invalidate func(ctx context.Context)) {
+	invalidate(ctx)
+
+	if p, ok := ctx.Value(pendingKey{}).(*pending); ok {
+		p.mu.Lock()
+		p.steps = append(p.steps, invalidate)
+		p.mu.Unlock()
+	}
+}
+
+type transactions struct {
+	inner repository.Transactor
+	inv   *invalidator
+}
+
+func (t *transactions) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	p := &pending{}
+	err := t.inner.WithTransaction(context.WithValue(ctx, pendingKey{}, p), fn)
+
+	for _, invalidate := range p.steps {
+		invalidate(context.WithoutCancel(ctx))
+	}
+
+	return err
+}
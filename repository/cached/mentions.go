@@ -0,0 +1,20 @@
+package cached
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type mentions struct {
+	repository.MentionRepository
+	inv *invalidator
+}
+
+func (r *mentions) Rename(ctx context.Context, userID primitive.ObjectID, handle string) ([]primitive.ObjectID, error) {
+	postIDs, err := r.MentionRepository.Rename(ctx, userID, handle)
+	for _, postID := range postIDs {
+		r.inv.post(ctx, postID)
+	}
+	return postIDs, err
+}
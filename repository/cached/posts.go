@@ -0,0 +1,118 @@
+package cached
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strings"
+)
+
+// posts caches posts without the viewer's like, which is looked up per request,
+// and refreshes the author fields from the cached profiles so that profile
+// changes show up without invalidating every post of the author.
+type posts struct {
+	repository.PostRepository
+	users repository.UserRepository
+	likes repository.LikeRepository
+	inv   *invalidator
+}
+
+func (r *posts) Create(ctx context.Context, post *types.Post) error {
+	err := r.PostRepository.Create(ctx, post)
+	r.inv.firstFeedPage(ctx)
+	return err
+}
+
+func (r *posts) FindItem(ctx context.Context, id, viewerID primitive.ObjectID) (types.FeedItem, error) {
+	item, err := r.inv.posts.Get(ctx, id.Hex(), func(ctx context.Context) (types.FeedItem, error) {
+		return r.PostRepository.FindItem(ctx, id, primitive.NilObjectID)
+	})
+	if err != nil {
+		return item, err
+	}
+
+	items := []types.FeedItem{item}
+	err = r.personalize(ctx, items, viewerID)
+	return items[0], err
+}
+
+func (r *posts) ListFeed(ctx context.Context, viewerID primitive.ObjectID, page utils.Page, filter repository.FeedFilter) ([]types.FeedItem, error) {
+	// Only the unfiltered first page of public posts by public users is the
+	// same for everyone.
+	first := page.After == nil && page.Skip == 0 && page.Limit == utils.DefaultPageLimit
+	filtered := len(filter.ExcludeAuthors) > 0 || !filter.QuotedPostID.IsZero() || filter.Tag != ""
+	if !first || filtered || len(filter.Following) > 0 || !filter.Reach.ViewerID.IsZero() {
+		return r.PostRepository.ListFeed(ctx, viewerID, page, filter)
+	}
+
+	items, err := r.inv.feed.Get(ctx, firstFeedPage, func(ctx context.Context) ([]types.FeedItem, error) {
+		return r.PostRepository.ListFeed(ctx, primitive.NilObjectID, page, filter)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.personalize(ctx, items, viewerID)
+	return items, err
+}
+
+func (r *posts) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	err := r.PostRepository.Update(ctx, id, fields)
+	r.inv.post(ctx, id)
+	return err
+}
+
+func (r *posts) IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	err := r.PostRepository.IncrementCounter(ctx, id, field, delta)
+	r.inv.post(ctx, id)
+	return err
+}
+
+func (r *posts) SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error {
+	err := r.PostRepository.SetCounter(ctx, id, field, from, to)
+	r.inv.post(ctx, id)
+	return err
+}
+
+func (r *posts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := r.PostRepository.Delete(ctx, id)
+	r.inv.post(ctx, id)
+	return err
+}
+
+// personalize fills in the current author fields and whether viewerID liked
+// each of the cached items.
+func (r *posts) personalize(ctx context.Context, items []types.FeedItem, viewerID primitive.ObjectID) error {
+	postIDs := make([]primitive.ObjectID, 0, len(items))
+
+	for i := range items {
+		item := &items[i]
+		postIDs = append(postIDs, item.Post.ID)
+
+		author, err := r.inv.profiles.Get(ctx, item.Post.UserID.Hex(), func(ctx context.Context) (types.User, error) {
+			return r.users.FindProfile(ctx, item.Post.UserID)
+		})
+		if err == nil {
+			item.UserName = strings.Join([]string{author.FirstName, author.LastName}, " ")
+			item.PhotoURL = utils.MediaURL(author.PhotoURL)
+			item.Handle = author.Handle
+		}
+	}
+
+	if viewerID.IsZero() {
+		return nil
+	}
+
+	liked, err := r.likes.LikedPosts(ctx, viewerID, postIDs)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].LikedByViewer = liked[items[i].Post.ID]
+	}
+
+	return nil
+}
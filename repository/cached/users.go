@@ -0,0 +1,44 @@
+package cached
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type users struct {
+	repository.UserRepository
+	inv *invalidator
+}
+
+func (r *users) FindProfile(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	return r.inv.profiles.Get(ctx, id.Hex(), func(ctx context.Context) (types.User, error) {
+		return r.UserRepository.FindProfile(ctx, id)
+	})
+}
+
+func (r *users) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	err := r.UserRepository.Update(ctx, id, fields)
+	r.inv.profile(ctx, id)
+	return err
+}
+
+func (r *users) IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	err := r.UserRepository.IncrementCounter(ctx, id, field, delta)
+	r.inv.profile(ctx, id)
+	return err
+}
+
+func (r *users) SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error {
+	err := r.UserRepository.SetCounter(ctx, id, field, from, to)
+	r.inv.profile(ctx, id)
+	return err
+}
+
+func (r *users) Delete(ctx context.Context, id primitive.ObjectID) error {
+	err := r.UserRepository.Delete(ctx, id)
+	r.inv.profile(ctx, id)
+	return err
+}
@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type blocks struct {
+	*store
+}
+
+func (r *blocks) Create(ctx context.Context, block *types.Block) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	block.ID = newID(block.ID)
+	r.blocks[block.ID] = clone(*block)
+
+	return nil
+}
+
+func (r *blocks) Exists(ctx context.Context, userID, blockedID primitive.ObjectID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, block := range r.blocks {
+		if block.UserID == userID && block.BlockedID == blockedID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *blocks) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Block, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Block
+	for _, block := range r.blocks {
+		if block.UserID == userID {
+			result = append(result, clone(block))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *blocks) BlockerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	for _, block := range r.blocks {
+		if block.BlockedID == userID {
+			ids = append(ids, block.UserID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *blocks) Delete(ctx context.Context, userID, blockedID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, block := range r.blocks {
+		if block.UserID == userID && block.BlockedID == blockedID {
+			delete(r.blocks, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
@@ -0,0 +1,103 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+)
+
+type closeFriends struct {
+	*store
+}
+
+func (r *closeFriends) Add(ctx context.Context, closeFriend *types.CloseFriend) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.closeFriends {
+		if existing.UserID == closeFriend.UserID && existing.FriendID == closeFriend.FriendID {
+			return repository.ErrDuplicate
+		}
+	}
+
+	closeFriend.ID = newID(closeFriend.ID)
+	r.closeFriends[closeFriend.ID] = *closeFriend
+
+	return nil
+}
+
+func (r *closeFriends) Remove(ctx context.Context, userID, friendID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, closeFriend := range r.closeFriends {
+		if closeFriend.UserID == userID && closeFriend.FriendID == friendID {
+			delete(r.closeFriends, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *closeFriends) FriendIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var listed []types.CloseFriend
+	for _, closeFriend := range r.closeFriends {
+		if closeFriend.UserID == userID {
+			listed = append(listed, closeFriend)
+		}
+	}
+	sort.Slice(listed, func(i, j int) bool {
+		if !listed[i].CreatedAt.Equal(listed[j].CreatedAt) {
+			return listed[i].CreatedAt.After(listed[j].CreatedAt)
+		}
+		return listed[i].ID.Hex() > listed[j].ID.Hex()
+	})
+
+	ids := make([]primitive.ObjectID, 0, len(listed))
+	for _, closeFriend := range listed {
+		ids = append(ids, closeFriend.FriendID)
+	}
+
+	return ids, nil
+}
+
+func (r *closeFriends) ListedBy(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.listedBy(userID), nil
+}
+
+// listedBy returns the users that have userID on their close friends list. The
+// caller must hold the lock.
+func (s *store) listedBy(userID primitive.ObjectID) []primitive.ObjectID {
+	var ids []primitive.ObjectID
+	for _, closeFriend := range s.closeFriends {
+		if closeFriend.FriendID == userID {
+			ids = append(ids, closeFriend.UserID)
+		}
+	}
+
+	return ids
+}
+
+func (r *closeFriends) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, closeFriend := range r.closeFriends {
+		if closeFriend.UserID == userID || closeFriend.FriendID == userID {
+			delete(r.closeFriends, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
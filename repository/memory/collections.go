@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+type collections struct {
+	*store
+}
+
+func (r *collections) Create(ctx context.Context, collection *types.Collection) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	collection.ID = newID(collection.ID)
+	r.collections[collection.ID] = clone(*collection)
+
+	return nil
+}
+
+func (r *collections) FindByID(ctx context.Context, id primitive.ObjectID) (types.Collection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	collection, ok := r.collections[id]
+	if !ok {
+		return types.Collection{}, repository.ErrNotFound
+	}
+
+	return clone(collection), nil
+}
+
+func (r *collections) ListByUser(ctx context.Context, userID primitive.ObjectID, publicOnly bool) ([]types.Collection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.find(func(collection types.Collection) bool {
+		return collection.UserID == userID && (!publicOnly || collection.Visibility == types.VisibilityPublic)
+	}), nil
+}
+
+func (r *collections) ListContaining(ctx context.Context, userID, postID primitive.ObjectID) ([]types.Collection, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	containing := map[primitive.ObjectID]bool{}
+	for _, item := range r.items {
+		if item.UserID == userID && item.PostID == postID {
+			containing[item.CollectionID] = true
+		}
+	}
+
+	return r.find(func(collection types.Collection) bool { return containing[collection.ID] }), nil
+}
+
+// find returns the collections matching filter, newest first. The caller must
+// hold the lock.
+func (r *collections) find(filter func(types.Collection) bool) []types.Collection {
+	var result []types.Collection
+	for _, collection := range r.collections {
+		if filter(collection) {
+			result = append(result, clone(collection))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := collectionCursor(result[i]), collectionCursor(result[j])
+		return a.Precedes(b.CreatedAt, b.ID)
+	})
+
+	return result
+}
+
+func (r *collections) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	collection, ok := r.collections[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	for field, value := range fields {
+		set[field] = value
+	}
+
+	collection, err := update(collection, set, nil)
+	if err != nil {
+		return err
+	}
+	r.collections[id] = collection
+
+	return nil
+}
+
+func (r *collections) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.collections[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.collections, id)
+
+	for itemID, item := range r.items {
+		if item.CollectionID == id {
+			delete(r.items, itemID)
+		}
+	}
+
+	return nil
+}
+
+func (r *collections) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, item := range r.items {
+		if item.UserID == userID {
+			delete(r.items, id)
+		}
+	}
+
+	var deleted int64
+	for id, collection := range r.collections {
+		if collection.UserID == userID {
+			delete(r.collections, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func (r *collections) AddItem(ctx context.Context, item *types.CollectionItem) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item.Position = 0
+	first := true
+	for _, existing := range r.items {
+		if existing.CollectionID != item.CollectionID {
+			continue
+		}
+		if existing.PostID == item.PostID {
+			return repository.ErrDuplicate
+		}
+		if first || existing.Position <= item.Position {
+			item.Position = existing.Position - 1
+			first = false
+		}
+	}
+
+	item.ID = newID(item.ID)
+	r.items[item.ID] = clone(*item)
+
+	return nil
+}
+
+func (r *collections) RemoveItem(ctx context.Context, collectionID, postID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, item := range r.items {
+		if item.CollectionID == collectionID && item.PostID == postID {
+			delete(r.items, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *collections) ListItems(ctx context.Context, collectionID primitive.ObjectID, skip, limit int64) ([]types.CollectionItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.CollectionItem
+	for _, item := range r.items {
+		if item.CollectionID == collectionID {
+			matched = append(matched, clone(item))
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Position != matched[j].Position {
+			return matched[i].Position < matched[j].Position
+		}
+		return bytes.Compare(matched[i].ID[:], matched[j].ID[:]) > 0
+	})
+
+	return page(matched, skip, limit), nil
+}
+
+func (r *collections) ItemPostIDs(ctx context.Context, collectionID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	items, err := r.ListItems(ctx, collectionID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.PostID)
+	}
+
+	return ids, nil
+}
+
+func (r *collections) Reorder(ctx context.Context, collectionID primitive.ObjectID, postIDs []primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	positions := make(map[primitive.ObjectID]int64, len(postIDs))
+	for i, postID := range postIDs {
+		positions[postID] = int64(i)
+	}
+
+	for id, item := range r.items {
+		if position, ok := positions[item.PostID]; ok && item.CollectionID == collectionID {
+			item.Position = position
+			r.items[id] = item
+		}
+	}
+
+	return nil
+}
+
+func (r *collections) RemoveSaved(ctx context.Context, userID, postID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var removed int64
+	for id, item := range r.items {
+		if item.UserID == userID && item.PostID == postID {
+			delete(r.items, id)
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+func (r *collections) DeleteItemsByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID := func(item types.CollectionItem) primitive.ObjectID { return item.PostID }
+	return deleteByPost(r.items, postID, postIDs), nil
+}
+
+func collectionCursor(collection types.Collection) utils.Cursor {
+	return utils.Cursor{CreatedAt: collection.CreatedAt, ID: collection.ID}
+}
@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+)
+
+type comments struct {
+	*store
+}
+
+func (r *comments) Create(ctx context.Context, comment *types.Comment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment.ID = newID(comment.ID)
+	r.comments[comment.ID] = clone(*comment)
+
+	return nil
+}
+
+func (r *comments) FindByID(ctx context.Context, id primitive.ObjectID) (types.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return types.Comment{}, repository.ErrNotFound
+	}
+
+	return clone(comment), nil
+}
+
+func (r *comments) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Comment, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Comment
+	for _, comment := range r.comments {
+		if comment.UserID == userID {
+			result = append(result, clone(comment))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *comments) ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page, excludeUsers []primitive.ObjectID) ([]types.CommentItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Comment
+	for _, comment := range r.comments {
+		if comment.PostID == postID && !slices.Contains(excludeUsers, comment.UserID) {
+			matched = append(matched, comment)
+		}
+	}
+	var result []types.CommentItem
+	for _, comment := range paginate(matched, page, commentCursor) {
+		userName, photoURL, _ := r.author(comment.UserID)
+		item := types.CommentItem{Comment: clone(comment), UserName: userName, PhotoURL: photoURL}
+		utils.ResolveMedia(&item)
+		result = append(result, item)
+	}
+
+	return result, nil
+}
+
+func (r *comments) UpdateContent(ctx context.Context, id primitive.ObjectID, content string, mentions []types.Mention) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	comment, ok := r.comments[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	comment.Content, comment.Mentions = content, slices.Clone(mentions)
+	r.comments[id] = comment
+
+	return nil
+}
+
+func (r *comments) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.comments[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.comments, id)
+
+	return nil
+}
+
+func (r *comments) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postID := func(comment types.Comment) primitive.ObjectID { return comment.PostID }
+	return countByPost(r.comments, postID, postIDs), nil
+}
+
+func (r *comments) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID := func(comment types.Comment) primitive.ObjectID { return comment.PostID }
+	return deleteByPost(r.comments, postID, postIDs), nil
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+)
+
+type conversations struct {
+	*store
+}
+
+func (r *conversations) Create(ctx context.Context, conversation *types.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation.ID = newID(conversation.ID)
+	r.conversations[conversation.ID] = clone(*conversation)
+
+	return nil
+}
+
+func (r *conversations) FindByID(ctx context.Context, id primitive.ObjectID) (types.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conversation, ok := r.conversations[id]
+	if !ok {
+		return types.Conversation{}, repository.ErrNotFound
+	}
+
+	return clone(conversation), nil
+}
+
+func (r *conversations) FindDirect(ctx context.Context, userID, otherID primitive.ObjectID) (types.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, conversation := range r.conversations {
+		if !conversation.IsGroup &&
+			slices.Contains(conversation.ParticipantsIds, userID) &&
+			slices.Contains(conversation.ParticipantsIds, otherID) {
+			return clone(conversation), nil
+		}
+	}
+
+	return types.Conversation{}, repository.ErrNotFound
+}
+
+func (r *conversations) ListByParticipant(ctx context.Context, userID primitive.ObjectID) ([]types.Conversation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Conversation
+	for _, conversation := range r.conversations {
+		if slices.Contains(conversation.ParticipantsIds, userID) {
+			result = append(result, clone(conversation))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *conversations) SetLastMessage(ctx context.Context, id primitive.ObjectID, message types.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation, ok := r.conversations[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	conversation.LastMessage = message
+	r.conversations[id] = conversation
+
+	return nil
+}
+
+func (r *conversations) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.conversations, id)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+type drafts struct {
+	*store
+}
+
+func (r *drafts) Create(ctx context.Context, draft *types.Draft) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	draft.ID = newID(draft.ID)
+	r.drafts[draft.ID] = clone(*draft)
+
+	return nil
+}
+
+func (r *drafts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Draft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	draft, ok := r.drafts[id]
+	if !ok {
+		return types.Draft{}, repository.ErrNotFound
+	}
+
+	return clone(draft), nil
+}
+
+func (r *drafts) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Draft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Draft
+	for _, draft := range r.drafts {
+		if draft.UserID == userID {
+			result = append(result, clone(draft))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *drafts) ListByUser(ctx context.Context, userID primitive.ObjectID, p utils.Page) ([]types.Draft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Draft
+	for _, draft := range r.drafts {
+		if draft.UserID == userID {
+			matched = append(matched, clone(draft))
+		}
+	}
+
+	return paginate(matched, p, draftCursor), nil
+}
+
+func (r *drafts) ListDue(ctx context.Context, now time.Time, limit int64) ([]types.Draft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var due []types.Draft
+	for _, draft := range r.drafts {
+		if draft.PublishAt != nil && !draft.PublishAt.After(now) {
+			due = append(due, clone(draft))
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].PublishAt.Before(*due[j].PublishAt) })
+
+	return page(due, 0, limit), nil
+}
+
+func (r *drafts) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	draft, ok := r.drafts[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	set := bson.M{"updatedAt": time.Now()}
+	for field, value := range fields {
+		set[field] = value
+	}
+
+	draft, err := update(draft, set, nil)
+	if err != nil {
+		return err
+	}
+	r.drafts[id] = draft
+
+	return nil
+}
+
+func (r *drafts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.drafts[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.drafts, id)
+
+	return nil
+}
+
+func (r *drafts) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, draft := range r.drafts {
+		if draft.UserID == userID {
+			delete(r.drafts, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+func draftCursor(draft types.Draft) utils.Cursor {
+	return utils.Cursor{CreatedAt: draft.CreatedAt, ID: draft.ID}
+}
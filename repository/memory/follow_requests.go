@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type followRequests struct {
+	*store
+}
+
+func (r *followRequests) Create(ctx context.Context, request *types.FollowRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.requests {
+		if existing.RequesterID == request.RequesterID && existing.TargetID == request.TargetID {
+			return repository.ErrDuplicate
+		}
+	}
+
+	request.ID = newID(request.ID)
+	r.requests[request.ID] = *request
+
+	return nil
+}
+
+func (r *followRequests) FindByID(ctx context.Context, id primitive.ObjectID) (types.FollowRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	request, ok := r.requests[id]
+	if !ok {
+		return types.FollowRequest{}, repository.ErrNotFound
+	}
+
+	return request, nil
+}
+
+func (r *followRequests) FindIncoming(ctx context.Context, targetID primitive.ObjectID) ([]types.FollowRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.FollowRequest
+	for _, request := range r.requests {
+		if request.TargetID == targetID {
+			result = append(result, request)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *followRequests) FindOutgoing(ctx context.Context, requesterID primitive.ObjectID) ([]types.FollowRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.FollowRequest
+	for _, request := range r.requests {
+		if request.RequesterID == requesterID {
+			result = append(result, request)
+		}
+	}
+
+	return result, nil
+}
+
+func (r *followRequests) ListIncoming(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error) {
+	return r.list(page, func(request types.FollowRequest) (primitive.ObjectID, bool) {
+		return request.RequesterID, request.TargetID == userID
+	})
+}
+
+func (r *followRequests) ListOutgoing(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error) {
+	return r.list(page, func(request types.FollowRequest) (primitive.ObjectID, bool) {
+		return request.TargetID, request.RequesterID == userID
+	})
+}
+
+// list pages through the requests that match selects and returns them with the
+// users at the other end, leaving out deleted users like the MongoDB pipeline
+// does.
+func (r *followRequests) list(p utils.Page, match func(types.FollowRequest) (primitive.ObjectID, bool)) ([]types.FollowRequestItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.FollowRequest
+	for _, request := range r.requests {
+		if _, ok := match(request); ok {
+			matched = append(matched, request)
+		}
+	}
+
+	var items []types.FollowRequestItem
+	for _, request := range paginate(matched, p, followRequestCursor) {
+		otherID, _ := match(request)
+		user, ok := r.users[otherID]
+		if !ok {
+			continue
+		}
+
+		item := types.FollowRequestItem{
+			ID:        request.ID,
+			UserID:    user.ID,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Handle:    user.Handle,
+			PhotoURL:  user.PhotoURL,
+			CreatedAt: request.CreatedAt,
+		}
+		utils.ResolveMedia(&item)
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func followRequestCursor(request types.FollowRequest) utils.Cursor {
+	return utils.Cursor{CreatedAt: request.CreatedAt, ID: request.ID}
+}
+
+func (r *followRequests) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.requests[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.requests, id)
+
+	return nil
+}
+
+func (r *followRequests) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, request := range r.requests {
+		if request.RequesterID == userID || request.TargetID == userID {
+			delete(r.requests, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
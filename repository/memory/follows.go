@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"time"
+)
+
+type follows struct {
+	*store
+}
+
+func (r *follows) Follow(ctx context.Context, followerID, followingID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.findFollow(followerID, followingID); ok {
+		return repository.ErrDuplicate
+	}
+
+	follow := types.Follow{
+		ID:          primitive.NewObjectID(),
+		FollowerID:  followerID,
+		FollowingID: followingID,
+		CreatedAt:   time.Now(),
+	}
+	r.follows[follow.ID] = follow
+
+	return nil
+}
+
+func (r *follows) Unfollow(ctx context.Context, followerID, followingID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	follow, ok := r.findFollow(followerID, followingID)
+	if !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.follows, follow.ID)
+
+	return nil
+}
+
+// findFollow returns the follow of followingID by followerID. The caller must
+// hold the lock.
+func (s *store) findFollow(followerID, followingID primitive.ObjectID) (types.Follow, bool) {
+	for _, follow := range s.follows {
+		if follow.FollowerID == followerID && follow.FollowingID == followingID {
+			return follow, true
+		}
+	}
+	return types.Follow{}, false
+}
+
+func (r *follows) IsFollowing(ctx context.Context, followerID, followingID primitive.ObjectID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.findFollow(followerID, followingID)
+	return ok, nil
+}
+
+func (r *follows) FollowingIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	for _, follow := range r.follows {
+		if follow.FollowerID == userID {
+			ids = append(ids, follow.FollowingID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *follows) FollowerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	for _, follow := range r.follows {
+		if follow.FollowingID == userID {
+			ids = append(ids, follow.FollowerID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *follows) ListFollowers(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error) {
+	return r.list(page, viewerID, func(follow types.Follow) (primitive.ObjectID, bool) {
+		return follow.FollowerID, follow.FollowingID == userID
+	})
+}
+
+func (r *follows) ListFollowing(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error) {
+	return r.list(page, viewerID, func(follow types.Follow) (primitive.ObjectID, bool) {
+		return follow.FollowingID, follow.FollowerID == userID
+	})
+}
+
+// list pages through the follows that match selects and returns the users at the
+// other end of them, leaving out deleted users like the MongoDB pipeline does.
+func (r *follows) list(p utils.Page, viewerID primitive.ObjectID, match func(types.Follow) (primitive.ObjectID, bool)) ([]types.FollowItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Follow
+	for _, follow := range r.follows {
+		if _, ok := match(follow); ok {
+			matched = append(matched, follow)
+		}
+	}
+
+	var items []types.FollowItem
+	for _, follow := range paginate(matched, p, followCursor) {
+		otherID, _ := match(follow)
+		user, ok := r.users[otherID]
+		if !ok {
+			continue
+		}
+
+		item := types.FollowItem{
+			ID:         user.ID,
+			FirstName:  user.FirstName,
+			LastName:   user.LastName,
+			Handle:     user.Handle,
+			PhotoURL:   user.PhotoURL,
+			Bio:        user.Bio,
+			FollowedAt: follow.CreatedAt,
+			FollowID:   follow.ID,
+		}
+		if !viewerID.IsZero() {
+			_, item.FollowsYou = r.findFollow(user.ID, viewerID)
+			_, item.YouFollow = r.findFollow(viewerID, user.ID)
+		}
+
+		utils.ResolveMedia(&item)
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func followCursor(follow types.Follow) utils.Cursor {
+	return utils.Cursor{CreatedAt: follow.CreatedAt, ID: follow.ID}
+}
+
+func (r *follows) CountFollowedBy(ctx context.Context, followerIDs, exclude []primitive.ObjectID, limit int64) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := map[primitive.ObjectID]int64{}
+	for _, follow := range r.follows {
+		if slices.Contains(followerIDs, follow.FollowerID) && !slices.Contains(exclude, follow.FollowingID) {
+			counts[follow.FollowingID]++
+		}
+	}
+
+	return topCounts(counts, limit), nil
+}
+
+func (r *follows) Counts(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[primitive.ObjectID]bool, len(userIDs))
+	for _, id := range userIDs {
+		wanted[id] = true
+	}
+
+	followers := map[primitive.ObjectID]int64{}
+	following := map[primitive.ObjectID]int64{}
+	for _, follow := range r.follows {
+		if wanted[follow.FollowingID] {
+			followers[follow.FollowingID]++
+		}
+		if wanted[follow.FollowerID] {
+			following[follow.FollowerID]++
+		}
+	}
+
+	return followers, following, nil
+}
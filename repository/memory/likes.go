@@ -0,0 +1,157 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"sort"
+	"time"
+)
+
+type likes struct {
+	*store
+}
+
+func (r *likes) Create(ctx context.Context, like *types.Like) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.likes {
+		if existing.PostID == like.PostID && existing.UserID == like.UserID {
+			return repository.ErrDuplicate
+		}
+	}
+
+	like.ID = newID(like.ID)
+	r.likes[like.ID] = clone(*like)
+
+	return nil
+}
+
+func (r *likes) Exists(ctx context.Context, postID, userID primitive.ObjectID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, like := range r.likes {
+		if like.PostID == postID && like.UserID == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *likes) LikedPosts(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	wanted := make(map[primitive.ObjectID]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	liked := map[primitive.ObjectID]bool{}
+	for _, like := range r.likes {
+		if like.UserID == userID && wanted[like.PostID] {
+			liked[like.PostID] = true
+		}
+	}
+
+	return liked, nil
+}
+
+func (r *likes) LikedTags(ctx context.Context, userID primitive.ObjectID, recent int64) (map[string]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := map[string]int64{}
+	for _, like := range r.latestLikes(userID, recent) {
+		for _, tag := range r.posts[like.PostID].Tags {
+			tags[tag]++
+		}
+	}
+
+	return tags, nil
+}
+
+func (r *likes) LikedAuthors(ctx context.Context, userID primitive.ObjectID, recent int64) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	authors := map[primitive.ObjectID]int64{}
+	for _, like := range r.latestLikes(userID, recent) {
+		if post, ok := r.posts[like.PostID]; ok {
+			authors[post.UserID]++
+		}
+	}
+
+	return authors, nil
+}
+
+// latestLikes returns the last recent likes of userID. The caller must hold the
+// lock.
+func (s *store) latestLikes(userID primitive.ObjectID, recent int64) []types.Like {
+	var liked []types.Like
+	for _, like := range s.likes {
+		if like.UserID == userID {
+			liked = append(liked, like)
+		}
+	}
+	sort.Slice(liked, func(i, j int) bool { return liked[i].CreatedAt.After(liked[j].CreatedAt) })
+
+	return page(liked, 0, recent)
+}
+
+func (r *likes) DecayedCountByPosts(ctx context.Context, postIDs []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postID := func(like types.Like) primitive.ObjectID { return like.PostID }
+	createdAt := func(like types.Like) time.Time { return like.CreatedAt }
+	return decayedCountByPost(r.likes, postID, createdAt, postIDs, halfLife), nil
+}
+
+func (r *likes) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Like, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Like
+	for _, like := range r.likes {
+		if like.UserID == userID {
+			result = append(result, clone(like))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *likes) Delete(ctx context.Context, postID, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, like := range r.likes {
+		if like.PostID == postID && like.UserID == userID {
+			delete(r.likes, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *likes) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postID := func(like types.Like) primitive.ObjectID { return like.PostID }
+	return countByPost(r.likes, postID, postIDs), nil
+}
+
+func (r *likes) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID := func(like types.Like) primitive.ObjectID { return like.PostID }
+	return deleteByPost(r.likes, postID, postIDs), nil
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"slices"
+	"sort"
+	"time"
+)
+
+type file struct {
+	name       string
+	data       []byte
+	uploadedAt time.Time
+}
+
+type media struct {
+	*store
+}
+
+func (r *media) Upload(ctx context.Context, filename string, reader io.Reader) (primitive.ObjectID, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := primitive.NewObjectID()
+	r.media[id] = file{name: filename, data: data, uploadedAt: time.Now()}
+
+	return id, nil
+}
+
+func (r *media) Restore(ctx context.Context, f repository.MediaFile, reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.media[f.ID]; ok {
+		return repository.ErrDuplicate
+	}
+	r.media[f.ID] = file{name: f.Filename, data: data, uploadedAt: time.Now()}
+
+	return nil
+}
+
+func (r *media) Find(ctx context.Context, id primitive.ObjectID) (repository.MediaFile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	f, ok := r.media[id]
+	if !ok {
+		return repository.MediaFile{}, repository.ErrNotFound
+	}
+
+	return repository.MediaFile{ID: id, Filename: f.name, Length: int64(len(f.data)), UploadedAt: f.uploadedAt}, nil
+}
+
+func (r *media) Download(ctx context.Context, id primitive.ObjectID, w io.Writer) error {
+	r.mu.RLock()
+	f, ok := r.media[id]
+	r.mu.RUnlock()
+
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	_, err := w.Write(f.data)
+	return err
+}
+
+func (r *media) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.media[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.media, id)
+
+	return nil
+}
+
+func (r *media) List(ctx context.Context, after primitive.ObjectID, limit int64) ([]repository.MediaFile, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]primitive.ObjectID, 0, len(r.media))
+	for id := range r.media {
+		if bytes.Compare(id[:], after[:]) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+
+	files := make([]repository.MediaFile, 0, len(ids))
+	for _, id := range page(ids, 0, limit) {
+		f := r.media[id]
+		files = append(files, repository.MediaFile{ID: id, Filename: f.name, Length: int64(len(f.data)), UploadedAt: f.uploadedAt})
+	}
+
+	return files, nil
+}
+
+func (r *media) Referenced(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var used []string
+	for _, user := range r.users {
+		used = append(used, user.PhotoURL, user.BannerURL)
+	}
+	for _, post := range r.posts {
+		used = append(used, post.Images...)
+		used = append(used, post.Files...)
+	}
+	for _, revision := range r.revisions {
+		used = append(used, revision.Images...)
+	}
+	for _, draft := range r.drafts {
+		used = append(used, draft.Images...)
+		used = append(used, draft.Files...)
+	}
+	for _, message := range r.messages {
+		used = append(used, message.Files...)
+	}
+	for _, portfolio := range r.portfolios {
+		for _, project := range portfolio.Projects {
+			used = append(used, project.Img)
+		}
+	}
+
+	referenced := map[primitive.ObjectID]bool{}
+	for _, id := range ids {
+		if slices.Contains(used, id.Hex()) {
+			referenced[id] = true
+		}
+	}
+
+	return referenced, nil
+}
@@ -0,0 +1,405 @@
+// Package memory implements the repositories in process. Nothing is persisted,
+// which makes it suitable for running the API in tests and local experiments.
+package memory
+
+import (
+	"bytes"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// store holds every collection behind a single lock so the repositories can
+// read across collections the way $lookup does.
+type store struct {
+	mu            sync.RWMutex
+	users         map[primitive.ObjectID]types.User
+	posts         map[primitive.ObjectID]types.Post
+	comments      map[primitive.ObjectID]types.Comment
+	likes         map[primitive.ObjectID]types.Like
+	saves         map[primitive.ObjectID]types.Save
+	collections   map[primitive.ObjectID]types.Collection
+	items         map[primitive.ObjectID]types.CollectionItem
+	follows       map[primitive.ObjectID]types.Follow
+	closeFriends  map[primitive.ObjectID]types.CloseFriend
+	requests      map[primitive.ObjectID]types.FollowRequest
+	blocks        map[primitive.ObjectID]types.Block
+	mutes         map[primitive.ObjectID]types.Mute
+	dismissals    map[primitive.ObjectID]types.Dismissal
+	timelines     map[primitive.ObjectID]types.TimelineEntry
+	reposts       map[primitive.ObjectID]types.Repost
+	revisions     map[primitive.ObjectID]types.PostRevision
+	tags          map[string]types.Tag
+	drafts        map[primitive.ObjectID]types.Draft
+	conversations map[primitive.ObjectID]types.Conversation
+	messages      map[primitive.ObjectID]types.Message
+	settings      map[primitive.ObjectID]types.Settings
+	portfolios    map[string]types.Portfolio
+	media         map[primitive.ObjectID]file
+}
+
+func New() *repository.Repositories {
+	s := &store{
+		users:         map[primitive.ObjectID]types.User{},
+		posts:         map[primitive.ObjectID]types.Post{},
+		comments:      map[primitive.ObjectID]types.Comment{},
+		likes:         map[primitive.ObjectID]types.Like{},
+		saves:         map[primitive.ObjectID]types.Save{},
+		collections:   map[primitive.ObjectID]types.Collection{},
+		items:         map[primitive.ObjectID]types.CollectionItem{},
+		follows:       map[primitive.ObjectID]types.Follow{},
+		closeFriends:  map[primitive.ObjectID]types.CloseFriend{},
+		requests:      map[primitive.ObjectID]types.FollowRequest{},
+		blocks:        map[primitive.ObjectID]types.Block{},
+		mutes:         map[primitive.ObjectID]types.Mute{},
+		dismissals:    map[primitive.ObjectID]types.Dismissal{},
+		timelines:     map[primitive.ObjectID]types.TimelineEntry{},
+		reposts:       map[primitive.ObjectID]types.Repost{},
+		revisions:     map[primitive.ObjectID]types.PostRevision{},
+		tags:          map[string]types.Tag{},
+		drafts:        map[primitive.ObjectID]types.Draft{},
+		conversations: map[primitive.ObjectID]types.Conversation{},
+		messages:      map[primitive.ObjectID]types.Message{},
+		settings:      map[primitive.ObjectID]types.Settings{},
+		portfolios:    map[string]types.Portfolio{},
+		media:         map[primitive.ObjectID]file{},
+	}
+
+	return &repository.Repositories{
+		Users:          &users{s},
+		Posts:          &posts{s},
+		Comments:       &comments{s},
+		Likes:          &likes{s},
+		Saves:          &saves{s},
+		Collections:    &collections{s},
+		Follows:        &follows{s},
+		CloseFriends:   &closeFriends{s},
+		FollowRequests: &followRequests{s},
+		Blocks:         &blocks{s},
+		Mutes:          &mutes{s},
+		Suggestions:    &suggestions{s},
+		Timelines:      &timelines{s},
+		Reposts:        &reposts{s},
+		Revisions:      &revisions{s},
+		Tags:           &tags{s},
+		Mentions:       &mentions{s},
+		Search:         &search{s},
+		Drafts:         &drafts{s},
+		Conversations:  &conversations{s},
+		Messages:       &messages{s},
+		Settings:       &settings{s},
+		Portfolios:     &portfolios{s},
+		Media:          &media{s},
+		Transactions:   repository.Compensating{},
+	}
+}
+
+// newID returns id, or a fresh ObjectID when id is unset, like an insert does.
+func newID(id primitive.ObjectID) primitive.ObjectID {
+	if id.IsZero() {
+		return primitive.NewObjectID()
+	}
+	return id
+}
+
+// clone deep-copies doc so callers can't modify what the store holds.
+func clone[T any](doc T) T {
+	var out T
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return doc
+	}
+	if err := bson.Unmarshal(raw, &out); err != nil {
+		return doc
+	}
+
+	return out
+}
+
+// update applies $set and $inc style changes to doc by round-tripping it
+// through bson, so field names match the ones used with MongoDB.
+func update[T any](doc T, set bson.M, inc bson.M) (T, error) {
+	var out T
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return out, err
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return out, err
+	}
+
+	for key, value := range set {
+		fields[key] = value
+	}
+	for key, delta := range inc {
+		fields[key] = toInt64(fields[key]) + toInt64(delta)
+	}
+
+	raw, err = bson.Marshal(fields)
+	if err != nil {
+		return out, err
+	}
+	err = bson.Unmarshal(raw, &out)
+	return out, err
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// fieldsOf returns doc as the bson fields it is stored with.
+func fieldsOf[T any](doc T) bson.M {
+	var fields bson.M
+
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return bson.M{}
+	}
+	if err := bson.Unmarshal(raw, &fields); err != nil {
+		return bson.M{}
+	}
+
+	return fields
+}
+
+// listCounters returns the fields of up to limit docs with an ID greater than
+// after, in ID order.
+func listCounters[T any](docs map[primitive.ObjectID]T, after primitive.ObjectID, limit int64, fields ...string) []repository.Counters {
+	ids := make([]primitive.ObjectID, 0, len(docs))
+	for id := range docs {
+		if bytes.Compare(id[:], after[:]) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return bytes.Compare(ids[i][:], ids[j][:]) < 0 })
+
+	result := make([]repository.Counters, 0, len(ids))
+	for _, id := range page(ids, 0, limit) {
+		values := fieldsOf(docs[id])
+		counters := repository.Counters{ID: id, Values: map[string]int64{}}
+		for _, field := range fields {
+			counters.Values[field] = toInt64(values[field])
+		}
+		result = append(result, counters)
+	}
+
+	return result
+}
+
+// setCounter sets field of the doc to the value to if it still holds from.
+func setCounter[T any](docs map[primitive.ObjectID]T, id primitive.ObjectID, field string, from, to int64) error {
+	doc, ok := docs[id]
+	if !ok || toInt64(fieldsOf(doc)[field]) != from {
+		return repository.ErrNotFound
+	}
+
+	doc, err := update(doc, bson.M{field: to}, nil)
+	if err != nil {
+		return err
+	}
+	docs[id] = doc
+
+	return nil
+}
+
+// countByPost counts the docs of each of the posts.
+func countByPost[T any](docs map[primitive.ObjectID]T, postID func(T) primitive.ObjectID, postIDs []primitive.ObjectID) map[primitive.ObjectID]int64 {
+	wanted := make(map[primitive.ObjectID]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	counts := map[primitive.ObjectID]int64{}
+	for _, doc := range docs {
+		if id := postID(doc); wanted[id] {
+			counts[id]++
+		}
+	}
+
+	return counts
+}
+
+// decayedCountByPost counts the docs of each of the posts, each counting half as
+// much every halfLife since createdAt.
+func decayedCountByPost[T any](docs map[primitive.ObjectID]T, postID func(T) primitive.ObjectID, createdAt func(T) time.Time, postIDs []primitive.ObjectID, halfLife time.Duration) map[primitive.ObjectID]float64 {
+	wanted := make(map[primitive.ObjectID]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	counts := map[primitive.ObjectID]float64{}
+	for _, doc := range docs {
+		if id := postID(doc); wanted[id] {
+			counts[id] += math.Pow(0.5, float64(time.Since(createdAt(doc)))/float64(halfLife))
+		}
+	}
+
+	return counts
+}
+
+// deleteByPost deletes the docs of the posts and returns how many there were.
+func deleteByPost[T any](docs map[primitive.ObjectID]T, postID func(T) primitive.ObjectID, postIDs []primitive.ObjectID) int64 {
+	wanted := make(map[primitive.ObjectID]bool, len(postIDs))
+	for _, id := range postIDs {
+		wanted[id] = true
+	}
+
+	var deleted int64
+	for id, doc := range docs {
+		if wanted[postID(doc)] {
+			delete(docs, id)
+			deleted++
+		}
+	}
+
+	return deleted
+}
+
+// topCounts keeps the limit highest counts, or all of them when limit is 0,
+// like sorting on count and limiting a $group does.
+func topCounts[K comparable](counts map[K]int64, limit int64) map[K]int64 {
+	if limit <= 0 || int64(len(counts)) <= limit {
+		return counts
+	}
+
+	keys := make([]K, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return counts[keys[i]] > counts[keys[j]] })
+
+	top := make(map[K]int64, limit)
+	for _, key := range keys[:limit] {
+		top[key] = counts[key]
+	}
+
+	return top
+}
+
+// page applies skip and limit to items that are already sorted.
+func page[T any](items []T, skip, limit int64) []T {
+	if skip < 0 {
+		skip = 0
+	}
+	if skip >= int64(len(items)) {
+		return nil
+	}
+	items = items[skip:]
+	if limit > 0 && limit < int64(len(items)) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// paginate sorts items by (createdAt, _id) newest first and selects page like
+// utils.PipelineBuilder.Paginate does.
+func paginate[T any](items []T, p utils.Page, cursor func(T) utils.Cursor) []T {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := cursor(items[i]), cursor(items[j])
+		return a.Precedes(b.CreatedAt, b.ID)
+	})
+
+	if p.After == nil {
+		return page(items, p.Skip, p.Limit+1)
+	}
+
+	var after []T
+	for _, item := range items {
+		if c := cursor(item); p.After.Precedes(c.CreatedAt, c.ID) {
+			after = append(after, item)
+		}
+	}
+
+	return page(after, 0, p.Limit+1)
+}
+
+// paginateByScore sorts items by (score, _id) best first and selects page like
+// utils.PipelineBuilder.PaginateByScore does.
+func paginateByScore[T any](items []T, p utils.ScorePage, cursor func(T) utils.ScoreCursor) []T {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := cursor(items[i]), cursor(items[j])
+		return a.Precedes(b.Score, b.ID)
+	})
+
+	if p.After == nil {
+		return page(items, 0, p.Limit+1)
+	}
+
+	var after []T
+	for _, item := range items {
+		if c := cursor(item); p.After.Precedes(c.Score, c.ID) {
+			after = append(after, item)
+		}
+	}
+
+	return page(after, 0, p.Limit+1)
+}
+
+func postCursor(post types.Post) utils.Cursor {
+	return utils.Cursor{CreatedAt: post.CreatedAt, ID: post.ID}
+}
+
+func commentCursor(comment types.Comment) utils.Cursor {
+	return utils.Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}
+}
+
+// private reports whether userID's profile is private. The caller must hold
+// the lock.
+func (s *store) private(userID primitive.ObjectID) bool {
+	for _, settings := range s.settings {
+		if settings.UserID == userID {
+			return settings.ProfileVisibility == types.VisibilityPrivate
+		}
+	}
+	return false
+}
+
+// author returns the fields that the MongoDB pipelines join from users.
+// The caller must hold the lock.
+func (s *store) author(userID primitive.ObjectID) (userName, photoURL, handle string) {
+	user, ok := s.users[userID]
+	if !ok {
+		return "", "", ""
+	}
+	return strings.Join([]string{user.FirstName, user.LastName}, " "), user.PhotoURL, user.Handle
+}
+
+func (s *store) feedItem(post types.Post, viewerID primitive.ObjectID) types.FeedItem {
+	userName, photoURL, handle := s.author(post.UserID)
+	item := types.FeedItem{Post: clone(post), UserName: userName, PhotoURL: photoURL, Handle: handle}
+
+	if !viewerID.IsZero() {
+		for _, like := range s.likes {
+			if like.PostID == post.ID && like.UserID == viewerID {
+				item.LikedByViewer = true
+				break
+			}
+		}
+	}
+
+	utils.ResolveMedia(&item)
+	return item
+}
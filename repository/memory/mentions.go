@@ -0,0 +1,45 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type mentions struct {
+	*store
+}
+
+func (r *mentions) Rename(ctx context.Context, userID primitive.ObjectID, handle string) ([]primitive.ObjectID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var postIDs []primitive.ObjectID
+	for id, post := range r.posts {
+		caption, mentions, ok := repository.RenameMention(post.Caption, post.Mentions, userID, handle)
+		if !ok {
+			continue
+		}
+		post.Caption, post.Mentions = caption, mentions
+		post.UpdatedAt = time.Now()
+		r.posts[id] = post
+		postIDs = append(postIDs, id)
+	}
+
+	for id, comment := range r.comments {
+		if content, mentions, ok := repository.RenameMention(comment.Content, comment.Mentions, userID, handle); ok {
+			comment.Content, comment.Mentions = content, mentions
+			r.comments[id] = comment
+		}
+	}
+
+	for id, message := range r.messages {
+		if content, mentions, ok := repository.RenameMention(message.Content, message.Mentions, userID, handle); ok {
+			message.Content, message.Mentions = content, mentions
+			r.messages[id] = message
+		}
+	}
+
+	return postIDs, nil
+}
@@ -0,0 +1,95 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"sort"
+	"time"
+)
+
+type messages struct {
+	*store
+}
+
+func (r *messages) Create(ctx context.Context, message *types.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message.ID = newID(message.ID)
+	r.messages[message.ID] = clone(*message)
+
+	return nil
+}
+
+func (r *messages) FindByID(ctx context.Context, id primitive.ObjectID) (types.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	message, ok := r.messages[id]
+	if !ok {
+		return types.Message{}, repository.ErrNotFound
+	}
+
+	return clone(message), nil
+}
+
+func (r *messages) ListByConversation(ctx context.Context, conversationID primitive.ObjectID) ([]types.Message, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Message
+	for _, message := range r.messages {
+		if message.ConversationID == conversationID {
+			result = append(result, clone(message))
+		}
+	}
+	// MongoDB returns messages in insertion order, which ObjectIDs preserve.
+	sort.Slice(result, func(i, j int) bool {
+		return bytes.Compare(result[i].ID[:], result[j].ID[:]) < 0
+	})
+
+	return result, nil
+}
+
+func (r *messages) Edit(ctx context.Context, id primitive.ObjectID, content string, mentions []types.Mention) (types.Message, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	message, ok := r.messages[id]
+	if !ok {
+		return types.Message{}, repository.ErrNotFound
+	}
+	message.Content = content
+	message.Mentions = slices.Clone(mentions)
+	message.IsEdited = true
+	message.UpdatedAt = time.Now()
+	r.messages[id] = message
+
+	return clone(message), nil
+}
+
+func (r *messages) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.messages, id)
+
+	return nil
+}
+
+func (r *messages) DeleteByConversation(ctx context.Context, conversationID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, message := range r.messages {
+		if message.ConversationID == conversationID {
+			delete(r.messages, id)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"time"
+)
+
+type mutes struct {
+	*store
+}
+
+func (r *mutes) Set(ctx context.Context, mute *types.Mute) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, existing := range r.mutes {
+		if existing.UserID == mute.UserID && existing.MutedID == mute.MutedID {
+			mute.ID = id
+		}
+	}
+
+	mute.ID = newID(mute.ID)
+	r.mutes[mute.ID] = clone(*mute)
+
+	return nil
+}
+
+func (r *mutes) Delete(ctx context.Context, userID, mutedID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, mute := range r.mutes {
+		if mute.UserID == userID && mute.MutedID == mutedID {
+			delete(r.mutes, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *mutes) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Mute, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Mute
+	for _, mute := range r.mutes {
+		if activeMute(mute, userID, "") {
+			result = append(result, clone(mute))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *mutes) MutedIDs(ctx context.Context, userID primitive.ObjectID, scope string) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	for _, mute := range r.mutes {
+		if activeMute(mute, userID, scope) {
+			ids = append(ids, mute.MutedID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *mutes) IsMuted(ctx context.Context, userID, mutedID primitive.ObjectID, scope string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, mute := range r.mutes {
+		if mute.MutedID == mutedID && activeMute(mute, userID, scope) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// activeMute reports whether mute is by userID for scope, or any scope when
+// it's empty, and hasn't expired.
+func activeMute(mute types.Mute, userID primitive.ObjectID, scope string) bool {
+	if mute.UserID != userID {
+		return false
+	}
+	if mute.ExpiresAt != nil && !mute.ExpiresAt.After(time.Now()) {
+		return false
+	}
+	return scope == "" || slices.Contains(mute.Scopes, scope)
+}
+
+func (r *mutes) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, mute := range r.mutes {
+		if mute.UserID == userID || mute.MutedID == userID {
+			delete(r.mutes, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
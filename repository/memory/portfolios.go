@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// portfolios is keyed by the owner's user ID.
+type portfolios struct {
+	*store
+}
+
+func (r *portfolios) Create(ctx context.Context, portfolio *types.Portfolio) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if portfolio.ID == "" {
+		portfolio.ID = primitive.NewObjectID().Hex()
+	}
+	r.portfolios[portfolio.UserID] = clone(*portfolio)
+
+	return nil
+}
+
+func (r *portfolios) FindByUser(ctx context.Context, userID string) (types.Portfolio, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	portfolio, ok := r.portfolios[userID]
+	if !ok {
+		return types.Portfolio{}, repository.ErrNotFound
+	}
+
+	return clone(portfolio), nil
+}
+
+func (r *portfolios) Delete(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.portfolios[userID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.portfolios, userID)
+
+	return nil
+}
@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math"
+	"slices"
+	"sort"
+	"time"
+)
+
+type posts struct {
+	*store
+}
+
+func (r *posts) Create(ctx context.Context, post *types.Post) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post.ID = newID(post.ID)
+	r.posts[post.ID] = clone(*post)
+
+	return nil
+}
+
+func (r *posts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return types.Post{}, repository.ErrNotFound
+	}
+
+	return clone(post), nil
+}
+
+func (r *posts) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Post, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Post
+	for _, post := range r.posts {
+		if post.UserID == userID {
+			result = append(result, clone(post))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *posts) FindItem(ctx context.Context, id, viewerID primitive.ObjectID) (types.FeedItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return types.FeedItem{}, repository.ErrNotFound
+	}
+
+	return r.feedItem(post, viewerID), nil
+}
+
+func (r *posts) ListFeed(ctx context.Context, viewerID primitive.ObjectID, page utils.Page, filter repository.FeedFilter) ([]types.FeedItem, error) {
+	return r.list(func(post types.Post) bool {
+		if !filter.QuotedPostID.IsZero() && (post.QuotedPostID == nil || *post.QuotedPostID != filter.QuotedPostID) {
+			return false
+		}
+		if filter.Tag != "" && !slices.Contains(post.Tags, filter.Tag) {
+			return false
+		}
+		if slices.Contains(filter.ExcludeAuthors, post.UserID) || !filter.Reach.Allows(post, false) {
+			return false
+		}
+		return slices.Contains(filter.Following, post.UserID) || !r.private(post.UserID)
+	}, viewerID, page), nil
+}
+
+func (r *posts) list(match func(types.Post) bool, viewerID primitive.ObjectID, page utils.Page) []types.FeedItem {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Post
+	for _, post := range r.posts {
+		if match(post) {
+			matched = append(matched, post)
+		}
+	}
+	var result []types.FeedItem
+	for _, post := range paginate(matched, page, postCursor) {
+		result = append(result, r.feedItem(post, viewerID))
+	}
+
+	return result
+}
+
+func (r *posts) AuthorActivity(ctx context.Context, query repository.ActivityQuery) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := map[primitive.ObjectID]int64{}
+	for _, post := range r.posts {
+		if post.CreatedAt.Before(query.Since) || slices.Contains(query.Exclude, post.UserID) {
+			continue
+		}
+		if len(query.Authors) > 0 && !slices.Contains(query.Authors, post.UserID) {
+			continue
+		}
+		if len(query.Tags) > 0 && !slices.ContainsFunc(post.Tags, func(tag string) bool { return slices.Contains(query.Tags, tag) }) {
+			continue
+		}
+		counts[post.UserID]++
+	}
+
+	return topCounts(counts, query.Limit), nil
+}
+
+func (r *posts) ListCandidates(ctx context.Context, query repository.CandidateQuery) ([]types.FeedItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Post
+	for _, post := range r.posts {
+		if !post.CreatedAt.Before(query.Since) && !slices.Contains(query.Exclude, post.UserID) && query.Reach.Allows(post, false) {
+			matched = append(matched, post)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].CreatedAt.After(matched[j].CreatedAt) })
+
+	var result []types.FeedItem
+	for _, post := range page(matched, 0, query.Limit) {
+		if slices.Contains(query.Following, post.UserID) || !r.private(post.UserID) {
+			result = append(result, r.feedItem(post, query.ViewerID))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *posts) FindQuoted(ctx context.Context, query repository.QuotedQuery) ([]types.FeedItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.FeedItem
+	for _, id := range query.IDs {
+		post, ok := r.posts[id]
+		if !ok || slices.Contains(query.Exclude, post.UserID) || !query.Reach.Allows(post, true) {
+			continue
+		}
+		if slices.Contains(query.Following, post.UserID) || !r.private(post.UserID) {
+			result = append(result, r.feedItem(post, query.ViewerID))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *posts) CountQuotes(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	quotedPostID := func(post types.Post) primitive.ObjectID {
+		if post.QuotedPostID == nil {
+			return primitive.NilObjectID
+		}
+		return *post.QuotedPostID
+	}
+	return countByPost(r.posts, quotedPostID, postIDs), nil
+}
+
+func (r *posts) TrendingTags(ctx context.Context, query repository.TrendingQuery) ([]types.TrendingTag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	byName := map[string]*types.TrendingTag{}
+	for _, post := range r.posts {
+		if post.CreatedAt.Before(query.Since) || !(repository.Reach{}).Allows(post, false) {
+			continue
+		}
+		weight := math.Pow(0.5, float64(time.Since(post.CreatedAt))/float64(query.HalfLife))
+		for _, name := range post.Tags {
+			tag, ok := byName[name]
+			if !ok {
+				tag = &types.TrendingTag{Name: name}
+				byName[name] = tag
+			}
+			tag.Score += weight
+			tag.PostsCount++
+		}
+	}
+
+	trending := make([]types.TrendingTag, 0, len(byName))
+	for _, tag := range byName {
+		trending = append(trending, *tag)
+	}
+	sort.Slice(trending, func(i, j int) bool { return trending[i].Score > trending[j].Score })
+
+	return page(trending, 0, query.Limit), nil
+}
+
+func (r *posts) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	set := bson.M{"updatedAt": time.Now()}
+	for field, value := range fields {
+		set[field] = value
+	}
+	return r.modify(id, set, nil)
+}
+
+func (r *posts) IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	return r.modify(id, nil, bson.M{field: delta})
+}
+
+func (r *posts) modify(id primitive.ObjectID, set, inc bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	post, ok := r.posts[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	post, err := update(post, set, inc)
+	if err != nil {
+		return err
+	}
+	r.posts[id] = post
+
+	return nil
+}
+
+func (r *posts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.posts, id)
+
+	return nil
+}
+
+func (r *posts) ListCounters(ctx context.Context, after primitive.ObjectID, limit int64) ([]repository.Counters, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return listCounters(r.posts, after, limit, repository.LikesCount, repository.CommentsCount, repository.RepostCount, repository.QuotesCount, repository.SavesCount), nil
+}
+
+func (r *posts) SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return setCounter(r.posts, id, field, from, to)
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"time"
+)
+
+type reposts struct {
+	*store
+}
+
+func (r *reposts) Create(ctx context.Context, repost *types.Repost) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.reposts {
+		if existing.RepostedBy == repost.RepostedBy && existing.PostID == repost.PostID {
+			return repository.ErrDuplicate
+		}
+	}
+
+	repost.ID = newID(repost.ID)
+	r.reposts[repost.ID] = clone(*repost)
+
+	return nil
+}
+
+func (r *reposts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Repost, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	repost, ok := r.reposts[id]
+	if !ok {
+		return types.Repost{}, repository.ErrNotFound
+	}
+
+	return clone(repost), nil
+}
+
+func (r *reposts) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Repost, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Repost
+	for _, repost := range r.reposts {
+		if repost.RepostedBy == userID {
+			result = append(result, clone(repost))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *reposts) ListByPost(ctx context.Context, postID primitive.ObjectID, p utils.Page, excludeUsers, following []primitive.ObjectID) ([]types.RepostItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Repost
+	for _, repost := range r.reposts {
+		if repost.PostID != postID || slices.Contains(excludeUsers, repost.RepostedBy) {
+			continue
+		}
+		if slices.Contains(following, repost.RepostedBy) || !r.private(repost.RepostedBy) {
+			matched = append(matched, repost)
+		}
+	}
+
+	var result []types.RepostItem
+	for _, repost := range paginate(matched, p, repostCursor) {
+		result = append(result, r.repostItem(repost))
+	}
+
+	return result, nil
+}
+
+// repostItem returns repost together with who reposted. The caller must hold
+// the lock.
+func (s *store) repostItem(repost types.Repost) types.RepostItem {
+	userName, photoURL, handle := s.author(repost.RepostedBy)
+	return types.RepostItem{
+		ID:        repost.ID,
+		UserID:    repost.RepostedBy,
+		UserName:  userName,
+		PhotoURL:  utils.MediaURL(photoURL),
+		Handle:    handle,
+		Caption:   repost.RepostCaption,
+		CreatedAt: repost.CreatedAt,
+	}
+}
+
+func repostCursor(repost types.Repost) utils.Cursor {
+	return utils.Cursor{CreatedAt: repost.CreatedAt, ID: repost.ID}
+}
+
+func (r *reposts) UpdateCaption(ctx context.Context, id primitive.ObjectID, caption string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	repost, ok := r.reposts[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	repost.RepostCaption = caption
+	repost.UpdatedAt = time.Now()
+	r.reposts[id] = repost
+
+	return nil
+}
+
+func (r *reposts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.reposts[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.reposts, id)
+
+	return nil
+}
+
+func (r *reposts) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postID := func(repost types.Repost) primitive.ObjectID { return repost.PostID }
+	return countByPost(r.reposts, postID, postIDs), nil
+}
+
+func (r *reposts) DecayedCountByPosts(ctx context.Context, postIDs []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postID := func(repost types.Repost) primitive.ObjectID { return repost.PostID }
+	createdAt := func(repost types.Repost) time.Time { return repost.CreatedAt }
+	return decayedCountByPost(r.reposts, postID, createdAt, postIDs, halfLife), nil
+}
+
+func (r *reposts) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID := func(repost types.Repost) primitive.ObjectID { return repost.PostID }
+	return deleteByPost(r.reposts, postID, postIDs), nil
+}
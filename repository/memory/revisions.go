@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type revisions struct {
+	*store
+}
+
+func (r *revisions) Create(ctx context.Context, revision *types.PostRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	revision.ID = newID(revision.ID)
+	r.revisions[revision.ID] = clone(*revision)
+
+	return nil
+}
+
+func (r *revisions) FindByID(ctx context.Context, id primitive.ObjectID) (types.PostRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revision, ok := r.revisions[id]
+	if !ok {
+		return types.PostRevision{}, repository.ErrNotFound
+	}
+
+	return clone(revision), nil
+}
+
+func (r *revisions) ListByPost(ctx context.Context, postID primitive.ObjectID, p utils.Page) ([]types.PostRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.PostRevision
+	for _, revision := range r.revisions {
+		if revision.PostID == postID {
+			matched = append(matched, clone(revision))
+		}
+	}
+
+	return paginate(matched, p, revisionCursor), nil
+}
+
+func (r *revisions) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.revisions[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.revisions, id)
+
+	return nil
+}
+
+func (r *revisions) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID := func(revision types.PostRevision) primitive.ObjectID { return revision.PostID }
+	return deleteByPost(r.revisions, postID, postIDs), nil
+}
+
+func revisionCursor(revision types.PostRevision) utils.Cursor {
+	return utils.Cursor{CreatedAt: revision.CreatedAt, ID: revision.ID}
+}
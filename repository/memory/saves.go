@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type saves struct {
+	*store
+}
+
+func (r *saves) Create(ctx context.Context, save *types.Save) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.saves {
+		if existing.PostID == save.PostID && existing.UserID == save.UserID {
+			return repository.ErrDuplicate
+		}
+	}
+
+	save.ID = newID(save.ID)
+	r.saves[save.ID] = clone(*save)
+
+	return nil
+}
+
+func (r *saves) Exists(ctx context.Context, postID, userID primitive.ObjectID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, save := range r.saves {
+		if save.PostID == postID && save.UserID == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (r *saves) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Save, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.Save
+	for _, save := range r.saves {
+		if save.UserID == userID {
+			result = append(result, clone(save))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *saves) ListByUser(ctx context.Context, userID primitive.ObjectID, p utils.Page) ([]types.Save, error) {
+	saved, err := r.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return paginate(saved, p, saveCursor), nil
+}
+
+func (r *saves) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	postID := func(save types.Save) primitive.ObjectID { return save.PostID }
+	return countByPost(r.saves, postID, postIDs), nil
+}
+
+func (r *saves) Delete(ctx context.Context, postID, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, save := range r.saves {
+		if save.PostID == postID && save.UserID == userID {
+			delete(r.saves, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
+
+func (r *saves) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	postID := func(save types.Save) primitive.ObjectID { return save.PostID }
+	return deleteByPost(r.saves, postID, postIDs), nil
+}
+
+func saveCursor(save types.Save) utils.Cursor {
+	return utils.Cursor{CreatedAt: save.CreatedAt, ID: save.ID}
+}
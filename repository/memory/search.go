@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/text/unicode/norm"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// search scores like the MongoDB text indexes, without stemming: every word
+// of the query found in a field adds the field's weight.
+type search struct {
+	*store
+}
+
+func (r *search) Users(ctx context.Context, query repository.SearchQuery) ([]types.UserResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := words(query.Text)
+
+	var matched []types.UserResult
+	for _, user := range r.users {
+		if user.SuspendedAt != nil || slices.Contains(query.Exclude, user.ID) {
+			continue
+		}
+
+		score := 3*matches(terms, user.Handle) + 2*matches(terms, user.FirstName) + 2*matches(terms, user.LastName)
+		if score > 0 {
+			result := userResult(user)
+			result.Score = score
+			matched = append(matched, result)
+		}
+	}
+
+	return paginateByScore(matched, query.Page, func(user types.UserResult) utils.ScoreCursor {
+		return utils.ScoreCursor{Score: user.Score, ID: user.ID.Hex()}
+	}), nil
+}
+
+func (r *search) Posts(ctx context.Context, query repository.SearchQuery) ([]types.PostResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	terms := words(query.Text)
+
+	var matched []types.PostResult
+	for _, post := range r.posts {
+		if slices.Contains(query.Exclude, post.UserID) || !query.Reach.Allows(post, false) {
+			continue
+		}
+		if !slices.Contains(query.Following, post.UserID) && r.private(post.UserID) {
+			continue
+		}
+
+		score := matches(terms, post.Caption) + 5*matches(terms, strings.Join(post.Tags, " "))
+		if score > 0 {
+			matched = append(matched, types.PostResult{FeedItem: r.feedItem(post, query.ViewerID), Score: score})
+		}
+	}
+
+	return paginateByScore(matched, query.Page, func(post types.PostResult) utils.ScoreCursor {
+		return utils.ScoreCursor{Score: post.Score, ID: post.Post.ID.Hex()}
+	}), nil
+}
+
+func (r *search) Tags(ctx context.Context, query repository.SearchQuery) ([]types.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Tag
+	for _, tag := range r.tags {
+		if tag.PostsCount > 0 && strings.HasPrefix(tag.Name, query.Text) {
+			matched = append(matched, tag)
+		}
+	}
+
+	return paginateByScore(matched, query.Page, func(tag types.Tag) utils.ScoreCursor {
+		return utils.ScoreCursor{Score: float64(tag.PostsCount), ID: tag.Name}
+	}), nil
+}
+
+func (r *search) Handles(ctx context.Context, prefix string, exclude []primitive.ObjectID, limit int64) ([]types.UserResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.UserResult
+	for _, user := range r.users {
+		if user.SuspendedAt == nil && !slices.Contains(exclude, user.ID) && strings.HasPrefix(user.Handle, prefix) {
+			matched = append(matched, userResult(user))
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].FollowersCount != matched[j].FollowersCount {
+			return matched[i].FollowersCount > matched[j].FollowersCount
+		}
+		return matched[i].ID.Hex() < matched[j].ID.Hex()
+	})
+
+	return page(matched, 0, limit), nil
+}
+
+func userResult(user types.User) types.UserResult {
+	return types.UserResult{
+		ID:             user.ID,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Handle:         user.Handle,
+		PhotoURL:       user.PhotoURL,
+		Bio:            user.Bio,
+		FollowersCount: user.FollowersCount,
+	}
+}
+
+// words splits text into lower case words without diacritics, the way the
+// text indexes tokenize it.
+func words(text string) []string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(text) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+
+	return strings.FieldsFunc(b.String(), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// matches returns how many of terms are words of text.
+func matches(terms []string, text string) float64 {
+	fieldWords := words(text)
+
+	var count float64
+	for _, term := range terms {
+		if slices.Contains(fieldWords, term) {
+			count++
+		}
+	}
+	return count
+}
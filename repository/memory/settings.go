@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type settings struct {
+	*store
+}
+
+func (r *settings) Create(ctx context.Context, settings *types.Settings) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	settings.ID = newID(settings.ID)
+	r.settings[settings.ID] = clone(*settings)
+
+	return nil
+}
+
+func (r *settings) FindByUser(ctx context.Context, userID primitive.ObjectID) (types.Settings, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, settings := range r.settings {
+		if settings.UserID == userID {
+			return clone(settings), nil
+		}
+	}
+
+	return types.Settings{}, repository.ErrNotFound
+}
+
+func (r *settings) Set(ctx context.Context, userID primitive.ObjectID, fields bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current := types.DefaultSettings(userID)
+	current.ID = primitive.NewObjectID()
+	for id, settings := range r.settings {
+		if settings.UserID == userID {
+			current = r.settings[id]
+			break
+		}
+	}
+
+	updated, err := update(current, fields, nil)
+	if err != nil {
+		return err
+	}
+	r.settings[updated.ID] = updated
+
+	return nil
+}
+
+func (r *settings) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, settings := range r.settings {
+		if settings.UserID == userID {
+			delete(r.settings, id)
+			return nil
+		}
+	}
+
+	return repository.ErrNotFound
+}
@@ -0,0 +1,57 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type suggestions struct {
+	*store
+}
+
+func (r *suggestions) Dismiss(ctx context.Context, userID, dismissedID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, dismissal := range r.dismissals {
+		if dismissal.UserID == userID && dismissal.DismissedID == dismissedID {
+			return nil
+		}
+	}
+
+	id := primitive.NewObjectID()
+	r.dismissals[id] = types.Dismissal{ID: id, UserID: userID, DismissedID: dismissedID, CreatedAt: time.Now()}
+
+	return nil
+}
+
+func (r *suggestions) DismissedIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	for _, dismissal := range r.dismissals {
+		if dismissal.UserID == userID {
+			ids = append(ids, dismissal.DismissedID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *suggestions) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, dismissal := range r.dismissals {
+		if dismissal.UserID == userID || dismissal.DismissedID == userID {
+			delete(r.dismissals, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
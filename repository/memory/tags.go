@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"sort"
+	"strings"
+	"time"
+)
+
+type tags struct {
+	*store
+}
+
+func (r *tags) Increment(ctx context.Context, names []string, delta int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range names {
+		tag, ok := r.tags[name]
+		if !ok && delta < 0 {
+			continue
+		}
+		tag.Name = name
+		tag.PostsCount += int64(delta)
+		if delta > 0 {
+			tag.LastUsedAt = time.Now()
+		}
+		r.tags[name] = tag
+	}
+
+	return nil
+}
+
+func (r *tags) Search(ctx context.Context, prefix string, limit int64) ([]types.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []types.Tag
+	for _, tag := range r.tags {
+		if tag.PostsCount > 0 && strings.HasPrefix(tag.Name, prefix) {
+			matched = append(matched, tag)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].PostsCount != matched[j].PostsCount {
+			return matched[i].PostsCount > matched[j].PostsCount
+		}
+		return matched[i].Name < matched[j].Name
+	})
+
+	return page(matched, 0, limit), nil
+}
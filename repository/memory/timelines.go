@@ -0,0 +1,222 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+)
+
+type timelines struct {
+	*store
+}
+
+func (r *timelines) List(ctx context.Context, query repository.TimelineQuery, page utils.Page) ([]types.TimelineItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.timeline(r.entriesOf(query.Authors), query, page), nil
+}
+
+func (r *timelines) ListStored(ctx context.Context, query repository.TimelineQuery, page utils.Page) ([]types.TimelineItem, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []types.TimelineEntry
+	for _, entry := range r.timelines {
+		if entry.OwnerID == query.ViewerID && slices.Contains(query.Authors, entry.AuthorID) {
+			entry.ID = entryID(entry)
+			entries = append(entries, entry)
+		}
+	}
+
+	return r.timeline(entries, query, page), nil
+}
+
+func (r *timelines) Entries(ctx context.Context, authors []primitive.ObjectID, limit int64) ([]types.TimelineEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := paginate(r.entriesOf(authors), utils.Page{Limit: limit}, entryCursor)
+	entries = page(entries, 0, limit)
+	for i := range entries {
+		entries[i].ID = primitive.NilObjectID
+	}
+
+	return entries, nil
+}
+
+func (r *timelines) Stored(ctx context.Context, ownerIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var ids []primitive.ObjectID
+	for _, entry := range r.timelines {
+		if slices.Contains(ownerIDs, entry.OwnerID) && !slices.Contains(ids, entry.OwnerID) {
+			ids = append(ids, entry.OwnerID)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *timelines) Store(ctx context.Context, entries []types.TimelineEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range entries {
+		if r.hasEntry(entry) {
+			continue
+		}
+		entry.ID = newID(entry.ID)
+		r.timelines[entry.ID] = entry
+	}
+
+	return nil
+}
+
+// hasEntry reports whether the owner's timeline already holds entry, which the
+// timeline_unique index rejects. The caller must hold the lock.
+func (s *store) hasEntry(entry types.TimelineEntry) bool {
+	for _, existing := range s.timelines {
+		if existing.OwnerID == entry.OwnerID && existing.PostID == entry.PostID && existing.RepostID == entry.RepostID {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *timelines) Clear(ctx context.Context, ownerID primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, entry := range r.timelines {
+		if entry.OwnerID == ownerID {
+			delete(r.timelines, id)
+		}
+	}
+
+	return nil
+}
+
+func (r *timelines) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for id, entry := range r.timelines {
+		if entry.OwnerID == userID || entry.AuthorID == userID || entry.PostAuthorID == userID {
+			delete(r.timelines, id)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
+
+// entriesOf returns the posts and reposts of authors as timeline entries whose
+// ID is the one of the post or repost. Reposts of deleted posts are left out.
+// The caller must hold the lock.
+func (s *store) entriesOf(authors []primitive.ObjectID) []types.TimelineEntry {
+	var entries []types.TimelineEntry
+	for _, post := range s.posts {
+		if slices.Contains(authors, post.UserID) {
+			entries = append(entries, types.TimelineEntry{
+				ID:           post.ID,
+				PostID:       post.ID,
+				AuthorID:     post.UserID,
+				PostAuthorID: post.UserID,
+				CreatedAt:    post.CreatedAt,
+			})
+		}
+	}
+
+	for _, repost := range s.reposts {
+		post, ok := s.posts[repost.PostID]
+		if !ok || !slices.Contains(authors, repost.RepostedBy) {
+			continue
+		}
+		entries = append(entries, types.TimelineEntry{
+			ID:           repost.ID,
+			PostID:       post.ID,
+			RepostID:     repost.ID,
+			AuthorID:     repost.RepostedBy,
+			PostAuthorID: post.UserID,
+			CreatedAt:    repost.CreatedAt,
+		})
+	}
+
+	return entries
+}
+
+// timeline selects page of the entries query lets through and shapes them
+// into items. The caller must hold the lock.
+func (s *store) timeline(entries []types.TimelineEntry, query repository.TimelineQuery, p utils.Page) []types.TimelineItem {
+	var visible []types.TimelineEntry
+	for _, entry := range entries {
+		if s.visible(entry, query) {
+			visible = append(visible, entry)
+		}
+	}
+
+	var result []types.TimelineItem
+	for _, entry := range paginate(visible, p, entryCursor) {
+		if item, ok := s.timelineItem(entry, query.ViewerID); ok {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+// visible reports whether query lets entry through, like the excluding and
+// visibleTo pipeline fragments do. The caller must hold the lock.
+func (s *store) visible(entry types.TimelineEntry, query repository.TimelineQuery) bool {
+	if slices.Contains(query.ExcludePosts, entry.PostAuthorID) {
+		return false
+	}
+	if post, ok := s.posts[entry.PostID]; ok && !query.Reach.Allows(post, false) {
+		return false
+	}
+	if !entry.RepostID.IsZero() && slices.Contains(query.ExcludeReposts, entry.AuthorID) {
+		return false
+	}
+	return slices.Contains(query.Following, entry.PostAuthorID) || !s.private(entry.PostAuthorID)
+}
+
+// timelineItem returns entry as an item, or false when its post or repost has
+// been deleted. The caller must hold the lock.
+func (s *store) timelineItem(entry types.TimelineEntry, viewerID primitive.ObjectID) (types.TimelineItem, bool) {
+	post, ok := s.posts[entry.PostID]
+	if !ok {
+		return types.TimelineItem{}, false
+	}
+
+	item := types.TimelineItem{ID: entry.ID, FeedItem: s.feedItem(post, viewerID), CreatedAt: entry.CreatedAt}
+	if entry.RepostID.IsZero() {
+		return item, true
+	}
+
+	repost, ok := s.reposts[entry.RepostID]
+	if !ok {
+		return types.TimelineItem{}, false
+	}
+	repostItem := s.repostItem(repost)
+	item.Repost = &repostItem
+
+	return item, true
+}
+
+// entryID returns the ID of the post or repost of entry.
+func entryID(entry types.TimelineEntry) primitive.ObjectID {
+	if entry.RepostID.IsZero() {
+		return entry.PostID
+	}
+	return entry.RepostID
+}
+
+func entryCursor(entry types.TimelineEntry) utils.Cursor {
+	return utils.Cursor{CreatedAt: entry.CreatedAt, ID: entry.ID}
+}
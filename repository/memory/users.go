@@ -0,0 +1,165 @@
+package memory
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+)
+
+type users struct {
+	*store
+}
+
+func (r *users) Create(ctx context.Context, user *types.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.taken(user.ID, user.Email, user.Handle) {
+		return repository.ErrDuplicate
+	}
+
+	user.ID = newID(user.ID)
+	r.users[user.ID] = clone(*user)
+
+	return nil
+}
+
+func (r *users) FindByID(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return types.User{}, repository.ErrNotFound
+	}
+
+	return clone(user), nil
+}
+
+func (r *users) FindProfile(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	user, err := r.FindByID(ctx, id)
+	user.Password = ""
+	user.Email = ""
+	return user, err
+}
+
+func (r *users) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]types.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			result = append(result, clone(user))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *users) FindByEmail(ctx context.Context, email string) (types.User, error) {
+	return r.findBy(func(user types.User) bool { return user.Email == email })
+}
+
+func (r *users) FindByHandle(ctx context.Context, handle string) (types.User, error) {
+	return r.findBy(func(user types.User) bool { return user.Handle == handle })
+}
+
+func (r *users) FindByHandles(ctx context.Context, handles []string) ([]types.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []types.User
+	for _, user := range r.users {
+		if slices.Contains(handles, user.Handle) {
+			result = append(result, clone(user))
+		}
+	}
+
+	return result, nil
+}
+
+func (r *users) findBy(match func(types.User) bool) (types.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			return clone(user), nil
+		}
+	}
+
+	return types.User{}, repository.ErrNotFound
+}
+
+func (r *users) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	return r.modify(id, fields, nil)
+}
+
+func (r *users) IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	return r.modify(id, nil, bson.M{field: delta})
+}
+
+func (r *users) modify(id primitive.ObjectID, set, inc bson.M) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+
+	user, err := update(user, set, inc)
+	if err != nil {
+		return err
+	}
+	if r.taken(id, user.Email, user.Handle) {
+		return repository.ErrDuplicate
+	}
+	r.users[id] = user
+
+	return nil
+}
+
+// taken mirrors the unique email and handle indexes. The caller must hold the lock.
+func (r *users) taken(id primitive.ObjectID, email, handle string) bool {
+	for _, user := range r.users {
+		if user.ID == id {
+			continue
+		}
+		if user.Email == email || (handle != "" && user.Handle == handle) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r *users) ListCounters(ctx context.Context, after primitive.ObjectID, limit int64) ([]repository.Counters, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return listCounters(r.users, after, limit, repository.FollowersCount, repository.FollowingCount), nil
+}
+
+func (r *users) SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return setCounter(r.users, id, field, from, to)
+}
+
+func (r *users) Delete(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.users, id)
+
+	return nil
+}
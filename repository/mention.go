@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RenameMention replaces the mentions of userID in text with @handle and moves
+// the ranges of the mentions after them. It reports whether anything changed.
+func RenameMention(text string, mentions []types.Mention, userID primitive.ObjectID, handle string) (string, []types.Mention, bool) {
+	runes := []rune(text)
+	replacement := []rune("@" + handle)
+
+	var result []rune
+	renamed := make([]types.Mention, len(mentions))
+	changed, last, shift := false, 0, 0
+	for i, mention := range mentions {
+		renamed[i] = mention
+		if mention.UserID != userID || mention.Start < last || mention.End > len(runes) {
+			renamed[i].Start += shift
+			renamed[i].End += shift
+			continue
+		}
+
+		result = append(append(result, runes[last:mention.Start]...), replacement...)
+		last = mention.End
+		renamed[i].Handle = handle
+		renamed[i].Start = mention.Start + shift
+		shift += len(replacement) - (mention.End - mention.Start)
+		renamed[i].End = mention.End + shift
+		changed = changed || mention.Handle != handle
+	}
+	if !changed {
+		return text, mentions, false
+	}
+
+	return string(append(result, runes[last:]...)), renamed, true
+}
@@ -0,0 +1,90 @@
+package repository_test
+
+import (
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"slices"
+	"testing"
+)
+
+func TestRenameMention(t *testing.T) {
+	alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+
+	tests := []struct {
+		name         string
+		text         string
+		mentions     []types.Mention
+		handle       string
+		want         string
+		wantMentions []types.Mention
+		wantChanged  bool
+	}{
+		{
+			name:         "shorter handle",
+			text:         "hi @alice and @bob!",
+			mentions:     []types.Mention{{UserID: alice, Handle: "alice", Start: 3, End: 9}, {UserID: bob, Handle: "bob", Start: 14, End: 18}},
+			handle:       "al",
+			want:         "hi @al and @bob!",
+			wantMentions: []types.Mention{{UserID: alice, Handle: "al", Start: 3, End: 6}, {UserID: bob, Handle: "bob", Start: 11, End: 15}},
+			wantChanged:  true,
+		},
+		{
+			name:         "longer handle",
+			text:         "@alice, @bob and @alice",
+			mentions:     []types.Mention{{UserID: alice, Handle: "alice", Start: 0, End: 6}, {UserID: bob, Handle: "bob", Start: 8, End: 12}, {UserID: alice, Handle: "alice", Start: 17, End: 23}},
+			handle:       "alice_wonder",
+			want:         "@alice_wonder, @bob and @alice_wonder",
+			wantMentions: []types.Mention{{UserID: alice, Handle: "alice_wonder", Start: 0, End: 13}, {UserID: bob, Handle: "bob", Start: 15, End: 19}, {UserID: alice, Handle: "alice_wonder", Start: 24, End: 37}},
+			wantChanged:  true,
+		},
+		{
+			name:         "multibyte text",
+			text:         "☕ @zoë @bob",
+			mentions:     []types.Mention{{UserID: alice, Handle: "zoë", Start: 2, End: 6}, {UserID: bob, Handle: "bob", Start: 7, End: 11}},
+			handle:       "zoé_b",
+			want:         "☕ @zoé_b @bob",
+			wantMentions: []types.Mention{{UserID: alice, Handle: "zoé_b", Start: 2, End: 8}, {UserID: bob, Handle: "bob", Start: 9, End: 13}},
+			wantChanged:  true,
+		},
+		{
+			name:         "same handle",
+			text:         "hi @alice",
+			mentions:     []types.Mention{{UserID: alice, Handle: "alice", Start: 3, End: 9}},
+			handle:       "alice",
+			want:         "hi @alice",
+			wantMentions: []types.Mention{{UserID: alice, Handle: "alice", Start: 3, End: 9}},
+		},
+		{
+			name:         "not mentioned",
+			text:         "hi @bob",
+			mentions:     []types.Mention{{UserID: bob, Handle: "bob", Start: 3, End: 7}},
+			handle:       "al",
+			want:         "hi @bob",
+			wantMentions: []types.Mention{{UserID: bob, Handle: "bob", Start: 3, End: 7}},
+		},
+		{
+			name:         "range past the text",
+			text:         "hi",
+			mentions:     []types.Mention{{UserID: alice, Handle: "alice", Start: 3, End: 9}},
+			handle:       "al",
+			want:         "hi",
+			wantMentions: []types.Mention{{UserID: alice, Handle: "alice", Start: 3, End: 9}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, mentions, changed := repository.RenameMention(tt.text, tt.mentions, alice, tt.handle)
+			if got != tt.want {
+				t.Errorf("text = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(mentions, tt.wantMentions) {
+				t.Errorf("mentions = %+v, want %+v", mentions, tt.wantMentions)
+			}
+			if changed != tt.wantChanged {
+				t.Errorf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type blocks struct {
+	collection *mongo.Collection
+}
+
+func (r *blocks) Create(ctx context.Context, block *types.Block) error {
+	res, err := r.collection.InsertOne(ctx, block)
+	if err != nil {
+		return translate(err)
+	}
+	block.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *blocks) Exists(ctx context.Context, userID, blockedID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"userID": userID, "blockedID": blockedID})
+	return count > 0, err
+}
+
+func (r *blocks) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Block, error) {
+	return findAll[types.Block](ctx, r.collection, bson.M{"userID": userID})
+}
+
+func (r *blocks) BlockerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	blocks, err := findAll[types.Block](ctx, r.collection, bson.M{"blockedID": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(blocks))
+	for _, block := range blocks {
+		ids = append(ids, block.UserID)
+	}
+
+	return ids, nil
+}
+
+func (r *blocks) Delete(ctx context.Context, userID, blockedID primitive.ObjectID) error {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"userID": userID, "blockedID": blockedID})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// closeFriends keeps one document per user on a close friends list. The
+// close_friend_unique index on (userID, friendID) rejects listing a user
+// twice.
+type closeFriends struct {
+	collection *mongo.Collection
+}
+
+func (r *closeFriends) Add(ctx context.Context, closeFriend *types.CloseFriend) error {
+	res, err := r.collection.InsertOne(ctx, closeFriend)
+	if err != nil {
+		return translate(err)
+	}
+	closeFriend.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *closeFriends) Remove(ctx context.Context, userID, friendID primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"userID": userID, "friendID": friendID})
+}
+
+func (r *closeFriends) FriendIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetSort(bson.D{{"createdAt", -1}, {"_id", -1}})
+	return r.ids(ctx, bson.M{"userID": userID}, opts, func(closeFriend types.CloseFriend) primitive.ObjectID {
+		return closeFriend.FriendID
+	})
+}
+
+func (r *closeFriends) ListedBy(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.ids(ctx, bson.M{"friendID": userID}, options.Find(), func(closeFriend types.CloseFriend) primitive.ObjectID {
+		return closeFriend.UserID
+	})
+}
+
+func (r *closeFriends) ids(ctx context.Context, filter bson.M, opts *options.FindOptions, id func(types.CloseFriend) primitive.ObjectID) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx, filter, opts.SetProjection(bson.M{"userID": 1, "friendID": 1}))
+	if err != nil {
+		return nil, err
+	}
+
+	var closeFriends []types.CloseFriend
+	if err := cursor.All(ctx, &closeFriends); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(closeFriends))
+	for _, closeFriend := range closeFriends {
+		ids = append(ids, id(closeFriend))
+	}
+
+	return ids, nil
+}
+
+func (r *closeFriends) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"userID": userID},
+		bson.M{"friendID": userID},
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,182 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type collections struct {
+	collection *mongo.Collection
+	items      *mongo.Collection
+}
+
+func (r *collections) Create(ctx context.Context, collection *types.Collection) error {
+	res, err := r.collection.InsertOne(ctx, collection)
+	if err != nil {
+		return translate(err)
+	}
+	collection.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *collections) FindByID(ctx context.Context, id primitive.ObjectID) (types.Collection, error) {
+	var collection types.Collection
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &collection)
+	return collection, err
+}
+
+func (r *collections) ListByUser(ctx context.Context, userID primitive.ObjectID, publicOnly bool) ([]types.Collection, error) {
+	filter := bson.M{"userID": userID}
+	if publicOnly {
+		filter["visibility"] = types.VisibilityPublic
+	}
+
+	return r.find(ctx, filter)
+}
+
+func (r *collections) ListContaining(ctx context.Context, userID, postID primitive.ObjectID) ([]types.Collection, error) {
+	items, err := findAll[types.CollectionItem](ctx, r.items, bson.M{"userID": userID, "postID": postID})
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.CollectionID)
+	}
+
+	return r.find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+// find returns the collections matching filter, newest first.
+func (r *collections) find(ctx context.Context, filter bson.M) ([]types.Collection, error) {
+	opts := options.Find().SetSort(bson.D{{"createdAt", -1}, {"_id", -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.Collection
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *collections) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	update := bson.M{"$set": fields, "$currentDate": bson.M{"updatedAt": true}}
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)
+}
+
+func (r *collections) Delete(ctx context.Context, id primitive.ObjectID) error {
+	if err := deleteOne(ctx, r.collection, bson.M{"_id": id}); err != nil {
+		return err
+	}
+
+	_, err := r.items.DeleteMany(ctx, bson.M{"collectionID": id})
+	return err
+}
+
+func (r *collections) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	if _, err := r.items.DeleteMany(ctx, bson.M{"userID": userID}); err != nil {
+		return 0, err
+	}
+
+	res, err := r.collection.DeleteMany(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+func (r *collections) AddItem(ctx context.Context, item *types.CollectionItem) error {
+	var first types.CollectionItem
+	opts := options.FindOne().SetSort(bson.D{{"position", 1}})
+	err := r.items.FindOne(ctx, bson.M{"collectionID": item.CollectionID}, opts).Decode(&first)
+	switch {
+	case err == nil:
+		item.Position = first.Position - 1
+	case errors.Is(err, mongo.ErrNoDocuments):
+		item.Position = 0
+	default:
+		return err
+	}
+
+	res, err := r.items.InsertOne(ctx, item)
+	if err != nil {
+		return translate(err)
+	}
+	item.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *collections) RemoveItem(ctx context.Context, collectionID, postID primitive.ObjectID) error {
+	return deleteOne(ctx, r.items, bson.M{"collectionID": collectionID, "postID": postID})
+}
+
+func (r *collections) ListItems(ctx context.Context, collectionID primitive.ObjectID, skip, limit int64) ([]types.CollectionItem, error) {
+	opts := options.Find().SetSort(bson.D{{"position", 1}, {"_id", -1}}).SetSkip(skip).SetLimit(limit)
+	cursor, err := r.items.Find(ctx, bson.M{"collectionID": collectionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.CollectionItem
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *collections) ItemPostIDs(ctx context.Context, collectionID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	items, err := r.ListItems(ctx, collectionID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.PostID)
+	}
+
+	return ids, nil
+}
+
+func (r *collections) Reorder(ctx context.Context, collectionID primitive.ObjectID, postIDs []primitive.ObjectID) error {
+	if len(postIDs) == 0 {
+		return nil
+	}
+
+	models := make([]mongo.WriteModel, 0, len(postIDs))
+	for i, postID := range postIDs {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"collectionID": collectionID, "postID": postID}).
+			SetUpdate(bson.M{"$set": bson.M{"position": int64(i)}}))
+	}
+
+	_, err := r.items.BulkWrite(ctx, models)
+	return err
+}
+
+func (r *collections) RemoveSaved(ctx context.Context, userID, postID primitive.ObjectID) (int64, error) {
+	res, err := r.items.DeleteMany(ctx, bson.M{"userID": userID, "postID": postID})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+func (r *collections) DeleteItemsByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteByPosts(ctx, r.items, postIDs)
+}
@@ -0,0 +1,69 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type comments struct {
+	collection *mongo.Collection
+}
+
+func (r *comments) Create(ctx context.Context, comment *types.Comment) error {
+	res, err := r.collection.InsertOne(ctx, comment)
+	if err != nil {
+		return translate(err)
+	}
+	comment.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *comments) FindByID(ctx context.Context, id primitive.ObjectID) (types.Comment, error) {
+	var comment types.Comment
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &comment)
+	return comment, err
+}
+
+func (r *comments) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Comment, error) {
+	return findAll[types.Comment](ctx, r.collection, bson.M{"userID": userID})
+}
+
+func (r *comments) ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page, excludeUsers []primitive.ObjectID) ([]types.CommentItem, error) {
+	match := bson.D{{"postID", postID}}
+	if len(excludeUsers) > 0 {
+		match = append(match, bson.E{Key: "userID", Value: bson.D{{"$nin", excludeUsers}}})
+	}
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		Paginate(page).
+		Apply(utils.WithAuthor("userID")).
+		Project(bson.D{
+			{"comment", "$$ROOT"},
+			{"userName", 1},
+			{"photoURL", 1},
+		}).Build()
+
+	return aggregate[types.CommentItem](ctx, r.collection, pipeline)
+}
+
+func (r *comments) UpdateContent(ctx context.Context, id primitive.ObjectID, content string, mentions []types.Mention) error {
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, bson.M{"$set": bson.M{"content": content, "mentions": mentions}})
+}
+
+func (r *comments) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id})
+}
+
+func (r *comments) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countBy(ctx, r.collection, "postID", postIDs)
+}
+
+func (r *comments) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteByPosts(ctx, r.collection, postIDs)
+}
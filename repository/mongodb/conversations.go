@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type conversations struct {
+	collection *mongo.Collection
+}
+
+func (r *conversations) Create(ctx context.Context, conversation *types.Conversation) error {
+	res, err := r.collection.InsertOne(ctx, conversation)
+	if err != nil {
+		return translate(err)
+	}
+	conversation.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *conversations) FindByID(ctx context.Context, id primitive.ObjectID) (types.Conversation, error) {
+	var conversation types.Conversation
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &conversation)
+	return conversation, err
+}
+
+func (r *conversations) FindDirect(ctx context.Context, userID, otherID primitive.ObjectID) (types.Conversation, error) {
+	filter := bson.M{
+		"isGroup": false,
+		"participantsIds": bson.M{
+			"$all": []primitive.ObjectID{userID, otherID},
+		},
+	}
+
+	var conversation types.Conversation
+	err := findOne(ctx, r.collection, filter, &conversation)
+	return conversation, err
+}
+
+func (r *conversations) ListByParticipant(ctx context.Context, userID primitive.ObjectID) ([]types.Conversation, error) {
+	return findAll[types.Conversation](ctx, r.collection, bson.M{"participantsIds": userID})
+}
+
+func (r *conversations) SetLastMessage(ctx context.Context, id primitive.ObjectID, message types.Message) error {
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastMessage": message}})
+}
+
+func (r *conversations) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
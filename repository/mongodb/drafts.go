@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type drafts struct {
+	collection *mongo.Collection
+}
+
+func (r *drafts) Create(ctx context.Context, draft *types.Draft) error {
+	res, err := r.collection.InsertOne(ctx, draft)
+	if err != nil {
+		return translate(err)
+	}
+	draft.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *drafts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Draft, error) {
+	var draft types.Draft
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &draft)
+	return draft, err
+}
+
+func (r *drafts) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Draft, error) {
+	return findAll[types.Draft](ctx, r.collection, bson.M{"userID": userID})
+}
+
+func (r *drafts) ListByUser(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.Draft, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"userID", userID}}).
+		Paginate(page).
+		Build()
+
+	return aggregate[types.Draft](ctx, r.collection, pipeline)
+}
+
+func (r *drafts) ListDue(ctx context.Context, now time.Time, limit int64) ([]types.Draft, error) {
+	opts := options.Find().SetSort(bson.D{{"publishAt", 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"publishAt": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.Draft
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (r *drafts) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	update := bson.M{"$set": fields, "$currentDate": bson.M{"updatedAt": true}}
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)
+}
+
+func (r *drafts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id})
+}
+
+func (r *drafts) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"userID": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
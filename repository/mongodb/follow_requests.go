@@ -0,0 +1,85 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// followRequests relies on the follow_request_unique index on (requesterID,
+// targetID) to reject asking twice.
+type followRequests struct {
+	collection *mongo.Collection
+}
+
+func (r *followRequests) Create(ctx context.Context, request *types.FollowRequest) error {
+	res, err := r.collection.InsertOne(ctx, request)
+	if err != nil {
+		return translate(err)
+	}
+	request.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *followRequests) FindByID(ctx context.Context, id primitive.ObjectID) (types.FollowRequest, error) {
+	var request types.FollowRequest
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &request)
+	return request, err
+}
+
+func (r *followRequests) FindIncoming(ctx context.Context, targetID primitive.ObjectID) ([]types.FollowRequest, error) {
+	return findAll[types.FollowRequest](ctx, r.collection, bson.M{"targetID": targetID})
+}
+
+func (r *followRequests) FindOutgoing(ctx context.Context, requesterID primitive.ObjectID) ([]types.FollowRequest, error) {
+	return findAll[types.FollowRequest](ctx, r.collection, bson.M{"requesterID": requesterID})
+}
+
+func (r *followRequests) ListIncoming(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error) {
+	return r.list(ctx, "targetID", userID, "requesterID", page)
+}
+
+func (r *followRequests) ListOutgoing(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error) {
+	return r.list(ctx, "requesterID", userID, "targetID", page)
+}
+
+// list pages through the requests whose field is userID and joins the users at
+// the other end of them.
+func (r *followRequests) list(ctx context.Context, field string, userID primitive.ObjectID, other string, page utils.Page) ([]types.FollowRequestItem, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{field, userID}}).
+		Paginate(page).
+		Lookup("users", other, "_id", "user").
+		Unwind("$user", false).
+		Project(bson.D{
+			{"userID", "$user._id"},
+			{"firstName", "$user.firstName"},
+			{"lastName", "$user.lastName"},
+			{"handle", "$user.handle"},
+			{"photoURL", "$user.photoURL"},
+			{"createdAt", 1},
+		}).
+		Build()
+
+	return aggregate[types.FollowRequestItem](ctx, r.collection, pipeline)
+}
+
+func (r *followRequests) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id})
+}
+
+func (r *followRequests) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"requesterID": userID},
+		bson.M{"targetID": userID},
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,157 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// follows keeps one document per follow. The follow_unique index on
+// (followerID, followedID) rejects following a user twice.
+type follows struct {
+	collection *mongo.Collection
+}
+
+func (r *follows) Follow(ctx context.Context, followerID, followingID primitive.ObjectID) error {
+	_, err := r.collection.InsertOne(ctx, types.Follow{
+		FollowerID:  followerID,
+		FollowingID: followingID,
+		CreatedAt:   time.Now(),
+	})
+	return translate(err)
+}
+
+func (r *follows) Unfollow(ctx context.Context, followerID, followingID primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"followerID": followerID, "followedID": followingID})
+}
+
+func (r *follows) IsFollowing(ctx context.Context, followerID, followingID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"followerID": followerID, "followedID": followingID})
+	return count > 0, err
+}
+
+func (r *follows) FollowingIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.ids(ctx, bson.M{"followerID": userID}, func(follow types.Follow) primitive.ObjectID {
+		return follow.FollowingID
+	})
+}
+
+func (r *follows) FollowerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	return r.ids(ctx, bson.M{"followedID": userID}, func(follow types.Follow) primitive.ObjectID {
+		return follow.FollowerID
+	})
+}
+
+func (r *follows) ids(ctx context.Context, filter bson.M, id func(types.Follow) primitive.ObjectID) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"followerID": 1, "followedID": 1})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var follows []types.Follow
+	if err := cursor.All(ctx, &follows); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(follows))
+	for _, follow := range follows {
+		ids = append(ids, id(follow))
+	}
+
+	return ids, nil
+}
+
+func (r *follows) ListFollowers(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error) {
+	return r.list(ctx, "followedID", userID, "followerID", viewerID, page)
+}
+
+func (r *follows) ListFollowing(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error) {
+	return r.list(ctx, "followerID", userID, "followedID", viewerID, page)
+}
+
+// list pages through the follows whose field is userID and joins the users at
+// the other end of them. Follows of deleted users are left out.
+func (r *follows) list(ctx context.Context, field string, userID primitive.ObjectID, other string, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{field, userID}}).
+		Paginate(page).
+		Lookup("users", other, "_id", "user").
+		Unwind("$user", false).
+		Project(bson.D{
+			{"_id", "$user._id"},
+			{"firstName", "$user.firstName"},
+			{"lastName", "$user.lastName"},
+			{"handle", "$user.handle"},
+			{"photoURL", "$user.photoURL"},
+			{"bio", "$user.bio"},
+			{"followedAt", "$createdAt"},
+			{"followID", "$_id"},
+		}).
+		Apply(relationTo(viewerID)).
+		Build()
+
+	return aggregate[types.FollowItem](ctx, r.collection, pipeline)
+}
+
+// relationTo adds followsYou and youFollow, whether the user follows viewerID and
+// the other way round. It does nothing for anonymous viewers.
+func relationTo(viewerID primitive.ObjectID) utils.Fragment {
+	return func(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+		if viewerID.IsZero() {
+			return pb
+		}
+
+		edge := func(from, to interface{}) *utils.PipelineBuilder {
+			return utils.NewPipeline().
+				Match(bson.D{{"$expr", bson.D{{"$and", bson.A{
+					bson.D{{"$eq", bson.A{"$followerID", from}}},
+					bson.D{{"$eq", bson.A{"$followedID", to}}},
+				}}}}}).
+				Limit(1).
+				Project(bson.D{{"_id", 1}})
+		}
+
+		return pb.
+			LookupPipeline("follows", bson.D{{"userID", "$_id"}}, edge("$$userID", viewerID), "followsViewer").
+			LookupPipeline("follows", bson.D{{"userID", "$_id"}}, edge(viewerID, "$$userID"), "followedByViewer").
+			AddFields(bson.D{
+				{"followsYou", bson.D{{"$gt", bson.A{bson.D{{"$size", "$followsViewer"}}, 0}}}},
+				{"youFollow", bson.D{{"$gt", bson.A{bson.D{{"$size", "$followedByViewer"}}, 0}}}},
+			}).
+			Unset("followsViewer", "followedByViewer")
+	}
+}
+
+func (r *follows) CountFollowedBy(ctx context.Context, followerIDs, exclude []primitive.ObjectID, limit int64) (map[primitive.ObjectID]int64, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{
+			{"followerID", bson.D{{"$in", followerIDs}}},
+			{"followedID", bson.D{{"$nin", exclude}}},
+		}).
+		Group("$followedID", bson.D{{"count", bson.D{{"$sum", 1}}}}).
+		Sort("count", -1).
+		Limit(limit).
+		Build()
+
+	return countGroups[primitive.ObjectID](ctx, r.collection, pipeline)
+}
+
+func (r *follows) Counts(ctx context.Context, userIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, map[primitive.ObjectID]int64, error) {
+	followers, err := countBy(ctx, r.collection, "followedID", userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	following, err := countBy(ctx, r.collection, "followerID", userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return followers, following, nil
+}
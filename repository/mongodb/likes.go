@@ -0,0 +1,91 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type likes struct {
+	collection *mongo.Collection
+}
+
+func (r *likes) Create(ctx context.Context, like *types.Like) error {
+	res, err := r.collection.InsertOne(ctx, like)
+	if err != nil {
+		return translate(err)
+	}
+	like.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *likes) Exists(ctx context.Context, postID, userID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"postID": postID, "userID": userID})
+	return count > 0, err
+}
+
+func (r *likes) LikedTags(ctx context.Context, userID primitive.ObjectID, recent int64) (map[string]int64, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"userID", userID}}).
+		Sort("createdAt", -1).
+		Limit(recent).
+		Lookup("posts", "postID", "_id", "post").
+		Unwind("$post", false).
+		Unwind("$post.tags", false).
+		Group("$post.tags", bson.D{{"count", bson.D{{"$sum", 1}}}}).
+		Build()
+
+	return countGroups[string](ctx, r.collection, pipeline)
+}
+
+func (r *likes) LikedAuthors(ctx context.Context, userID primitive.ObjectID, recent int64) (map[primitive.ObjectID]int64, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"userID", userID}}).
+		Sort("createdAt", -1).
+		Limit(recent).
+		Lookup("posts", "postID", "_id", "post").
+		Unwind("$post", false).
+		Group("$post.userID", bson.D{{"count", bson.D{{"$sum", 1}}}}).
+		Build()
+
+	return countGroups[primitive.ObjectID](ctx, r.collection, pipeline)
+}
+
+func (r *likes) DecayedCountByPosts(ctx context.Context, postIDs []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error) {
+	return decayedCountBy(ctx, r.collection, "postID", postIDs, halfLife)
+}
+
+func (r *likes) LikedPosts(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	found, err := findAll[types.Like](ctx, r.collection, bson.M{"userID": userID, "postID": bson.M{"$in": postIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	liked := make(map[primitive.ObjectID]bool, len(found))
+	for _, like := range found {
+		liked[like.PostID] = true
+	}
+
+	return liked, nil
+}
+
+func (r *likes) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Like, error) {
+	return findAll[types.Like](ctx, r.collection, bson.M{"userID": userID})
+}
+
+func (r *likes) Delete(ctx context.Context, postID, userID primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"postID": postID, "userID": userID})
+}
+
+func (r *likes) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countBy(ctx, r.collection, "postID", postIDs)
+}
+
+func (r *likes) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteByPosts(ctx, r.collection, postIDs)
+}
@@ -0,0 +1,150 @@
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"io"
+	"time"
+)
+
+// media stores uploads in the default GridFS bucket.
+type media struct {
+	database *mongo.Database
+}
+
+func (r *media) Upload(ctx context.Context, filename string, reader io.Reader) (primitive.ObjectID, error) {
+	bucket, err := gridfs.NewBucket(r.database)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return bucket.UploadFromStream(filename, reader)
+}
+
+func (r *media) Download(ctx context.Context, id primitive.ObjectID, w io.Writer) error {
+	bucket, err := gridfs.NewBucket(r.database)
+	if err != nil {
+		return err
+	}
+
+	_, err = bucket.DownloadToStream(id, w)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return repository.ErrNotFound
+	}
+
+	return err
+}
+
+func (r *media) Delete(ctx context.Context, id primitive.ObjectID) error {
+	bucket, err := gridfs.NewBucket(r.database)
+	if err != nil {
+		return err
+	}
+
+	err = bucket.Delete(id)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return repository.ErrNotFound
+	}
+
+	return err
+}
+
+func (r *media) Restore(ctx context.Context, file repository.MediaFile, reader io.Reader) error {
+	bucket, err := gridfs.NewBucket(r.database)
+	if err != nil {
+		return err
+	}
+
+	return translate(bucket.UploadFromStreamWithID(file.ID, file.Filename, reader))
+}
+
+func (r *media) Find(ctx context.Context, id primitive.ObjectID) (repository.MediaFile, error) {
+	var file gridFile
+	if err := findOne(ctx, r.database.Collection("fs.files"), bson.M{"_id": id}, &file); err != nil {
+		return repository.MediaFile{}, err
+	}
+
+	return file.mediaFile(), nil
+}
+
+func (r *media) List(ctx context.Context, after primitive.ObjectID, limit int64) ([]repository.MediaFile, error) {
+	opts := options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(limit)
+	cursor, err := r.database.Collection("fs.files").Find(ctx, bson.M{"_id": bson.M{"$gt": after}}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []gridFile
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+
+	result := make([]repository.MediaFile, 0, len(files))
+	for _, file := range files {
+		result = append(result, file.mediaFile())
+	}
+
+	return result, nil
+}
+
+// gridFile is a document of the fs.files collection.
+type gridFile struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Filename   string             `bson:"filename"`
+	Length     int64              `bson:"length"`
+	UploadDate time.Time          `bson:"uploadDate"`
+}
+
+func (f gridFile) mediaFile() repository.MediaFile {
+	return repository.MediaFile{
+		ID:         f.ID,
+		Filename:   f.Filename,
+		Length:     f.Length,
+		UploadedAt: f.UploadDate,
+	}
+}
+
+// mediaFields lists the fields of each collection that hold file IDs as hex
+// strings.
+var mediaFields = map[string][]string{
+	"users":          {"photoURL", "bannerURL"},
+	"posts":          {"images", "files"},
+	"post_revisions": {"images"},
+	"drafts":         {"images", "files"},
+	"messages":       {"files"},
+	"portfolios":     {"projects.img"},
+}
+
+func (r *media) Referenced(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error) {
+	byHex := make(map[string]primitive.ObjectID, len(ids))
+	hexIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		byHex[id.Hex()] = id
+		hexIDs = append(hexIDs, id.Hex())
+	}
+
+	referenced := map[primitive.ObjectID]bool{}
+	for name, fields := range mediaFields {
+		for _, field := range fields {
+			values, err := r.database.Collection(name).Distinct(ctx, field, bson.M{field: bson.M{"$in": hexIDs}})
+			if err != nil {
+				return nil, err
+			}
+			// Distinct returns the elements of array fields, not the arrays.
+			for _, value := range values {
+				hex, _ := value.(string)
+				if id, ok := byHex[hex]; ok {
+					referenced[id] = true
+				}
+			}
+		}
+	}
+
+	return referenced, nil
+}
@@ -0,0 +1,86 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// mentionFields names the text field the mentions of each collection point
+// into.
+var mentionFields = map[string]string{
+	"posts":    "caption",
+	"comments": "content",
+	"messages": "content",
+}
+
+type mentions struct {
+	database *mongo.Database
+}
+
+func (r *mentions) Rename(ctx context.Context, userID primitive.ObjectID, handle string) ([]primitive.ObjectID, error) {
+	var postIDs []primitive.ObjectID
+	for name, field := range mentionFields {
+		changed, err := r.rename(ctx, r.database.Collection(name), field, userID, handle)
+		if err != nil {
+			return postIDs, err
+		}
+		if name == "posts" {
+			postIDs = changed
+		}
+	}
+
+	return postIDs, nil
+}
+
+// rename rewrites the mentions of userID in field of collection and returns
+// the IDs of the documents that changed.
+func (r *mentions) rename(ctx context.Context, collection *mongo.Collection, field string, userID primitive.ObjectID, handle string) ([]primitive.ObjectID, error) {
+	filter := bson.M{"mentions": bson.M{"$elemMatch": bson.M{"userID": userID, "handle": bson.M{"$ne": handle}}}}
+	opts := options.Find().SetProjection(bson.M{field: 1, "mentions": 1})
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changed []primitive.ObjectID
+	var writes []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Mentions []types.Mention    `bson:"mentions"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		text, _ := cursor.Current.Lookup(field).StringValueOK()
+		text, renamed, ok := repository.RenameMention(text, doc.Mentions, userID, handle)
+		if !ok {
+			continue
+		}
+
+		changed = append(changed, doc.ID)
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": doc.ID}).
+			SetUpdate(bson.M{"$set": bson.M{field: text, "mentions": renamed}}))
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(writes) == 0 {
+		return nil, nil
+	}
+	if _, err := collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false)); err != nil {
+		return nil, err
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,54 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+type messages struct {
+	collection *mongo.Collection
+}
+
+func (r *messages) Create(ctx context.Context, message *types.Message) error {
+	res, err := r.collection.InsertOne(ctx, message)
+	if err != nil {
+		return translate(err)
+	}
+	message.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *messages) FindByID(ctx context.Context, id primitive.ObjectID) (types.Message, error) {
+	var message types.Message
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &message)
+	return message, err
+}
+
+func (r *messages) ListByConversation(ctx context.Context, conversationID primitive.ObjectID) ([]types.Message, error) {
+	return findAll[types.Message](ctx, r.collection, bson.M{"conversationID": conversationID})
+}
+
+func (r *messages) Edit(ctx context.Context, id primitive.ObjectID, content string, mentions []types.Mention) (types.Message, error) {
+	update := bson.M{"$set": bson.M{"content": content, "mentions": mentions, "isEdited": true, "updatedAt": time.Now()}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var message types.Message
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&message)
+	return message, translate(err)
+}
+
+func (r *messages) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+func (r *messages) DeleteByConversation(ctx context.Context, conversationID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"conversationID": conversationID})
+	return err
+}
@@ -0,0 +1,244 @@
+// Package mongodb implements the repositories on top of a MongoDB database.
+package mongodb
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+func New(database *mongo.Database) *repository.Repositories {
+	return &repository.Repositories{
+		Users:          &users{collection: database.Collection("users")},
+		Posts:          &posts{collection: database.Collection("posts"), settings: database.Collection("settings")},
+		Comments:       &comments{collection: database.Collection("comments")},
+		Likes:          &likes{collection: database.Collection("likes")},
+		Saves:          &saves{collection: database.Collection("saves")},
+		Collections:    &collections{collection: database.Collection("collections"), items: database.Collection("collection_items")},
+		Follows:        &follows{collection: database.Collection("follows")},
+		CloseFriends:   &closeFriends{collection: database.Collection("close_friends")},
+		FollowRequests: &followRequests{collection: database.Collection("follow_requests")},
+		Blocks:         &blocks{collection: database.Collection("blocked_users")},
+		Mutes:          &mutes{collection: database.Collection("mutes")},
+		Suggestions:    &suggestions{collection: database.Collection("dismissed_suggestions")},
+		Timelines:      &timelines{collection: database.Collection("timelines"), posts: database.Collection("posts")},
+		Reposts:        &reposts{collection: database.Collection("reposts"), settings: database.Collection("settings")},
+		Revisions:      &revisions{collection: database.Collection("post_revisions")},
+		Tags:           &tags{collection: database.Collection("tags")},
+		Mentions:       &mentions{database: database},
+		Search:         &search{users: database.Collection("users"), posts: database.Collection("posts"), tags: database.Collection("tags")},
+		Drafts:         &drafts{collection: database.Collection("drafts")},
+		Conversations:  &conversations{collection: database.Collection("conversations")},
+		Messages:       &messages{collection: database.Collection("messages")},
+		Settings:       &settings{collection: database.Collection("settings")},
+		Portfolios:     &portfolios{collection: database.Collection("portfolios")},
+		Media:          &media{database: database},
+		Transactions:   &transactions{client: database.Client()},
+	}
+}
+
+// translate maps driver errors onto the repository errors.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return repository.ErrNotFound
+	case mongo.IsDuplicateKeyError(err):
+		return repository.ErrDuplicate
+	default:
+		return err
+	}
+}
+
+func findOne(ctx context.Context, collection *mongo.Collection, filter interface{}, out interface{}) error {
+	return translate(collection.FindOne(ctx, filter).Decode(out))
+}
+
+func findAll[T any](ctx context.Context, collection *mongo.Collection, filter interface{}) ([]T, error) {
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []T
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func aggregate[T any](ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) ([]T, error) {
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.DecodeAll[T](ctx, cursor)
+}
+
+// updateOne runs update on the document matching filter and returns
+// repository.ErrNotFound when there is none.
+func updateOne(ctx context.Context, collection *mongo.Collection, filter, update interface{}) error {
+	res, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return translate(err)
+	}
+	if res.MatchedCount == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// deleteOne deletes the document matching filter and returns
+// repository.ErrNotFound when there is none.
+func deleteOne(ctx context.Context, collection *mongo.Collection, filter interface{}) error {
+	res, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+// listCounters returns the fields of up to limit documents with an ID greater
+// than after, in ID order.
+func listCounters(ctx context.Context, collection *mongo.Collection, after primitive.ObjectID, limit int64, fields ...string) ([]repository.Counters, error) {
+	filter := bson.M{}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+
+	projection := bson.M{}
+	for _, field := range fields {
+		projection[field] = 1
+	}
+
+	opts := options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(limit).SetProjection(projection)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []bson.M
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	result := make([]repository.Counters, 0, len(docs))
+	for _, doc := range docs {
+		counters := repository.Counters{ID: doc["_id"].(primitive.ObjectID), Values: map[string]int64{}}
+		for _, field := range fields {
+			counters.Values[field] = toInt64(doc[field])
+		}
+		result = append(result, counters)
+	}
+
+	return result, nil
+}
+
+// setCounter sets field to the value to if it still holds from. A missing field
+// counts as 0.
+func setCounter(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, field string, from, to int64) error {
+	filter := bson.M{"_id": id, field: from}
+	if from == 0 {
+		filter[field] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	return updateOne(ctx, collection, filter, bson.M{"$set": bson.M{field: to}})
+}
+
+// groupCount is a document counted per _id by a $group or $project stage.
+type groupCount[T any] struct {
+	ID    T     `bson:"_id"`
+	Count int64 `bson:"count"`
+}
+
+// weightedCount is a groupCount whose documents weigh less than one.
+type weightedCount struct {
+	ID    primitive.ObjectID `bson:"_id"`
+	Count float64            `bson:"count"`
+}
+
+// countBy counts the documents of collection for each of the ids in field.
+func countBy(ctx context.Context, collection *mongo.Collection, field string, ids []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{field, bson.D{{"$in", ids}}}}).
+		Group("$"+field, bson.D{{"count", bson.D{{"$sum", 1}}}}).
+		Build()
+
+	return countGroups[primitive.ObjectID](ctx, collection, pipeline)
+}
+
+// decayedCountBy counts the documents of collection for each of the ids in
+// field, each counting half as much every halfLife since its createdAt.
+func decayedCountBy(ctx context.Context, collection *mongo.Collection, field string, ids []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error) {
+	age := bson.D{{"$subtract", bson.A{time.Now(), "$createdAt"}}}
+	weight := bson.D{{"$pow", bson.A{0.5, bson.D{{"$divide", bson.A{age, halfLife.Milliseconds()}}}}}}
+
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{field, bson.D{{"$in", ids}}}}).
+		Group("$"+field, bson.D{{"count", bson.D{{"$sum", weight}}}}).
+		Build()
+
+	groups, err := aggregate[weightedCount](ctx, collection, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[primitive.ObjectID]float64, len(groups))
+	for _, group := range groups {
+		counts[group.ID] = group.Count
+	}
+
+	return counts, nil
+}
+
+// countGroups runs a pipeline that outputs groupCounts and returns the counts by
+// _id.
+func countGroups[T comparable](ctx context.Context, collection *mongo.Collection, pipeline mongo.Pipeline) (map[T]int64, error) {
+	groups, err := aggregate[groupCount[T]](ctx, collection, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[T]int64, len(groups))
+	for _, group := range groups {
+		counts[group.ID] = group.Count
+	}
+
+	return counts, nil
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float64:
+		return int64(v)
+	default:
+		return 0
+	}
+}
+
+// deleteByPosts deletes the documents of the posts and returns how many there were.
+func deleteByPosts(ctx context.Context, collection *mongo.Collection, postIDs []primitive.ObjectID) (int64, error) {
+	res, err := collection.DeleteMany(ctx, bson.M{"postID": bson.M{"$in": postIDs}})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,104 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// mutes has one document per muted user. The mute_ttl index deletes expired
+// mutes, which may take a minute, so reads leave them out themselves.
+type mutes struct {
+	collection *mongo.Collection
+}
+
+func (r *mutes) Set(ctx context.Context, mute *types.Mute) error {
+	update := bson.M{
+		"$set":         bson.M{"scopes": mute.Scopes, "createdAt": mute.CreatedAt},
+		"$setOnInsert": bson.M{"userID": mute.UserID, "mutedID": mute.MutedID},
+	}
+	if mute.ExpiresAt != nil {
+		update["$set"].(bson.M)["expiresAt"] = mute.ExpiresAt
+	} else {
+		update["$unset"] = bson.M{"expiresAt": ""}
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After).SetProjection(bson.M{"_id": 1})
+	var saved types.Mute
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"userID": mute.UserID, "mutedID": mute.MutedID}, update, opts).Decode(&saved)
+	if err != nil {
+		return translate(err)
+	}
+	mute.ID = saved.ID
+
+	return nil
+}
+
+func (r *mutes) Delete(ctx context.Context, userID, mutedID primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"userID": userID, "mutedID": mutedID})
+}
+
+func (r *mutes) ListByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Mute, error) {
+	return findAll[types.Mute](ctx, r.collection, activeMutes(userID, ""))
+}
+
+func (r *mutes) MutedIDs(ctx context.Context, userID primitive.ObjectID, scope string) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"mutedID": 1})
+	cursor, err := r.collection.Find(ctx, activeMutes(userID, scope), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var mutes []types.Mute
+	if err := cursor.All(ctx, &mutes); err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(mutes))
+	for _, mute := range mutes {
+		ids = append(ids, mute.MutedID)
+	}
+
+	return ids, nil
+}
+
+func (r *mutes) IsMuted(ctx context.Context, userID, mutedID primitive.ObjectID, scope string) (bool, error) {
+	filter := activeMutes(userID, scope)
+	filter["mutedID"] = mutedID
+
+	count, err := r.collection.CountDocuments(ctx, filter)
+	return count > 0, err
+}
+
+// activeMutes matches the mutes by userID for scope, or any scope when it's
+// empty, that haven't expired.
+func activeMutes(userID primitive.ObjectID, scope string) bson.M {
+	filter := bson.M{
+		"userID": userID,
+		"$or": bson.A{
+			bson.M{"expiresAt": nil},
+			bson.M{"expiresAt": bson.M{"$gt": time.Now()}},
+		},
+	}
+	if scope != "" {
+		filter["scopes"] = scope
+	}
+
+	return filter
+}
+
+func (r *mutes) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"userID": userID},
+		bson.M{"mutedID": userID},
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
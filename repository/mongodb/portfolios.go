@@ -0,0 +1,27 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type portfolios struct {
+	collection *mongo.Collection
+}
+
+func (r *portfolios) Create(ctx context.Context, portfolio *types.Portfolio) error {
+	_, err := r.collection.InsertOne(ctx, portfolio)
+	return translate(err)
+}
+
+func (r *portfolios) FindByUser(ctx context.Context, userID string) (types.Portfolio, error) {
+	var portfolio types.Portfolio
+	err := findOne(ctx, r.collection, bson.M{"userID": userID}, &portfolio)
+	return portfolio, err
+}
+
+func (r *portfolios) Delete(ctx context.Context, userID string) error {
+	return deleteOne(ctx, r.collection, bson.M{"userID": userID})
+}
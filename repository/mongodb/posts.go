@@ -0,0 +1,223 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type posts struct {
+	collection *mongo.Collection
+	settings   *mongo.Collection
+}
+
+func (r *posts) Create(ctx context.Context, post *types.Post) error {
+	res, err := r.collection.InsertOne(ctx, post)
+	if err != nil {
+		return translate(err)
+	}
+	post.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *posts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Post, error) {
+	var post types.Post
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &post)
+	return post, err
+}
+
+func (r *posts) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Post, error) {
+	return findAll[types.Post](ctx, r.collection, bson.M{"userID": userID})
+}
+
+func (r *posts) FindItem(ctx context.Context, id, viewerID primitive.ObjectID) (types.FeedItem, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"_id", id}}).
+		Apply(feedItem(viewerID)).
+		Build()
+
+	items, err := aggregate[types.FeedItem](ctx, r.collection, pipeline)
+	if err != nil {
+		return types.FeedItem{}, err
+	}
+	if len(items) == 0 {
+		return types.FeedItem{}, repository.ErrNotFound
+	}
+
+	return items[0], nil
+}
+
+func (r *posts) ListFeed(ctx context.Context, viewerID primitive.ObjectID, page utils.Page, filter repository.FeedFilter) ([]types.FeedItem, error) {
+	pipeline := utils.NewPipeline()
+	if !filter.QuotedPostID.IsZero() {
+		pipeline.Match(bson.D{{"quotedPostID", filter.QuotedPostID}})
+	}
+	if filter.Tag != "" {
+		pipeline.Match(bson.D{{"tags", filter.Tag}})
+	}
+	if len(filter.ExcludeAuthors) > 0 {
+		pipeline.Match(bson.D{{"userID", bson.D{{"$nin", filter.ExcludeAuthors}}}})
+	}
+	hidden, err := hiddenAuthors(ctx, r.settings, filter.Following)
+	if err != nil {
+		return nil, err
+	}
+	if len(hidden) > 0 {
+		pipeline.Match(bson.D{{"userID", bson.D{{"$nin", hidden}}}})
+	}
+	pipeline.Match(reachable("userID", "audience", filter.Reach, false))
+
+	return aggregate[types.FeedItem](ctx, r.collection, pipeline.
+		Paginate(page).
+		Apply(feedItem(viewerID)).
+		Build())
+}
+
+func (r *posts) AuthorActivity(ctx context.Context, query repository.ActivityQuery) (map[primitive.ObjectID]int64, error) {
+	match := bson.D{{"createdAt", bson.D{{"$gte", query.Since}}}}
+
+	author := bson.D{}
+	if len(query.Authors) > 0 {
+		author = append(author, bson.E{Key: "$in", Value: query.Authors})
+	}
+	if len(query.Exclude) > 0 {
+		author = append(author, bson.E{Key: "$nin", Value: query.Exclude})
+	}
+	if len(author) > 0 {
+		match = append(match, bson.E{Key: "userID", Value: author})
+	}
+	if len(query.Tags) > 0 {
+		match = append(match, bson.E{Key: "tags", Value: bson.D{{"$in", query.Tags}}})
+	}
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		Group("$userID", bson.D{{"count", bson.D{{"$sum", 1}}}}).
+		Sort("count", -1).
+		Limit(query.Limit).
+		Build()
+
+	return countGroups[primitive.ObjectID](ctx, r.collection, pipeline)
+}
+
+func (r *posts) ListCandidates(ctx context.Context, query repository.CandidateQuery) ([]types.FeedItem, error) {
+	hidden, err := hiddenAuthors(ctx, r.settings, query.Following)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.D{{"createdAt", bson.D{{"$gte", query.Since}}}}
+	if exclude := append(hidden, query.Exclude...); len(exclude) > 0 {
+		match = append(match, bson.E{Key: "userID", Value: bson.D{{"$nin", exclude}}})
+	}
+	match = append(match, reachable("userID", "audience", query.Reach, false)...)
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		Sort("createdAt", -1).
+		Limit(query.Limit).
+		Apply(feedItem(query.ViewerID)).
+		Build()
+
+	return aggregate[types.FeedItem](ctx, r.collection, pipeline)
+}
+
+func (r *posts) FindQuoted(ctx context.Context, query repository.QuotedQuery) ([]types.FeedItem, error) {
+	match := bson.D{{"_id", bson.D{{"$in", query.IDs}}}}
+	if len(query.Exclude) > 0 {
+		match = append(match, bson.E{Key: "userID", Value: bson.D{{"$nin", query.Exclude}}})
+	}
+	match = append(match, reachable("userID", "audience", query.Reach, true)...)
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		Apply(visibleTo("userID", query.Following), feedItem(query.ViewerID)).
+		Build()
+
+	return aggregate[types.FeedItem](ctx, r.collection, pipeline)
+}
+
+func (r *posts) CountQuotes(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countBy(ctx, r.collection, "quotedPostID", postIDs)
+}
+
+func (r *posts) TrendingTags(ctx context.Context, query repository.TrendingQuery) ([]types.TrendingTag, error) {
+	age := bson.D{{"$subtract", bson.A{time.Now(), "$createdAt"}}}
+	weight := bson.D{{"$pow", bson.A{0.5, bson.D{{"$divide", bson.A{age, query.HalfLife.Milliseconds()}}}}}}
+
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"createdAt", bson.D{{"$gte", query.Since}}}, {"tags.0", bson.D{{"$exists", true}}}}).
+		Match(reachable("userID", "audience", repository.Reach{}, false)).
+		Unwind("$tags", false).
+		Group("$tags", bson.D{{"score", bson.D{{"$sum", weight}}}, {"postsCount", bson.D{{"$sum", 1}}}}).
+		Sort("score", -1).
+		Limit(query.Limit).
+		Build()
+
+	return aggregate[types.TrendingTag](ctx, r.collection, pipeline)
+}
+
+func (r *posts) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	update := bson.M{"$set": fields, "$currentDate": bson.M{"updatedAt": true}}
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)
+}
+
+func (r *posts) IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, bson.M{"$inc": bson.M{field: delta}})
+}
+
+func (r *posts) ListCounters(ctx context.Context, after primitive.ObjectID, limit int64) ([]repository.Counters, error) {
+	return listCounters(ctx, r.collection, after, limit, repository.LikesCount, repository.CommentsCount, repository.RepostCount, repository.QuotesCount, repository.SavesCount)
+}
+
+func (r *posts) SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error {
+	return setCounter(ctx, r.collection, id, field, from, to)
+}
+
+func (r *posts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// reachable matches the posts within reach, whose author and audience are in
+// authorField and audienceField. Unlisted posts only match when linked is set.
+func reachable(authorField, audienceField string, reach repository.Reach, linked bool) bson.D {
+	audiences := bson.A{nil, types.AudiencePublic}
+	if linked {
+		audiences = append(audiences, types.AudienceUnlisted)
+	}
+
+	within := bson.A{bson.D{{audienceField, bson.D{{"$in", audiences}}}}}
+	if !reach.ViewerID.IsZero() {
+		within = append(within, bson.D{{authorField, reach.ViewerID}})
+	}
+	if len(reach.Followed) > 0 {
+		within = append(within, bson.D{{audienceField, types.AudienceFollowers}, {authorField, bson.D{{"$in", reach.Followed}}}})
+	}
+	if len(reach.CloseFriendOf) > 0 {
+		within = append(within, bson.D{{audienceField, types.AudienceCloseFriends}, {authorField, bson.D{{"$in", reach.CloseFriendOf}}}})
+	}
+
+	return bson.D{{"$or", within}}
+}
+
+// feedItem shapes post documents into types.FeedItem.
+func feedItem(viewerID primitive.ObjectID) utils.Fragment {
+	return func(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+		return pb.
+			Apply(utils.WithAuthor("userID"), utils.ViewerHasLiked(viewerID)).
+			Project(bson.D{
+				{"post", "$$ROOT"},
+				{"userName", 1},
+				{"photoURL", 1},
+				{"handle", 1},
+				{"likedByViewer", 1},
+			})
+	}
+}
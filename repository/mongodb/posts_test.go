@@ -0,0 +1,47 @@
+package mongodb
+
+import (
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	"testing"
+)
+
+func TestReachable(t *testing.T) {
+	viewer, followed, friend := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+	public := bson.D{{"audience", bson.D{{"$in", bson.A{nil, types.AudiencePublic}}}}}
+	linked := bson.D{{"audience", bson.D{{"$in", bson.A{nil, types.AudiencePublic, types.AudienceUnlisted}}}}}
+	own := bson.D{{"userID", viewer}}
+
+	tests := []struct {
+		name   string
+		reach  repository.Reach
+		linked bool
+		want   bson.A
+	}{
+		{name: "anonymous", want: bson.A{public}},
+		{name: "anonymous by link", linked: true, want: bson.A{linked}},
+		{name: "viewer", reach: repository.Reach{ViewerID: viewer}, want: bson.A{public, own}},
+		{
+			name:  "follower and close friend",
+			reach: repository.Reach{ViewerID: viewer, Followed: []primitive.ObjectID{followed}, CloseFriendOf: []primitive.ObjectID{friend}},
+			want: bson.A{
+				public,
+				own,
+				bson.D{{"audience", types.AudienceFollowers}, {"userID", bson.D{{"$in", []primitive.ObjectID{followed}}}}},
+				bson.D{{"audience", types.AudienceCloseFriends}, {"userID", bson.D{{"$in", []primitive.ObjectID{friend}}}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := reachable("userID", "audience", tt.reach, tt.linked)
+			if want := (bson.D{{"$or", tt.want}}); !reflect.DeepEqual(got, want) {
+				t.Errorf("reachable = %v, want %v", got, want)
+			}
+		})
+	}
+}
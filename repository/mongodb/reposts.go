@@ -0,0 +1,91 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+type reposts struct {
+	collection *mongo.Collection
+	settings   *mongo.Collection
+}
+
+func (r *reposts) Create(ctx context.Context, repost *types.Repost) error {
+	res, err := r.collection.InsertOne(ctx, repost)
+	if err != nil {
+		return translate(err)
+	}
+	repost.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *reposts) FindByID(ctx context.Context, id primitive.ObjectID) (types.Repost, error) {
+	var repost types.Repost
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &repost)
+	return repost, err
+}
+
+func (r *reposts) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Repost, error) {
+	return findAll[types.Repost](ctx, r.collection, bson.M{"repostedBy": userID})
+}
+
+func (r *reposts) ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page, excludeUsers, following []primitive.ObjectID) ([]types.RepostItem, error) {
+	hidden, err := hiddenAuthors(ctx, r.settings, following)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.D{{"postID", postID}}
+	if exclude := append(hidden, excludeUsers...); len(exclude) > 0 {
+		match = append(match, bson.E{Key: "repostedBy", Value: bson.D{{"$nin", exclude}}})
+	}
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		Paginate(page).
+		Apply(repostItem).
+		Build()
+
+	return aggregate[types.RepostItem](ctx, r.collection, pipeline)
+}
+
+// repostItem shapes reposts into types.RepostItem.
+func repostItem(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+	return pb.
+		Apply(utils.WithAuthor("repostedBy")).
+		Project(bson.D{
+			{"userID", "$repostedBy"},
+			{"userName", 1},
+			{"photoURL", 1},
+			{"handle", 1},
+			{"caption", "$repostCaption"},
+			{"createdAt", 1},
+		})
+}
+
+func (r *reposts) UpdateCaption(ctx context.Context, id primitive.ObjectID, caption string) error {
+	update := bson.M{"$set": bson.M{"repostCaption": caption}, "$currentDate": bson.M{"updatedAt": true}}
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, update)
+}
+
+func (r *reposts) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id})
+}
+
+func (r *reposts) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countBy(ctx, r.collection, "postID", postIDs)
+}
+
+func (r *reposts) DecayedCountByPosts(ctx context.Context, postIDs []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error) {
+	return decayedCountBy(ctx, r.collection, "postID", postIDs, halfLife)
+}
+
+func (r *reposts) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteByPosts(ctx, r.collection, postIDs)
+}
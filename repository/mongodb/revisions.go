@@ -0,0 +1,47 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type revisions struct {
+	collection *mongo.Collection
+}
+
+func (r *revisions) Create(ctx context.Context, revision *types.PostRevision) error {
+	res, err := r.collection.InsertOne(ctx, revision)
+	if err != nil {
+		return translate(err)
+	}
+	revision.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *revisions) FindByID(ctx context.Context, id primitive.ObjectID) (types.PostRevision, error) {
+	var revision types.PostRevision
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &revision)
+	return revision, err
+}
+
+func (r *revisions) ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page) ([]types.PostRevision, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"postID", postID}}).
+		Paginate(page).
+		Build()
+
+	return aggregate[types.PostRevision](ctx, r.collection, pipeline)
+}
+
+func (r *revisions) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id})
+}
+
+func (r *revisions) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteByPosts(ctx, r.collection, postIDs)
+}
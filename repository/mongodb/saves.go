@@ -0,0 +1,54 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type saves struct {
+	collection *mongo.Collection
+}
+
+func (r *saves) Create(ctx context.Context, save *types.Save) error {
+	res, err := r.collection.InsertOne(ctx, save)
+	if err != nil {
+		return translate(err)
+	}
+	save.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *saves) Exists(ctx context.Context, postID, userID primitive.ObjectID) (bool, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"postID": postID, "userID": userID})
+	return count > 0, err
+}
+
+func (r *saves) FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Save, error) {
+	return findAll[types.Save](ctx, r.collection, bson.M{"userID": userID})
+}
+
+func (r *saves) ListByUser(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.Save, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"userID", userID}}).
+		Paginate(page).
+		Build()
+
+	return aggregate[types.Save](ctx, r.collection, pipeline)
+}
+
+func (r *saves) CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error) {
+	return countBy(ctx, r.collection, "postID", postIDs)
+}
+
+func (r *saves) Delete(ctx context.Context, postID, userID primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"postID": postID, "userID": userID})
+}
+
+func (r *saves) DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error) {
+	return deleteByPosts(ctx, r.collection, postIDs)
+}
@@ -0,0 +1,132 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+)
+
+// search uses the text indexes of users and posts, which weigh handles over
+// names and tags over captions.
+type search struct {
+	users *mongo.Collection
+	posts *mongo.Collection
+	tags  *mongo.Collection
+}
+
+// userResult are the fields of types.UserResult but its score.
+var userResult = bson.D{
+	{"firstName", 1},
+	{"lastName", 1},
+	{"handle", 1},
+	{"photoURL", 1},
+	{"bio", 1},
+	{"followersCount", 1},
+}
+
+var textScore = bson.D{{"score", bson.D{{"$meta", "textScore"}}}}
+
+func (r *search) Users(ctx context.Context, query repository.SearchQuery) ([]types.UserResult, error) {
+	after, err := afterObjectID(query.Page)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.D{{"$text", bson.D{{"$search", query.Text}}}, {"suspendedAt", nil}}
+	if len(query.Exclude) > 0 {
+		match = append(match, bson.E{Key: "_id", Value: bson.D{{"$nin", query.Exclude}}})
+	}
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		AddFields(textScore).
+		PaginateByScore(query.Page, after).
+		Project(append(userResult, bson.E{Key: "score", Value: 1})).
+		Build()
+
+	return aggregate[types.UserResult](ctx, r.users, pipeline)
+}
+
+func (r *search) Posts(ctx context.Context, query repository.SearchQuery) ([]types.PostResult, error) {
+	after, err := afterObjectID(query.Page)
+	if err != nil {
+		return nil, err
+	}
+
+	match := bson.D{{"$text", bson.D{{"$search", query.Text}}}}
+	if len(query.Exclude) > 0 {
+		match = append(match, bson.E{Key: "userID", Value: bson.D{{"$nin", query.Exclude}}})
+	}
+	match = append(match, reachable("userID", "audience", query.Reach, false)...)
+
+	pipeline := utils.NewPipeline().
+		Match(match).
+		AddFields(textScore).
+		Apply(visibleTo("userID", query.Following)).
+		PaginateByScore(query.Page, after).
+		Apply(feedItem(query.ViewerID)).
+		AddFields(bson.D{{"score", "$post.score"}}).
+		Unset("post.score").
+		Build()
+
+	return aggregate[types.PostResult](ctx, r.posts, pipeline)
+}
+
+func (r *search) Tags(ctx context.Context, query repository.SearchQuery) ([]types.Tag, error) {
+	var after interface{}
+	if query.Page.After != nil {
+		after = query.Page.After.ID
+	}
+
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"_id", bson.D{{"$regex", "^" + regexp.QuoteMeta(query.Text)}}}, {"postsCount", bson.D{{"$gt", 0}}}}).
+		AddFields(bson.D{{"score", "$postsCount"}}).
+		PaginateByScore(query.Page, after).
+		Unset("score").
+		Build()
+
+	return aggregate[types.Tag](ctx, r.tags, pipeline)
+}
+
+func (r *search) Handles(ctx context.Context, prefix string, exclude []primitive.ObjectID, limit int64) ([]types.UserResult, error) {
+	filter := bson.M{"handle": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}, "suspendedAt": nil}
+	if len(exclude) > 0 {
+		filter["_id"] = bson.M{"$nin": exclude}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"followersCount", -1}, {"_id", 1}}).
+		SetLimit(limit).
+		SetProjection(userResult)
+
+	cursor, err := r.users.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.UserResult
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// afterObjectID returns the ObjectID of the cursor of page, if it has one.
+func afterObjectID(page utils.ScorePage) (interface{}, error) {
+	if page.After == nil {
+		return nil, nil
+	}
+
+	id, err := primitive.ObjectIDFromHex(page.After.ID)
+	if err != nil {
+		return nil, utils.ErrInvalidCursor
+	}
+	return id, nil
+}
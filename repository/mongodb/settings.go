@@ -0,0 +1,55 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type settings struct {
+	collection *mongo.Collection
+}
+
+func (r *settings) Create(ctx context.Context, settings *types.Settings) error {
+	res, err := r.collection.InsertOne(ctx, settings)
+	if err != nil {
+		return translate(err)
+	}
+	settings.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *settings) FindByUser(ctx context.Context, userID primitive.ObjectID) (types.Settings, error) {
+	var settings types.Settings
+	err := findOne(ctx, r.collection, bson.M{"userID": userID}, &settings)
+	return settings, err
+}
+
+func (r *settings) Set(ctx context.Context, userID primitive.ObjectID, fields bson.M) error {
+	defaults := types.DefaultSettings(userID)
+	onInsert := bson.M{
+		"theme":             defaults.Theme,
+		"language":          defaults.Language,
+		"profileVisibility": defaults.ProfileVisibility,
+	}
+	// A field can't be in both $set and $setOnInsert.
+	for key := range fields {
+		delete(onInsert, key)
+	}
+
+	update := bson.M{"$set": fields}
+	if len(onInsert) > 0 {
+		update["$setOnInsert"] = onInsert
+	}
+
+	_, err := r.collection.UpdateOne(ctx, bson.M{"userID": userID}, update, options.Update().SetUpsert(true))
+	return translate(err)
+}
+
+func (r *settings) Delete(ctx context.Context, userID primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"userID": userID})
+}
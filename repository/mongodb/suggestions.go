@@ -0,0 +1,50 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
+)
+
+// suggestions keeps the dismissed suggestions, one document per dismissed user.
+type suggestions struct {
+	collection *mongo.Collection
+}
+
+func (r *suggestions) Dismiss(ctx context.Context, userID, dismissedID primitive.ObjectID) error {
+	filter := bson.M{"userID": userID, "dismissedID": dismissedID}
+	update := bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return translate(err)
+}
+
+func (r *suggestions) DismissedIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	dismissals, err := findAll[types.Dismissal](ctx, r.collection, bson.M{"userID": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(dismissals))
+	for _, dismissal := range dismissals {
+		ids = append(ids, dismissal.DismissedID)
+	}
+
+	return ids, nil
+}
+
+func (r *suggestions) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"userID": userID},
+		bson.M{"dismissedID": userID},
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
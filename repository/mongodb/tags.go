@@ -0,0 +1,50 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"regexp"
+	"time"
+)
+
+type tags struct {
+	collection *mongo.Collection
+}
+
+func (r *tags) Increment(ctx context.Context, names []string, delta int) error {
+	if delta < 0 {
+		_, err := r.collection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": names}}, bson.M{"$inc": bson.M{"postsCount": delta}})
+		return err
+	}
+
+	writes := make([]mongo.WriteModel, 0, len(names))
+	for _, name := range names {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": name}).
+			SetUpdate(bson.M{"$inc": bson.M{"postsCount": delta}, "$set": bson.M{"lastUsedAt": time.Now()}}).
+			SetUpsert(true))
+	}
+
+	_, err := r.collection.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+func (r *tags) Search(ctx context.Context, prefix string, limit int64) ([]types.Tag, error) {
+	filter := bson.M{"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)}, "postsCount": bson.M{"$gt": 0}}
+	opts := options.Find().SetSort(bson.D{{"postsCount", -1}, {"_id", 1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []types.Tag
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,268 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// timelines merges the posts and reposts of the followed users on read. The
+// precomputed timelines in collection hold one entry per owner and post or
+// repost; the timeline_ttl index drops the old ones.
+type timelines struct {
+	collection *mongo.Collection
+	posts      *mongo.Collection
+}
+
+func (r *timelines) List(ctx context.Context, query repository.TimelineQuery, page utils.Page) ([]types.TimelineItem, error) {
+	pipeline := entriesOf(query.Authors, page, excluding(query), visibleTo("postAuthorID", query.Following)).
+		Paginate(page).
+		Apply(timelineItem(query.ViewerID)).
+		Build()
+
+	return aggregate[types.TimelineItem](ctx, r.posts, pipeline)
+}
+
+func (r *timelines) ListStored(ctx context.Context, query repository.TimelineQuery, page utils.Page) ([]types.TimelineItem, error) {
+	pipeline := utils.NewPipeline().
+		Match(bson.D{{"ownerID", query.ViewerID}, {"authorID", bson.D{{"$in", query.Authors}}}}).
+		Project(bson.D{
+			{"_id", bson.D{{"$ifNull", bson.A{"$repostID", "$postID"}}}},
+			{"createdAt", 1},
+			{"postID", 1},
+			{"repostID", 1},
+			{"authorID", 1},
+			{"postAuthorID", 1},
+		}).
+		Apply(audienceOf, excluding(query), visibleTo("postAuthorID", query.Following)).
+		Paginate(page).
+		Apply(timelineItem(query.ViewerID)).
+		Build()
+
+	return aggregate[types.TimelineItem](ctx, r.collection, pipeline)
+}
+
+func (r *timelines) Entries(ctx context.Context, authors []primitive.ObjectID, limit int64) ([]types.TimelineEntry, error) {
+	page := utils.Page{Limit: limit}
+	pipeline := entriesOf(authors, page, nil, nil).
+		Paginate(page).
+		Limit(limit).
+		Unset("_id").
+		Build()
+
+	return aggregate[types.TimelineEntry](ctx, r.posts, pipeline)
+}
+
+func (r *timelines) Stored(ctx context.Context, ownerIDs []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	values, err := r.collection.Distinct(ctx, "ownerID", bson.M{"ownerID": bson.M{"$in": ownerIDs}})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *timelines) Store(ctx context.Context, entries []types.TimelineEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		docs = append(docs, entry)
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
+
+func (r *timelines) Clear(ctx context.Context, ownerID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"ownerID": ownerID})
+	return err
+}
+
+func (r *timelines) DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	res, err := r.collection.DeleteMany(ctx, bson.M{"$or": bson.A{
+		bson.M{"ownerID": userID},
+		bson.M{"authorID": userID},
+		bson.M{"postAuthorID": userID},
+	}})
+	if err != nil {
+		return 0, err
+	}
+
+	return res.DeletedCount, nil
+}
+
+// entriesOf merges the posts and reposts of authors into timeline entries
+// whose _id is the one of the post or repost. Each side is cut down to what
+// page can hold before they are merged, so the caller still has to paginate
+// the result. filter applies to both sides and reposts only to the reposts,
+// which also carry posts by users who aren't among authors.
+func entriesOf(authors []primitive.ObjectID, page utils.Page, filter, reposts utils.Fragment) *utils.PipelineBuilder {
+	bounded := utils.Page{After: page.After, Limit: page.Skip + page.Limit}
+
+	repostEntries := utils.NewPipeline().
+		Match(bson.D{{"repostedBy", bson.D{{"$in", authors}}}}).
+		Lookup("posts", "postID", "_id", "post").
+		Unwind("$post", false).
+		Project(bson.D{
+			{"createdAt", 1},
+			{"postID", 1},
+			{"repostID", "$_id"},
+			{"authorID", "$repostedBy"},
+			{"postAuthorID", "$post.userID"},
+			{"audience", "$post.audience"},
+		}).
+		Apply(optional(filter), optional(reposts)).
+		Paginate(bounded)
+
+	return utils.NewPipeline().
+		Match(bson.D{{"userID", bson.D{{"$in", authors}}}}).
+		Project(bson.D{
+			{"createdAt", 1},
+			{"postID", "$_id"},
+			{"authorID", "$userID"},
+			{"postAuthorID", "$userID"},
+			{"audience", 1},
+		}).
+		Apply(optional(filter)).
+		Paginate(bounded).
+		UnionWith("reposts", repostEntries)
+}
+
+// optional returns fragment, or one that adds nothing when it's nil.
+func optional(fragment utils.Fragment) utils.Fragment {
+	if fragment == nil {
+		return func(pb *utils.PipelineBuilder) *utils.PipelineBuilder { return pb }
+	}
+	return fragment
+}
+
+// audienceOf adds the audience of the post to stored timeline entries, since
+// the author may change it after the entries are stored.
+func audienceOf(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+	post := utils.NewPipeline().
+		Match(bson.D{{"$expr", bson.D{{"$eq", bson.A{"$_id", "$$postID"}}}}}).
+		Project(bson.D{{"audience", 1}})
+
+	return pb.
+		LookupPipeline("posts", bson.D{{"postID", "$postID"}}, post, "audience").
+		AddFields(bson.D{{"audience", bson.D{{"$arrayElemAt", bson.A{"$audience.audience", 0}}}}})
+}
+
+// excluding leaves out the entries of the users query excludes and the posts
+// out of its reach.
+func excluding(query repository.TimelineQuery) utils.Fragment {
+	return func(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+		conditions := bson.A{reachable("postAuthorID", "audience", query.Reach, false)}
+		if len(query.ExcludePosts) > 0 {
+			conditions = append(conditions, bson.D{{"postAuthorID", bson.D{{"$nin", query.ExcludePosts}}}})
+		}
+		if len(query.ExcludeReposts) > 0 {
+			conditions = append(conditions, bson.D{{"$or", bson.A{
+				bson.D{{"repostID", bson.D{{"$exists", false}}}},
+				bson.D{{"authorID", bson.D{{"$nin", query.ExcludeReposts}}}},
+			}}})
+		}
+
+		return pb.Match(bson.D{{"$and", conditions}})
+	}
+}
+
+// visibleTo leaves out the documents whose field is a private user that isn't
+// among following.
+func visibleTo(field string, following []primitive.ObjectID) utils.Fragment {
+	if following == nil {
+		following = []primitive.ObjectID{}
+	}
+
+	return func(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+		private := utils.NewPipeline().
+			Match(bson.D{{"$expr", bson.D{{"$and", bson.A{
+				bson.D{{"$eq", bson.A{"$userID", "$$userID"}}},
+				bson.D{{"$eq", bson.A{"$profileVisibility", types.VisibilityPrivate}}},
+			}}}}}).
+			Limit(1).
+			Project(bson.D{{"_id", 1}})
+
+		return pb.
+			LookupPipeline("settings", bson.D{{"userID", "$" + field}}, private, "private").
+			Match(bson.D{{"$or", bson.A{
+				bson.D{{"private", bson.D{{"$size", 0}}}},
+				bson.D{{field, bson.D{{"$in", following}}}},
+			}}}).
+			Unset("private")
+	}
+}
+
+// hiddenAuthors returns the private users that aren't among following. Queries
+// that sort and limit leave them out with a $nin before sorting, which unlike
+// visibleTo keeps the sort on the index.
+func hiddenAuthors(ctx context.Context, settings *mongo.Collection, following []primitive.ObjectID) ([]primitive.ObjectID, error) {
+	filter := bson.M{"profileVisibility": types.VisibilityPrivate}
+	if len(following) > 0 {
+		filter["userID"] = bson.M{"$nin": following}
+	}
+
+	values, err := settings.Distinct(ctx, "userID", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, value := range values {
+		if id, ok := value.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// timelineItem shapes timeline entries into types.TimelineItem. Entries of
+// deleted posts and reposts are left out.
+func timelineItem(viewerID primitive.ObjectID) utils.Fragment {
+	return func(pb *utils.PipelineBuilder) *utils.PipelineBuilder {
+		post := utils.NewPipeline().
+			Match(bson.D{{"$expr", bson.D{{"$eq", bson.A{"$_id", "$$postID"}}}}}).
+			Apply(feedItem(viewerID))
+
+		repost := utils.NewPipeline().
+			Match(bson.D{{"$expr", bson.D{{"$eq", bson.A{"$_id", "$$repostID"}}}}}).
+			Apply(repostItem)
+
+		return pb.
+			LookupPipeline("posts", bson.D{{"postID", "$postID"}}, post, "item").
+			Unwind("$item", false).
+			LookupPipeline("reposts", bson.D{{"repostID", "$repostID"}}, repost, "repost").
+			Match(bson.D{{"$or", bson.A{
+				bson.D{{"repostID", bson.D{{"$exists", false}}}},
+				bson.D{{"repost.0", bson.D{{"$exists", true}}}},
+			}}}).
+			Project(bson.D{
+				{"createdAt", 1},
+				{"post", "$item.post"},
+				{"userName", "$item.userName"},
+				{"photoURL", "$item.photoURL"},
+				{"handle", "$item.handle"},
+				{"likedByViewer", "$item.likedByViewer"},
+				{"repost", bson.D{{"$arrayElemAt", bson.A{"$repost", 0}}}},
+			})
+	}
+}
@@ -0,0 +1,77 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"sync"
+	"time"
+)
+
+// probeTimeout bounds the check for transaction support, which runs detached
+// from the request that triggers it.
+const probeTimeout = 5 * time.Second
+
+// transactions runs fn in a session transaction. Standalone servers don't support
+// transactions, so there it falls back to repository.Compensating.
+type transactions struct {
+	client    *mongo.Client
+	mu        sync.Mutex
+	checked   bool
+	supported bool
+}
+
+func (t *transactions) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.supportsTransactions() {
+		return repository.Compensating{}.WithTransaction(ctx, fn)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+
+	return err
+}
+
+// supportsTransactions reports whether the server is a replica set member or a
+// mongos router. The answer is remembered once the server gives one; when the
+// check fails, this call falls back to compensating writes and the next one
+// checks again.
+func (t *transactions) supportsTransactions() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.checked {
+		return t.supported
+	}
+
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+
+	err := t.client.Database("admin").RunCommand(ctx, bson.D{{"hello", 1}}).Decode(&hello)
+	if err != nil {
+		log.Printf("Failed to check for transaction support: %v", err)
+		return false
+	}
+
+	t.checked = true
+	t.supported = hello.SetName != "" || hello.Msg == "isdbgrid"
+	if !t.supported {
+		log.Println("MongoDB does not support transactions, falling back to compensating writes")
+	}
+
+	return t.supported
+}
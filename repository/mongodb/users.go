@@ -0,0 +1,78 @@
+package mongodb
+
+import (
+	"context"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type users struct {
+	collection *mongo.Collection
+}
+
+func (r *users) Create(ctx context.Context, user *types.User) error {
+	res, err := r.collection.InsertOne(ctx, user)
+	if err != nil {
+		return translate(err)
+	}
+	user.ID = res.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (r *users) FindByID(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	var user types.User
+	err := findOne(ctx, r.collection, bson.M{"_id": id}, &user)
+	return user, err
+}
+
+func (r *users) FindProfile(ctx context.Context, id primitive.ObjectID) (types.User, error) {
+	var user types.User
+	opts := options.FindOne().SetProjection(bson.M{"password": 0, "email": 0})
+	err := translate(r.collection.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&user))
+	return user, err
+}
+
+func (r *users) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]types.User, error) {
+	return findAll[types.User](ctx, r.collection, bson.M{"_id": bson.M{"$in": ids}})
+}
+
+func (r *users) FindByEmail(ctx context.Context, email string) (types.User, error) {
+	var user types.User
+	err := findOne(ctx, r.collection, bson.M{"email": email}, &user)
+	return user, err
+}
+
+func (r *users) FindByHandle(ctx context.Context, handle string) (types.User, error) {
+	var user types.User
+	err := findOne(ctx, r.collection, bson.M{"handle": handle}, &user)
+	return user, err
+}
+
+func (r *users) FindByHandles(ctx context.Context, handles []string) ([]types.User, error) {
+	return findAll[types.User](ctx, r.collection, bson.M{"handle": bson.M{"$in": handles}})
+}
+
+func (r *users) Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error {
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, bson.M{"$set": fields})
+}
+
+func (r *users) IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error {
+	return updateOne(ctx, r.collection, bson.M{"_id": id}, bson.M{"$inc": bson.M{field: delta}})
+}
+
+func (r *users) ListCounters(ctx context.Context, after primitive.ObjectID, limit int64) ([]repository.Counters, error) {
+	return listCounters(ctx, r.collection, after, limit, repository.FollowersCount, repository.FollowingCount)
+}
+
+func (r *users) SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error {
+	return setCounter(ctx, r.collection, id, field, from, to)
+}
+
+func (r *users) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return deleteOne(ctx, r.collection, bson.M{"_id": id})
+}
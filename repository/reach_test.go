@@ -0,0 +1,51 @@
+package repository_test
+
+import (
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+)
+
+func TestReachAllows(t *testing.T) {
+	viewer, author := primitive.NewObjectID(), primitive.NewObjectID()
+	follower := repository.Reach{ViewerID: viewer, Followed: []primitive.ObjectID{author}}
+	closeFriend := repository.Reach{ViewerID: viewer, CloseFriendOf: []primitive.ObjectID{author}}
+	stranger := repository.Reach{ViewerID: viewer}
+
+	tests := []struct {
+		name     string
+		reach    repository.Reach
+		audience string
+		// byViewer makes the viewer the author of the post.
+		byViewer bool
+		linked   bool
+		want     bool
+	}{
+		{name: "public to anonymous", audience: types.AudiencePublic, want: true},
+		{name: "no audience is public", audience: "", want: true},
+		{name: "followers to follower", reach: follower, audience: types.AudienceFollowers, want: true},
+		{name: "followers to stranger", reach: stranger, audience: types.AudienceFollowers},
+		{name: "followers to anonymous", audience: types.AudienceFollowers},
+		{name: "close friends to close friend", reach: closeFriend, audience: types.AudienceCloseFriends, want: true},
+		{name: "close friends to follower", reach: follower, audience: types.AudienceCloseFriends},
+		{name: "unlisted linked", reach: stranger, audience: types.AudienceUnlisted, linked: true, want: true},
+		{name: "unlisted in a feed", reach: follower, audience: types.AudienceUnlisted},
+		{name: "own close friends post", reach: stranger, audience: types.AudienceCloseFriends, byViewer: true, want: true},
+		{name: "own unlisted post in a feed", reach: stranger, audience: types.AudienceUnlisted, byViewer: true, want: true},
+		{name: "unknown audience", reach: follower, audience: "secret"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := types.Post{UserID: author, Audience: tt.audience}
+			if tt.byViewer {
+				post.UserID = viewer
+			}
+
+			if got := tt.reach.Allows(post, tt.linked); got != tt.want {
+				t.Errorf("Allows = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
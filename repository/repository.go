@@ -0,0 +1,592 @@
+// Package repository defines the persistence interfaces the handlers depend on.
+// The mongodb subpackage implements them on top of MongoDB and the memory
+// subpackage keeps everything in process, which lets the API run without a database.
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"slices"
+	"time"
+)
+
+var (
+	ErrNotFound  = errors.New("not found")
+	ErrDuplicate = errors.New("duplicate")
+)
+
+// Counter fields that can be changed with IncrementCounter.
+const (
+	LikesCount     = "likesCount"
+	CommentsCount  = "commentsCount"
+	RepostCount    = "repostCount"
+	QuotesCount    = "quotesCount"
+	SavesCount     = "savesCount"
+	FollowersCount = "followersCount"
+	FollowingCount = "followingCount"
+)
+
+// Counters holds the stored counter values of one document, keyed by field.
+type Counters struct {
+	ID     primitive.ObjectID
+	Values map[string]int64
+}
+
+// MediaFile describes an uploaded file.
+type MediaFile struct {
+	ID         primitive.ObjectID `json:"id"`
+	Filename   string             `json:"filename"`
+	Length     int64              `json:"length"`
+	UploadedAt time.Time          `json:"uploadedAt"`
+}
+
+type Repositories struct {
+	Users          UserRepository
+	Posts          PostRepository
+	Comments       CommentRepository
+	Likes          LikeRepository
+	Saves          SaveRepository
+	Collections    CollectionRepository
+	Follows        FollowRepository
+	CloseFriends   CloseFriendRepository
+	FollowRequests FollowRequestRepository
+	Blocks         BlockRepository
+	Mutes          MuteRepository
+	Suggestions    SuggestionRepository
+	Timelines      TimelineRepository
+	Reposts        RepostRepository
+	Revisions      RevisionRepository
+	Tags           TagRepository
+	Mentions       MentionRepository
+	Search         SearchRepository
+	Drafts         DraftRepository
+	Conversations  ConversationRepository
+	Messages       MessageRepository
+	Settings       SettingsRepository
+	Portfolios     PortfolioRepository
+	Media          MediaRepository
+	Transactions   Transactor
+}
+
+type UserRepository interface {
+	Create(ctx context.Context, user *types.User) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.User, error)
+	// FindProfile returns the user without their password and email, as shown to
+	// other users.
+	FindProfile(ctx context.Context, id primitive.ObjectID) (types.User, error)
+	FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]types.User, error)
+	FindByEmail(ctx context.Context, email string) (types.User, error)
+	FindByHandle(ctx context.Context, handle string) (types.User, error)
+	FindByHandles(ctx context.Context, handles []string) ([]types.User, error)
+	// Update sets the given bson fields on the user.
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error
+	// ListCounters returns the follow counters of up to limit users with an ID
+	// greater than after, in ID order.
+	ListCounters(ctx context.Context, after primitive.ObjectID, limit int64) ([]Counters, error)
+	// SetCounter sets field to the value to if it still holds from and returns
+	// ErrNotFound otherwise.
+	SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error
+	// Delete removes the user document only. Their posts and other data are
+	// left for the caller to remove.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type PostRepository interface {
+	Create(ctx context.Context, post *types.Post) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Post, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Post, error)
+	// FindItem returns the post together with its author and whether viewerID
+	// liked it. viewerID may be zero for anonymous viewers.
+	FindItem(ctx context.Context, id, viewerID primitive.ObjectID) (types.FeedItem, error)
+	// ListFeed returns the posts of page newest first like FindItem, including
+	// the one extra post utils.NewPaged needs.
+	ListFeed(ctx context.Context, viewerID primitive.ObjectID, page utils.Page, filter FeedFilter) ([]types.FeedItem, error)
+	// AuthorActivity counts the posts that match query per author and returns
+	// the query.Limit most active authors.
+	AuthorActivity(ctx context.Context, query ActivityQuery) (map[primitive.ObjectID]int64, error)
+	// ListCandidates returns the newest posts query selects for ranking.
+	ListCandidates(ctx context.Context, query CandidateQuery) ([]types.FeedItem, error)
+	// FindQuoted returns the posts query selects like FindItem, in no
+	// particular order.
+	FindQuoted(ctx context.Context, query QuotedQuery) ([]types.FeedItem, error)
+	// CountQuotes returns the number of quotes of each post that has any.
+	CountQuotes(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	// TrendingTags scores the tags of the posts query selects and returns the
+	// highest scoring ones first.
+	TrendingTags(ctx context.Context, query TrendingQuery) ([]types.TrendingTag, error)
+	// Update sets the given bson fields on the post and its updatedAt.
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	IncrementCounter(ctx context.Context, id primitive.ObjectID, field string, delta int) error
+	// ListCounters returns the like, comment, repost, quote and save counters of
+	// up to limit posts with an ID greater than after, in ID order.
+	ListCounters(ctx context.Context, after primitive.ObjectID, limit int64) ([]Counters, error)
+	// SetCounter sets field to the value to if it still holds from and returns
+	// ErrNotFound otherwise.
+	SetCounter(ctx context.Context, id primitive.ObjectID, field string, from, to int64) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+// FeedFilter narrows down the posts of a feed.
+type FeedFilter struct {
+	// ExcludeAuthors hides the posts of these users, like the ones the viewer
+	// muted.
+	ExcludeAuthors []primitive.ObjectID
+	// QuotedPostID only lets through the quotes of this post when it's set.
+	QuotedPostID primitive.ObjectID
+	// Tag only lets through the posts with this tag when it's set.
+	Tag string
+	// Following lets through the posts of these private users. Posts of
+	// other private users are left out.
+	Following []primitive.ObjectID
+	// Reach lets through the posts that aren't public. The zero Reach only
+	// lets through public posts.
+	Reach Reach
+}
+
+// Reach is what ViewerID may see besides public posts: their own posts, the
+// followers-only posts of the users in Followed and the close-friends posts of
+// the users in CloseFriendOf. Unlisted posts are only reached by their link.
+type Reach struct {
+	ViewerID      primitive.ObjectID
+	Followed      []primitive.ObjectID
+	CloseFriendOf []primitive.ObjectID
+}
+
+// Allows reports whether post is within reach. Unlisted posts only are when
+// linked is set, for reads of the post by its ID.
+func (r Reach) Allows(post types.Post, linked bool) bool {
+	switch {
+	case post.Audience == "" || post.Audience == types.AudiencePublic:
+		return true
+	case !r.ViewerID.IsZero() && post.UserID == r.ViewerID:
+		return true
+	}
+
+	switch post.Audience {
+	case types.AudienceFollowers:
+		return slices.Contains(r.Followed, post.UserID)
+	case types.AudienceCloseFriends:
+		return slices.Contains(r.CloseFriendOf, post.UserID)
+	case types.AudienceUnlisted:
+		return linked
+	default:
+		return false
+	}
+}
+
+// ActivityQuery selects the posts AuthorActivity counts: the ones since Since,
+// by Authors and with any of Tags when they are given, and never by Exclude.
+type ActivityQuery struct {
+	Since   time.Time
+	Authors []primitive.ObjectID
+	Exclude []primitive.ObjectID
+	Tags    []string
+	Limit   int64
+}
+
+// CandidateQuery selects up to Limit posts since Since for ViewerID, never by
+// Exclude and within Reach. Posts of private users only show up when they are
+// among Following.
+type CandidateQuery struct {
+	ViewerID  primitive.ObjectID
+	Since     time.Time
+	Exclude   []primitive.ObjectID
+	Following []primitive.ObjectID
+	Reach     Reach
+	Limit     int64
+}
+
+// TrendingQuery selects the public posts since Since for TrendingTags. Each post adds
+// to the score of its tags, half as much every HalfLife. Limit is the most tags
+// returned.
+type TrendingQuery struct {
+	Since    time.Time
+	HalfLife time.Duration
+	Limit    int64
+}
+
+// QuotedQuery selects the posts among IDs that ViewerID may see as quoted
+// posts: never by Exclude, by private users only when they are among
+// Following, and within Reach. The IDs count as links to unlisted posts.
+type QuotedQuery struct {
+	ViewerID  primitive.ObjectID
+	IDs       []primitive.ObjectID
+	Exclude   []primitive.ObjectID
+	Following []primitive.ObjectID
+	Reach     Reach
+}
+
+type CommentRepository interface {
+	Create(ctx context.Context, comment *types.Comment) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Comment, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Comment, error)
+	// ListByPost returns the comments of page newest first together with their
+	// authors, including the one extra comment utils.NewPaged needs. Comments by
+	// excludeUsers are left out.
+	ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page, excludeUsers []primitive.ObjectID) ([]types.CommentItem, error)
+	UpdateContent(ctx context.Context, id primitive.ObjectID, content string, mentions []types.Mention) error
+	// CountByPosts returns the number of comments of each post that has any.
+	CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByPosts deletes every comment of the posts and returns how many there were.
+	DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+type LikeRepository interface {
+	Create(ctx context.Context, like *types.Like) error
+	Exists(ctx context.Context, postID, userID primitive.ObjectID) (bool, error)
+	// LikedPosts returns which of the posts userID liked.
+	LikedPosts(ctx context.Context, userID primitive.ObjectID, postIDs []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Like, error)
+	// LikedTags counts the tags of the last recent posts userID liked.
+	LikedTags(ctx context.Context, userID primitive.ObjectID, recent int64) (map[string]int64, error)
+	// LikedAuthors counts the authors of the last recent posts userID liked.
+	LikedAuthors(ctx context.Context, userID primitive.ObjectID, recent int64) (map[primitive.ObjectID]int64, error)
+	// CountByPosts returns the number of likes of each post that has any.
+	CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	// DecayedCountByPosts counts the likes of each post that has any, each
+	// like counting half as much every halfLife.
+	DecayedCountByPosts(ctx context.Context, postIDs []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error)
+	// Delete returns ErrNotFound when userID hasn't liked postID.
+	Delete(ctx context.Context, postID, userID primitive.ObjectID) error
+	// DeleteByPosts deletes every like of the posts and returns how many there were.
+	DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+type SaveRepository interface {
+	// Create returns ErrDuplicate when the user already saved the post.
+	Create(ctx context.Context, save *types.Save) error
+	Exists(ctx context.Context, postID, userID primitive.ObjectID) (bool, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Save, error)
+	// ListByUser returns the saves of userID of page, newest first, including
+	// the one extra save utils.NewPaged needs.
+	ListByUser(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.Save, error)
+	// CountByPosts returns the number of saves of each post that has any.
+	CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	// Delete returns ErrNotFound when userID hasn't saved postID.
+	Delete(ctx context.Context, postID, userID primitive.ObjectID) error
+	// DeleteByPosts deletes every save of the posts and returns how many there were.
+	DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+type CollectionRepository interface {
+	Create(ctx context.Context, collection *types.Collection) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Collection, error)
+	// ListByUser returns the collections of userID, newest first. Private
+	// collections are left out when publicOnly is set.
+	ListByUser(ctx context.Context, userID primitive.ObjectID, publicOnly bool) ([]types.Collection, error)
+	// ListContaining returns the collections of userID that postID is in,
+	// newest first.
+	ListContaining(ctx context.Context, userID, postID primitive.ObjectID) ([]types.Collection, error)
+	// Update sets the given bson fields on the collection and its updatedAt.
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	// Delete deletes the collection together with its items.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByUser deletes the collections of userID together with their items
+	// and returns how many collections there were.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+
+	// AddItem puts the item before the first item of its collection. It returns
+	// ErrDuplicate when the post is in the collection already.
+	AddItem(ctx context.Context, item *types.CollectionItem) error
+	// RemoveItem returns ErrNotFound when postID isn't in the collection.
+	RemoveItem(ctx context.Context, collectionID, postID primitive.ObjectID) error
+	// ListItems returns up to limit items of the collection after skipping
+	// skip, by position.
+	ListItems(ctx context.Context, collectionID primitive.ObjectID, skip, limit int64) ([]types.CollectionItem, error)
+	// ItemPostIDs returns the posts of every item of the collection, by
+	// position.
+	ItemPostIDs(ctx context.Context, collectionID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// Reorder gives the items of postIDs the positions of their index.
+	Reorder(ctx context.Context, collectionID primitive.ObjectID, postIDs []primitive.ObjectID) error
+	// RemoveSaved removes postID from every collection of userID.
+	RemoveSaved(ctx context.Context, userID, postID primitive.ObjectID) (int64, error)
+	// DeleteItemsByPosts removes the posts from every collection and returns
+	// how many items there were.
+	DeleteItemsByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+type FollowRepository interface {
+	// Follow returns ErrDuplicate when followerID already follows followingID.
+	Follow(ctx context.Context, followerID, followingID primitive.ObjectID) error
+	// Unfollow returns ErrNotFound when followerID doesn't follow followingID.
+	Unfollow(ctx context.Context, followerID, followingID primitive.ObjectID) error
+	IsFollowing(ctx context.Context, followerID, followingID primitive.ObjectID) (bool, error)
+	FollowingIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	FollowerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// ListFollowers and ListFollowing return the users of page, most recently
+	// followed first, with their relation to viewerID. They include the one extra
+	// user utils.NewPaged needs.
+	ListFollowers(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error)
+	ListFollowing(ctx context.Context, userID, viewerID primitive.ObjectID, page utils.Page) ([]types.FollowItem, error)
+	// CountFollowedBy counts how many of followerIDs follow each user and returns
+	// the limit users most of them follow. Users in exclude are left out.
+	CountFollowedBy(ctx context.Context, followerIDs, exclude []primitive.ObjectID, limit int64) (map[primitive.ObjectID]int64, error)
+	// Counts returns how many followers each of the users has and how many users
+	// each of them follows. Users without any are left out.
+	Counts(ctx context.Context, userIDs []primitive.ObjectID) (followers, following map[primitive.ObjectID]int64, err error)
+}
+
+type CloseFriendRepository interface {
+	// Add returns ErrDuplicate when the friend is on the user's list already.
+	Add(ctx context.Context, closeFriend *types.CloseFriend) error
+	// Remove returns ErrNotFound when friendID isn't on userID's list.
+	Remove(ctx context.Context, userID, friendID primitive.ObjectID) error
+	// FriendIDs returns the close friends of userID, most recently added first.
+	FriendIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// ListedBy returns the users that have userID on their close friends list.
+	ListedBy(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// DeleteByUser deletes the list of userID and takes them off the lists of
+	// others.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type FollowRequestRepository interface {
+	// Create returns ErrDuplicate when the requester already asked to follow the
+	// target.
+	Create(ctx context.Context, request *types.FollowRequest) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.FollowRequest, error)
+	// FindIncoming returns every request to follow targetID.
+	FindIncoming(ctx context.Context, targetID primitive.ObjectID) ([]types.FollowRequest, error)
+	// FindOutgoing returns every request by requesterID.
+	FindOutgoing(ctx context.Context, requesterID primitive.ObjectID) ([]types.FollowRequest, error)
+	// ListIncoming and ListOutgoing return the requests to and by userID of page,
+	// newest first, with the user on the other side. They include the one extra
+	// request utils.NewPaged needs.
+	ListIncoming(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error)
+	ListOutgoing(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.FollowRequestItem, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByUser deletes the requests to and by userID.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type BlockRepository interface {
+	Create(ctx context.Context, block *types.Block) error
+	Exists(ctx context.Context, userID, blockedID primitive.ObjectID) (bool, error)
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Block, error)
+	// BlockerIDs returns the users that blocked userID.
+	BlockerIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// Delete returns ErrNotFound when userID has not blocked blockedID.
+	Delete(ctx context.Context, userID, blockedID primitive.ObjectID) error
+}
+
+type MuteRepository interface {
+	// Set mutes mute.MutedID for mute.UserID, replacing the scopes and expiry of
+	// an earlier mute.
+	Set(ctx context.Context, mute *types.Mute) error
+	// Delete returns ErrNotFound when userID hasn't muted mutedID.
+	Delete(ctx context.Context, userID, mutedID primitive.ObjectID) error
+	// ListByUser returns the mutes of userID that haven't expired.
+	ListByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Mute, error)
+	// MutedIDs returns the users that userID muted for scope, or for anything
+	// when scope is empty, and that haven't expired.
+	MutedIDs(ctx context.Context, userID primitive.ObjectID, scope string) ([]primitive.ObjectID, error)
+	// IsMuted reports whether userID muted mutedID for scope, or for anything
+	// when scope is empty, and it hasn't expired.
+	IsMuted(ctx context.Context, userID, mutedID primitive.ObjectID, scope string) (bool, error)
+	// DeleteByUser deletes the mutes by and of userID.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type SuggestionRepository interface {
+	// Dismiss keeps dismissedID out of userID's suggestions. Dismissing twice
+	// isn't an error.
+	Dismiss(ctx context.Context, userID, dismissedID primitive.ObjectID) error
+	DismissedIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error)
+	// DeleteByUser deletes the dismissals by and of userID.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// TimelineRepository reads home timelines, either straight from the posts and
+// reposts of the followed users or from timelines precomputed on write.
+type TimelineRepository interface {
+	// List returns a page of the posts and reposts of query.Authors.
+	List(ctx context.Context, query TimelineQuery, page utils.Page) ([]types.TimelineItem, error)
+	// ListStored returns a page of the precomputed timeline of query.ViewerID.
+	ListStored(ctx context.Context, query TimelineQuery, page utils.Page) ([]types.TimelineItem, error)
+	// Entries returns the limit latest posts and reposts of authors as entries
+	// without an owner.
+	Entries(ctx context.Context, authors []primitive.ObjectID, limit int64) ([]types.TimelineEntry, error)
+	// Stored returns which of ownerIDs have a precomputed timeline.
+	Stored(ctx context.Context, ownerIDs []primitive.ObjectID) ([]primitive.ObjectID, error)
+	// Store adds the entries to their owners' timelines. Entries that are
+	// already there are skipped.
+	Store(ctx context.Context, entries []types.TimelineEntry) error
+	// Clear deletes the precomputed timeline of ownerID.
+	Clear(ctx context.Context, ownerID primitive.ObjectID) error
+	// DeleteByUser deletes the timeline of userID and the entries by them.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+// TimelineQuery selects what a timeline shows ViewerID. Posts of private users
+// only show up when they are among Following, and other posts only within
+// Reach, also when they are reposted.
+type TimelineQuery struct {
+	ViewerID primitive.ObjectID
+	// Authors are the users whose posts and reposts make up the timeline.
+	Authors []primitive.ObjectID
+	// Following are the users whose posts ViewerID may see when they're private.
+	Following []primitive.ObjectID
+	// ExcludePosts are the users whose posts are left out, also when someone
+	// else reposted them.
+	ExcludePosts []primitive.ObjectID
+	// ExcludeReposts are the users whose reposts are left out.
+	ExcludeReposts []primitive.ObjectID
+	Reach          Reach
+}
+
+type TagRepository interface {
+	// Increment adds delta to the post counts of the tags. Missing tags are
+	// created when delta is positive and ignored otherwise.
+	Increment(ctx context.Context, names []string, delta int) error
+	// Search returns up to limit tags in use that start with prefix, the most
+	// used first.
+	Search(ctx context.Context, prefix string, limit int64) ([]types.Tag, error)
+}
+
+// SearchQuery is a search for Text as ViewerID. Results by the users in
+// Exclude are left out, and so are the posts of private users that aren't in
+// Following and the posts out of Reach.
+type SearchQuery struct {
+	Text      string
+	ViewerID  primitive.ObjectID
+	Exclude   []primitive.ObjectID
+	Following []primitive.ObjectID
+	Reach     Reach
+	Page      utils.ScorePage
+}
+
+// SearchRepository finds users, posts and tags, the best match first. Each
+// method returns the one extra result utils.NewScorePaged needs. It's kept
+// apart from the other repositories so search can move to a dedicated engine.
+type SearchRepository interface {
+	// Users matches the words of the query against the names and handles of
+	// users that aren't suspended.
+	Users(ctx context.Context, query SearchQuery) ([]types.UserResult, error)
+	// Posts matches the words of the query against the captions and tags of
+	// posts.
+	Posts(ctx context.Context, query SearchQuery) ([]types.PostResult, error)
+	// Tags returns the tags in use that start with the query, the most used
+	// first. Their score is their PostsCount.
+	Tags(ctx context.Context, query SearchQuery) ([]types.Tag, error)
+	// Handles returns up to limit users that aren't suspended or in exclude
+	// whose handle starts with prefix, the most followed first.
+	Handles(ctx context.Context, prefix string, exclude []primitive.ObjectID, limit int64) ([]types.UserResult, error)
+}
+
+type MentionRepository interface {
+	// Rename rewrites every mention of userID in captions, comments and
+	// messages to the new handle, moving the ranges of the mentions after it.
+	// It returns the IDs of the posts that changed.
+	Rename(ctx context.Context, userID primitive.ObjectID, handle string) ([]primitive.ObjectID, error)
+}
+
+type RevisionRepository interface {
+	Create(ctx context.Context, revision *types.PostRevision) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.PostRevision, error)
+	// ListByPost returns the revisions of postID of page, newest first,
+	// including the one extra revision utils.NewPaged needs.
+	ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page) ([]types.PostRevision, error)
+	// Delete only undoes Create; revisions are otherwise kept until their post
+	// is deleted.
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByPosts deletes every revision of the posts and returns how many
+	// there were.
+	DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+type DraftRepository interface {
+	Create(ctx context.Context, draft *types.Draft) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Draft, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Draft, error)
+	// ListByUser returns the drafts of userID of page, newest first, including
+	// the one extra draft utils.NewPaged needs.
+	ListByUser(ctx context.Context, userID primitive.ObjectID, page utils.Page) ([]types.Draft, error)
+	// ListDue returns up to limit drafts whose PublishAt isn't after now,
+	// longest due first.
+	ListDue(ctx context.Context, now time.Time, limit int64) ([]types.Draft, error)
+	Update(ctx context.Context, id primitive.ObjectID, fields bson.M) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByUser deletes the drafts of userID.
+	DeleteByUser(ctx context.Context, userID primitive.ObjectID) (int64, error)
+}
+
+type RepostRepository interface {
+	// Create returns ErrDuplicate when the user already reposted the post.
+	Create(ctx context.Context, repost *types.Repost) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Repost, error)
+	FindByUser(ctx context.Context, userID primitive.ObjectID) ([]types.Repost, error)
+	// ListByPost returns the reposts of page newest first together with who
+	// reposted, including the one extra repost utils.NewPaged needs. Reposts by
+	// excludeUsers and by private users who aren't among following are left
+	// out.
+	ListByPost(ctx context.Context, postID primitive.ObjectID, page utils.Page, excludeUsers, following []primitive.ObjectID) ([]types.RepostItem, error)
+	UpdateCaption(ctx context.Context, id primitive.ObjectID, caption string) error
+	// CountByPosts returns the number of reposts of each post that has any.
+	CountByPosts(ctx context.Context, postIDs []primitive.ObjectID) (map[primitive.ObjectID]int64, error)
+	// DecayedCountByPosts counts the reposts of each post that has any, each
+	// repost counting half as much every halfLife.
+	DecayedCountByPosts(ctx context.Context, postIDs []primitive.ObjectID, halfLife time.Duration) (map[primitive.ObjectID]float64, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// DeleteByPosts deletes every repost of the posts and returns how many there were.
+	DeleteByPosts(ctx context.Context, postIDs []primitive.ObjectID) (int64, error)
+}
+
+type ConversationRepository interface {
+	Create(ctx context.Context, conversation *types.Conversation) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Conversation, error)
+	// FindDirect returns the one-to-one conversation between two users.
+	FindDirect(ctx context.Context, userID, otherID primitive.ObjectID) (types.Conversation, error)
+	ListByParticipant(ctx context.Context, userID primitive.ObjectID) ([]types.Conversation, error)
+	SetLastMessage(ctx context.Context, id primitive.ObjectID, message types.Message) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type MessageRepository interface {
+	Create(ctx context.Context, message *types.Message) error
+	FindByID(ctx context.Context, id primitive.ObjectID) (types.Message, error)
+	ListByConversation(ctx context.Context, conversationID primitive.ObjectID) ([]types.Message, error)
+	// Edit replaces the content and mentions, marks the message as edited and
+	// returns it.
+	Edit(ctx context.Context, id primitive.ObjectID, content string, mentions []types.Mention) (types.Message, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteByConversation(ctx context.Context, conversationID primitive.ObjectID) error
+}
+
+type SettingsRepository interface {
+	Create(ctx context.Context, settings *types.Settings) error
+	FindByUser(ctx context.Context, userID primitive.ObjectID) (types.Settings, error)
+	// Set updates the given bson fields, creating the default settings first if
+	// the user has none yet.
+	Set(ctx context.Context, userID primitive.ObjectID, fields bson.M) error
+	// Delete returns ErrNotFound when the user has no settings.
+	Delete(ctx context.Context, userID primitive.ObjectID) error
+}
+
+type PortfolioRepository interface {
+	Create(ctx context.Context, portfolio *types.Portfolio) error
+	FindByUser(ctx context.Context, userID string) (types.Portfolio, error)
+	// Delete returns ErrNotFound when the user has no portfolio.
+	Delete(ctx context.Context, userID string) error
+}
+
+type MediaRepository interface {
+	Upload(ctx context.Context, filename string, r io.Reader) (primitive.ObjectID, error)
+	// Restore uploads r as file, keeping its ID. It returns ErrDuplicate when
+	// the ID is taken.
+	Restore(ctx context.Context, file MediaFile, r io.Reader) error
+	Find(ctx context.Context, id primitive.ObjectID) (MediaFile, error)
+	Download(ctx context.Context, id primitive.ObjectID, w io.Writer) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	// List returns up to limit files with an ID greater than after, in ID order.
+	List(ctx context.Context, after primitive.ObjectID, limit int64) ([]MediaFile, error)
+	// Referenced returns which of the files are still used by a user, post, post
+	// revision, draft, message or portfolio.
+	Referenced(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]bool, error)
+}
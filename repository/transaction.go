@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"log"
+)
+
+// Transactor runs a group of repository calls all-or-nothing. The repositories
+// must be called with the context passed to fn.
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type undoKey struct{}
+
+type undoLog struct {
+	steps []func(ctx context.Context) error
+}
+
+// Compensating is the Transactor for databases without multi-document
+// transactions. Every step registers how to revert itself with OnRollback and
+// when fn fails the registered steps are reverted in reverse order.
+type Compensating struct{}
+
+func (Compensating) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	undo := &undoLog{}
+
+	err := fn(context.WithValue(ctx, undoKey{}, undo))
+	if err == nil {
+		return nil
+	}
+
+	// Roll back even if the request that started the transaction was cancelled.
+	rollbackCtx := context.WithoutCancel(ctx)
+	for i := len(undo.steps) - 1; i >= 0; i-- {
+		if undoErr := undo.steps[i](rollbackCtx); undoErr != nil {
+			log.Printf("Failed to roll back transaction step: %v", undoErr)
+		}
+	}
+
+	return err
+}
+
+// OnRollback registers how to revert a write that just succeeded. It does nothing
+// when ctx belongs to a real database transaction, which rolls back by itself.
+func OnRollback(ctx context.Context, undo func(ctx context.Context) error) {
+	if undoLog, ok := ctx.Value(undoKey{}).(*undoLog); ok {
+		undoLog.steps = append(undoLog.steps, undo)
+	}
+}
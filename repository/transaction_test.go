@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"slices"
+	"testing"
+)
+
+func TestCompensatingRollback(t *testing.T) {
+	errFailed := errors.New("failed")
+	errUndo := errors.New("undo failed")
+
+	tests := []struct {
+		name string
+		// steps are registered in order and fn fails after them when fail is set.
+		// The undo step of failing returns an error.
+		steps    []string
+		failing  string
+		fail     bool
+		wantErr  error
+		wantUndo []string
+	}{
+		{name: "success", steps: []string{"a", "b", "c"}},
+		{name: "failure reverts in reverse order", steps: []string{"a", "b", "c"}, fail: true, wantErr: errFailed, wantUndo: []string{"c", "b", "a"}},
+		{name: "failure before any step", fail: true, wantErr: errFailed},
+		{name: "failing undo step doesn't stop the others", steps: []string{"a", "b", "c"}, failing: "b", fail: true, wantErr: errFailed, wantUndo: []string{"c", "b", "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var undone []string
+			err := memory.New().Transactions.WithTransaction(ctx, func(ctx context.Context) error {
+				for _, step := range tt.steps {
+					repository.OnRollback(ctx, func(ctx context.Context) error {
+						if ctx.Err() != nil {
+							t.Errorf("step %s rolled back with a cancelled context", step)
+						}
+						undone = append(undone, step)
+						if step == tt.failing {
+							return errUndo
+						}
+						return nil
+					})
+				}
+				if tt.fail {
+					// Rollback must not depend on the request still running.
+					cancel()
+					return errFailed
+				}
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if !slices.Equal(undone, tt.wantUndo) {
+				t.Errorf("undone %v, want %v", undone, tt.wantUndo)
+			}
+		})
+	}
+}
+
+func TestOnRollbackOutsideTransaction(t *testing.T) {
+	repository.OnRollback(context.Background(), func(ctx context.Context) error {
+		t.Error("step registered outside a transaction ran")
+		return nil
+	})
+}
@@ -11,34 +11,158 @@ type User struct {
 	LastName       string             `json:"lastName" bson:"lastName"`
 	Email          string             `json:"email" bson:"email"`
 	Password       string             `json:"password" bson:"password"`
-	Handle         string             `json:"handle"`
+	Handle         string             `json:"handle" bson:"handle"`
 	PhotoURL       string             `json:"photoURL" bson:"photoURL"`
 	BannerURL      string             `json:"bannerURL" bson:"bannerURL"`
 	FollowersCount uint32             `json:"followersCount" bson:"followersCount"`
 	FollowingCount uint32             `json:"followingCount" bson:"followingCount"`
 	Bio            string             `json:"bio" bson:"bio"`
-	FollowedBy     []string           `json:"followedBy" bson:"followedBy"`
-	FollowedUsers  []string           `json:"followedUsers" bson:"followedUsers"`
 	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
 	Settings       *Settings          `json:"settings" bson:"settings"`
 	IsOnline       bool               `json:"isOnline" bson:"isOnline"`
 	LastSeen       time.Time          `json:"lastSeen" bson:"lastSeen"`
+	Roles          []string           `json:"roles,omitempty" bson:"roles,omitempty"`
+	SuspendedAt    *time.Time         `json:"suspendedAt,omitempty" bson:"suspendedAt,omitempty"`
 }
 
+// Roles that can be granted to users.
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+)
+
+var Roles = []string{RoleAdmin, RoleModerator}
+
+// Post is a published post. AltTexts[i] describes Images[i]; EditedAt is when
+// its author last edited it, nil if they never did.
 type Post struct {
-	ID            primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
-	UserID        primitive.ObjectID `json:"userID,omitempty" bson:"userID"`
-	Caption       string             `json:"caption"`
-	Images        []string           `json:"images"`
-	Files         []string           `json:"files"`
-	Tags          []string           `json:"tags"`
-	LikesCount    uint32             `json:"likesCount" bson:"likesCount"`
-	CommentsCount uint32             `json:"commentsCount" bson:"commentsCount"`
-	RepostCount   uint32             `json:"repostCount" bson:"repostCount"`
-	LikedBy       []string           `json:"likedBy" bson:"likedBy"`
-	CommentedBy   []string           `json:"commentedBy" bson:"commentedBy"`
-	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt     time.Time          `json:"updatedAt" bson:"updatedAt"`
+	ID            primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID        primitive.ObjectID  `json:"userID,omitempty" bson:"userID"`
+	Caption       string              `json:"caption"`
+	Images        []string            `json:"images"`
+	AltTexts      []string            `json:"altTexts,omitempty" bson:"altTexts,omitempty"`
+	Files         []string            `json:"files"`
+	Tags          []string            `json:"tags"`
+	LikesCount    uint32              `json:"likesCount" bson:"likesCount"`
+	CommentsCount uint32              `json:"commentsCount" bson:"commentsCount"`
+	RepostCount   uint32              `json:"repostCount" bson:"repostCount"`
+	QuotesCount   uint32              `json:"quotesCount" bson:"quotesCount"`
+	SavesCount    uint32              `json:"savesCount" bson:"savesCount"`
+	QuotedPostID  *primitive.ObjectID `json:"quotedPostID,omitempty" bson:"quotedPostID,omitempty"`
+	Audience      string              `json:"audience,omitempty" bson:"audience,omitempty"`
+	LikedBy       []string            `json:"likedBy" bson:"likedBy"`
+	CommentedBy   []string            `json:"commentedBy" bson:"commentedBy"`
+	CreatedAt     time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time           `json:"updatedAt" bson:"updatedAt"`
+	EditedAt      *time.Time          `json:"editedAt,omitempty" bson:"editedAt,omitempty"`
+	Mentions      []Mention           `json:"mentions,omitempty" bson:"mentions,omitempty"`
+}
+
+// Post audiences. Posts without one are public. Followers-only posts are for
+// the author's followers and close-friends posts for the users on their close
+// friends list. Unlisted posts are for anyone with the link but left out of
+// every list of posts.
+const (
+	AudiencePublic       = "public"
+	AudienceFollowers    = "followers"
+	AudienceCloseFriends = "close_friends"
+	AudienceUnlisted     = "unlisted"
+)
+
+// ValidAudience reports whether audience is one of the post audiences.
+func ValidAudience(audience string) bool {
+	switch audience {
+	case AudiencePublic, AudienceFollowers, AudienceCloseFriends, AudienceUnlisted:
+		return true
+	default:
+		return false
+	}
+}
+
+// Mention is a user mentioned as @handle in a caption, comment or message.
+// Start and End are the character (not byte) offsets of "@handle" in the
+// text, End excluded.
+type Mention struct {
+	UserID primitive.ObjectID `json:"userID" bson:"userID"`
+	Handle string             `json:"handle" bson:"handle"`
+	Start  int                `json:"start" bson:"start"`
+	End    int                `json:"end" bson:"end"`
+}
+
+// PostRevision is how a post looked before one of its edits. Revisions are
+// never changed; CreatedAt is when the edit replaced it.
+type PostRevision struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	PostID    primitive.ObjectID `json:"postID" bson:"postID"`
+	Caption   string             `json:"caption" bson:"caption"`
+	Images    []string           `json:"images" bson:"images"`
+	AltTexts  []string           `json:"altTexts,omitempty" bson:"altTexts,omitempty"`
+	Tags      []string           `json:"tags" bson:"tags"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Tag is a tag and the number of posts that use it.
+type Tag struct {
+	Name       string    `json:"name" bson:"_id"`
+	PostsCount int64     `json:"postsCount" bson:"postsCount"`
+	LastUsedAt time.Time `json:"lastUsedAt" bson:"lastUsedAt"`
+}
+
+// TrendingTag is a tag with how much it was used lately. PostsCount only counts
+// the posts of the trending window.
+type TrendingTag struct {
+	Name       string  `json:"name" bson:"_id"`
+	Score      float64 `json:"score" bson:"score"`
+	PostsCount int64   `json:"postsCount" bson:"postsCount"`
+}
+
+// Draft is a post that isn't published yet. The scheduler publishes drafts
+// once their PublishAt has passed; Timezone is the one it was given in.
+type Draft struct {
+	ID      primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID  primitive.ObjectID `json:"userID" bson:"userID"`
+	Caption string             `json:"caption" bson:"caption"`
+	Images  []string           `json:"images" bson:"images"`
+	Files   []string           `json:"files" bson:"files"`
+	// Uploads are the images and files uploaded with the draft. Deleting the
+	// draft deletes them.
+	Uploads      []string            `json:"uploads,omitempty" bson:"uploads,omitempty"`
+	Tags         []string            `json:"tags" bson:"tags"`
+	QuotedPostID *primitive.ObjectID `json:"quotedPostID,omitempty" bson:"quotedPostID,omitempty"`
+	Audience     string              `json:"audience,omitempty" bson:"audience,omitempty"`
+	PublishAt    *time.Time          `json:"publishAt,omitempty" bson:"publishAt,omitempty"`
+	Timezone     string              `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	// PublishError is why publishing the draft at its scheduled time failed.
+	// Scheduling it again clears it.
+	PublishError string    `json:"publishError,omitempty" bson:"publishError,omitempty"`
+	CreatedAt    time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// FeedItem is a post together with the author fields shown next to it.
+type FeedItem struct {
+	Post          Post   `json:"post"`
+	UserName      string `json:"userName" bson:"userName"`
+	PhotoURL      string `json:"photoURL" bson:"photoURL"`
+	Handle        string `json:"handle"`
+	LikedByViewer bool   `json:"likedByViewer" bson:"likedByViewer"`
+	// Quoted is filled in by the quote package for quote posts.
+	Quoted *QuotedPost `json:"quoted,omitempty" bson:"-"`
+}
+
+// QuotedPost is the post a quote post embeds. Item is left out when the post
+// has been deleted or the viewer can't see it.
+type QuotedPost struct {
+	PostID    primitive.ObjectID `json:"postID"`
+	Available bool               `json:"available"`
+	Item      *FeedItem          `json:"item,omitempty"`
+}
+
+func (f *FeedItem) ResolveMedia(resolve func(id string) string) {
+	f.PhotoURL = resolve(f.PhotoURL)
+	for i := range f.Post.Images {
+		f.Post.Images[i] = resolve(f.Post.Images[i])
+	}
 }
 
 type Message struct {
@@ -53,6 +177,7 @@ type Message struct {
 	IsEdited       bool               `json:"isEdited" bson:"isEdited"`
 	IsReply        bool               `json:"isReply" bson:"isReply"`
 	ReplyTo        primitive.ObjectID `json:"replyTo" bson:"replyTo"`
+	Mentions       []Mention          `json:"mentions,omitempty" bson:"mentions,omitempty"`
 }
 
 type Participant struct {
@@ -81,14 +206,69 @@ type Like struct {
 	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
 }
 
+// Save is a post a user saved. Saved posts can be put in any of the user's
+// collections.
+type Save struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	PostID    primitive.ObjectID `json:"postID" bson:"postID"`
+	UserID    primitive.ObjectID `json:"userID" bson:"userID"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// Collection is a named list of saved posts. Only its owner sees it unless
+// its Visibility is VisibilityPublic, which shares it on their profile.
+type Collection struct {
+	ID          primitive.ObjectID  `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID  `json:"userID" bson:"userID"`
+	Name        string              `json:"name" bson:"name"`
+	Description string              `json:"description" bson:"description"`
+	Visibility  string              `json:"visibility" bson:"visibility"`
+	CoverPostID *primitive.ObjectID `json:"coverPostID,omitempty" bson:"coverPostID,omitempty"`
+	CreatedAt   time.Time           `json:"createdAt" bson:"createdAt"`
+	UpdatedAt   time.Time           `json:"updatedAt" bson:"updatedAt"`
+	// ItemsCount and Cover, the first image of the cover post, are filled in
+	// for the viewer by the collections handler.
+	ItemsCount int    `json:"itemsCount" bson:"-"`
+	Cover      string `json:"cover,omitempty" bson:"-"`
+}
+
+// CollectionItem is a post in a collection. Items are listed by Position,
+// lowest first.
+type CollectionItem struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	CollectionID primitive.ObjectID `json:"collectionID" bson:"collectionID"`
+	UserID       primitive.ObjectID `json:"userID" bson:"userID"`
+	PostID       primitive.ObjectID `json:"postID" bson:"postID"`
+	Position     int64              `json:"position" bson:"position"`
+	AddedAt      time.Time          `json:"addedAt" bson:"addedAt"`
+}
+
 type Comment struct {
 	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	PostID    primitive.ObjectID `json:"postID" bson:"postID"`
 	UserID    primitive.ObjectID `json:"userID" bson:"userID"`
 	Content   string             `json:"content"`
+	Mentions  []Mention          `json:"mentions,omitempty" bson:"mentions,omitempty"`
 	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
 }
 
+// CommentItem is a comment together with the author fields shown next to it.
+type CommentItem struct {
+	Comment  Comment `json:"comment" bson:"comment"`
+	UserName string  `json:"userName" bson:"userName"`
+	PhotoURL string  `json:"photoURL" bson:"photoURL"`
+}
+
+func (c *CommentItem) ResolveMedia(resolve func(id string) string) {
+	c.PhotoURL = resolve(c.PhotoURL)
+}
+
+// Profile visibilities. Private accounts approve their followers.
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
 type Settings struct {
 	ID                primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	UserID            primitive.ObjectID `json:"userID" bson:"userID"`
@@ -97,9 +277,109 @@ type Settings struct {
 	ProfileVisibility string             `json:"profileVisibility" bson:"profileVisibility"`
 }
 
+func DefaultSettings(userID primitive.ObjectID) Settings {
+	return Settings{
+		UserID:            userID,
+		Theme:             "light",
+		Language:          "en",
+		ProfileVisibility: VisibilityPublic,
+	}
+}
+
 type Follow struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
 	FollowerID  primitive.ObjectID `json:"followerID" bson:"followerID"` // user that follows
 	FollowingID primitive.ObjectID `json:"followedID" bson:"followedID"` // user that is being followed
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// FollowItem is a user in a followers or following list. FollowsYou and YouFollow
+// relate the user to the viewer of the list.
+type FollowItem struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	FirstName  string             `json:"firstName" bson:"firstName"`
+	LastName   string             `json:"lastName" bson:"lastName"`
+	Handle     string             `json:"handle" bson:"handle"`
+	PhotoURL   string             `json:"photoURL" bson:"photoURL"`
+	Bio        string             `json:"bio" bson:"bio"`
+	FollowedAt time.Time          `json:"followedAt" bson:"followedAt"`
+	FollowsYou bool               `json:"followsYou" bson:"followsYou"`
+	YouFollow  bool               `json:"youFollow" bson:"youFollow"`
+	// FollowID identifies the follow for pagination.
+	FollowID primitive.ObjectID `json:"-" bson:"followID"`
+}
+
+func (f *FollowItem) ResolveMedia(resolve func(id string) string) {
+	f.PhotoURL = resolve(f.PhotoURL)
+}
+
+// Suggestion is a user suggested to follow, with the signals it was ranked by:
+// how many of the users the viewer follows follow them, how many of their recent
+// posts have tags the viewer likes and how many posts they made recently.
+type Suggestion struct {
+	ID            primitive.ObjectID `json:"_id" bson:"_id"`
+	FirstName     string             `json:"firstName" bson:"firstName"`
+	LastName      string             `json:"lastName" bson:"lastName"`
+	Handle        string             `json:"handle" bson:"handle"`
+	PhotoURL      string             `json:"photoURL" bson:"photoURL"`
+	Bio           string             `json:"bio" bson:"bio"`
+	MutualFollows int64              `json:"mutualFollows" bson:"mutualFollows"`
+	TagMatches    int64              `json:"tagMatches" bson:"tagMatches"`
+	RecentPosts   int64              `json:"recentPosts" bson:"recentPosts"`
+	Score         float64            `json:"score" bson:"score"`
+}
+
+// Dismissal keeps DismissedID out of UserID's suggestions.
+type Dismissal struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"userID" bson:"userID"`
+	DismissedID primitive.ObjectID `json:"dismissedID" bson:"dismissedID"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// FollowRequest is a pending follow of a private account.
+type FollowRequest struct {
+	ID          primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	RequesterID primitive.ObjectID `json:"requesterID" bson:"requesterID"` // user that wants to follow
+	TargetID    primitive.ObjectID `json:"targetID" bson:"targetID"`       // private account being requested
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// FollowRequestItem is a follow request in the incoming or outgoing list, with
+// the user on the other side of it.
+type FollowRequestItem struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"userID" bson:"userID"`
+	FirstName string             `json:"firstName" bson:"firstName"`
+	LastName  string             `json:"lastName" bson:"lastName"`
+	Handle    string             `json:"handle" bson:"handle"`
+	PhotoURL  string             `json:"photoURL" bson:"photoURL"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+func (r *FollowRequestItem) ResolveMedia(resolve func(id string) string) {
+	r.PhotoURL = resolve(r.PhotoURL)
+}
+
+// Notification types.
+const (
+	NotificationFollow         = "follow"
+	NotificationFollowRequest  = "follow_request"
+	NotificationFollowAccepted = "follow_accepted"
+	// The mention notifications have the post, comment or message as subject.
+	NotificationPostMention    = "post_mention"
+	NotificationCommentMention = "comment_mention"
+	NotificationMessageMention = "message_mention"
+)
+
+// Notification tells RecipientID that ActorID did something. SubjectID is what
+// it was done to, like the follow request, if anything.
+type Notification struct {
+	Type        string             `json:"type" bson:"type"`
+	RecipientID primitive.ObjectID `json:"recipientID" bson:"recipientID"`
+	ActorID     primitive.ObjectID `json:"actorID" bson:"actorID"`
+	SubjectID   primitive.ObjectID `json:"subjectID,omitempty" bson:"subjectID,omitempty"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
 }
 
 type Block struct {
@@ -109,6 +389,39 @@ type Block struct {
 	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
 }
 
+// CloseFriend puts FriendID on the close friends list of UserID, who can then
+// share close-friends posts with them.
+type CloseFriend struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"userID" bson:"userID"`
+	FriendID  primitive.ObjectID `json:"friendID" bson:"friendID"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// What a mute hides from the user who muted.
+const (
+	MuteScopePosts    = "posts"
+	MuteScopeReposts  = "reposts"
+	MuteScopeComments = "comments"
+	MuteScopeMessages = "messages"
+	// MuteScopeNotifications stops the notifications the muted user causes,
+	// like follows and mentions.
+	MuteScopeNotifications = "notifications"
+)
+
+var MuteScopes = []string{MuteScopePosts, MuteScopeReposts, MuteScopeComments, MuteScopeMessages, MuteScopeNotifications}
+
+// Mute hides what MutedID posts in Scopes from UserID until ExpiresAt, or for
+// good when ExpiresAt is nil. The muted user isn't told.
+type Mute struct {
+	ID        primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"userID" bson:"userID"`   // who is muting
+	MutedID   primitive.ObjectID `json:"mutedID" bson:"mutedID"` // who is being muted
+	Scopes    []string           `json:"scopes" bson:"scopes"`
+	ExpiresAt *time.Time         `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
 type Portfolio struct {
 	ID          string               `json:"_id,omitempty" bson:"_id,omitempty"`
 	UserID      string               `json:"userID" bson:"userID"`
@@ -148,3 +461,92 @@ type Repost struct {
 	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
 	UpdatedAt     time.Time          `json:"updatedAt" bson:"updatedAt"`
 }
+
+// TimelineItem is a post in a home timeline or profile, either posted or
+// reposted by its owner or someone the viewer follows. ID and CreatedAt are the
+// ones of the repost for reposts.
+type TimelineItem struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	FeedItem  `bson:",inline"`
+	Repost    *RepostItem `json:"repost,omitempty" bson:"repost,omitempty"`
+	CreatedAt time.Time   `json:"createdAt" bson:"createdAt"`
+}
+
+func (t *TimelineItem) ResolveMedia(resolve func(id string) string) {
+	t.FeedItem.ResolveMedia(resolve)
+	if t.Repost != nil {
+		t.Repost.ResolveMedia(resolve)
+	}
+}
+
+// RepostItem is a repost with the user who reposted.
+type RepostItem struct {
+	ID        primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID    primitive.ObjectID `json:"userID" bson:"userID"`
+	UserName  string             `json:"userName" bson:"userName"`
+	PhotoURL  string             `json:"photoURL" bson:"photoURL"`
+	Handle    string             `json:"handle" bson:"handle"`
+	Caption   string             `json:"caption" bson:"caption"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+func (r *RepostItem) ResolveMedia(resolve func(id string) string) {
+	r.PhotoURL = resolve(r.PhotoURL)
+}
+
+// TimelineEntry is a post or repost in the precomputed timeline of OwnerID.
+// AuthorID posted or reposted it and PostAuthorID wrote the post.
+type TimelineEntry struct {
+	ID           primitive.ObjectID `json:"_id,omitempty" bson:"_id,omitempty"`
+	OwnerID      primitive.ObjectID `json:"ownerID" bson:"ownerID"`
+	PostID       primitive.ObjectID `json:"postID" bson:"postID"`
+	RepostID     primitive.ObjectID `json:"repostID,omitempty" bson:"repostID,omitempty"`
+	AuthorID     primitive.ObjectID `json:"authorID" bson:"authorID"`
+	PostAuthorID primitive.ObjectID `json:"postAuthorID" bson:"postAuthorID"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// UserResult is a user found by search. Score is how well they match; handle
+// completions don't have one.
+type UserResult struct {
+	ID             primitive.ObjectID `json:"_id" bson:"_id"`
+	FirstName      string             `json:"firstName" bson:"firstName"`
+	LastName       string             `json:"lastName" bson:"lastName"`
+	Handle         string             `json:"handle" bson:"handle"`
+	PhotoURL       string             `json:"photoURL" bson:"photoURL"`
+	Bio            string             `json:"bio" bson:"bio"`
+	FollowersCount uint32             `json:"followersCount" bson:"followersCount"`
+	Score          float64            `json:"score,omitempty" bson:"score"`
+}
+
+// PostResult is a post found by search. Score is how well it matches.
+type PostResult struct {
+	FeedItem `bson:",inline"`
+	Score    float64 `json:"score" bson:"score"`
+}
+
+// RankedItem is a post in the For You feed. Explanation is only set in debug
+// mode.
+type RankedItem struct {
+	FeedItem
+	Score       float64           `json:"score"`
+	Explanation *ScoreExplanation `json:"explanation,omitempty"`
+}
+
+// ScoreExplanation breaks the score of a RankedItem down into its signals.
+type ScoreExplanation struct {
+	Engagement     Signal `json:"engagement"`
+	TagAffinity    Signal `json:"tagAffinity"`
+	AuthorAffinity Signal `json:"authorAffinity"`
+	Freshness      Signal `json:"freshness"`
+	// Rank is where the score put the post, before the diversity rules moved
+	// it down.
+	Rank int `json:"rank"`
+}
+
+// Signal is one input of a ranking score. Score is Value scaled and weighted.
+type Signal struct {
+	Value  float64 `json:"value"`
+	Weight float64 `json:"weight"`
+	Score  float64 `json:"score"`
+}
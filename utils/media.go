@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"go.mongodb.org/mongo-driver/mongo"
+	"strings"
+)
+
+// MediaResolver is implemented by read models that reference uploaded media by
+// GridFS ID and should expose URLs instead.
+type MediaResolver interface {
+	ResolveMedia(resolve func(id string) string)
+}
+
+// MediaURL returns the URL the uploaded file id is served from. Empty values and
+// values that already are URLs are returned unchanged.
+func MediaURL(id string) string {
+	if id == "" || strings.Contains(id, "://") {
+		return id
+	}
+	return BuildImgURL(id)
+}
+
+// ResolveMedia rewrites the media IDs of v into URLs if v is a MediaResolver.
+func ResolveMedia(v interface{}) {
+	if resolver, ok := v.(MediaResolver); ok {
+		resolver.ResolveMedia(MediaURL)
+	}
+}
+
+// DecodeAll decodes every document of cursor into a T and resolves its media.
+func DecodeAll[T any](ctx context.Context, cursor *mongo.Cursor) ([]T, error) {
+	var result []T
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	for i := range result {
+		ResolveMedia(&result[i])
+	}
+
+	return result, nil
+}
@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 50
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the position of a document in a list sorted by (createdAt, _id),
+// newest first.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        primitive.ObjectID
+}
+
+// Encode returns the cursor as an opaque string for clients.
+func (c Cursor) Encode() string {
+	raw := fmt.Sprintf("%d_%s", c.CreatedAt.UnixMilli(), c.ID.Hex())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(s string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	millis, hexID, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	id, err := ParseHexID(hexID)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	return Cursor{CreatedAt: time.UnixMilli(ms).UTC(), ID: id}, nil
+}
+
+// Precedes reports whether the cursor comes before the document at
+// (createdAt, id) in newest-first order.
+func (c Cursor) Precedes(createdAt time.Time, id primitive.ObjectID) bool {
+	createdAt = createdAt.Truncate(time.Millisecond)
+	if !createdAt.Equal(c.CreatedAt) {
+		return createdAt.Before(c.CreatedAt)
+	}
+	return id.Hex() < c.ID.Hex()
+}
+
+// Page selects a slice of a newest-first list. After takes precedence over
+// Skip, which only remains for clients that still paginate by page number.
+type Page struct {
+	After *Cursor
+	Skip  int64
+	Limit int64
+}
+
+// ParseLimit reads the limit query parameter, DefaultPageLimit when it's missing
+// and at most MaxPageLimit.
+func ParseLimit(c *fiber.Ctx) (int64, error) {
+	limit := c.Query("limit")
+	if limit == "" {
+		return DefaultPageLimit, nil
+	}
+
+	l, err := strconv.ParseInt(limit, 10, 64)
+	if err != nil || l < 1 {
+		return 0, errors.New("invalid limit")
+	}
+	return min(l, MaxPageLimit), nil
+}
+
+// ParsePage reads the cursor, limit and legacy page query parameters.
+func ParsePage(c *fiber.Ctx) (Page, error) {
+	limit, err := ParseLimit(c)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Limit: limit}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := DecodeCursor(cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		page.After = &after
+		return page, nil
+	}
+
+	if n, err := strconv.ParseInt(c.Query("page", "1"), 10, 64); err == nil && n > 1 {
+		page.Skip = (n - 1) * page.Limit
+	}
+
+	return page, nil
+}
+
+// Paged is a page of a list together with the cursor of the next page.
+type Paged[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+}
+
+// NewPaged builds the page from items fetched with Paginate, which fetches one
+// item more than the limit to tell whether there are more.
+func NewPaged[T any](items []T, page Page, cursor func(T) Cursor) Paged[T] {
+	paged := Paged[T]{Items: items}
+	if paged.Items == nil {
+		paged.Items = []T{}
+	}
+
+	if int64(len(items)) > page.Limit {
+		paged.Items = items[:page.Limit]
+		paged.HasMore = true
+		paged.NextCursor = cursor(paged.Items[len(paged.Items)-1]).Encode()
+	}
+
+	return paged
+}
+
+// Paginate sorts by (createdAt, _id) newest first and selects page, fetching one
+// document more than page.Limit for NewPaged.
+func (pb *PipelineBuilder) Paginate(page Page) *PipelineBuilder {
+	if page.After != nil {
+		pb.Match(bson.D{{"$or", bson.A{
+			bson.D{{"createdAt", bson.D{{"$lt", page.After.CreatedAt}}}},
+			bson.D{{"createdAt", page.After.CreatedAt}, {"_id", bson.D{{"$lt", page.After.ID}}}},
+		}}})
+	}
+
+	pb.stages = append(pb.stages, bson.D{{"$sort", bson.D{{"createdAt", -1}, {"_id", -1}}}})
+
+	if page.After == nil && page.Skip > 0 {
+		pb.Skip(page.Skip)
+	}
+
+	return pb.Limit(page.Limit + 1)
+}
+
+// ScoreCursor is the position of a result in a list sorted by score, best
+// first, and then by ID.
+type ScoreCursor struct {
+	Score float64
+	ID    string
+}
+
+// Encode returns the cursor as an opaque string for clients.
+func (c ScoreCursor) Encode() string {
+	raw := strconv.FormatFloat(c.Score, 'g', -1, 64) + "_" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeScoreCursor(s string) (ScoreCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return ScoreCursor{}, ErrInvalidCursor
+	}
+
+	score, id, ok := strings.Cut(string(raw), "_")
+	if !ok || id == "" {
+		return ScoreCursor{}, ErrInvalidCursor
+	}
+
+	f, err := strconv.ParseFloat(score, 64)
+	if err != nil {
+		return ScoreCursor{}, ErrInvalidCursor
+	}
+
+	return ScoreCursor{Score: f, ID: id}, nil
+}
+
+// Precedes reports whether the cursor comes before the result with score and
+// id.
+func (c ScoreCursor) Precedes(score float64, id string) bool {
+	if score != c.Score {
+		return score < c.Score
+	}
+	return id > c.ID
+}
+
+// ScorePage selects a slice of a list sorted by score.
+type ScorePage struct {
+	After *ScoreCursor
+	Limit int64
+}
+
+// ParseScorePage reads the cursor and limit query parameters.
+func ParseScorePage(c *fiber.Ctx) (ScorePage, error) {
+	limit, err := ParseLimit(c)
+	if err != nil {
+		return ScorePage{}, err
+	}
+	page := ScorePage{Limit: limit}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		after, err := DecodeScoreCursor(cursor)
+		if err != nil {
+			return ScorePage{}, err
+		}
+		page.After = &after
+	}
+
+	return page, nil
+}
+
+// NewScorePaged is NewPaged for lists sorted by score.
+func NewScorePaged[T any](items []T, page ScorePage, cursor func(T) ScoreCursor) Paged[T] {
+	paged := Paged[T]{Items: items}
+	if paged.Items == nil {
+		paged.Items = []T{}
+	}
+
+	if int64(len(items)) > page.Limit {
+		paged.Items = items[:page.Limit]
+		paged.HasMore = true
+		paged.NextCursor = cursor(paged.Items[len(paged.Items)-1]).Encode()
+	}
+
+	return paged
+}
+
+// PaginateByScore sorts by (score, _id), best first, and selects page, fetching
+// one document more than page.Limit for NewScorePaged. afterID is the ID of
+// page.After as stored in _id.
+func (pb *PipelineBuilder) PaginateByScore(page ScorePage, afterID interface{}) *PipelineBuilder {
+	if page.After != nil {
+		pb.Match(bson.D{{"$or", bson.A{
+			bson.D{{"score", bson.D{{"$lt", page.After.Score}}}},
+			bson.D{{"score", page.After.Score}, {"_id", bson.D{{"$gt", afterID}}}},
+		}}})
+	}
+
+	pb.stages = append(pb.stages, bson.D{{"$sort", bson.D{{"score", -1}, {"_id", 1}}}})
+
+	return pb.Limit(page.Limit + 1)
+}
+
+// RespondWithPage sends the page as {items, nextCursor, hasMore}. v1 clients
+// expect a bare array, so they get the items with the cursor in X-Next-Cursor.
+func RespondWithPage[T any](c *fiber.Ctx, paged Paged[T]) error {
+	if GetAPIVersion(c) >= 2 {
+		return c.Status(200).JSON(paged)
+	}
+
+	if paged.HasMore {
+		c.Set("X-Next-Cursor", paged.NextCursor)
+	}
+	return c.Status(200).JSON(paged.Items)
+}
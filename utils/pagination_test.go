@@ -0,0 +1,130 @@
+package utils_test
+
+import (
+	"context"
+	"errors"
+	"github.com/edisss1/fiabesco-backend/repository"
+	"github.com/edisss1/fiabesco-backend/repository/memory"
+	"github.com/edisss1/fiabesco-backend/types"
+	"github.com/edisss1/fiabesco-backend/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    utils.Cursor
+		wantErr error
+	}{
+		{
+			name:    "truncated to milliseconds",
+			encoded: utils.Cursor{CreatedAt: createdAt, ID: id}.Encode(),
+			want:    utils.Cursor{CreatedAt: createdAt.Truncate(time.Millisecond), ID: id},
+		},
+		{
+			name:    "before the epoch",
+			encoded: utils.Cursor{CreatedAt: time.UnixMilli(-1500).UTC(), ID: id}.Encode(),
+			want:    utils.Cursor{CreatedAt: time.UnixMilli(-1500).UTC(), ID: id},
+		},
+		{name: "not base64", encoded: "%%%", wantErr: utils.ErrInvalidCursor},
+		{name: "missing separator", encoded: "MTIz", wantErr: utils.ErrInvalidCursor},
+		{name: "invalid ID", encoded: utils.Cursor{CreatedAt: createdAt}.Encode() + "AA", wantErr: utils.ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := utils.DecodeCursor(tt.encoded)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (!got.CreatedAt.Equal(tt.want.CreatedAt) || got.ID != tt.want.ID) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginateWithCursors(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		posts int
+		limit int64
+		// step separates the createdAt of consecutive posts. Below a
+		// millisecond, cursors only tell them apart by ID.
+		step time.Duration
+	}{
+		{name: "empty", posts: 0, limit: 10, step: time.Minute},
+		{name: "single page", posts: 7, limit: 10, step: time.Minute},
+		{name: "exact pages", posts: 20, limit: 10, step: time.Minute},
+		{name: "partial last page", posts: 23, limit: 10, step: time.Minute},
+		{name: "ties on createdAt", posts: 12, limit: 5},
+		{name: "sub-millisecond times", posts: 9, limit: 2, step: 100 * time.Microsecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repos := memory.New()
+			author := primitive.NewObjectID()
+
+			for i := 0; i < tt.posts; i++ {
+				post := types.Post{UserID: author, CreatedAt: base.Add(time.Duration(i) * tt.step)}
+				if err := repos.Posts.Create(ctx, &post); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			seen := map[primitive.ObjectID]bool{}
+			var previous *utils.Cursor
+			page := utils.Page{Limit: tt.limit}
+			for pages := 0; ; pages++ {
+				if pages > tt.posts {
+					t.Fatal("pagination doesn't end")
+				}
+
+				items, err := repos.Posts.ListFeed(ctx, primitive.NilObjectID, page, repository.FeedFilter{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				paged := utils.NewPaged(items, page, func(item types.FeedItem) utils.Cursor {
+					return utils.Cursor{CreatedAt: item.Post.CreatedAt, ID: item.Post.ID}
+				})
+
+				for _, item := range paged.Items {
+					if seen[item.Post.ID] {
+						t.Fatalf("post %s listed twice", item.Post.ID.Hex())
+					}
+					seen[item.Post.ID] = true
+					if previous != nil && !previous.Precedes(item.Post.CreatedAt, item.Post.ID) {
+						t.Fatalf("post %s out of order", item.Post.ID.Hex())
+					}
+					previous = &utils.Cursor{CreatedAt: item.Post.CreatedAt.Truncate(time.Millisecond), ID: item.Post.ID}
+				}
+
+				if !paged.HasMore {
+					if paged.NextCursor != "" {
+						t.Error("last page has a next cursor")
+					}
+					break
+				}
+				after, err := utils.DecodeCursor(paged.NextCursor)
+				if err != nil {
+					t.Fatal(err)
+				}
+				page.After = &after
+			}
+
+			if len(seen) != tt.posts {
+				t.Errorf("listed %d posts, want %d", len(seen), tt.posts)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package utils
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"sort"
+)
+
+type PipelineBuilder struct {
+	stages mongo.Pipeline
+}
+
+// Fragment is a reusable sequence of stages, added with PipelineBuilder.Apply.
+type Fragment func(pb *PipelineBuilder) *PipelineBuilder
+
+func NewPipeline() *PipelineBuilder {
+	return &PipelineBuilder{stages: mongo.Pipeline{}}
+}
+
+func (pb *PipelineBuilder) Sort(field string, order int) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$sort", bson.D{{field, order}}}})
+	return pb
+}
+
+func (pb *PipelineBuilder) Match(field bson.D) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$match", field}})
+	return pb
+}
+
+func (pb *PipelineBuilder) Skip(n int64) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$skip", n}})
+	return pb
+}
+
+func (pb *PipelineBuilder) Limit(n int64) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$limit", n}})
+	return pb
+}
+
+func (pb *PipelineBuilder) Lookup(from, localField, foreignField, as string) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$lookup", bson.D{
+		{"from", from},
+		{"localField", localField},
+		{"foreignField", foreignField},
+		{"as", as},
+	}}})
+
+	return pb
+}
+
+// LookupPipeline joins the documents of from that pipeline selects. The let
+// variables are available in pipeline as $$name.
+func (pb *PipelineBuilder) LookupPipeline(from string, let bson.D, pipeline *PipelineBuilder, as string) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$lookup", bson.D{
+		{"from", from},
+		{"let", let},
+		{"pipeline", pipeline.Build()},
+		{"as", as},
+	}}})
+
+	return pb
+}
+
+// UnionWith appends the documents pipeline selects from coll.
+func (pb *PipelineBuilder) UnionWith(coll string, pipeline *PipelineBuilder) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$unionWith", bson.D{
+		{"coll", coll},
+		{"pipeline", pipeline.Build()},
+	}}})
+
+	return pb
+}
+
+func (pb *PipelineBuilder) Unwind(path string, preserve bool) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$unwind", bson.D{
+		{"path", path},
+		{"preserveNullAndEmptyArrays", preserve},
+	}}})
+
+	return pb
+}
+
+func (pb *PipelineBuilder) Project(fields bson.D) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$project", fields}})
+	return pb
+}
+
+func (pb *PipelineBuilder) AddFields(fields bson.D) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$addFields", fields}})
+	return pb
+}
+
+func (pb *PipelineBuilder) Unset(fields ...string) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$unset", fields}})
+	return pb
+}
+
+// Group groups by id, which is an expression like "$postID", and computes the
+// accumulator fields for each group.
+func (pb *PipelineBuilder) Group(id interface{}, fields bson.D) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$group", append(bson.D{{"_id", id}}, fields...)}})
+	return pb
+}
+
+// Facet runs each of the pipelines on the same input and outputs one document
+// with their results under the facet names.
+func (pb *PipelineBuilder) Facet(facets map[string]*PipelineBuilder) *PipelineBuilder {
+	names := make([]string, 0, len(facets))
+	for name := range facets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	spec := make(bson.D, 0, len(names))
+	for _, name := range names {
+		spec = append(spec, bson.E{Key: name, Value: facets[name].Build()})
+	}
+
+	pb.stages = append(pb.stages, bson.D{{"$facet", spec}})
+	return pb
+}
+
+// Count replaces the documents with one document holding their number in field.
+func (pb *PipelineBuilder) Count(field string) *PipelineBuilder {
+	pb.stages = append(pb.stages, bson.D{{"$count", field}})
+	return pb
+}
+
+func (pb *PipelineBuilder) Apply(fragments ...Fragment) *PipelineBuilder {
+	for _, fragment := range fragments {
+		pb = fragment(pb)
+	}
+	return pb
+}
+
+func (pb *PipelineBuilder) Build() mongo.Pipeline {
+	return pb.stages
+}
+
+// WithAuthor joins the user referenced by localField and adds their userName,
+// photoURL and handle to the document.
+func WithAuthor(localField string) Fragment {
+	return func(pb *PipelineBuilder) *PipelineBuilder {
+		return pb.
+			Lookup("users", localField, "_id", "author").
+			Unwind("$author", true).
+			AddFields(bson.D{
+				{"userName", bson.D{{"$concat", bson.A{"$author.firstName", " ", "$author.lastName"}}}},
+				{"photoURL", "$author.photoURL"},
+				{"handle", "$author.handle"},
+			}).
+			Unset("author")
+	}
+}
+
+// ViewerHasLiked adds likedByViewer, whether viewerID liked the post. It does
+// nothing for anonymous viewers.
+func ViewerHasLiked(viewerID primitive.ObjectID) Fragment {
+	return func(pb *PipelineBuilder) *PipelineBuilder {
+		if viewerID.IsZero() {
+			return pb
+		}
+
+		likes := NewPipeline().
+			Match(bson.D{{"$expr", bson.D{{"$and", bson.A{
+				bson.D{{"$eq", bson.A{"$postID", "$$postID"}}},
+				bson.D{{"$eq", bson.A{"$userID", viewerID}}},
+			}}}}}).
+			Limit(1).
+			Project(bson.D{{"_id", 1}})
+
+		return pb.
+			LookupPipeline("likes", bson.D{{"postID", "$_id"}}, likes, "viewerLike").
+			AddFields(bson.D{{"likedByViewer", bson.D{{"$gt", bson.A{bson.D{{"$size", "$viewerLike"}}, 0}}}}}).
+			Unset("viewerLike")
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"errors"
+	"time"
+	// Embedded so timezones resolve on hosts without a zoneinfo database.
+	_ "time/tzdata"
+)
+
+var ErrInvalidTime = errors.New("invalid time")
+
+// localLayouts are the accepted date and time layouts without an offset.
+var localLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// ParseLocalTime parses value as RFC 3339, or as a date and time without an
+// offset like "2026-05-01T18:00" in the IANA timezone, UTC when it's empty.
+// An offset in value wins over timezone.
+func ParseLocalTime(value, timezone string) (time.Time, error) {
+	location := time.UTC
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return time.Time{}, ErrInvalidTime
+		}
+		location = loc
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, value, location); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, ErrInvalidTime
+}
@@ -1,23 +1,18 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
 	"math/rand"
 )
 
-var baseImgURL = "http://localhost:3000/images"
+var baseImgURL = "http://localhost:3000/v1/images"
 
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 
-type PipelineBuilder struct {
-	stages mongo.Pipeline
-}
-
 func GenerateHandle(l int) string {
 
 	b := make([]rune, l)
@@ -34,6 +29,16 @@ func RespondWithError(c *fiber.Ctx, code int, msg string) error {
 	return c.Status(code).JSON(fiber.Map{"error": msg})
 }
 
+// RespondWithFiberError responds with the code and message of a *fiber.Error,
+// and with a 500 for any other error.
+func RespondWithFiberError(c *fiber.Ctx, err error) error {
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return RespondWithError(c, fiberErr.Code, fiberErr.Message)
+	}
+	return RespondWithError(c, 500, err.Error())
+}
+
 func BuildImgURL(imageID string) string {
 	return fmt.Sprintf("%s/%s", baseImgURL, imageID)
 }
@@ -71,55 +76,15 @@ func GetUserID(c *fiber.Ctx) (primitive.ObjectID, error) {
 	return userID, nil
 }
 
-func NewPipeline() *PipelineBuilder {
-	return &PipelineBuilder{stages: mongo.Pipeline{}}
-}
-
-func (pb *PipelineBuilder) Sort(field string, order int) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$sort", bson.D{{field, order}}}})
-	return pb
-}
-
-func (pb *PipelineBuilder) Match(field bson.D) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$match", field}})
-	return pb
-}
-
-func (pb *PipelineBuilder) Skip(n int64) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$skip", n}})
-	return pb
-}
+// APIVersionKey is the locals key under which middleware.APIVersion stores the
+// version of the current route.
+const APIVersionKey = "apiVersion"
 
-func (pb *PipelineBuilder) Limit(n int64) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$limit", n}})
-	return pb
-}
-
-func (pb *PipelineBuilder) Lookup(from, localField, foreignField, as string) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$lookup", bson.D{
-		{"from", from},
-		{"localField", localField},
-		{"foreignField", foreignField},
-		{"as", as},
-	}}})
-
-	return pb
-}
-
-func (pb *PipelineBuilder) Unwind(path string, preserve bool) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$unwind", bson.D{
-		{"path", path},
-		{"preserveNullAndEmptyArrays", preserve},
-	}}})
-
-	return pb
-}
-
-func (pb *PipelineBuilder) Project(fields bson.D) *PipelineBuilder {
-	pb.stages = append(pb.stages, bson.D{{"$project", fields}})
-	return pb
-}
-
-func (pb *PipelineBuilder) Build() mongo.Pipeline {
-	return pb.stages
+// GetAPIVersion returns the API version the request was routed to. Unversioned
+// legacy routes are v1.
+func GetAPIVersion(c *fiber.Ctx) int {
+	if version, ok := c.Locals(APIVersionKey).(int); ok {
+		return version
+	}
+	return 1
 }